@@ -8,6 +8,7 @@ package bytes_test
 
 import (
 	. "bytes"
+	"internal/bytealg"
 	"syscall"
 	"testing"
 )
@@ -98,3 +99,31 @@ func TestIndexNearPageBoundary(t *testing.T) {
 	}
 	q[len(q)-1] = 0
 }
+
+func TestIndexWideNearPageBoundary(t *testing.T) {
+	if bytealg.MaxWideLen == 0 {
+		t.Skip("IndexWide not available")
+	}
+	t.Parallel()
+	q := dangerousSlice(t)
+	q = q[len(q)-bytealg.MaxWideLen:]
+	b := dangerousSlice(t)
+	for i := range b {
+		b[i] = 0
+	}
+	// Match the first and last bytes of q everywhere, so that
+	// q is compared with b at each position, and each block of b,
+	// up to the end of the page, is loaded.
+	for j := 2; j <= len(q); j++ {
+		q := q[len(q)-j:]
+		q[0], q[len(q)-2] = 0, 1
+		for _, s := range [][]byte{b, b[len(b)-300:], b[:300]} {
+			for i := len(s) - 200; i <= len(s); i++ {
+				if idx := bytealg.IndexWide(s[i:], q); idx != -1 {
+					t.Fatalf("IndexWide(b[%d:], q[%d:])=%d, want -1\n", i, len(q)-j, idx)
+				}
+			}
+		}
+		q[len(q)-2] = 0
+	}
+}
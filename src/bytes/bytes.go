@@ -60,15 +60,7 @@ func Count(s, sep []byte) int {
 	if len(sep) == 1 {
 		return bytealg.Count(s, sep[0])
 	}
-	n := 0
-	for {
-		i := Index(s, sep)
-		if i == -1 {
-			return n
-		}
-		n++
-		s = s[i+len(sep):]
-	}
+	return bytealg.CountSep(s, sep)
 }
 
 // Contains reports whether subslice is within b.
@@ -115,21 +107,23 @@ func LastIndex(s, sep []byte) int {
 		return -1
 	case n > len(s):
 		return -1
+	case n >= bytealg.MinSkipLen:
+		return bytealg.LastIndexSkipBytes(s, sep)
 	}
 	// Rabin-Karp search from the end of the string
-	hashss, pow := bytealg.HashStrRevBytes(sep)
+	hashss, pow := bytealg.HashStrRevBytes64(sep)
 	last := len(s) - n
-	var h uint32
+	var h uint64
 	for i := len(s) - 1; i >= last; i-- {
-		h = h*bytealg.PrimeRK + uint32(s[i])
+		h = h*bytealg.PrimeRK64 + uint64(s[i])
 	}
 	if h == hashss && Equal(s[last:], sep) {
 		return last
 	}
 	for i := last - 1; i >= 0; i-- {
-		h *= bytealg.PrimeRK
-		h += uint32(s[i])
-		h -= pow * uint32(s[i+n])
+		h *= bytealg.PrimeRK64
+		h += uint64(s[i])
+		h -= pow * uint64(s[i+n])
 		if h == hashss && Equal(s[i:i+n], sep) {
 			return i
 		}
@@ -157,7 +151,13 @@ func IndexRune(s []byte, r rune) int {
 	case 0 <= r && r < utf8.RuneSelf:
 		return IndexByte(s, byte(r))
 	case r == utf8.RuneError:
-		for i := 0; i < len(s); {
+		// In the prefix of s that is valid UTF-8, which is found a block
+		// at a time, only the encoding of U+FFFD itself can match.
+		valid := bytealg.ValidUTF8Prefix(s)
+		if i := indexRune(s[:valid], utf8.RuneError); i >= 0 {
+			return i
+		}
+		for i := valid; i < len(s); {
 			r1, n := utf8.DecodeRune(s[i:])
 			if r1 == utf8.RuneError {
 				return i
@@ -168,12 +168,77 @@ func IndexRune(s []byte, r rune) int {
 	case !utf8.ValidRune(r):
 		return -1
 	default:
-		var b [utf8.UTFMax]byte
-		n := utf8.EncodeRune(b[:], r)
-		return Index(s, b[:n])
+		return indexRune(s, r)
 	}
 }
 
+// indexRune returns the index of the first instance of the UTF-8
+// encoding of r in s, or -1 if it is not present. It requires that r
+// be a valid rune of at least 2 bytes.
+//
+// Unless s is long enough for bytealg.IndexWide, it looks for the last
+// byte of the encoding with IndexByte, as the first byte of the
+// encodings of most runes is one of a few values.
+// If IndexByte finds too many bytes that do not end an instance, it
+// switches to bytealg.Index if that handles the encoding, or else to
+// a brute-force search, which for so short a needle is faster than
+// Index's general algorithms.
+func indexRune(s []byte, r rune) int {
+	var b [utf8.UTFMax]byte
+	n := utf8.EncodeRune(b[:], r)
+	if n <= bytealg.MaxWideLen && len(s) >= bytealg.MinWideSearch {
+		return bytealg.IndexWide(s, b[:n])
+	}
+	last := n - 1
+	i := last
+	fails := 0
+	for i < len(s) {
+		if s[i] != b[last] {
+			o := IndexByte(s[i+1:], b[last])
+			if o < 0 {
+				return -1
+			}
+			i += o + 1
+		}
+		// Step backwards comparing bytes.
+		for j := 1; j < n; j++ {
+			if s[i-j] != b[last-j] {
+				goto next
+			}
+		}
+		return i - last
+	next:
+		fails++
+		i++
+		if i < len(s) && (n <= bytealg.MaxLen && fails > bytealg.Cutover(i) ||
+			n > bytealg.MaxLen && fails >= 4+i>>4) {
+			goto fallback
+		}
+	}
+	return -1
+
+fallback:
+	if n <= bytealg.MaxLen {
+		if j := bytealg.Index(s[i-last:], b[:n]); j >= 0 {
+			return i + j - last
+		}
+		return -1
+	}
+	c0, c1 := b[last], b[last-1]
+loop:
+	for ; i < len(s); i++ {
+		if s[i] == c0 && s[i-1] == c1 {
+			for k := 2; k < n; k++ {
+				if s[i-k] != b[last-k] {
+					continue loop
+				}
+			}
+			return i - last
+		}
+	}
+	return -1
+}
+
 // IndexAny interprets s as a sequence of UTF-8-encoded Unicode code points.
 // It returns the byte index of the first occurrence in s of any of the Unicode
 // code points in chars. It returns -1 if chars is empty or if there is no code
@@ -207,13 +272,8 @@ func IndexAny(s []byte, chars string) int {
 		return IndexRune(s, r)
 	}
 	if len(s) > 8 {
-		if as, isASCII := makeASCIISet(chars); isASCII {
-			for i, c := range s {
-				if as.contains(c) {
-					return i
-				}
-			}
-			return -1
+		if as, isASCII := bytealg.MakeASCIISet(chars); isASCII {
+			return bytealg.IndexAny(s, &as)
 		}
 	}
 	var width int
@@ -262,13 +322,8 @@ func LastIndexAny(s []byte, chars string) int {
 		return -1
 	}
 	if len(s) > 8 {
-		if as, isASCII := makeASCIISet(chars); isASCII {
-			for i := len(s) - 1; i >= 0; i-- {
-				if as.contains(s[i]) {
-					return i
-				}
-			}
-			return -1
+		if as, isASCII := bytealg.MakeASCIISet(chars); isASCII {
+			return bytealg.LastIndexAny(s, &as)
 		}
 	}
 	if len(s) == 1 {
@@ -403,6 +458,54 @@ func SplitAfter(s, sep []byte) [][]byte {
 	return genSplit(s, sep, len(sep), -1)
 }
 
+// SplitSeq returns an iterator over the subslices of s separated by sep.
+// It yields the same subslices that Split returns, in order, without
+// building the slice of them.
+//
+// Iteration stops early if yield returns false.
+func SplitSeq(s, sep []byte) func(yield func([]byte) bool) {
+	return splitSeq(s, sep, 0)
+}
+
+// SplitAfterSeq returns an iterator over the subslices of s split after
+// each instance of sep. It yields the same subslices that SplitAfter
+// returns, in order, without building the slice of them.
+//
+// Iteration stops early if yield returns false.
+func SplitAfterSeq(s, sep []byte) func(yield func([]byte) bool) {
+	return splitSeq(s, sep, len(sep))
+}
+
+// splitSeq is the iterator form of genSplit with n < 0: it splits after
+// each instance of sep, including sepSave bytes of sep in the subslices.
+func splitSeq(s, sep []byte, sepSave int) func(yield func([]byte) bool) {
+	return func(yield func([]byte) bool) {
+		if len(sep) == 0 {
+			// Split after each UTF-8 sequence, as explode does.
+			for len(s) > 0 {
+				_, size := utf8.DecodeRune(s)
+				if !yield(s[:size:size]) {
+					return
+				}
+				s = s[size:]
+			}
+			return
+		}
+		for {
+			i := Index(s, sep)
+			if i < 0 {
+				break
+			}
+			m := i + sepSave
+			if !yield(s[:m:m]) {
+				return
+			}
+			s = s[i+len(sep):]
+		}
+		yield(s)
+	}
+}
+
 var asciiSpace = [256]uint8{'\t': 1, '\n': 1, '\v': 1, '\f': 1, '\r': 1, ' ': 1}
 
 // Fields interprets s as a sequence of UTF-8-encoded code points.
@@ -602,7 +705,8 @@ func Repeat(b []byte, count int) []byte {
 		panic("bytes: Repeat count causes overflow")
 	}
 
-	nb := make([]byte, len(b)*count)
+	// Every byte of nb is overwritten, so it need not be zeroed first.
+	nb := bytealg.MakeNoZero(len(b) * count)
 	bp := copy(nb, b)
 	for bp < len(nb) {
 		copy(nb[bp:], nb[:bp])
@@ -614,62 +718,42 @@ func Repeat(b []byte, count int) []byte {
 // ToUpper returns a copy of the byte slice s with all Unicode letters mapped to
 // their upper case.
 func ToUpper(s []byte) []byte {
-	isASCII, hasLower := true, false
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= utf8.RuneSelf {
-			isASCII = false
-			break
-		}
-		hasLower = hasLower || ('a' <= c && c <= 'z')
-	}
-
-	if isASCII { // optimize for ASCII-only byte slices.
-		if !hasLower {
-			// Just return a copy.
-			return append([]byte(""), s...)
-		}
-		b := make([]byte, len(s))
-		for i := 0; i < len(s); i++ {
-			c := s[i]
-			if 'a' <= c && c <= 'z' {
-				c -= 'a' - 'A'
-			}
-			b[i] = c
-		}
-		return b
-	}
-	return Map(unicode.ToUpper, s)
+	return changeCase(s, 'a', unicode.ToUpper)
 }
 
 // ToLower returns a copy of the byte slice s with all Unicode letters mapped to
 // their lower case.
 func ToLower(s []byte) []byte {
-	isASCII, hasUpper := true, false
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= utf8.RuneSelf {
-			isASCII = false
-			break
-		}
-		hasUpper = hasUpper || ('A' <= c && c <= 'Z')
-	}
+	return changeCase(s, 'A', unicode.ToLower)
+}
 
-	if isASCII { // optimize for ASCII-only byte slices.
-		if !hasUpper {
-			return append([]byte(""), s...)
-		}
-		b := make([]byte, len(s))
-		for i := 0; i < len(s); i++ {
-			c := s[i]
-			if 'A' <= c && c <= 'Z' {
-				c += 'a' - 'A'
-			}
-			b[i] = c
+// changeCase returns a copy of s with all Unicode letters mapped by
+// mapping, which changes the case of the 26 ASCII letters starting at
+// lo and of no other ASCII bytes.
+func changeCase(s []byte, lo byte, mapping func(rune) rune) []byte {
+	i := bytealg.IndexCaseASCII(s, lo)
+	if i < 0 {
+		// Just return a copy.
+		return append([]byte(""), s...)
+	}
+	if s[i] >= utf8.RuneSelf {
+		return Map(mapping, s)
+	}
+	// Change the ASCII bytes of s a block at a time, up to the
+	// first that is not ASCII, and the rest a rune at a time.
+	b := make([]byte, len(s))
+	copy(b, s[:i])
+	n := i + bytealg.ChangeCaseASCII(b[i:], s[i:], lo)
+	b = b[:n]
+	for n < len(s) {
+		r, wid := rune(s[n]), 1
+		if r >= utf8.RuneSelf {
+			r, wid = utf8.DecodeRune(s[n:])
 		}
-		return b
+		b = utf8.AppendRune(b, mapping(r))
+		n += wid
 	}
-	return Map(unicode.ToLower, s)
+	return b
 }
 
 // ToTitle treats s as UTF-8-encoded bytes and returns a copy with all the Unicode letters mapped to their title case.
@@ -1036,31 +1120,37 @@ func trimRightUnicode(s []byte, cutset string) []byte {
 // TrimSpace returns a subslice of s by slicing off all leading and
 // trailing white space, as defined by Unicode.
 func TrimSpace(s []byte) []byte {
-	// Fast path for ASCII: look for the first ASCII non-space byte
+	// Fast path for ASCII: look for the first ASCII non-space byte,
+	// a block of bytes at a time past the first few.
 	start := 0
-	for ; start < len(s); start++ {
-		c := s[start]
-		if c >= utf8.RuneSelf {
-			// If we run into a non-ASCII byte, fall back to the
-			// slower unicode-aware method on the remaining bytes
-			return TrimFunc(s[start:], unicode.IsSpace)
-		}
-		if asciiSpace[c] == 0 {
+	for ; start < len(s) && asciiSpace[s[start]] != 0; start++ {
+		if start == 16 {
+			if start = bytealg.IndexNonSpaceASCII(s); start < 0 {
+				// Special case to preserve previous TrimLeftFunc behavior,
+				// returning nil instead of empty slice if all spaces.
+				return nil
+			}
 			break
 		}
 	}
+	if start < len(s) && s[start] >= utf8.RuneSelf {
+		// If we run into a non-ASCII byte, fall back to the
+		// slower unicode-aware method on the remaining bytes
+		return TrimFunc(s[start:], unicode.IsSpace)
+	}
 
-	// Now look for the first ASCII non-space byte from the end
+	// Now look for the last ASCII non-space byte likewise. There is
+	// one, at start, unless s is all spaces.
 	stop := len(s)
-	for ; stop > start; stop-- {
-		c := s[stop-1]
-		if c >= utf8.RuneSelf {
-			return TrimFunc(s[start:stop], unicode.IsSpace)
-		}
-		if asciiSpace[c] == 0 {
+	for ; stop > start && asciiSpace[s[stop-1]] != 0; stop-- {
+		if stop == len(s)-16 {
+			stop = start + bytealg.LastIndexNonSpaceASCII(s[start:]) + 1
 			break
 		}
 	}
+	if stop > start && s[stop-1] >= utf8.RuneSelf {
+		return TrimFunc(s[start:stop], unicode.IsSpace)
+	}
 
 	// At this point s[start:stop] starts and ends with an ASCII
 	// non-space bytes, so we're done. Non-ASCII cases have already
@@ -1209,6 +1299,8 @@ func Index(s, sep []byte) int {
 		return -1
 	case n > len(s):
 		return -1
+	case n <= bytealg.MaxWideLen && len(s) >= bytealg.MinWideSearch:
+		return bytealg.IndexWide(s, sep)
 	case n <= bytealg.MaxLen:
 		// Use brute force when s and sep both are small
 		if len(s) <= bytealg.MaxBruteForce {
@@ -1265,14 +1357,16 @@ func Index(s, sep []byte) int {
 		fails++
 		if fails >= 4+i>>4 && i < t {
 			// Give up on IndexByte, it isn't skipping ahead
-			// far enough to be better than Rabin-Karp.
+			// far enough to be better than Two-Way search,
+			// which, unlike repeated calls to Equal, takes time
+			// linear in len(s) whatever s and sep hold.
 			// Experiments (using IndexPeriodic) suggest
 			// the cutover is about 16 byte skips.
 			// TODO: if large prefixes of sep are matching
 			// we should cutover at even larger average skips,
 			// because Equal becomes that much more expensive.
 			// This code does not take that effect into account.
-			j := bytealg.IndexRabinKarpBytes(s[i:], sep)
+			j := bytealg.IndexTwoWayBytes(s[i:], sep)
 			if j < 0 {
 				return -1
 			}
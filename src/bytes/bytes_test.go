@@ -7,6 +7,7 @@ package bytes_test
 import (
 	. "bytes"
 	"fmt"
+	"internal/bytealg"
 	"internal/testenv"
 	"math/rand"
 	"reflect"
@@ -143,7 +144,7 @@ var indexTests = []BinOpTest{
 	{"oooooooooooooooooooooo", "r", -1},
 	{"oxoxoxoxoxoxoxoxoxoxoxoy", "oy", 22},
 	{"oxoxoxoxoxoxoxoxoxoxoxox", "oy", -1},
-	// test fallback to Rabin-Karp.
+	// test fallback to Two-Way search.
 	{"000000000000000000000000000000000000000000000000000000000000000000000001", "0000000000000000000000000000000000000000000000000000000000000000001", 5},
 }
 
@@ -289,6 +290,64 @@ func TestLastIndexByte(t *testing.T) {
 	}
 }
 
+// TestVectorLengths checks IndexByte, Count, Equal and Compare on
+// inputs that end just before, at and just after multiples of the
+// vector lengths that the implementations may use, from 16 bytes for
+// NEON to 256 bytes for the longest SVE vectors.
+func TestVectorLengths(t *testing.T) {
+	var lengths []int
+	for v := 16; v <= 256; v *= 2 {
+		for _, n := range []int{v - 1, v, v + 1, 2*v - 1, 2 * v, 2*v + 1, 3*v + 5} {
+			lengths = append(lengths, n)
+		}
+	}
+	for _, n := range lengths {
+		a := make([]byte, n)
+		b := make([]byte, n)
+		for i := range a {
+			a[i] = byte(i%7) + 1
+		}
+		copy(b, a)
+		if got := IndexByte(a, 0); got != -1 {
+			t.Errorf("len %d: IndexByte(no match) = %d, want -1", n, got)
+		}
+		if got := Count(a, []byte{0}); got != 0 {
+			t.Errorf("len %d: Count(no match) = %d, want 0", n, got)
+		}
+		if !Equal(a, b) || Compare(a, b) != 0 {
+			t.Errorf("len %d: equal inputs compare unequal", n)
+		}
+		for i := 0; i < n; i++ {
+			a[i] = 0
+			if got := IndexByte(a, 0); got != i {
+				t.Errorf("len %d: IndexByte(match at %d) = %d", n, i, got)
+			}
+			if got := Count(a, []byte{0}); got != 1 {
+				t.Errorf("len %d: Count(match at %d) = %d, want 1", n, i, got)
+			}
+			if Equal(a, b) {
+				t.Errorf("len %d: Equal(differ at %d) = true", n, i)
+			}
+			if got := Compare(a, b); got != -1 {
+				t.Errorf("len %d: Compare(differ at %d) = %d, want -1", n, i, got)
+			}
+			if got := Compare(b, a); got != 1 {
+				t.Errorf("len %d: Compare(differ at %d, reversed) = %d, want 1", n, i, got)
+			}
+			if got := Compare(b[:i], a[:i+1]); got != -1 {
+				t.Errorf("len %d: Compare(prefix of %d) = %d, want -1", n, i, got)
+			}
+			a[i] = b[i]
+		}
+		for i := range a {
+			a[i] = 0
+		}
+		if got := Count(a, []byte{0}); got != n {
+			t.Errorf("len %d: Count(all match) = %d, want %d", n, got, n)
+		}
+	}
+}
+
 // test a larger buffer with different sizes and alignments
 func TestIndexByteBig(t *testing.T) {
 	var n = 1024
@@ -401,6 +460,16 @@ func TestIndexRune(t *testing.T) {
 		{"☻x\xe2\x98", '�', len("☻x")},
 		{"☻x\xe2\x98�", '�', len("☻x")},
 		{"☻x\xe2\x98x", '�', len("☻x")},
+		{strings.Repeat("☺", 20) + "x�", '�', 3*20 + 1},
+		{strings.Repeat("☺", 20) + "\xe2\x98x�", '�', 3 * 20},
+		{strings.Repeat("x", 40) + "\x80" + strings.Repeat("x", 40), '�', 40},
+
+		// Many bytes equal to the last byte of the rune's encoding.
+		{strings.Repeat("\xba", 500) + "☺", '☺', 500},
+		{strings.Repeat("\x98\xba", 500) + "☺", '☺', 1000},
+		{strings.Repeat("\xba", 500), '☺', -1},
+		{strings.Repeat("\x84\x9e", 500) + "𝄞", '𝄞', 1000},
+		{strings.Repeat("\x9d\x84\x9e", 500) + "𝄞", '𝄞', 1500},
 
 		// Invalid rune values should never match.
 		{"a☺b☻c☹d\xe2\x98�\xff�\xed\xa0\x80", -1, -1},
@@ -427,6 +496,38 @@ func TestIndexRune(t *testing.T) {
 	}
 }
 
+func TestIndexRuneExhaustive(t *testing.T) {
+	// Haystacks of pieces of the encodings of the runes, invalid
+	// bytes and ASCII, long enough to switch searches.
+	runes := []rune{'é', '☺', '𝄞', utf8.RuneError}
+	pieces := []string{"é", "☺", "𝄞", "\uFFFD", "\xa9", "\xba", "\x98", "\x9e", "\xf0\x9d\x84", "a"}
+	naive := func(s string, r rune) int {
+		for i, c := range s {
+			if c == r {
+				return i
+			}
+		}
+		return -1
+	}
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 2000; n++ {
+		var sb strings.Builder
+		for l := rng.Intn(100); l > 0; l-- {
+			p := pieces[rng.Intn(len(pieces))]
+			if rng.Intn(4) > 0 {
+				p = pieces[4+rng.Intn(len(pieces)-4)]
+			}
+			sb.WriteString(p)
+		}
+		s := sb.String()
+		for _, r := range runes {
+			if got, want := IndexRune([]byte(s), r), naive(s, r); got != want {
+				t.Fatalf("IndexRune(%q, %q) = %d; want %d", s, r, got, want)
+			}
+		}
+	}
+}
+
 // test count of a single byte across page offsets
 func TestCountByte(t *testing.T) {
 	b := make([]byte, 5015) // bigger than a page
@@ -492,6 +593,71 @@ func TestCountByteNoMatch(t *testing.T) {
 	}
 }
 
+// Make sure bytes that differ from the one counted only in
+// their top or bottom bits are not counted.
+func TestCountByteNeighbors(t *testing.T) {
+	for _, c := range []byte{0, 1, '\n', 0x7f, 0x80, 0xfe, 0xff} {
+		others := []byte{c, c ^ 0x80, c ^ 0x7f, c ^ 0x01, c ^ 0xff}
+		b := make([]byte, 100)
+		for i := range b {
+			b[i] = others[(i*i+i/3)%len(others)]
+		}
+		for i := 0; i < len(b); i++ {
+			for j := i; j <= len(b); j += 7 {
+				want := 0
+				for _, x := range b[i:j] {
+					if x == c {
+						want++
+					}
+				}
+				if got := Count(b[i:j], []byte{c}); got != want {
+					t.Fatalf("Count(%q, %q) = %d, want %d", b[i:j], c, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCountSep(t *testing.T) {
+	// Long inputs take each of the searches Count switches between:
+	// the wide search of long slices, the search after too many
+	// false first-byte matches, and Two-Way search for long seps.
+	tests := []struct{ s, sep string }{
+		{"abc1231231123q", "123"},
+		{"11111", "11"},
+		{strings.Repeat("a", 10000), "ab"},
+		{strings.Repeat("a", 10000) + "b", "ab"},
+		{strings.Repeat("ab", 10000), "ab"},
+		{strings.Repeat("aab", 10000), "aab"},
+		{strings.Repeat("aab", 10000), "aba"},
+		{strings.Repeat("some line of text\r\n", 1000), "\r\n"},
+		{strings.Repeat("ABC", 1<<12) + "123" + strings.Repeat("ABC", 1<<12), "ABC123ABC"},
+		{strings.Repeat("ABC", 1<<12), strings.Repeat("ABC", 1<<5)},
+		{strings.Repeat("a", 10000) + strings.Repeat("a", 100) + "b", strings.Repeat("a", 100) + "b"},
+		{strings.Repeat(strings.Repeat("a", 99)+"b", 200), strings.Repeat("a", 100)},
+	}
+	for _, tt := range tests {
+		for _, s := range []string{tt.s, tt.s[1:], tt.s[:len(tt.s)/3]} {
+			want := 0
+			for rest := s; ; want++ {
+				i := strings.Index(rest, tt.sep)
+				if i < 0 {
+					break
+				}
+				rest = rest[i+len(tt.sep):]
+			}
+			if got := Count([]byte(s), []byte(tt.sep)); got != want {
+				t.Errorf("Count(%.20q (len %d), %.20q (len %d)) = %d, want %d",
+					s, len(s), tt.sep, len(tt.sep), got, want)
+			}
+			if got := len(Split([]byte(s), []byte(tt.sep))); got != want+1 {
+				t.Errorf("len(Split(%.20q (len %d), %.20q (len %d))) = %d, want %d",
+					s, len(s), tt.sep, len(tt.sep), got, want+1)
+			}
+		}
+	}
+}
+
 var bmbuf []byte
 
 func valName(x int) string {
@@ -757,6 +923,9 @@ func TestSplit(t *testing.T) {
 			if !reflect.DeepEqual(a, b) {
 				t.Errorf("Split disagrees withSplitN(%q, %q, %d) = %v; want %v", tt.s, tt.sep, tt.n, b, a)
 			}
+			if b := collect(SplitSeq([]byte(tt.s), []byte(tt.sep))); !eq(sliceOfString(b), tt.a) {
+				t.Errorf("SplitSeq(%q, %q) yielded %q; want %q", tt.s, tt.sep, b, tt.a)
+			}
 		}
 		if len(a) > 0 {
 			in, out := a[0], s
@@ -812,6 +981,37 @@ func TestSplitAfter(t *testing.T) {
 			if !reflect.DeepEqual(a, b) {
 				t.Errorf("SplitAfter disagrees withSplitAfterN(%q, %q, %d) = %v; want %v", tt.s, tt.sep, tt.n, b, a)
 			}
+			if b := collect(SplitAfterSeq([]byte(tt.s), []byte(tt.sep))); !eq(sliceOfString(b), tt.a) {
+				t.Errorf("SplitAfterSeq(%q, %q) yielded %q; want %q", tt.s, tt.sep, b, tt.a)
+			}
+		}
+	}
+}
+
+// collect returns the subslices seq yields, appending to each
+// to check that doing so does not change the next.
+func collect(seq func(yield func([]byte) bool)) [][]byte {
+	a := [][]byte{}
+	seq(func(b []byte) bool {
+		a = append(a, b)
+		_ = append(b, 'z')
+		return true
+	})
+	return a
+}
+
+func TestSplitSeqStop(t *testing.T) {
+	for _, seq := range []func(func([]byte) bool){
+		SplitSeq([]byte("a,b,c"), []byte(",")),
+		SplitSeq([]byte("abc"), nil),
+	} {
+		var a []string
+		seq(func(b []byte) bool {
+			a = append(a, string(b))
+			return string(b) != "b"
+		})
+		if want := []string{"a", "b"}; !eq(a, want) {
+			t.Errorf("yielded %q before stopping; want %q", a, want)
 		}
 	}
 }
@@ -922,6 +1122,9 @@ var upperTests = []StringTest{
 	{"long\u0250string\u0250with\u0250nonascii\u2C6Fchars", []byte("LONG\u2C6FSTRING\u2C6FWITH\u2C6FNONASCII\u2C6FCHARS")},
 	{"\u0250\u0250\u0250\u0250\u0250", []byte("\u2C6F\u2C6F\u2C6F\u2C6F\u2C6F")}, // grows one byte per char
 	{"a\u0080\U0010FFFF", []byte("A\u0080\U0010FFFF")},                           // test utf8.RuneSelf and utf8.MaxRune
+	{"the quick brown fox jumps over the lazy dog", []byte("THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG")},
+	{"the quick brown fox jumps over the lazy dog\u0250", []byte("THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG\u2C6F")},
+	{"the quick brown fox jumps over the lazy dog\xffab", []byte("THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG\uFFFDAB")},
 }
 
 var lowerTests = []StringTest{
@@ -933,6 +1136,9 @@ var lowerTests = []StringTest{
 	{"LONG\u2C6FSTRING\u2C6FWITH\u2C6FNONASCII\u2C6FCHARS", []byte("long\u0250string\u0250with\u0250nonascii\u0250chars")},
 	{"\u2C6D\u2C6D\u2C6D\u2C6D\u2C6D", []byte("\u0251\u0251\u0251\u0251\u0251")}, // shrinks one byte per char
 	{"A\u0080\U0010FFFF", []byte("a\u0080\U0010FFFF")},                           // test utf8.RuneSelf and utf8.MaxRune
+	{"THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG", []byte("the quick brown fox jumps over the lazy dog")},
+	{"THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG\u2C6F", []byte("the quick brown fox jumps over the lazy dog\u0250")},
+	{"THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG\xffAB", []byte("the quick brown fox jumps over the lazy dog\uFFFDab")},
 }
 
 const space = "\t\v\r\f\n\u0085\u00a0\u2000\u3000"
@@ -1059,6 +1265,28 @@ func TestToUpper(t *testing.T) { runStringTests(t, ToUpper, "ToUpper", upperTest
 
 func TestToLower(t *testing.T) { runStringTests(t, ToLower, "ToLower", lowerTests) }
 
+func TestToUpperLowerASCIIBlocks(t *testing.T) {
+	// Put each of the bytes that ToUpper and ToLower treat differently
+	// at each position of slices around the block sizes of bytealg's
+	// IndexCaseASCII and ChangeCaseASCII.
+	special := []string{"@", "A", "Z", "[", "`", "a", "z", "{", "\x7f", "\xff", "\u0250", "\u2C6F"}
+	for n := 0; n <= 100; n++ {
+		for _, base := range []string{strings.Repeat("q", n), strings.Repeat("Q", n), strings.Repeat("0", n)} {
+			for i := 0; i <= n; i++ {
+				for _, c := range special {
+					s := []byte(base[:i] + c + base[i:])
+					if got, want := ToUpper(s), Map(unicode.ToUpper, s); !Equal(got, want) {
+						t.Fatalf("ToUpper(%q) = %q, want %q", s, got, want)
+					}
+					if got, want := ToLower(s), Map(unicode.ToLower, s); !Equal(got, want) {
+						t.Fatalf("ToLower(%q) = %q, want %q", s, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
 func BenchmarkToUpper(b *testing.B) {
 	for _, tc := range upperTests {
 		tin := []byte(tc.in)
@@ -1119,6 +1347,24 @@ func TestToValidUTF8(t *testing.T) {
 
 func TestTrimSpace(t *testing.T) { runStringTests(t, TrimSpace, "TrimSpace", trimSpaceTests) }
 
+func TestTrimSpaceASCIIBlocks(t *testing.T) {
+	// Runs of white space around the block size of bytealg's
+	// IndexNonSpaceASCII and LastIndexNonSpaceASCII, next to
+	// non-space bytes, ASCII and not.
+	for n := 0; n <= 40; n++ {
+		for _, ws := range []string{strings.Repeat(" ", n), strings.Repeat("\t\n\v\f\r ", n)[:n]} {
+			for _, inner := range []string{"", "x", "x y", "\x1f", "!", "\xff", "\u2000", "\u3000x\u0085", "é"} {
+				for _, s := range []string{ws + inner, inner + ws, ws + inner + ws, ws + inner + ws + inner + ws} {
+					got, want := TrimSpace([]byte(s)), TrimFunc([]byte(s), unicode.IsSpace)
+					if !Equal(got, want) || (got == nil) != (want == nil) {
+						t.Fatalf("TrimSpace(%q) = %#v, want %#v", s, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
 type RepeatTest struct {
 	in, out string
 	count   int
@@ -1849,6 +2095,7 @@ func BenchmarkTrimSpace(b *testing.B) {
 		{"ASCII", []byte("  foo bar  ")},
 		{"SomeNonASCII", []byte("    \u2000\t\r\n x\t\t\r\r\ny\n \u3000    ")},
 		{"JustNonASCII", []byte("\u2000\u2000\u2000☺☺☺☺\u3000\u3000\u3000")},
+		{"LongASCII", []byte(strings.Repeat(" ", 100) + "foo bar" + strings.Repeat("\t\r\n", 100))},
 	}
 	for _, test := range tests {
 		b.Run(test.name, func(b *testing.B) {
@@ -1926,6 +2173,9 @@ func BenchmarkIndexHard4(b *testing.B) {
 func BenchmarkLastIndexHard1(b *testing.B) { benchmarkLastIndexHard(b, []byte("<>")) }
 func BenchmarkLastIndexHard2(b *testing.B) { benchmarkLastIndexHard(b, []byte("</pre>")) }
 func BenchmarkLastIndexHard3(b *testing.B) { benchmarkLastIndexHard(b, []byte("<b>hello world</b>")) }
+func BenchmarkLastIndexHard4(b *testing.B) {
+	benchmarkLastIndexHard(b, []byte("<pre><b>hello</b><strong>world</strong></pre>"))
+}
 
 func BenchmarkCountHard1(b *testing.B) { benchmarkCountHard(b, []byte("<>")) }
 func BenchmarkCountHard2(b *testing.B) { benchmarkCountHard(b, []byte("</pre>")) }
@@ -1971,6 +2221,25 @@ func BenchmarkRepeat(b *testing.B) {
 	}
 }
 
+func BenchmarkRepeatLarge(b *testing.B) {
+	s := Repeat([]byte("@"), 8*1024)
+	for j := 8; j <= 24; j += 4 {
+		for _, k := range []int{1, 16, 4097} {
+			s := s[:k]
+			n := (1 << j) / k
+			if n == 0 {
+				continue
+			}
+			b.Run(fmt.Sprintf("%d/%d", 1<<j, k), func(b *testing.B) {
+				b.SetBytes(int64(n * len(s)))
+				for i := 0; i < b.N; i++ {
+					bmbuf = Repeat(s, n)
+				}
+			})
+		}
+	}
+}
+
 func BenchmarkBytesCompare(b *testing.B) {
 	for n := 1; n <= 2048; n <<= 1 {
 		b.Run(fmt.Sprint(n), func(b *testing.B) {
@@ -2084,3 +2353,116 @@ func BenchmarkIndexPeriodic(b *testing.B) {
 		})
 	}
 }
+
+func FuzzIndexTwoWay(f *testing.F) {
+	f.Add([]byte("oxoxoxoxoxoxoxoxoxoxoxoy"), []byte("oy"))
+	f.Add([]byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaab"), []byte("aaaaaaaab"))
+	f.Add([]byte("abababababcabababababc"), []byte("ababababc"))
+	f.Fuzz(func(t *testing.T, s, sep []byte) {
+		want := -1
+		for i := 0; i+len(sep) <= len(s); i++ {
+			if Equal(s[i:i+len(sep)], sep) {
+				want = i
+				break
+			}
+		}
+		if got := bytealg.IndexTwoWayBytes(s, sep); got != want {
+			t.Errorf("IndexTwoWayBytes(%q, %q) = %d, want %d", s, sep, got, want)
+		}
+		if got := Index(s, sep); got != want {
+			t.Errorf("Index(%q, %q) = %d, want %d", s, sep, got, want)
+		}
+	})
+}
+
+func FuzzLastIndexTwoWay(f *testing.F) {
+	f.Add([]byte("oyoxoxoxoxoxoxoxoxoxoxox"), []byte("oy"))
+	f.Add([]byte("baaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"), []byte("baaaaaaaa"))
+	f.Add([]byte("cbababababacbababababa"), []byte("cbababababa"))
+	f.Fuzz(func(t *testing.T, s, sep []byte) {
+		want := -1
+		for i := len(s) - len(sep); i >= 0; i-- {
+			if Equal(s[i:i+len(sep)], sep) {
+				want = i
+				break
+			}
+		}
+		if got := bytealg.LastIndexTwoWayBytes(s, sep); got != want {
+			t.Errorf("LastIndexTwoWayBytes(%q, %q) = %d, want %d", s, sep, got, want)
+		}
+		if got := LastIndex(s, sep); got != want {
+			t.Errorf("LastIndex(%q, %q) = %d, want %d", s, sep, got, want)
+		}
+	})
+}
+
+// BenchmarkIndexHashCollision searches for a needle ending in a
+// Thue-Morse string in a haystack made of copies of the needle with
+// that suffix complemented. Each copy has the same 32-bit Rabin-Karp
+// hash as the needle but differs from it only at the end.
+func BenchmarkIndexHashCollision(b *testing.B) {
+	tm, ctm := []byte("a"), []byte("b")
+	for i := 0; i < 8; i++ {
+		tm, ctm = append(tm[:len(tm):len(tm)], ctm...), append(ctm[:len(ctm):len(ctm)], tm...)
+	}
+	prefix := Repeat([]byte("x"), 1024)
+	sep := append(prefix[:len(prefix):len(prefix)], tm...)
+	s := Repeat(append(prefix[:len(prefix):len(prefix)], ctm...), 64)
+	b.Run("IndexRabinKarpBytes", func(b *testing.B) {
+		b.SetBytes(int64(len(s)))
+		for i := 0; i < b.N; i++ {
+			bytealg.IndexRabinKarpBytes(s, sep)
+		}
+	})
+	b.Run("IndexRabinKarpBytes64", func(b *testing.B) {
+		b.SetBytes(int64(len(s)))
+		for i := 0; i < b.N; i++ {
+			bytealg.IndexRabinKarpBytes64(s, sep)
+		}
+	})
+	b.Run("LastIndex", func(b *testing.B) {
+		b.SetBytes(int64(len(s)))
+		for i := 0; i < b.N; i++ {
+			LastIndex(s, sep)
+		}
+	})
+}
+
+func TestIndexAnyASCIISet(t *testing.T) {
+	// The background bytes share a low or high nibble with
+	// the members of the set, or are not ASCII.
+	const chars = "/?#:"
+	const background = "O_\x8f\xaf\x0f\x3b\xbf"
+	naive := func(s []byte, last bool) int {
+		r := -1
+		for i, c := range s {
+			if IndexByte([]byte(chars), c) >= 0 {
+				if !last {
+					return i
+				}
+				r = i
+			}
+		}
+		return r
+	}
+	for n := 0; n < 70; n++ {
+		for i := -1; i < n; i++ {
+			s := make([]byte, n)
+			for j := range s {
+				s[j] = background[j%len(background)]
+			}
+			if i >= 0 {
+				s[i] = chars[i%len(chars)]
+				if i+5 < n {
+					s[i+5] = chars[(i+1)%len(chars)]
+				}
+			}
+			if got, want := IndexAny(s, chars), naive(s, false); got != want {
+				t.Fatalf("IndexAny(%q, %q) = %d, want %d", s, chars, got, want)
+			}
+			if got, want := LastIndexAny(s, chars), naive(s, true); got != want {
+				t.Fatalf("LastIndexAny(%q, %q) = %d, want %d", s, chars, got, want)
+			}
+		}
+	}
+}
@@ -120,6 +120,35 @@ func TestCompareBytes(t *testing.T) {
 	}
 }
 
+func TestCompareFirstDifference(t *testing.T) {
+	// The first differing byte decides the result, however the bytes
+	// after it differ, whether in the same word, the same block of
+	// words, or in bytes that an implementation compares first.
+	a := make([]byte, 200)
+	for i := range a {
+		a[i] = byte(1 + 31*i%254)
+	}
+	b := make([]byte, len(a))
+	for n := 1; n <= len(a); n++ {
+		for k := 0; k < n; k++ {
+			for _, j := range []int{k + 1, k + 7, k + 8, k + 15, k + 31, n - 1} {
+				if j <= k || j >= n {
+					continue
+				}
+				copy(b, a)
+				b[k] = a[k] + 1
+				b[j] = a[j] - 1
+				if cmp := Compare(a[:n], b[:n]); cmp != -1 {
+					t.Errorf("Compare(a, b) with b[%d] bigger, b[%d] smaller, len %d = %d, want -1", k, j, n, cmp)
+				}
+				if cmp := Compare(b[:n], a[:n]); cmp != 1 {
+					t.Errorf("Compare(b, a) with b[%d] bigger, b[%d] smaller, len %d = %d, want 1", k, j, n, cmp)
+				}
+			}
+		}
+	}
+}
+
 func TestEndianBaseCompare(t *testing.T) {
 	// This test compares byte slices that are almost identical, except one
 	// difference that for some j, a[j]>b[j] and a[j+1]<b[j+1]. If the implementation
@@ -98,6 +98,11 @@ func mkzosarch(dir, file string) {
 //
 //	package build
 //  var cgoEnabled = map[string]bool{}
+//  var distPorts = map[string]bool{}
+//
+// cgoEnabled lists the platforms that support cgo, and distPorts lists
+// the platforms listed by 'go tool dist list', each mapped to whether
+// it is a first class port.
 //
 // It is invoked to write go/build/zcgo.go.
 func mkzcgo(dir, file string) {
@@ -123,5 +128,20 @@ func mkzcgo(dir, file string) {
 	}
 	fmt.Fprintf(&buf, "}\n")
 
+	list = list[:0]
+	for plat := range cgoEnabled {
+		if !incomplete[plat] {
+			list = append(list, plat)
+		}
+	}
+	sort.Strings(list)
+
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "var distPorts = map[string]bool{\n")
+	for _, plat := range list {
+		fmt.Fprintf(&buf, "\t%q: %v,\n", plat, firstClass[plat])
+	}
+	fmt.Fprintf(&buf, "}\n")
+
 	writefile(buf.String(), file, writeSkipSame)
 }
@@ -14,6 +14,7 @@ import (
 	"go/build"
 	"go/scanner"
 	"go/token"
+	"internal/cgoflags"
 	"internal/goroot"
 	"io/fs"
 	"os"
@@ -27,7 +28,6 @@ import (
 	"strings"
 	"time"
 	"unicode"
-	"unicode/utf8"
 
 	"cmd/go/internal/base"
 	"cmd/go/internal/cfg"
@@ -2441,18 +2441,9 @@ func (p *Package) setBuildInfo(includeVCS bool) {
 // SafeArg reports whether arg is a "safe" command-line argument,
 // meaning that when it appears in a command-line, it probably
 // doesn't have some special meaning other than its own name.
-// Obviously args beginning with - are not safe (they look like flags).
-// Less obviously, args beginning with @ are not safe (they look like
-// GNU binutils flagfile specifiers, sometimes called "response files").
-// To be conservative, we reject almost any arg beginning with non-alphanumeric ASCII.
-// We accept leading . _ and / as likely in file system paths.
-// There is a copy of this function in cmd/compile/internal/gc/noder.go.
+// See internal/cgoflags.SafeArg, which it calls.
 func SafeArg(name string) bool {
-	if name == "" {
-		return false
-	}
-	c := name[0]
-	return '0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || c == '.' || c == '_' || c == '/' || c >= utf8.RuneSelf
+	return cgoflags.SafeArg(name)
 }
 
 // LinkerDeps returns the list of linker-induced dependencies for main package p.
@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"internal/cgoflags"
 	exec "internal/execabs"
 	"internal/lazyregexp"
 	"io"
@@ -1408,67 +1409,6 @@ func (b *Builder) PkgconfigCmd() string {
 	return envList("PKG_CONFIG", cfg.DefaultPkgConfig)[0]
 }
 
-// splitPkgConfigOutput parses the pkg-config output into a slice of
-// flags. This implements the algorithm from pkgconf/libpkgconf/argvsplit.c.
-func splitPkgConfigOutput(out []byte) ([]string, error) {
-	if len(out) == 0 {
-		return nil, nil
-	}
-	var flags []string
-	flag := make([]byte, 0, len(out))
-	escaped := false
-	quote := byte(0)
-
-	for _, c := range out {
-		if escaped {
-			if quote != 0 {
-				switch c {
-				case '$', '`', '"', '\\':
-				default:
-					flag = append(flag, '\\')
-				}
-				flag = append(flag, c)
-			} else {
-				flag = append(flag, c)
-			}
-			escaped = false
-		} else if quote != 0 {
-			if c == quote {
-				quote = 0
-			} else {
-				switch c {
-				case '\\':
-					escaped = true
-				default:
-					flag = append(flag, c)
-				}
-			}
-		} else if strings.IndexByte(" \t\n\v\f\r", c) < 0 {
-			switch c {
-			case '\\':
-				escaped = true
-			case '\'', '"':
-				quote = c
-			default:
-				flag = append(flag, c)
-			}
-		} else if len(flag) != 0 {
-			flags = append(flags, string(flag))
-			flag = flag[:0]
-		}
-	}
-	if escaped {
-		return nil, errors.New("broken character escaping in pkgconf output ")
-	}
-	if quote != 0 {
-		return nil, errors.New("unterminated quoted string in pkgconf output ")
-	} else if len(flag) != 0 {
-		flags = append(flags, string(flag))
-	}
-
-	return flags, nil
-}
-
 // Calls pkg-config if needed and returns the cflags/ldflags needed to build the package.
 func (b *Builder) getPkgConfigFlags(p *load.Package) (cflags, ldflags []string, err error) {
 	if pcargs := p.CgoPkgConfig; len(pcargs) > 0 {
@@ -1498,7 +1438,7 @@ func (b *Builder) getPkgConfigFlags(p *load.Package) (cflags, ldflags []string,
 			return nil, nil, errPrintedOutput
 		}
 		if len(out) > 0 {
-			cflags, err = splitPkgConfigOutput(out)
+			cflags, err = cgoflags.SplitPkgConfigOutput(out)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -0,0 +1,108 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// An ImportSpec names a package to be imported by ImportPackages.
+// Its fields are the corresponding arguments to Import.
+type ImportSpec struct {
+	Path   string // import path
+	SrcDir string // directory for interpreting local import paths
+}
+
+// ImportPackages imports the packages named by specs concurrently and
+// returns them in the same order, so that pkgs[i] is the package that
+// Import(specs[i].Path, specs[i].SrcDir, mode) would return. Specs that
+// are equal are imported only once, sharing the resulting *Package and,
+// in module mode, the invocation of the go command that locates it.
+//
+// If ctxt.Cache is nil and the context uses the local file system,
+// ImportPackages uses a new Cache for the duration of the call, so
+// that directories shared by the packages are read only once.
+//
+// ImportPackages returns the first error, in the order of specs, that
+// Import returned, along with all of the packages. If ctx is canceled
+// before all of the packages have been imported, ImportPackages returns
+// ctx.Err(), and the packages not yet imported are nil.
+func (ctxt *Context) ImportPackages(ctx context.Context, specs []ImportSpec, mode ImportMode) ([]*Package, error) {
+	pkgs, errs := ctxt.importPackages(ctx, specs, mode)
+	if err := ctx.Err(); err != nil {
+		return pkgs, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return pkgs, err
+		}
+	}
+	return pkgs, nil
+}
+
+// minGoListWorkers is the least number of packages that importPackages
+// imports at once when the context has a GoList.
+const minGoListWorkers = 8
+
+// importPackages is like ImportPackages but returns the error
+// from importing each of the packages.
+func (ctxt *Context) importPackages(ctx context.Context, specs []ImportSpec, mode ImportMode) ([]*Package, []error) {
+	c := *ctxt
+	if c.Cache == nil && c.FS == nil && c.ReadDir == nil && c.ReadDirEntries == nil && c.OpenFile == nil {
+		c.Cache = new(Cache)
+	}
+
+	// Import each distinct spec once, at the index of its first use.
+	first := make(map[ImportSpec]int)
+	var todo []int
+	for i, spec := range specs {
+		if _, ok := first[spec]; !ok {
+			first[spec] = i
+			todo = append(todo, i)
+		}
+	}
+
+	pkgs := make([]*Package, len(specs))
+	errs := make([]error, len(specs))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	n := runtime.GOMAXPROCS(0)
+	if c.GoList != nil && n < minGoListWorkers {
+		// Workers waiting for the go command use no CPU, and the more
+		// of them wait at once, the more lookups GoList can combine.
+		n = minGoListWorkers
+	}
+	if n > len(todo) {
+		n = len(todo)
+	}
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				pkgs[i], errs[i] = c.Import(specs[i].Path, specs[i].SrcDir, mode)
+			}
+		}()
+	}
+Feed:
+	for _, i := range todo {
+		select {
+		case work <- i:
+		case <-ctx.Done():
+			break Feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	for i, spec := range specs {
+		if j := first[spec]; j != i {
+			pkgs[i], errs[i] = pkgs[j], errs[j]
+		}
+	}
+	return pkgs, errs
+}
@@ -11,14 +11,13 @@ import (
 	"go/ast"
 	"go/build/constraint"
 	"go/doc"
+	"go/scanner"
 	"go/token"
 	"internal/buildcfg"
-	exec "internal/execabs"
 	"internal/goroot"
 	"internal/goversion"
 	"io"
 	"io/fs"
-	"io/ioutil"
 	"os"
 	pathpkg "path"
 	"path/filepath"
@@ -26,6 +25,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"unicode"
 	"unicode/utf8"
 )
@@ -45,6 +46,23 @@ type Context struct {
 	// be absolute.
 	Dir string
 
+	// Env is the environment, in the form returned by os.Environ,
+	// in which Import runs the go command to locate packages in module
+	// mode, and from which it reads the variables, such as GO111MODULE,
+	// GOFLAGS and GOMODCACHE, that decide whether and how to use modules.
+	// When a variable appears more than once, the last one wins.
+	// If Env is nil, Import uses the environment of the running process.
+	// In either case, the go command sees the values of GOOS, GOARCH,
+	// GOROOT, GOPATH and CGO_ENABLED given by the Context.
+	Env []string
+
+	// GoList, if non-nil, runs the go command on behalf of Import in
+	// module mode, remembering its answers and combining the lookups made
+	// concurrently, such as by ImportPackages and LoadGraph, into shared
+	// invocations. If GoList is nil, Import runs the go command once per
+	// lookup.
+	GoList *GoList
+
 	CgoEnabled  bool   // whether cgo files are included
 	UseAllFiles bool   // use files regardless of +build lines, file names
 	Compiler    string // compiler to assume when computing target paths
@@ -59,10 +77,28 @@ type Context struct {
 	// In addition to the BuildTags, ToolTags, and ReleaseTags, build constraints
 	// consider the values of GOARCH and GOOS as satisfied tags.
 	// The last element in ReleaseTags is assumed to be the current release.
+	//
+	// An element of BuildTags of the form "!tag", such as "!netgo",
+	// instead makes the tag explicitly unsatisfied, overriding all of
+	// the above: "!linux" excludes files for linux even when GOOS is
+	// linux. Such elements affect only the evaluation of build
+	// constraints and file names, and they are not passed to the go
+	// command in module mode; to disable cgo, clear CgoEnabled.
 	BuildTags   []string
 	ToolTags    []string
 	ReleaseTags []string
 
+	// TagExpr, if non-nil, settles the tags it names, taking precedence
+	// over all of the above: for TagExpr parsed from
+	// "//go:build netgo && !cgo", the netgo tag is satisfied and the cgo
+	// tag is not, whatever BuildTags and CgoEnabled say. Only the tags
+	// and negated tags joined by && at the top level of TagExpr are
+	// settled; other parts, such as "a || b", do not decide any single
+	// tag and are ignored. As with "!tag" elements of BuildTags, the
+	// negated tags are not passed to the go command in module mode, but
+	// the others are.
+	TagExpr constraint.Expr
+
 	// The install suffix specifies a suffix to use in the name of the installation
 	// directory. By default it is empty, but custom builds that need to keep
 	// their outputs separate can set InstallSuffix to do so. For example, when
@@ -71,6 +107,19 @@ type Context struct {
 	// "linux_386_race" instead of the usual "linux_386".
 	InstallSuffix string
 
+	// PkgTarget, if non-nil, returns the locations that Import reports
+	// in Package.PkgTargetRoot and Package.PkgObj for the package with
+	// the given import path found in the Go tree root, and that it
+	// checks for a compiled package when AllowBinary is set. It is called
+	// only for packages that have an install location, which local
+	// imports and the packages of unknown compilers do not. If PkgTarget
+	// is nil, Import uses root/pkg/GOOS_GOARCH, or
+	// root/pkg/gccgo_GOOS_GOARCH for gccgo, followed by "_" and the
+	// InstallSuffix if it is set, as the install root, and the import
+	// path with a ".a" suffix within it, or, for gccgo, with a "lib"
+	// prefix on its last element, as the archive.
+	PkgTarget func(root, importPath string) (targetRoot, obj string)
+
 	// By default, Import uses the operating system's file system calls
 	// to read directories and files. To read from other sources,
 	// callers can set the following functions. They all have default
@@ -85,6 +134,14 @@ type Context struct {
 	// If SplitPathList is nil, Import uses filepath.SplitList.
 	SplitPathList func(list string) []string
 
+	// FilterGOPATH, if non-nil, is given the GOPATH directories, in the
+	// order listed, each time Import, SrcDirs, or another method consults
+	// them, and returns the directories to use, in the order to try them.
+	// It may reorder or drop directories but should not add new ones.
+	// The slice it is given is not used again and may be modified.
+	// If FilterGOPATH is nil, Import uses the GOPATH directories in order.
+	FilterGOPATH func(dirs []string) []string
+
 	// IsAbsPath reports whether path is an absolute path.
 	// If IsAbsPath is nil, Import uses filepath.IsAbs.
 	IsAbsPath func(path string) bool
@@ -104,12 +161,114 @@ type Context struct {
 
 	// ReadDir returns a slice of fs.FileInfo, sorted by Name,
 	// describing the content of the named directory.
-	// If ReadDir is nil, Import uses ioutil.ReadDir.
+	// If ReadDir and ReadDirEntries are nil, Import uses os.ReadDir.
 	ReadDir func(dir string) ([]fs.FileInfo, error)
 
+	// ReadDirEntries returns a slice of fs.DirEntry, sorted by Name,
+	// describing the content of the named directory. Import needs only
+	// the name and type of most entries, so ReadDirEntries, unlike
+	// ReadDir, can avoid retrieving the full information for each one.
+	// If ReadDirEntries is set, Import uses it in place of ReadDir.
+	ReadDirEntries func(dir string) ([]fs.DirEntry, error)
+
 	// OpenFile opens a file (not a directory) for reading.
 	// If OpenFile is nil, Import uses os.Open.
 	OpenFile func(path string) (io.ReadCloser, error)
+
+	// FS, if non-nil, is the file system that Import reads directories
+	// and files from, in place of the operating system's. Paths used with
+	// the context, such as GOROOT, GOPATH, and the directories passed to
+	// Import and ImportDir, are then slash-separated: a path beginning
+	// with a slash names the file at the rest of the path in FS, so that
+	// "/goroot/src" names "goroot/src" and "/" names the root, and other
+	// paths are also interpreted relative to the root. Import joins paths
+	// with path.Join and compares them lexically, and it never invokes
+	// the go command.
+	//
+	// Any of the functions above that is set is still used
+	// in place of the corresponding operation on FS.
+	FS fs.FS
+
+	// IgnoreDirs lists patterns, in the syntax of path.Match, for the
+	// names of directories that Walk and the "..." patterns of
+	// MatchPatterns never descend into, such as "node_modules", ".git",
+	// or "bazel-*". A pattern is matched against the last element of the
+	// directory's path alone. A directory passed to Walk as its root is
+	// walked whatever its name, and Import and ImportDir ignore
+	// IgnoreDirs. A malformed pattern matches nothing.
+	IgnoreDirs []string
+
+	// OpenModuleZip, if non-nil, lets Import read a module that has been
+	// downloaded to the module cache but not extracted there directly
+	// from its zip file, such as
+	// $GOMODCACHE/cache/download/example.com/m/@v/v1.2.3.zip, as though
+	// the zip file were mounted read-only at the directory the module
+	// would be extracted to, such as $GOMODCACHE/example.com/m@v1.2.3.
+	// That directory is then the Dir of the packages Import finds in the
+	// module, and it can be passed to ImportDir. An extracted module
+	// takes precedence over its zip file.
+	//
+	// OpenModuleZip opens the named zip file and returns a file system
+	// holding its contents, which is closed, if it implements io.Closer,
+	// once it is no longer needed. To use package archive/zip:
+	//
+	//	ctxt.OpenModuleZip = func(file string) (fs.FS, error) {
+	//		zr, err := zip.OpenReader(file)
+	//		if err != nil {
+	//			return nil, err
+	//		}
+	//		return zr, nil
+	//	}
+	//
+	// OpenModuleZip is not used when FS is set: to import from an
+	// arbitrary zip file, set FS to a *zip.Reader instead.
+	OpenModuleZip func(file string) (fs.FS, error)
+
+	// Stats, if non-nil, counts the directories and files Import reads,
+	// the build constraints it evaluates, the go commands it runs, and
+	// how often Cache spares it work, as described for ScanStats.
+	Stats *ScanStats
+
+	// Cache, if non-nil, remembers the directories Import reads and
+	// the headers of the source files it scans, so that later calls
+	// using the same Cache, from this or other contexts, need not read
+	// them again. The Cache is used only for the local file system:
+	// when FS, ReadDir, ReadDirEntries, or OpenFile is set, it is
+	// consulted instead.
+	Cache *Cache
+}
+
+// getenv returns the value of the environment variable key
+// in ctxt.Env or, if it is nil, in the process environment.
+func (ctxt *Context) getenv(key string) string {
+	if ctxt.Env == nil {
+		return os.Getenv(key)
+	}
+	for i := len(ctxt.Env) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(ctxt.Env[i], "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// environ returns ctxt.Env or, if it is nil, the process environment.
+func (ctxt *Context) environ() []string {
+	if ctxt.Env == nil {
+		return os.Environ()
+	}
+	return ctxt.Env
+}
+
+// ignoreDir reports whether the directory name, a single path element,
+// matches one of ctxt.IgnoreDirs.
+func (ctxt *Context) ignoreDir(name string) bool {
+	for _, pattern := range ctxt.IgnoreDirs {
+		if ok, _ := pathpkg.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // joinPath calls ctxt.JoinPath (if not nil) or else filepath.Join.
@@ -117,6 +276,9 @@ func (ctxt *Context) joinPath(elem ...string) string {
 	if f := ctxt.JoinPath; f != nil {
 		return f(elem...)
 	}
+	if ctxt.FS != nil {
+		return pathpkg.Join(elem...)
+	}
 	return filepath.Join(elem...)
 }
 
@@ -133,6 +295,9 @@ func (ctxt *Context) isAbsPath(path string) bool {
 	if f := ctxt.IsAbsPath; f != nil {
 		return f(path)
 	}
+	if ctxt.FS != nil {
+		return pathpkg.IsAbs(path)
+	}
 	return filepath.IsAbs(path)
 }
 
@@ -141,16 +306,40 @@ func (ctxt *Context) isDir(path string) bool {
 	if f := ctxt.IsDir; f != nil {
 		return f(path)
 	}
+	if ctxt.FS != nil {
+		fi, err := fs.Stat(ctxt.FS, fsPath(path))
+		return err == nil && fi.IsDir()
+	}
+	if file, name, ok := ctxt.moduleZip(path); ok {
+		return ctxt.isZipDir(file, name)
+	}
 	fi, err := os.Stat(path)
 	return err == nil && fi.IsDir()
 }
 
+// Subdir reports whether dir is within root, perhaps multiple levels
+// below, as Import decides whether a directory is in GOROOT or GOPATH.
+// If so, Subdir sets rel to a slash-separated path that can be joined
+// to root to produce a path equivalent to dir.
+//
+// Subdir calls ctxt.HasSubdir if it is set. Otherwise, it compares the
+// paths lexically, and then, if they are in the local file system, with
+// their symbolic links evaluated, so that dir is within root if either
+// is reached through a link. If ctxt.Cache is set, it remembers the
+// links resolved, so that later calls need not resolve them again.
+func (ctxt *Context) Subdir(root, dir string) (rel string, ok bool) {
+	return ctxt.hasSubdir(root, dir)
+}
+
 // hasSubdir calls ctxt.HasSubdir (if not nil) or else uses
 // the local file system to answer the question.
 func (ctxt *Context) hasSubdir(root, dir string) (rel string, ok bool) {
 	if f := ctxt.HasSubdir; f != nil {
 		return f(root, dir)
 	}
+	if ctxt.FS != nil {
+		return hasSubdirSlash(root, dir)
+	}
 
 	// Try using paths we received.
 	if rel, ok = hasSubdir(root, dir); ok {
@@ -160,8 +349,12 @@ func (ctxt *Context) hasSubdir(root, dir string) (rel string, ok bool) {
 	// Try expanding symlinks and comparing
 	// expanded against unexpanded and
 	// expanded against expanded.
-	rootSym, _ := filepath.EvalSymlinks(root)
-	dirSym, _ := filepath.EvalSymlinks(dir)
+	evalSymlinks := filepath.EvalSymlinks
+	if c := ctxt.Cache; c != nil {
+		evalSymlinks = c.links.EvalSymlinks
+	}
+	rootSym, _ := evalSymlinks(root)
+	dirSym, _ := evalSymlinks(dir)
 
 	if rel, ok = hasSubdir(rootSym, dir); ok {
 		return
@@ -186,13 +379,51 @@ func hasSubdir(root, dir string) (rel string, ok bool) {
 	return filepath.ToSlash(dir[len(root):]), true
 }
 
-// readDir calls ctxt.ReadDir (if not nil) or else ioutil.ReadDir.
-func (ctxt *Context) readDir(path string) ([]fs.FileInfo, error) {
-	if f := ctxt.ReadDir; f != nil {
+// hasSubdirSlash is like hasSubdir for slash-separated paths.
+func hasSubdirSlash(root, dir string) (rel string, ok bool) {
+	root = pathpkg.Clean(root)
+	if !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+	dir = pathpkg.Clean(dir)
+	if !strings.HasPrefix(dir, root) {
+		return "", false
+	}
+	return dir[len(root):], true
+}
+
+// fsPath returns the name in ctxt.FS of the path used with ctxt.
+func fsPath(path string) string {
+	return pathpkg.Clean(strings.TrimPrefix(pathpkg.Clean(path), "/"))
+}
+
+// readDir calls ctxt.ReadDirEntries or ctxt.ReadDir (if not nil)
+// or else os.ReadDir.
+func (ctxt *Context) readDir(path string) ([]fs.DirEntry, error) {
+	if s := ctxt.Stats; s != nil {
+		atomic.AddInt64(&s.ReadDirs, 1)
+	}
+	if f := ctxt.ReadDirEntries; f != nil {
 		return f(path)
 	}
-	// TODO: use os.ReadDir
-	return ioutil.ReadDir(path)
+	if f := ctxt.ReadDir; f != nil {
+		infos, err := f(path)
+		ents := make([]fs.DirEntry, len(infos))
+		for i, info := range infos {
+			ents[i] = fs.FileInfoToDirEntry(info)
+		}
+		return ents, err
+	}
+	if ctxt.FS != nil {
+		return fs.ReadDir(ctxt.FS, fsPath(path))
+	}
+	if file, name, ok := ctxt.moduleZip(path); ok {
+		return ctxt.readZipDir(file, name)
+	}
+	if c := ctxt.Cache; c != nil {
+		return c.readDir(path, ctxt.Stats)
+	}
+	return os.ReadDir(path)
 }
 
 // openFile calls ctxt.OpenFile (if not nil) or else os.Open.
@@ -200,7 +431,20 @@ func (ctxt *Context) openFile(path string) (io.ReadCloser, error) {
 	if fn := ctxt.OpenFile; fn != nil {
 		return fn(path)
 	}
+	if ctxt.FS != nil {
+		f, err := ctxt.FS.Open(fsPath(path))
+		if err != nil {
+			return nil, err // nil interface
+		}
+		return f, nil
+	}
+	if file, name, ok := ctxt.moduleZip(path); ok {
+		return ctxt.openZipFile(file, name)
+	}
+	return openOSFile(path)
+}
 
+func openOSFile(path string) (io.ReadCloser, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err // nil interface
@@ -208,6 +452,38 @@ func (ctxt *Context) openFile(path string) (io.ReadCloser, error) {
 	return f, nil
 }
 
+// readFileInfo reads the header of the file info.name, using ctxt.Cache
+// if it applies, into info, as described for readFileInfo.
+func (ctxt *Context) readFileInfo(info *fileInfo, isGo bool) error {
+	if c := ctxt.Cache; c != nil && ctxt.OpenFile == nil && ctxt.FS == nil {
+		if _, _, ok := ctxt.moduleZip(info.name); ok {
+			return readFileInfo(ctxt.Stats.countReads(ctxt.openFile), info, isGo)
+		}
+		return c.readFileInfo(info, isGo, ctxt.Stats)
+	}
+	return readFileInfo(ctxt.Stats.countReads(ctxt.openFile), info, isGo)
+}
+
+// readFileInfo opens the file info.name using open and reads its
+// header into info: with readGoInfo if isGo is set, and otherwise
+// with readComments.
+func readFileInfo(open func(string) (io.ReadCloser, error), info *fileInfo, isGo bool) error {
+	f, err := open(info.name)
+	if err != nil {
+		return err
+	}
+	if isGo {
+		err = readGoInfo(f, info)
+	} else {
+		info.header, err = readComments(f)
+	}
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("read %s: %v", info.name, err)
+	}
+	return nil
+}
+
 // isFile determines whether path is a file by trying to open it.
 // It reuses openFile instead of adding another function to the
 // list in Context.
@@ -248,27 +524,52 @@ func (ctxt *Context) gopath() []string {
 		}
 		all = append(all, p)
 	}
+	if f := ctxt.FilterGOPATH; f != nil {
+		all = f(all)
+	}
 	return all
 }
 
+// GOPATHDirs returns the Go path directories that Import searches,
+// in order: the entries of ctxt.GOPATH, less any that are empty,
+// equal to GOROOT, or begin with ~, as filtered by ctxt.FilterGOPATH.
+// Import computes the list anew on each call, so changes to GOPATH
+// or to the behavior of FilterGOPATH take effect immediately.
+func (ctxt *Context) GOPATHDirs() []string {
+	return ctxt.gopath()
+}
+
 // SrcDirs returns a list of package source root directories.
 // It draws from the current Go root and Go path but omits directories
 // that do not exist.
 func (ctxt *Context) SrcDirs() []string {
 	var all []string
-	if ctxt.GOROOT != "" && ctxt.Compiler != "gccgo" {
-		dir := ctxt.joinPath(ctxt.GOROOT, "src")
-		if ctxt.isDir(dir) {
-			all = append(all, dir)
+	ctxt.SrcDirsSeq()(func(dir string) bool {
+		all = append(all, dir)
+		return true
+	})
+	return all
+}
+
+// SrcDirsSeq returns an iterator over the directories that SrcDirs
+// returns, in the same order. Each directory is checked for existence
+// only when the iteration reaches it, so a caller that stops as soon
+// as it finds what it is looking for does not stat the rest.
+func (ctxt *Context) SrcDirsSeq() func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		if ctxt.GOROOT != "" && ctxt.Compiler != "gccgo" {
+			dir := ctxt.joinPath(ctxt.GOROOT, "src")
+			if ctxt.isDir(dir) && !yield(dir) {
+				return
+			}
 		}
-	}
-	for _, p := range ctxt.gopath() {
-		dir := ctxt.joinPath(p, "src")
-		if ctxt.isDir(dir) {
-			all = append(all, dir)
+		for _, p := range ctxt.gopath() {
+			dir := ctxt.joinPath(p, "src")
+			if ctxt.isDir(dir) && !yield(dir) {
+				return
+			}
 		}
 	}
-	return all
 }
 
 // Default is the default Context for builds.
@@ -352,6 +653,109 @@ func defaultContext() Context {
 	return c
 }
 
+// ApplyEnv updates ctxt to describe the build configured by the
+// environment variables in env, each of the form "key=value" as returned
+// by os.Environ, as the go command would configure its own build context
+// from them. When a variable appears more than once, the last one wins.
+//
+// GOOS, GOARCH, GOROOT, GOPATH and CGO_ENABLED set the corresponding
+// fields of ctxt; if absent, the fields are left unchanged. ApplyEnv then
+// sets ToolTags to the "goexperiment." tags for the experiments enabled
+// by GOEXPERIMENT, or by default when it is absent, on ctxt's GOOS and
+// GOARCH. Of the flags in GOFLAGS, -tags sets BuildTags, -compiler sets
+// Compiler, -installsuffix sets InstallSuffix, and -race, -msan and
+// -asan add the tag of the same name to ToolTags and InstallSuffix.
+// Other flags in GOFLAGS do not affect ctxt and are ignored, as is
+// GODEBUG, which affects programs only as they run.
+//
+// ApplyEnv returns an error, and leaves ctxt unchanged, if GOEXPERIMENT,
+// GOFLAGS or CGO_ENABLED is malformed.
+func (ctxt *Context) ApplyEnv(env []string) error {
+	vars := make(map[string]string)
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			vars[k] = v
+		}
+	}
+
+	c := *ctxt
+	for k, field := range map[string]*string{
+		"GOOS":   &c.GOOS,
+		"GOARCH": &c.GOARCH,
+		"GOROOT": &c.GOROOT,
+		"GOPATH": &c.GOPATH,
+	} {
+		if v, ok := vars[k]; ok {
+			*field = v
+		}
+	}
+	if v, ok := vars["CGO_ENABLED"]; ok {
+		switch v {
+		case "0":
+			c.CgoEnabled = false
+		case "1":
+			c.CgoEnabled = true
+		default:
+			return fmt.Errorf("go/build: invalid CGO_ENABLED=%q: must be 0 or 1", v)
+		}
+	}
+
+	goexp, ok := vars["GOEXPERIMENT"]
+	if !ok {
+		goexp = buildcfg.DefaultGOEXPERIMENT
+	}
+	exp, err := buildcfg.ParseGOEXPERIMENT(c.GOOS, c.GOARCH, goexp)
+	if err != nil {
+		return fmt.Errorf("go/build: invalid GOEXPERIMENT: %v", err)
+	}
+	c.ToolTags = nil
+	for _, name := range exp.Enabled() {
+		c.ToolTags = append(c.ToolTags, "goexperiment."+name)
+	}
+
+	mode := "" // race, msan or asan; the go command allows only one
+	for _, flag := range strings.Fields(vars["GOFLAGS"]) {
+		if !strings.HasPrefix(flag, "-") {
+			return fmt.Errorf("go/build: parsing GOFLAGS: non-flag %q", flag)
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(flag[1:], "-"), "=")
+		switch name {
+		case "tags":
+			c.BuildTags = nil
+			for _, tag := range strings.Split(value, ",") {
+				if tag != "" {
+					c.BuildTags = append(c.BuildTags, tag)
+				}
+			}
+		case "compiler":
+			c.Compiler = value
+		case "installsuffix":
+			c.InstallSuffix = value
+		case "race", "msan", "asan":
+			if hasValue {
+				on, err := strconv.ParseBool(value)
+				if err != nil {
+					return fmt.Errorf("go/build: parsing GOFLAGS: invalid value %q for flag -%s", value, name)
+				}
+				if !on {
+					continue
+				}
+			}
+			mode = name
+		}
+	}
+	if mode != "" {
+		if c.InstallSuffix != "" {
+			c.InstallSuffix += "_"
+		}
+		c.InstallSuffix += mode
+		c.ToolTags = append(c.ToolTags, mode)
+	}
+
+	*ctxt = c
+	return nil
+}
+
 func envOr(name, def string) string {
 	s := os.Getenv(name)
 	if s == "" {
@@ -405,6 +809,43 @@ const (
 	// are always the exact import paths from the source files:
 	// Import makes no attempt to resolve or check those paths.
 	IgnoreVendor
+
+	// If SkipTestFiles is set, Import neither reads nor lists files
+	// whose names end in "_test.go", as if they were not there:
+	// the returned package's TestGoFiles, XTestGoFiles, and related
+	// fields are empty, and a directory containing only test files
+	// results in a *NoGoError.
+	SkipTestFiles
+
+	// If LazyLoad is set, Import stops after locating the package
+	// directory, as with FindOnly, and returns a Package on which
+	// the Complete method must be called to read the files in the
+	// directory and fill in the remaining fields. Import then reports
+	// only errors found while locating the directory; Complete reports
+	// the rest. LazyLoad lets callers that discard most of the packages
+	// they import avoid reading those packages' files.
+	LazyLoad
+
+	// If ResolveDeps is set, Import also sets the package's Deps field
+	// to the import paths of all the packages it depends on, directly or
+	// indirectly, through the imports listed in Package.Imports. Import
+	// locates each dependency as it would when importing it from the
+	// directory of a package that imports it, and reads only as much of
+	// its files as needed to find its imports, sharing Context.Cache, if
+	// set, or else a cache of its own, between the lookups. ResolveDeps
+	// has no effect with FindOnly. With LazyLoad, Complete sets Deps.
+	ResolveDeps
+
+	// If SkipDoc is set, Import leaves the package's Doc field empty,
+	// sparing the work of extracting the synopsis of its documentation.
+	SkipDoc
+
+	// If FileDocs is set, Import records the package documentation
+	// comment of each file described in the package's Files field,
+	// test files included, in the file's Doc field. Package.Doc is
+	// still taken from the first non-test file with such a comment,
+	// unless SkipDoc is also set.
+	FileDocs
 )
 
 // A Package describes the Go package found in a directory.
@@ -441,6 +882,16 @@ type Package struct {
 	SwigCXXFiles      []string // .swigcxx files
 	SysoFiles         []string // .syso system object files to add to archive
 
+	// InvalidFiles describes the first problem detected in each file
+	// listed in InvalidGoFiles: InvalidFiles[i] describes InvalidGoFiles[i].
+	InvalidFiles []*FileError
+
+	// IgnoredReasons records why each file listed in IgnoredGoFiles or
+	// IgnoredOtherFiles was left out of the build. It also records the
+	// source files left out because their names begin with "_" or ".",
+	// which are not listed anywhere else.
+	IgnoredReasons map[string]IgnoreReason
+
 	// Cgo directives
 	CgoCFLAGS    []string // Cgo CFLAGS directives
 	CgoCPPFLAGS  []string // Cgo CPPFLAGS directives
@@ -449,6 +900,21 @@ type Package struct {
 	CgoLDFLAGS   []string // Cgo LDFLAGS directives
 	CgoPkgConfig []string // Cgo pkg-config directives
 
+	// Cgo flags, as resolved by ResolveCgoFlags
+	ResolvedCgoCPPFLAGS []string // CGO_CPPFLAGS, CgoCPPFLAGS and pkg-config --cflags
+	ResolvedCgoCFLAGS   []string // CGO_CFLAGS and CgoCFLAGS
+	ResolvedCgoCXXFLAGS []string // CGO_CXXFLAGS and CgoCXXFLAGS
+	ResolvedCgoFFLAGS   []string // CGO_FFLAGS and CgoFFLAGS
+	ResolvedCgoLDFLAGS  []string // CGO_LDFLAGS, CgoLDFLAGS and pkg-config --libs
+
+	// Deps lists the import paths of the packages that the package
+	// depends on, directly or indirectly, in sorted order, if it was
+	// imported with the ResolveDeps mode. The paths are those of the
+	// packages as located, so that a vendored package is listed under
+	// its full path. Packages that could not be located are listed under
+	// the path by which they were imported; LoadGraph reports why.
+	Deps []string
+
 	// Test information
 	TestGoFiles  []string // _test.go files in package
 	XTestGoFiles []string // _test.go files outside package
@@ -472,6 +938,28 @@ type Package struct {
 	TestEmbedPatternPos  map[string][]token.Position // line information for TestEmbedPatterns
 	XTestEmbedPatterns   []string                    // patterns from XTestGoFiles
 	XTestEmbedPatternPos map[string][]token.Position // line information for XTestEmbedPatternPos
+	EmbedFiles           []string                    // files matched by EmbedPatterns; see ResolveEmbeds
+	TestEmbedFiles       []string                    // files matched by TestEmbedPatterns
+	XTestEmbedFiles      []string                    // files matched by XTestEmbedPatterns
+
+	// Files describes each of the files in GoFiles, CgoFiles, TestGoFiles
+	// and XTestGoFiles individually, in directory order. The import and
+	// embed information above is the union of that recorded here.
+	Files []*GoFile
+
+	lazy *lazyState // non-nil if imported with LazyLoad; see Complete
+}
+
+// A GoFile describes the build constraint, imports, embed patterns and,
+// optionally, documentation of a single Go source file in a package.
+type GoFile struct {
+	Name            string           // file name, relative to Package.Dir
+	Constraint      constraint.Expr  // //go:build or combined // +build constraint; nil if none
+	Imports         []string         // import paths, in source order
+	ImportPos       []token.Position // ImportPos[i] is the position of Imports[i]
+	EmbedPatterns   []string         // //go:embed patterns, in source order
+	EmbedPatternPos []token.Position // EmbedPatternPos[i] is the position of EmbedPatterns[i]
+	Doc             string           // package documentation comment, if imported with FileDocs
 }
 
 // IsCommand reports whether the package is considered a
@@ -487,6 +975,66 @@ func (ctxt *Context) ImportDir(dir string, mode ImportMode) (*Package, error) {
 	return ctxt.Import(".", dir, mode)
 }
 
+// ImportOptions holds the settings for a call to ImportOpts.
+// Each field that is nil leaves the corresponding setting
+// of the Context unchanged.
+type ImportOptions struct {
+	Mode        ImportMode // mode for Import
+	BuildTags   []string   // replaces Context.BuildTags; use an empty slice for none
+	ReleaseTags []string   // replaces Context.ReleaseTags; use an empty slice for none
+	CgoEnabled  *bool      // replaces Context.CgoEnabled
+}
+
+// ImportOpts is like Import but with the settings in opts overriding
+// those of ctxt for the duration of the call. It does not modify ctxt,
+// so a single Context may be used concurrently to import the same
+// package under different build tags.
+func (ctxt *Context) ImportOpts(path, srcDir string, opts ImportOptions) (*Package, error) {
+	c := *ctxt
+	if opts.BuildTags != nil {
+		c.BuildTags = opts.BuildTags
+	}
+	if opts.ReleaseTags != nil {
+		c.ReleaseTags = opts.ReleaseTags
+	}
+	if opts.CgoEnabled != nil {
+		c.CgoEnabled = *opts.CgoEnabled
+	}
+	return c.Import(path, srcDir, opts.Mode)
+}
+
+// Errors classifying the failures described by an ImportError.
+var (
+	ErrInvalidImportPath = errors.New("invalid import path")        // the import path cannot be imported
+	ErrNotFound          = errors.New("cannot find package")        // no directory holds the package
+	ErrNotInGOROOT       = errors.New("package is not in GOROOT")   // a standard library path names no package
+	ErrNoGoMod           = errors.New("go.mod file not found")      // module mode is on but there is no main module
+	ErrNoModule          = errors.New("no module provides package") // no module in the build list holds the package
+)
+
+// An ImportError describes a failure of Import to locate the package
+// named by an import path. Its Err field holds one of the Err values
+// above, classifying the failure, so that callers can test for the
+// kind of failure with errors.Is. The error text is that produced by
+// Import or, in module mode, by the go command.
+type ImportError struct {
+	ImportPath string // the import path passed to Import
+	Err        error  // the class of failure, such as ErrNotFound
+	msg        string
+}
+
+func importError(path string, class error, format string, args ...any) *ImportError {
+	return &ImportError{ImportPath: path, Err: class, msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *ImportError) Error() string {
+	return e.msg
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
 // NoGoError is the error used by Import to describe a directory
 // containing no buildable Go source files. (It may still contain
 // test files, files hidden by build tags, and so on.)
@@ -511,6 +1059,77 @@ func (e *MultiplePackageError) Error() string {
 	return fmt.Sprintf("found packages %s (%s) and %s (%s) in %s", e.Packages[0], e.Files[0], e.Packages[1], e.Files[1], e.Dir)
 }
 
+// A FileError describes a problem with a source file
+// that caused Import to list it in Package.InvalidGoFiles.
+type FileError struct {
+	Name string         // name of the file, as listed in InvalidGoFiles
+	Kind FileErrorKind  // what is wrong with the file
+	Pos  token.Position // where the problem was found; Line is 0 if unknown
+	Err  error          // the problem, as it would be returned by Import
+}
+
+func (e *FileError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// A FileErrorKind classifies the problem described by a FileError.
+type FileErrorKind int
+
+const (
+	FileReadError          FileErrorKind = iota // the file could not be read
+	FileConstraintError                         // the build constraints could not be parsed
+	FileSyntaxError                             // the package clause or imports could not be parsed
+	FilePackageError                            // the package name differs from that of other files
+	FileImportCommentError                      // the import comment is malformed or conflicts with another
+	FileCgoError                                // the use of cgo is invalid
+)
+
+var fileErrorKinds = [...]string{
+	FileReadError:          "read error",
+	FileConstraintError:    "invalid build constraint",
+	FileSyntaxError:        "syntax error",
+	FilePackageError:       "package name mismatch",
+	FileImportCommentError: "invalid import comment",
+	FileCgoError:           "invalid cgo use",
+}
+
+func (k FileErrorKind) String() string {
+	if 0 <= k && int(k) < len(fileErrorKinds) {
+		return fileErrorKinds[k]
+	}
+	return "FileErrorKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// An IgnoreReason explains why Import left a source file out of a package.
+type IgnoreReason int
+
+const (
+	IgnoredPrefix        IgnoreReason = 1 + iota // the name begins with "_" or "."
+	IgnoredFileName                              // the name has a _GOOS or _GOARCH suffix that does not match
+	IgnoredConstraint                            // a //go:build or // +build line is not satisfied
+	IgnoredCgo                                   // the file needs cgo, which is disabled or not used by the package
+	IgnoredDocumentation                         // the file is in package documentation
+)
+
+var ignoreReasons = [...]string{
+	IgnoredPrefix:        "name begins with _ or .",
+	IgnoredFileName:      "excluded by file name",
+	IgnoredConstraint:    "excluded by build constraints",
+	IgnoredCgo:           "requires cgo",
+	IgnoredDocumentation: "package documentation",
+}
+
+func (r IgnoreReason) String() string {
+	if 0 < r && int(r) < len(ignoreReasons) {
+		return ignoreReasons[r]
+	}
+	return "IgnoreReason(" + strconv.Itoa(int(r)) + ")"
+}
+
 func nameExt(name string) string {
 	i := strings.LastIndex(name, ".")
 	if i < 0 {
@@ -540,7 +1159,7 @@ func (ctxt *Context) Import(path string, srcDir string, mode ImportMode) (*Packa
 		ImportPath: path,
 	}
 	if path == "" {
-		return p, fmt.Errorf("import %q: invalid import path", path)
+		return p, importError(path, ErrInvalidImportPath, "import %q: invalid import path", path)
 	}
 
 	var pkgtargetroot string
@@ -569,12 +1188,16 @@ func (ctxt *Context) Import(path string, srcDir string, mode ImportMode) (*Packa
 		}
 	}
 	setPkga()
+	hasPkgObj := func(root string) bool {
+		_, obj := ctxt.pkgTarget(root, p.ImportPath, pkgtargetroot, pkga)
+		return obj != "" && ctxt.isFile(obj)
+	}
 
 	binaryOnly := false
 	if IsLocalImport(path) {
 		pkga = "" // local imports have no installed path
 		if srcDir == "" {
-			return p, fmt.Errorf("import %q: import relative to unknown directory", path)
+			return p, importError(path, ErrInvalidImportPath, "import %q: import relative to unknown directory", path)
 		}
 		if !ctxt.isAbsPath(path) {
 			p.Dir = ctxt.joinPath(srcDir, path)
@@ -627,7 +1250,7 @@ func (ctxt *Context) Import(path string, srcDir string, mode ImportMode) (*Packa
 		// Keep going with the information we have.
 	} else {
 		if strings.HasPrefix(path, "/") {
-			return p, fmt.Errorf("import %q: cannot import absolute path", path)
+			return p, importError(path, ErrInvalidImportPath, "import %q: cannot import absolute path", path)
 		}
 
 		if err := ctxt.importGo(p, path, srcDir, mode); err == nil {
@@ -698,7 +1321,7 @@ func (ctxt *Context) Import(path string, srcDir string, mode ImportMode) (*Packa
 				dir := ctxt.joinPath(ctxt.GOROOT, "src", path)
 				if ctxt.Compiler != "gccgo" {
 					isDir := ctxt.isDir(dir)
-					binaryOnly = !isDir && mode&AllowBinary != 0 && pkga != "" && ctxt.isFile(ctxt.joinPath(ctxt.GOROOT, pkga))
+					binaryOnly = !isDir && mode&AllowBinary != 0 && hasPkgObj(ctxt.GOROOT)
 					if isDir || binaryOnly {
 						p.Dir = dir
 						p.Goroot = true
@@ -718,7 +1341,7 @@ func (ctxt *Context) Import(path string, srcDir string, mode ImportMode) (*Packa
 		for _, root := range gopath {
 			dir := ctxt.joinPath(root, "src", path)
 			isDir := ctxt.isDir(dir)
-			binaryOnly = !isDir && mode&AllowBinary != 0 && pkga != "" && ctxt.isFile(ctxt.joinPath(root, pkga))
+			binaryOnly = !isDir && mode&AllowBinary != 0 && hasPkgObj(root)
 			if isDir || binaryOnly {
 				p.Dir = dir
 				p.Root = root
@@ -734,7 +1357,7 @@ func (ctxt *Context) Import(path string, srcDir string, mode ImportMode) (*Packa
 			dir := ctxt.joinPath(ctxt.GOROOT, "src", path)
 			if ctxt.Compiler != "gccgo" {
 				isDir := ctxt.isDir(dir)
-				binaryOnly = !isDir && mode&AllowBinary != 0 && pkga != "" && ctxt.isFile(ctxt.joinPath(ctxt.GOROOT, pkga))
+				binaryOnly = !isDir && mode&AllowBinary != 0 && hasPkgObj(ctxt.GOROOT)
 				if isDir || binaryOnly {
 					p.Dir = dir
 					p.Goroot = true
@@ -765,7 +1388,7 @@ func (ctxt *Context) Import(path string, srcDir string, mode ImportMode) (*Packa
 		if len(tried.gopath) == 0 {
 			paths = append(paths, "\t($GOPATH not set. For more details see: 'go help gopath')")
 		}
-		return p, fmt.Errorf("cannot find package %q in any of:\n%s", path, strings.Join(paths, "\n"))
+		return p, importError(path, ErrNotFound, "cannot find package %q in any of:\n%s", path, strings.Join(paths, "\n"))
 	}
 
 Found:
@@ -773,10 +1396,7 @@ Found:
 		p.SrcRoot = ctxt.joinPath(p.Root, "src")
 		p.PkgRoot = ctxt.joinPath(p.Root, "pkg")
 		p.BinDir = ctxt.joinPath(p.Root, "bin")
-		if pkga != "" {
-			p.PkgTargetRoot = ctxt.joinPath(p.Root, pkgtargetroot)
-			p.PkgObj = ctxt.joinPath(p.Root, pkga)
-		}
+		p.PkgTargetRoot, p.PkgObj = ctxt.pkgTarget(p.Root, p.ImportPath, pkgtargetroot, pkga)
 	}
 
 	// If it's a local import path, by the time we get here, we still haven't checked
@@ -791,7 +1411,7 @@ Found:
 		}
 
 		// package was not found
-		return p, fmt.Errorf("cannot find package %q in:\n\t%s", p.ImportPath, p.Dir)
+		return p, importError(path, ErrNotFound, "cannot find package %q in:\n\t%s", p.ImportPath, p.Dir)
 	}
 
 	if mode&FindOnly != 0 {
@@ -805,24 +1425,93 @@ Found:
 		// gccgo has no sources for GOROOT packages.
 		return p, nil
 	}
-
+	if mode&LazyLoad != 0 {
+		p.lazy = &lazyState{mode: mode &^ LazyLoad, err: pkgerr}
+		return p, nil
+	}
 	dirs, err := ctxt.readDir(p.Dir)
-	if err != nil {
-		return p, err
+	if err == nil {
+		err = ctxt.importFiles(p, dirs, mode, pkgerr)
+	}
+	if mode&ResolveDeps != 0 {
+		ctxt.resolveDeps(p, mode)
+	}
+	return p, err
+}
+
+// Complete finishes loading a package returned by Import with the
+// LazyLoad mode set: it reads the files in p.Dir and fills in the
+// remaining fields of p as Import would have done without LazyLoad,
+// returning the error that Import would have returned. The Context
+// ctxt should be the one that imported p.
+//
+// Complete does nothing and returns nil if p is already complete.
+// Calling it more than once returns the same error each time.
+// It must not be called concurrently for the same Package.
+func (p *Package) Complete(ctxt *Context) error {
+	l := p.lazy
+	if l == nil {
+		return nil
+	}
+	if !l.done {
+		dirs, err := ctxt.readDir(p.Dir)
+		p.complete(ctxt, dirs, err)
+	}
+	return l.err
+}
+
+// complete completes p, which was imported with LazyLoad, from the
+// entries dirs of p.Dir or the error readErr from reading them.
+func (p *Package) complete(ctxt *Context, dirs []fs.DirEntry, readErr error) {
+	l := p.lazy
+	if readErr != nil {
+		l.err = readErr
+	} else {
+		l.err = ctxt.importFiles(p, dirs, l.mode, l.err)
+	}
+	if l.mode&ResolveDeps != 0 {
+		ctxt.resolveDeps(p, l.mode)
 	}
+	l.done = true
+}
+
+// A lazyState records how to complete a package imported with LazyLoad.
+type lazyState struct {
+	mode ImportMode
+	done bool  // importFiles has run
+	err  error // error from Import before completion, or from importFiles after
+}
 
+// importFiles reads the files dirs in p.Dir and fills in the corresponding
+// fields of p. It returns the error to report from Import, which is
+// pkgerr if there is no more pressing problem with the package.
+func (ctxt *Context) importFiles(p *Package, dirs []fs.DirEntry, mode ImportMode, pkgerr error) error {
 	var badGoError error
 	badFiles := make(map[string]bool)
-	badFile := func(name string, err error) {
+	badFile := func(name string, kind FileErrorKind, pos token.Position, err error) {
+		fe, ok := err.(*FileError)
+		if ok {
+			err = fe.Err
+		} else {
+			fe = &FileError{Name: name, Kind: kind, Pos: pos, Err: err}
+		}
 		if badGoError == nil {
 			badGoError = err
 		}
 		if !badFiles[name] {
 			p.InvalidGoFiles = append(p.InvalidGoFiles, name)
+			p.InvalidFiles = append(p.InvalidFiles, fe)
 			badFiles[name] = true
 		}
 	}
 
+	ignored := func(name string, reason IgnoreReason) {
+		if p.IgnoredReasons == nil {
+			p.IgnoredReasons = make(map[string]IgnoreReason)
+		}
+		p.IgnoredReasons[name] = reason
+	}
+
 	var Sfiles []string // files with ".S"(capital S)/.sx(capital s equivalent for case insensitive filesystems)
 	var firstFile, firstCommentFile string
 	embedPos := make(map[string][]token.Position)
@@ -833,11 +1522,12 @@ Found:
 	xTestImportPos := make(map[string][]token.Position)
 	allTags := make(map[string]bool)
 	fset := token.NewFileSet()
-	for _, d := range dirs {
+	scans := ctxt.scanFiles(p.Dir, dirs, mode, fset)
+	for i, d := range dirs {
 		if d.IsDir() {
 			continue
 		}
-		if d.Mode()&fs.ModeSymlink != 0 {
+		if d.Type()&fs.ModeSymlink != 0 {
 			if ctxt.isDir(ctxt.joinPath(p.Dir, d.Name())) {
 				// Symlinks to directories are not source files.
 				continue
@@ -847,17 +1537,40 @@ Found:
 		name := d.Name()
 		ext := nameExt(name)
 
-		info, err := ctxt.matchFile(p.Dir, name, allTags, &p.BinaryOnly, fset)
+		if mode&SkipTestFiles != 0 && strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		var info *fileInfo
+		var reason IgnoreReason
+		var err error
+		if scans != nil && scans[i].done {
+			s := &scans[i]
+			info, reason, err = s.info, s.reason, s.err
+			for _, tag := range s.tags {
+				allTags[tag] = true
+			}
+			if s.binaryOnly {
+				p.BinaryOnly = true
+			}
+		} else {
+			info, reason, err = ctxt.matchFile(p.Dir, name, allTags, &p.BinaryOnly, fset)
+		}
 		if err != nil {
-			badFile(name, err)
+			badFile(name, FileReadError, token.Position{Filename: ctxt.joinPath(p.Dir, name)}, err)
 			continue
 		}
 		if info == nil {
-			if strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+			if ext != ".go" && fileListForExt(p, ext) == nil {
+				// not a source file
+				continue
+			}
+			ignored(name, reason)
+			if reason == IgnoredPrefix {
 				// not due to build constraints - don't report
 			} else if ext == ".go" {
 				p.IgnoredGoFiles = append(p.IgnoredGoFiles, name)
-			} else if fileListForExt(p, ext) != nil {
+			} else {
 				p.IgnoredOtherFiles = append(p.IgnoredOtherFiles, name)
 			}
 			continue
@@ -880,7 +1593,11 @@ Found:
 		}
 
 		if info.parseErr != nil {
-			badFile(name, info.parseErr)
+			pos := token.Position{Filename: filename}
+			if list, ok := info.parseErr.(scanner.ErrorList); ok && len(list) > 0 {
+				pos = list[0].Pos
+			}
+			badFile(name, FileSyntaxError, pos, info.parseErr)
 			// Fall through: we might still have a partial AST in info.parsed,
 			// and we want to list files with parse errors anyway.
 		}
@@ -890,6 +1607,7 @@ Found:
 			pkg = info.parsed.Name.Name
 			if pkg == "documentation" {
 				p.IgnoredGoFiles = append(p.IgnoredGoFiles, name)
+				ignored(name, IgnoredDocumentation)
 				continue
 			}
 		}
@@ -908,14 +1626,14 @@ Found:
 			// TODO(#45999): The choice of p.Name is arbitrary based on file iteration
 			// order. Instead of resolving p.Name arbitrarily, we should clear out the
 			// existing name and mark the existing files as also invalid.
-			badFile(name, &MultiplePackageError{
+			badFile(name, FilePackageError, fset.Position(info.parsed.Name.Pos()), &MultiplePackageError{
 				Dir:      p.Dir,
 				Packages: []string{p.Name, pkg},
 				Files:    []string{firstFile, name},
 			})
 		}
 		// Grab the first package comment as docs, provided it is not from a test file.
-		if info.parsed != nil && info.parsed.Doc != nil && p.Doc == "" && !isTest && !isXTest {
+		if mode&SkipDoc == 0 && info.parsed != nil && info.parsed.Doc != nil && p.Doc == "" && !isTest && !isXTest {
 			p.Doc = doc.Synopsis(info.parsed.Doc.Text())
 		}
 
@@ -924,12 +1642,12 @@ Found:
 			if line != 0 {
 				com, err := strconv.Unquote(qcom)
 				if err != nil {
-					badFile(name, fmt.Errorf("%s:%d: cannot parse import comment", filename, line))
+					badFile(name, FileImportCommentError, token.Position{Filename: filename, Line: line}, fmt.Errorf("%s:%d: cannot parse import comment", filename, line))
 				} else if p.ImportComment == "" {
 					p.ImportComment = com
 					firstCommentFile = name
 				} else if p.ImportComment != com {
-					badFile(name, fmt.Errorf("found import comments %q (%s) and %q (%s) in %s", p.ImportComment, firstCommentFile, com, name, p.Dir))
+					badFile(name, FileImportCommentError, token.Position{Filename: filename, Line: line}, fmt.Errorf("found import comments %q (%s) and %q (%s) in %s", p.ImportComment, firstCommentFile, com, name, p.Dir))
 				}
 			}
 		}
@@ -939,13 +1657,13 @@ Found:
 		for _, imp := range info.imports {
 			if imp.path == "C" {
 				if isTest {
-					badFile(name, fmt.Errorf("use of cgo in test %s not supported", filename))
+					badFile(name, FileCgoError, fset.Position(imp.pos), fmt.Errorf("use of cgo in test %s not supported", filename))
 					continue
 				}
 				isCgo = true
 				if imp.doc != nil {
 					if err := ctxt.saveCgo(filename, p, imp.doc); err != nil {
-						badFile(name, err)
+						badFile(name, FileCgoError, fset.Position(imp.doc.Pos()), err)
 					}
 				}
 			}
@@ -963,6 +1681,7 @@ Found:
 			} else {
 				// Ignore imports and embeds from cgo files if cgo is disabled.
 				fileList = &p.IgnoredGoFiles
+				ignored(name, IgnoredCgo)
 			}
 		case isXTest:
 			fileList = &p.XTestGoFiles
@@ -978,16 +1697,25 @@ Found:
 			embedMap = embedPos
 		}
 		*fileList = append(*fileList, name)
-		if importMap != nil {
-			for _, imp := range info.imports {
-				importMap[imp.path] = append(importMap[imp.path], fset.Position(imp.pos))
-			}
+		if importMap == nil {
+			continue
 		}
-		if embedMap != nil {
-			for _, emb := range info.embeds {
-				embedMap[emb.pattern] = append(embedMap[emb.pattern], emb.pos)
-			}
+		f := &GoFile{Name: name, Constraint: fileConstraint(info.header)}
+		if mode&FileDocs != 0 && info.parsed != nil && info.parsed.Doc != nil {
+			f.Doc = info.parsed.Doc.Text()
 		}
+		for _, imp := range info.imports {
+			pos := fset.Position(imp.pos)
+			importMap[imp.path] = append(importMap[imp.path], pos)
+			f.Imports = append(f.Imports, imp.path)
+			f.ImportPos = append(f.ImportPos, pos)
+		}
+		for _, emb := range info.embeds {
+			embedMap[emb.pattern] = append(embedMap[emb.pattern], emb.pos)
+			f.EmbedPatterns = append(f.EmbedPatterns, emb.pattern)
+			f.EmbedPatternPos = append(f.EmbedPatternPos, emb.pos)
+		}
+		p.Files = append(p.Files, f)
 	}
 
 	for tag := range allTags {
@@ -1012,15 +1740,18 @@ Found:
 	} else {
 		p.IgnoredOtherFiles = append(p.IgnoredOtherFiles, Sfiles...)
 		sort.Strings(p.IgnoredOtherFiles)
+		for _, name := range Sfiles {
+			ignored(name, IgnoredCgo)
+		}
 	}
 
 	if badGoError != nil {
-		return p, badGoError
+		return badGoError
 	}
 	if len(p.GoFiles)+len(p.CgoFiles)+len(p.TestGoFiles)+len(p.XTestGoFiles) == 0 {
-		return p, &NoGoError{p.Dir}
+		return &NoGoError{p.Dir}
 	}
-	return p, pkgerr
+	return pkgerr
 }
 
 func fileListForExt(p *Package, ext string) *[]string {
@@ -1079,8 +1810,8 @@ func (ctxt *Context) importGo(p *Package, path, srcDir string, mode ImportMode)
 	// To invoke the go command,
 	// we must not being doing special things like AllowBinary or IgnoreVendor,
 	// and all the file system callbacks must be nil (we're meant to use the local file system).
-	if mode&AllowBinary != 0 || mode&IgnoreVendor != 0 ||
-		ctxt.JoinPath != nil || ctxt.SplitPathList != nil || ctxt.IsAbsPath != nil || ctxt.IsDir != nil || ctxt.HasSubdir != nil || ctxt.ReadDir != nil || ctxt.OpenFile != nil || !equal(ctxt.ToolTags, defaultToolTags) || !equal(ctxt.ReleaseTags, defaultReleaseTags) {
+	if mode&AllowBinary != 0 || mode&IgnoreVendor != 0 || ctxt.FS != nil ||
+		ctxt.JoinPath != nil || ctxt.SplitPathList != nil || ctxt.IsAbsPath != nil || ctxt.IsDir != nil || ctxt.HasSubdir != nil || ctxt.ReadDir != nil || ctxt.ReadDirEntries != nil || ctxt.OpenFile != nil || !equal(ctxt.ToolTags, defaultToolTags) || !equal(ctxt.ReleaseTags, defaultReleaseTags) {
 		return errNoModules
 	}
 
@@ -1095,7 +1826,7 @@ func (ctxt *Context) importGo(p *Package, path, srcDir string, mode ImportMode)
 	// GO111MODULE and looking for a go.mod file in the source directory or
 	// one of its parents. Running 'go env GOMOD' in the source directory would
 	// give a canonical answer, but we'd prefer not to execute another command.
-	go111Module := os.Getenv("GO111MODULE")
+	go111Module := ctxt.getenv("GO111MODULE")
 	switch go111Module {
 	case "off":
 		return errNoModules
@@ -1171,62 +1902,57 @@ func (ctxt *Context) importGo(p *Package, path, srcDir string, mode ImportMode)
 		}
 	}
 
-	goCmd := filepath.Join(ctxt.GOROOT, "bin", "go")
-	cmd := exec.Command(goCmd, "list", "-e", "-compiler="+ctxt.Compiler, "-tags="+strings.Join(ctxt.BuildTags, ","), "-installsuffix="+ctxt.InstallSuffix, "-f={{.Dir}}\n{{.ImportPath}}\n{{.Root}}\n{{.Goroot}}\n{{if .Error}}{{.Error}}{{end}}\n", "--", path)
-
-	if ctxt.Dir != "" {
-		cmd.Dir = ctxt.Dir
+	// In the common case that the module providing path has already been
+	// downloaded, we can find it without the overhead of running go list.
+	if ctxt.importModule(p, path) {
+		return nil
 	}
 
-	var stdout, stderr strings.Builder
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	cgo := "0"
-	if ctxt.CgoEnabled {
-		cgo = "1"
-	}
-	cmd.Env = append(os.Environ(),
-		"GOOS="+ctxt.GOOS,
-		"GOARCH="+ctxt.GOARCH,
-		"GOROOT="+ctxt.GOROOT,
-		"GOPATH="+ctxt.GOPATH,
-		"CGO_ENABLED="+cgo,
+	var (
+		res goListResult
+		err error
 	)
-	if cmd.Dir != "" {
-		// If possible, set PWD: if an error occurs and PWD includes a symlink, we
-		// want the error to refer to Dir, not some other name for it.
-		if abs, err := filepath.Abs(cmd.Dir); err == nil {
-			cmd.Env = append(cmd.Env, "PWD="+abs)
-		}
-	}
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("go/build: go list %s: %v\n%s\n", path, err, stderr.String())
+	if ctxt.GoList != nil {
+		res, err = ctxt.GoList.lookup(ctxt, path)
+	} else {
+		var results map[string]goListResult
+		results, err = ctxt.runGoList([]string{path})
+		res = results[path]
 	}
-
-	f := strings.SplitN(stdout.String(), "\n", 5)
-	if len(f) != 5 {
-		return fmt.Errorf("go/build: importGo %s: unexpected output:\n%s\n", path, stdout.String())
+	if err != nil {
+		return err
 	}
-	dir := f[0]
-	errStr := strings.TrimSpace(f[4])
-	if errStr != "" && dir == "" {
+	if res.err != "" && res.dir == "" {
 		// If 'go list' could not locate the package (dir is empty),
 		// return the same error that 'go list' reported.
-		return errors.New(errStr)
+		return &ImportError{ImportPath: path, Err: goListErrorClass(res.err), msg: res.err}
 	}
 
 	// If 'go list' did locate the package, ignore the error.
 	// It was probably related to loading source files, and we'll
 	// encounter it ourselves shortly if the FindOnly flag isn't set.
-	p.Dir = dir
-	p.ImportPath = f[1]
-	p.Root = f[2]
-	p.Goroot = f[3] == "true"
+	p.Dir = res.dir
+	p.ImportPath = res.importPath
+	p.Root = res.root
+	p.Goroot = res.goroot
 	return nil
 }
 
+// goListErrorClass returns the ImportError class for the error
+// reported by 'go list' when it could not locate a package.
+func goListErrorClass(msg string) error {
+	switch {
+	case strings.Contains(msg, "go.mod file not found"):
+		return ErrNoGoMod
+	case strings.Contains(msg, "is not in GOROOT") || strings.Contains(msg, "is not in std"):
+		return ErrNotInGOROOT
+	case strings.Contains(msg, "no required module provides package") ||
+		strings.Contains(msg, "cannot find module providing package"):
+		return ErrNoModule
+	}
+	return ErrNotFound
+}
+
 func equal(x, y []string) bool {
 	if len(x) != len(y) {
 		return false
@@ -1366,7 +2092,76 @@ func parseWord(data []byte) (word, rest []byte) {
 // MatchFile considers the name of the file and may use ctxt.OpenFile to
 // read some or all of the file's content.
 func (ctxt *Context) MatchFile(dir, name string) (match bool, err error) {
-	info, err := ctxt.matchFile(dir, name, nil, nil, nil)
+	info, _, err := ctxt.matchFile(dir, name, nil, nil, nil)
+	return info != nil, err
+}
+
+// MatchDir reports whether the directory dir contains a Go source file,
+// possibly a test file, that Import would include in a package for ctxt,
+// which is to say whether ImportDir would find a package there instead
+// of returning a *NoGoError. It considers the files in directory order
+// and stops at the first that matches, reading only as much of each
+// as Import needs to decide: the leading comments and import
+// declarations, and nothing at all for files excluded by their names.
+//
+// MatchDir returns an error only if dir cannot be read. A file that
+// cannot be read, or whose build constraints cannot be parsed, does
+// not match.
+func (ctxt *Context) MatchDir(dir string) (bool, error) {
+	ents, err := ctxt.readDir(dir)
+	if err != nil {
+		return false, err
+	}
+	return ctxt.matchDirEntries(dir, ents), nil
+}
+
+// matchDirEntries is MatchDir for the entries ents of dir,
+// which the caller has already read.
+func (ctxt *Context) matchDirEntries(dir string, ents []fs.DirEntry) bool {
+	for _, d := range ents {
+		name := d.Name()
+		if d.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		if d.Type()&fs.ModeSymlink != 0 && ctxt.isDir(ctxt.joinPath(dir, name)) {
+			continue
+		}
+		info, _, err := ctxt.matchFile(dir, name, nil, nil, nil)
+		if err != nil || info == nil {
+			continue
+		}
+		if info.parsed != nil && info.parsed.Name.Name == "documentation" {
+			continue
+		}
+		if !ctxt.CgoEnabled && !strings.HasSuffix(name, "_test.go") && importsC(info) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// importsC reports whether the file described by info imports "C".
+func importsC(info *fileInfo) bool {
+	for _, imp := range info.imports {
+		if imp.path == "C" {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchFileContent is like MatchFile but considers data to be the
+// content of the file, instead of reading it. It lets callers that
+// already hold the file in memory, such as editors or archive readers,
+// match it without a custom OpenFile hook. The directory dir is used
+// only to form the file name reported in errors.
+func (ctxt *Context) MatchFileContent(dir, name string, data []byte) (match bool, err error) {
+	c := *ctxt
+	c.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	info, _, err := c.matchFile(dir, name, nil, nil, nil)
 	return info != nil, err
 }
 
@@ -1398,6 +2193,7 @@ type fileEmbed struct {
 // matchFile determines whether the file with the given name in the given directory
 // should be included in the package being constructed.
 // If the file should be included, matchFile returns a non-nil *fileInfo (and a nil error).
+// Otherwise, if the file is a source file, it also returns the reason for excluding it.
 // Non-nil errors are reserved for unexpected problems.
 //
 // If name denotes a Go program, matchFile reads until the end of the
@@ -1407,10 +2203,10 @@ type fileEmbed struct {
 //
 // If allTags is non-nil, matchFile records any encountered build tag
 // by setting allTags[tag] = true.
-func (ctxt *Context) matchFile(dir, name string, allTags map[string]bool, binaryOnly *bool, fset *token.FileSet) (*fileInfo, error) {
+func (ctxt *Context) matchFile(dir, name string, allTags map[string]bool, binaryOnly *bool, fset *token.FileSet) (*fileInfo, IgnoreReason, error) {
 	if strings.HasPrefix(name, "_") ||
 		strings.HasPrefix(name, ".") {
-		return nil, nil
+		return nil, IgnoredPrefix, nil
 	}
 
 	i := strings.LastIndex(name, ".")
@@ -1420,53 +2216,120 @@ func (ctxt *Context) matchFile(dir, name string, allTags map[string]bool, binary
 	ext := name[i:]
 
 	if !ctxt.goodOSArchFile(name, allTags) && !ctxt.UseAllFiles {
-		return nil, nil
+		return nil, IgnoredFileName, nil
 	}
 
 	if ext != ".go" && fileListForExt(&dummyPkg, ext) == nil {
 		// skip
-		return nil, nil
+		return nil, 0, nil
 	}
 
 	info := &fileInfo{name: ctxt.joinPath(dir, name), fset: fset}
 	if ext == ".syso" {
 		// binary, no reading
-		return info, nil
+		return info, 0, nil
 	}
 
-	f, err := ctxt.openFile(info.name)
-	if err != nil {
-		return nil, err
+	isGo := strings.HasSuffix(name, ".go")
+	if err := ctxt.readFileInfo(info, isGo); err != nil {
+		return nil, 0, err
 	}
-
-	if strings.HasSuffix(name, ".go") {
-		err = readGoInfo(f, info)
+	if isGo {
 		if strings.HasSuffix(name, "_test.go") {
 			binaryOnly = nil // ignore //go:binary-only-package comments in _test.go files
 		}
 	} else {
 		binaryOnly = nil // ignore //go:binary-only-package comments in non-Go sources
-		info.header, err = readComments(f)
-	}
-	f.Close()
-	if err != nil {
-		return nil, fmt.Errorf("read %s: %v", info.name, err)
 	}
 
 	// Look for +build comments to accept or reject the file.
 	ok, sawBinaryOnly, err := ctxt.shouldBuild(info.header, allTags)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %v", name, err)
+		return nil, 0, &FileError{
+			Name: name,
+			Kind: FileConstraintError,
+			Pos:  token.Position{Filename: info.name},
+			Err:  fmt.Errorf("%s: %v", name, err),
+		}
 	}
 	if !ok && !ctxt.UseAllFiles {
-		return nil, nil
+		return nil, IgnoredConstraint, nil
 	}
 
 	if binaryOnly != nil && sawBinaryOnly {
 		*binaryOnly = true
 	}
 
-	return info, nil
+	return info, 0, nil
+}
+
+// minParallelScan is the number of files in a directory
+// above which Import scans their headers concurrently.
+const minParallelScan = 16
+
+// A fileScan holds the result of calling matchFile for one
+// directory entry on behalf of Import.
+type fileScan struct {
+	done       bool // the entry was scanned; the other fields are valid
+	info       *fileInfo
+	reason     IgnoreReason
+	err        error
+	tags       []string // the tags matchFile recorded in allTags
+	binaryOnly bool     // matchFile set *binaryOnly
+}
+
+// scanFiles calls matchFile concurrently for the files among ents that
+// Import will consider, so that reading and scanning large directories
+// is not limited to one file at a time. The result holds the outcome for
+// each entry of ents, in order; entries that were not scanned must be
+// matched by the caller. scanFiles returns nil, scanning nothing, when
+// there are too few files to gain from concurrency, or when ctxt reads
+// files through OpenFile or FS, which need not be safe for concurrent use.
+func (ctxt *Context) scanFiles(dir string, ents []fs.DirEntry, mode ImportMode, fset *token.FileSet) []fileScan {
+	if ctxt.OpenFile != nil || ctxt.FS != nil {
+		return nil
+	}
+	var todo []int
+	for i, d := range ents {
+		name := d.Name()
+		if d.IsDir() || d.Type()&fs.ModeSymlink != 0 ||
+			mode&SkipTestFiles != 0 && strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		todo = append(todo, i)
+	}
+	if len(todo) < minParallelScan {
+		return nil
+	}
+
+	scans := make([]fileScan, len(ents))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	n := runtime.GOMAXPROCS(0)
+	if n > len(todo) {
+		n = len(todo)
+	}
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				s := &scans[i]
+				allTags := make(map[string]bool)
+				s.info, s.reason, s.err = ctxt.matchFile(dir, ents[i].Name(), allTags, &s.binaryOnly, fset)
+				for tag := range allTags {
+					s.tags = append(s.tags, tag)
+				}
+				s.done = true
+			}
+		}()
+	}
+	for _, i := range todo {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return scans
 }
 
 func cleanDecls(m map[string][]token.Position) ([]string, map[string][]token.Position) {
@@ -1514,6 +2377,24 @@ func isGoBuildComment(line []byte) bool {
 // for more about the design of binary-only packages.
 var binaryOnlyComment = []byte("//go:binary-only-package")
 
+// ShouldBuild reports whether a source file with the given content
+// should be built in ctxt according to its build constraints: the
+// //go:build line or, in its absence, the // +build lines in the leading
+// run of comments and blank lines, as described in the package
+// documentation. It also reports whether the file contains a
+// //go:binary-only-package comment. An error is returned only for a
+// malformed //go:build line or for more than one of them.
+//
+// Only the beginning of the file is examined, so content may be just
+// the header of the file, as long as it extends past the package clause.
+// ShouldBuild does not consider the name of the file; see MatchFile.
+//
+// If allTags is non-nil, ShouldBuild sets allTags[tag] = true for each
+// build tag it consults.
+func (ctxt *Context) ShouldBuild(content []byte, allTags map[string]bool) (shouldBuild, binaryOnly bool, err error) {
+	return ctxt.shouldBuild(content, allTags)
+}
+
 // shouldBuild reports whether it is okay to use this file,
 // The rule is that in the file's leading run of // comments
 // and blank lines, which must be followed by a blank line
@@ -1579,6 +2460,100 @@ func (ctxt *Context) shouldBuild(content []byte, allTags map[string]bool) (shoul
 	return shouldBuild, sawBinaryOnly, nil
 }
 
+// ParseFileConstraints reads the header of a Go source file from r,
+// stopping at the first token that is not a comment, and returns the
+// build constraint in effect for the file: its //go:build line or, in
+// the absence of one, the conjunction of its // +build lines. The
+// expression is nil if the file has no constraint. ParseFileConstraints
+// also returns the text of the // +build lines, whether or not a
+// //go:build line overrides them, and reports whether the file has a
+// //go:binary-only-package comment.
+//
+// As for ShouldBuild, only lines in the leading run of comments and
+// blank lines that is followed by a blank line are considered.
+// ParseFileConstraints returns an error if r cannot be read, if there is
+// more than one //go:build line, or if the //go:build line is malformed.
+// A // +build line that cannot be parsed is ignored, as in ShouldBuild.
+func ParseFileConstraints(r io.Reader) (expr constraint.Expr, legacyLines []string, binaryOnly bool, err error) {
+	header, err := readComments(r)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	content, goBuild, binaryOnly, err := parseFileHeader(header)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	legacyLines = plusBuildLines(content)
+	if goBuild != nil {
+		expr, err = constraint.Parse(string(goBuild))
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("parsing //go:build line: %v", err)
+		}
+		return expr, legacyLines, binaryOnly, nil
+	}
+	return andPlusBuildLines(legacyLines), legacyLines, binaryOnly, nil
+}
+
+// fileConstraint returns the build constraint in the header of a Go
+// source file: its //go:build line or, if it has none, the conjunction
+// of its // +build lines. It returns nil if the file has no constraint
+// or if the constraint cannot be parsed.
+func fileConstraint(content []byte) constraint.Expr {
+	content, goBuild, _, err := parseFileHeader(content)
+	if err != nil {
+		return nil
+	}
+	if goBuild != nil {
+		x, err := constraint.Parse(string(goBuild))
+		if err != nil {
+			return nil
+		}
+		return x
+	}
+	return andPlusBuildLines(plusBuildLines(content))
+}
+
+// plusBuildLines returns the // +build lines in content,
+// the header returned by parseFileHeader.
+func plusBuildLines(content []byte) []string {
+	var lines []string
+	p := content
+	for len(p) > 0 {
+		line := p
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line, p = line[:i], p[i+1:]
+		} else {
+			p = p[len(p):]
+		}
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, bSlashSlash) || !bytes.Contains(line, bPlusBuild) {
+			continue
+		}
+		if text := string(line); constraint.IsPlusBuild(text) {
+			lines = append(lines, text)
+		}
+	}
+	return lines
+}
+
+// andPlusBuildLines returns the conjunction of the // +build lines,
+// ignoring any that cannot be parsed, or nil if there are none.
+func andPlusBuildLines(lines []string) constraint.Expr {
+	var expr constraint.Expr
+	for _, text := range lines {
+		x, err := constraint.Parse(text)
+		if err != nil {
+			continue
+		}
+		if expr == nil {
+			expr = x
+		} else {
+			expr = &constraint.AndExpr{X: expr, Y: x}
+		}
+	}
+	return expr
+}
+
 func parseFileHeader(content []byte) (trimmed, goBuild []byte, sawBinaryOnly bool, err error) {
 	end := 0
 	p := content
@@ -1878,9 +2853,41 @@ func (ctxt *Context) matchAuto(text string, allTags map[string]bool) bool {
 }
 
 func (ctxt *Context) eval(x constraint.Expr, allTags map[string]bool) bool {
+	if s := ctxt.Stats; s != nil {
+		atomic.AddInt64(&s.ConstraintEvals, 1)
+	}
 	return x.Eval(func(tag string) bool { return ctxt.matchTag(tag, allTags) })
 }
 
+// MatchConstraint reports whether the build constraint expression x
+// is satisfied in ctxt. Each tag in x is satisfied as it would be in
+// a //go:build line of a file being considered by Import: if it is the
+// target operating system or architecture, or an operating system that
+// the target implies, such as "unix" or "linux" for "android"; the
+// compiler; "cgo" when cgo is enabled; or one of ctxt.BuildTags,
+// ctxt.ToolTags, or ctxt.ReleaseTags; and if it is not negated by
+// a "!tag" element of ctxt.BuildTags. ctxt.TagExpr overrides all of
+// these for the tags it settles.
+func (ctxt *Context) MatchConstraint(x constraint.Expr) bool {
+	return ctxt.eval(x, nil)
+}
+
+// MatchConstraintString is like MatchConstraint but parses the build
+// constraint from s, which is an expression such as
+// "linux && (amd64 || arm64)" or a complete //go:build or // +build line.
+// It returns an error if s cannot be parsed.
+func (ctxt *Context) MatchConstraintString(s string) (bool, error) {
+	line := s
+	if !strings.HasPrefix(s, "//") {
+		line = "//go:build " + s
+	}
+	x, err := constraint.Parse(line)
+	if err != nil {
+		return false, err
+	}
+	return ctxt.eval(x, nil), nil
+}
+
 // matchTag reports whether the name is one of:
 //
 //	cgo (if cgo is enabled)
@@ -1891,12 +2898,28 @@ func (ctxt *Context) eval(x constraint.Expr, allTags map[string]bool) bool {
 //	solaris (if GOOS = illumos)
 //	tag (if tag is listed in ctxt.BuildTags or ctxt.ReleaseTags)
 //
+// unless "!" followed by the name is listed in ctxt.BuildTags,
+// and unless ctxt.TagExpr settles the name otherwise.
 // It records all consulted tags in allTags.
 func (ctxt *Context) matchTag(name string, allTags map[string]bool) bool {
 	if allTags != nil {
 		allTags[name] = true
 	}
 
+	// tags settled by TagExpr
+	if ctxt.TagExpr != nil {
+		if value, ok := settledTag(ctxt.TagExpr, name); ok {
+			return value
+		}
+	}
+
+	// negated tags
+	for _, tag := range ctxt.BuildTags {
+		if len(tag) > 1 && tag[0] == '!' && tag[1:] == name {
+			return false
+		}
+	}
+
 	// special tags
 	if ctxt.CgoEnabled && name == "cgo" {
 		return true
@@ -1937,6 +2960,39 @@ func (ctxt *Context) matchTag(name string, allTags map[string]bool) bool {
 	return false
 }
 
+// settledTag reports the value that x, an expression as described for
+// Context.TagExpr, gives the tag name, and whether it gives one.
+func settledTag(x constraint.Expr, name string) (value, ok bool) {
+	switch x := x.(type) {
+	case *constraint.TagExpr:
+		return true, x.Tag == name
+	case *constraint.NotExpr:
+		if t, isTag := x.X.(*constraint.TagExpr); isTag && t.Tag == name {
+			return false, true
+		}
+	case *constraint.AndExpr:
+		if value, ok := settledTag(x.X, name); ok {
+			return value, true
+		}
+		return settledTag(x.Y, name)
+	}
+	return false, false
+}
+
+// MatchFileName reports whether a file with the given name would be
+// included in ctxt according to the $GOOS and $GOARCH suffixes in the
+// name alone, as in "x_linux.go", "x_amd64_test.go", or "x_linux_amd64.s".
+// A name without such suffixes is always included. MatchFileName does
+// not consider the rest of the file name, such as the extension or a
+// leading '_' or '.', nor the contents of the file; see MatchFile and
+// ShouldBuild.
+//
+// If allTags is non-nil, MatchFileName sets allTags[tag] = true for the
+// operating system and architecture named by the suffixes, if any.
+func (ctxt *Context) MatchFileName(name string, allTags map[string]bool) bool {
+	return ctxt.goodOSArchFile(name, allTags)
+}
+
 // goodOSArchFile returns false if the name contains a $GOOS or $GOARCH
 // suffix which does not match the current system.
 // The recognized name formats are:
@@ -1982,7 +3038,11 @@ func (ctxt *Context) goodOSArchFile(name string, allTags map[string]bool) bool {
 	return true
 }
 
-// ToolDir is the directory containing build tools.
+// ToolDir is the directory containing build tools. For the gc
+// toolchain, it defaults to $GOTOOLDIR if that is set and otherwise to
+// pkg/tool/GOOS_GOARCH in the GOROOT of the running program. For gccgo,
+// it defaults to $GCCGOTOOLDIR if that is set and otherwise to the
+// directory configured when gccgo was built.
 var ToolDir = getToolDir()
 
 // IsLocalImport reports whether the import path is
@@ -1992,6 +3052,46 @@ func IsLocalImport(path string) bool {
 		strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../")
 }
 
+// pkgTarget returns the install root and the installed archive of the
+// package with the given import path in the Go tree root, given their
+// default locations relative to root, targetRoot and obj, by calling
+// ctxt.PkgTarget if it is set. It returns "" for both if obj is "",
+// meaning that the package has no install location.
+func (ctxt *Context) pkgTarget(root, importPath, targetRoot, obj string) (string, string) {
+	if obj == "" {
+		return "", ""
+	}
+	if f := ctxt.PkgTarget; f != nil {
+		return f(root, importPath)
+	}
+	return ctxt.joinPath(root, targetRoot), ctxt.joinPath(root, obj)
+}
+
+// IsRelativeImportPath reports whether the import path is relative to
+// the importing directory, like ".", "..", "./foo", or "../foo".
+// It is the same as IsLocalImport, under the name the go command uses.
+func IsRelativeImportPath(path string) bool {
+	return IsLocalImport(path)
+}
+
+// IsStandardImportPath reports whether the import path names a package
+// in the standard library or the Go distribution's commands, as the go
+// command decides it: by whether the first element of the path lacks a
+// dot. So "fmt", "cmd/internal/obj", and "vendor/golang.org/x/net/route"
+// are standard, but "golang.org/x/net/route" is not. The path need not
+// name a package that exists.
+func IsStandardImportPath(path string) bool {
+	elem, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(elem, ".")
+}
+
+// IsMetaPackage reports whether name is one of the reserved names that
+// the go command accepts in place of a list of import paths: "std",
+// "cmd", and "all". No package can have such an import path.
+func IsMetaPackage(name string) bool {
+	return name == "std" || name == "cmd" || name == "all"
+}
+
 // ArchChar returns "?" and an error.
 // In earlier versions of Go, the returned string was used to derive
 // the compiler and linker tool names, the default object file suffix,
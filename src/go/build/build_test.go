@@ -5,14 +5,27 @@
 package build
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build/constraint"
 	"internal/testenv"
 	"io"
+	"io/fs"
 	"os"
+	"os/exec"
+	pathpkg "path"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestMain(m *testing.M) {
@@ -343,7 +356,7 @@ func TestShouldBuild(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx := &Context{BuildTags: []string{"yes"}}
 			tags := map[string]bool{}
-			shouldBuild, binaryOnly, err := ctx.shouldBuild([]byte(tt.content), tags)
+			shouldBuild, binaryOnly, err := ctx.ShouldBuild([]byte(tt.content), tags)
 			if shouldBuild != tt.shouldBuild || binaryOnly != tt.binaryOnly || !reflect.DeepEqual(tags, tt.tags) || err != tt.err {
 				t.Errorf("mismatch:\n"+
 					"have shouldBuild=%v, binaryOnly=%v, tags=%v, err=%v\n"+
@@ -402,6 +415,37 @@ var matchFileTests = []struct {
 	{ctxtAndroid, "amd64.s", "", true},
 }
 
+func TestMatchFileName(t *testing.T) {
+	ctxt := &Context{GOOS: "android", GOARCH: "arm64"}
+	tests := []struct {
+		name string
+		want bool
+		tags []string
+	}{
+		{"x.go", true, nil},
+		{"linux.go", true, nil},
+		{"x_linux.go", true, []string{"linux"}},
+		{"x_android_arm64.s", true, []string{"android", "arm64"}},
+		{"x_windows.go", false, []string{"windows"}},
+		{"x_amd64_test.go", false, []string{"amd64"}},
+		{"x_linux_arm64_test.go", true, []string{"arm64", "linux"}},
+		{"x_test.go", true, nil},
+		{"x_unix.go", true, nil},
+	}
+	for _, tt := range tests {
+		tags := map[string]bool{}
+		got := ctxt.MatchFileName(tt.name, tags)
+		var gotTags []string
+		for tag := range tags {
+			gotTags = append(gotTags, tag)
+		}
+		sort.Strings(gotTags)
+		if got != tt.want || !reflect.DeepEqual(gotTags, tt.tags) {
+			t.Errorf("MatchFileName(%q) = %v with tags %q, want %v with tags %q", tt.name, got, gotTags, tt.want, tt.tags)
+		}
+	}
+}
+
 func TestMatchFile(t *testing.T) {
 	for _, tt := range matchFileTests {
 		ctxt := tt.ctxt
@@ -421,6 +465,20 @@ func TestMatchFile(t *testing.T) {
 	}
 }
 
+func TestMatchFileContent(t *testing.T) {
+	for _, tt := range matchFileTests {
+		ctxt := tt.ctxt
+		ctxt.OpenFile = func(path string) (io.ReadCloser, error) {
+			t.Fatalf("MatchFileContent opened %q", path)
+			return nil, nil
+		}
+		match, err := ctxt.MatchFileContent("x", tt.name, []byte(tt.data))
+		if match != tt.match || err != nil {
+			t.Fatalf("MatchFileContent(%q) = %v, %v, want %v, nil", tt.name, match, err, tt.match)
+		}
+	}
+}
+
 func TestImportCmd(t *testing.T) {
 	if runtime.GOOS == "ios" {
 		t.Skipf("skipping on %s/%s, no valid GOROOT", runtime.GOOS, runtime.GOARCH)
@@ -523,14 +581,18 @@ func TestImportDirNotExist(t *testing.T) {
 
 				errOk := (err != nil && strings.HasPrefix(err.Error(), "cannot find package"))
 				wantErr := `"cannot find package" error`
+				wantIs := ErrNotFound
 				if test.srcDir == "" {
 					if err != nil && strings.Contains(err.Error(), "is not in GOROOT") {
 						errOk = true
+						wantIs = ErrNotInGOROOT
 					}
 					wantErr = `"cannot find package" or "is not in GOROOT" error`
 				}
 				if !errOk {
 					t.Errorf("%s got error: %q, want %s", test.label, err, wantErr)
+				} else if !errors.Is(err, wantIs) {
+					t.Errorf("%s got error: %v, want %v", test.label, err, wantIs)
 				}
 				// If an error occurs, build.Import is documented to return
 				// a non-nil *Package containing partial information.
@@ -645,6 +707,8 @@ func TestImportPackageOutsideModule(t *testing.T) {
 		t.Fatal("importing package when no go.mod is present succeeded unexpectedly")
 	} else if errStr := err.Error(); !strings.Contains(errStr, want) {
 		t.Fatalf("error when importing package when no go.mod is present: got %q; want %q", errStr, want)
+	} else if !errors.Is(err, ErrNoGoMod) {
+		t.Fatalf("error when importing package when no go.mod is present: got %v; want ErrNoGoMod", err)
 	} else {
 		t.Logf(`ctxt.Import("example.com/p", _, FindOnly): %v`, err)
 	}
@@ -679,6 +743,29 @@ func TestIssue23594(t *testing.T) {
 	}
 }
 
+func TestSkipTestFiles(t *testing.T) {
+	p, err := ImportDir("testdata/doc", SkipTestFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.GoFiles, []string{"e.go", "f.go"}) || len(p.TestGoFiles) != 0 || len(p.XTestGoFiles) != 0 || len(p.IgnoredGoFiles) != 0 {
+		t.Errorf("ImportDir with SkipTestFiles: GoFiles = %q, TestGoFiles = %q, XTestGoFiles = %q, IgnoredGoFiles = %q",
+			p.GoFiles, p.TestGoFiles, p.XTestGoFiles, p.IgnoredGoFiles)
+	}
+
+	// A directory of test files has no Go files without them.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x_test.go"), []byte("package x\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportDir(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportDir(dir, SkipTestFiles); !errors.As(err, new(*NoGoError)) {
+		t.Errorf("ImportDir of test-only directory with SkipTestFiles: error = %v, want *NoGoError", err)
+	}
+}
+
 // TestMissingImportErrorRepetition checks that when an unknown package is
 // imported, the package path is only shown once in the error.
 // Verifies golang.org/issue/34752.
@@ -731,3 +818,2215 @@ func TestCgoImportsIgnored(t *testing.T) {
 		}
 	}
 }
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, data string, mtime time.Time) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(ctxt *Context, wantGoFiles, wantImports []string) {
+		t.Helper()
+		p, err := ctxt.ImportDir(dir, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p.GoFiles, wantGoFiles) || !reflect.DeepEqual(p.Imports, wantImports) {
+			t.Errorf("ImportDir: GoFiles = %q, Imports = %q; want %q, %q", p.GoFiles, p.Imports, wantGoFiles, wantImports)
+		}
+	}
+
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeFile("a.go", "package a\n\nimport \"fmt\"\n", mtime)
+	if err := os.Chtimes(dir, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	ctxt := Default
+	ctxt.Cache = new(Cache)
+	check(&ctxt, []string{"a.go"}, []string{"fmt"})
+
+	// Changes that keep the modification time and size go unnoticed,
+	// also by other contexts sharing the cache.
+	writeFile("a.go", "package a\n\nimport \"net\"\n", mtime)
+	writeFile("b.go", "package a\n", mtime)
+	if err := os.Chtimes(dir, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	ctxt2 := ctxt
+	ctxt2.CgoEnabled = !ctxt.CgoEnabled
+	check(&ctxt2, []string{"a.go"}, []string{"fmt"})
+
+	// Until the directory is invalidated.
+	ctxt.Cache.Invalidate(dir)
+	check(&ctxt, []string{"a.go", "b.go"}, []string{"net"})
+
+	// Other changes are noticed.
+	writeFile("a.go", "package a\n\nimport \"os\"\n", mtime)
+	check(&ctxt, []string{"a.go", "b.go"}, []string{"os"})
+	writeFile("a.go", "package a\n\nimport \"io\"\n", mtime.Add(time.Second))
+	check(&ctxt, []string{"a.go", "b.go"}, []string{"io"})
+	if err := os.Remove(filepath.Join(dir, "b.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dir, mtime.Add(time.Second), mtime.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	check(&ctxt, []string{"a.go"}, []string{"io"})
+}
+
+func TestImportPackages(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs := []ImportSpec{
+		{"./testdata/doc", wd},
+		{"./testdata/other/file", wd},
+		{"./testdata/doc", wd},
+		{"./testdata/notexist", wd},
+		{"./testdata/cgo_disabled", wd},
+	}
+	pkgs, err := Default.ImportPackages(context.Background(), specs, 0)
+	if err == nil {
+		t.Fatal("ImportPackages succeeded, want error for ./testdata/notexist")
+	}
+	if len(pkgs) != len(specs) {
+		t.Fatalf("ImportPackages returned %d packages, want %d", len(pkgs), len(specs))
+	}
+	for i, spec := range specs {
+		want, wantErr := Import(spec.Path, spec.SrcDir, 0)
+		p := pkgs[i]
+		if p == nil {
+			t.Errorf("package %d (%s) is nil", i, spec.Path)
+			continue
+		}
+		if p.Dir != want.Dir || p.Name != want.Name || !reflect.DeepEqual(p.GoFiles, want.GoFiles) {
+			t.Errorf("package %d (%s) = %s in %s with %q, want %s in %s with %q", i, spec.Path, p.Name, p.Dir, p.GoFiles, want.Name, want.Dir, want.GoFiles)
+		}
+		if (wantErr != nil) != (i == 3) {
+			t.Errorf("Import(%q) error = %v", spec.Path, wantErr)
+		}
+	}
+	if pkgs[0] != pkgs[2] {
+		t.Errorf("equal specs imported twice")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Default.ImportPackages(ctx, specs, 0); err != context.Canceled {
+		t.Errorf("ImportPackages with canceled context: error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	var got []string
+	err := Default.Walk("testdata", func(p *Package, err error) error {
+		rel, _ := filepath.Rel("testdata", p.Dir)
+		rel = filepath.ToSlash(rel)
+		if err != nil {
+			rel += " (error)"
+		}
+		got = append(got, rel)
+		if rel == "other" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"cgo_disabled",
+		"doc",
+		"multi (error)",
+		"other",
+		"withvendor/src/a/b",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk visited %q, want %q", got, want)
+	}
+}
+
+func TestWalkReadDirError(t *testing.T) {
+	errDenied := errors.New("permission denied")
+	ctxt := Default
+	ctxt.ReadDirEntries = func(dir string) ([]fs.DirEntry, error) {
+		if filepath.Base(dir) == "other" {
+			return nil, errDenied
+		}
+		return os.ReadDir(dir)
+	}
+	var got []string
+	var gotErr error
+	err := ctxt.Walk("testdata", func(p *Package, err error) error {
+		rel, _ := filepath.Rel("testdata", p.Dir)
+		rel = filepath.ToSlash(rel)
+		if rel == "other" {
+			gotErr = err
+		}
+		got = append(got, rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(gotErr, errDenied) {
+		t.Errorf("Walk passed error %v for other, want %v", gotErr, errDenied)
+	}
+	for _, rel := range got {
+		if strings.HasPrefix(rel, "other/") {
+			t.Errorf("Walk visited %s inside unreadable directory", rel)
+		}
+	}
+}
+
+func TestContextFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"goroot/src/fmt/fmt.go":                     {Data: []byte("package fmt\n")},
+		"gopath/src/example.com/p/p.go":             {Data: []byte("package p\n\nimport (\n\t\"fmt\"\n\t\"v\"\n)\n")},
+		"gopath/src/example.com/p/p_other.go":       {Data: []byte("//go:build other\n\npackage p\n")},
+		"gopath/src/example.com/p/p_test.go":        {Data: []byte("package p\n")},
+		"gopath/src/example.com/p/vendor/v/v.go":    {Data: []byte("package v\n")},
+		"gopath/src/example.com/p/internal/i/i.go":  {Data: []byte("package i\n")},
+		"gopath/src/example.com/p/testdata/x/x.go":  {Data: []byte("package x\n")},
+		"gopath/src/example.com/q/q.go":             {Data: []byte("package q\n\nimport \"v\"\n")},
+		"gopath/src/example.com/q/_ignored/ig.go":   {Data: []byte("package ig\n")},
+		"gopath/src/example.com/q/README":           {Data: []byte("q\n")},
+		"gopath/src/example.com/empty/doc/empty.md": {Data: []byte("\n")},
+	}
+	ctxt := Context{
+		GOOS:     "linux",
+		GOARCH:   "amd64",
+		GOROOT:   "/goroot",
+		GOPATH:   "/gopath",
+		Compiler: "gc",
+		FS:       fsys,
+		// Set to something other than a local Cache,
+		// which must not be used.
+		Cache: new(Cache),
+	}
+
+	p, err := ctxt.Import("example.com/p", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Dir != "/gopath/src/example.com/p" || !reflect.DeepEqual(p.GoFiles, []string{"p.go"}) ||
+		!reflect.DeepEqual(p.TestGoFiles, []string{"p_test.go"}) || !reflect.DeepEqual(p.Imports, []string{"fmt", "v"}) {
+		t.Errorf("Import(example.com/p) = %s with GoFiles %q, TestGoFiles %q, Imports %q", p.Dir, p.GoFiles, p.TestGoFiles, p.Imports)
+	}
+
+	fmtPkg, err := ctxt.Import("fmt", p.Dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fmtPkg.Goroot || fmtPkg.Dir != "/goroot/src/fmt" {
+		t.Errorf("Import(fmt) = %s, Goroot = %v; want /goroot/src/fmt, true", fmtPkg.Dir, fmtPkg.Goroot)
+	}
+
+	v, err := ctxt.Import("v", p.Dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ImportPath != "example.com/p/vendor/v" {
+		t.Errorf("Import(v) from %s = %s, want example.com/p/vendor/v", p.Dir, v.ImportPath)
+	}
+	if _, err := ctxt.Import("v", "/gopath/src/example.com/q", 0); err == nil {
+		t.Errorf("Import(v) from example.com/q succeeded, want error")
+	}
+
+	var walked []string
+	var stats ScanStats
+	ctxt.Stats = &stats
+	err = ctxt.Walk("/gopath/src", func(p *Package, err error) error {
+		if err != nil {
+			t.Errorf("Walk: %s: %v", p.Dir, err)
+		}
+		walked = append(walked, p.ImportPath)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"example.com/p", "example.com/p/internal/i", "example.com/q"}
+	if !reflect.DeepEqual(walked, want) {
+		t.Errorf("Walk visited %q, want %q", walked, want)
+	}
+	// src, example.com, p, p/internal, p/internal/i, q, empty and empty/doc,
+	// each read once.
+	if stats.ReadDirs != 8 {
+		t.Errorf("Walk read %d directories, want 8", stats.ReadDirs)
+	}
+}
+
+func TestMatchConstraint(t *testing.T) {
+	ctxt := &Context{
+		GOOS:        "android",
+		GOARCH:      "arm64",
+		Compiler:    "gc",
+		CgoEnabled:  true,
+		BuildTags:   []string{"yes"},
+		ToolTags:    []string{"goexperiment.x"},
+		ReleaseTags: []string{"go1.1", "go1.2"},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"android", true},
+		{"linux && unix", true},
+		{"linux && (amd64 || arm64)", true},
+		{"linux && amd64", false},
+		{"!windows", true},
+		{"gc && cgo", true},
+		{"gccgo", false},
+		{"yes && !no", true},
+		{"goexperiment.x", true},
+		{"go1.2 && !go1.3", true},
+		{"//go:build arm64", true},
+		{"// +build darwin arm64", true},
+		{"// +build darwin,arm64", false},
+	}
+	for _, tt := range tests {
+		got, err := ctxt.MatchConstraintString(tt.expr)
+		if err != nil || got != tt.want {
+			t.Errorf("MatchConstraintString(%q) = %v, %v; want %v, nil", tt.expr, got, err, tt.want)
+		}
+		line := tt.expr
+		if !strings.HasPrefix(line, "//") {
+			line = "//go:build " + line
+		}
+		x, err := constraint.Parse(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ctxt.MatchConstraint(x); got != tt.want {
+			t.Errorf("MatchConstraint(%q) = %v, want %v", x, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "linux &&", "(linux", "linux\namd64"} {
+		if _, err := ctxt.MatchConstraintString(bad); err == nil {
+			t.Errorf("MatchConstraintString(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":      "package a\n",
+		"b.go":      "// Copyright\n\npackage b\n",
+		"c.go":      "package a\n\nimport (\n",
+		"d.go":      "//go:build linux &&\n\npackage a\n",
+		"e_test.go": "package a\n\nimport \"C\"\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := ImportDir(dir, 0)
+	if _, ok := err.(*MultiplePackageError); !ok {
+		t.Errorf("ImportDir error = %v, want *MultiplePackageError", err)
+	}
+	want := []struct {
+		name string
+		kind FileErrorKind
+		line int
+	}{
+		{"b.go", FilePackageError, 3},
+		{"c.go", FileSyntaxError, 3},
+		{"d.go", FileConstraintError, 0},
+		{"e_test.go", FileCgoError, 3},
+	}
+	if len(p.InvalidFiles) != len(want) || len(p.InvalidGoFiles) != len(want) {
+		t.Fatalf("InvalidGoFiles = %q, InvalidFiles = %v; want %d files", p.InvalidGoFiles, p.InvalidFiles, len(want))
+	}
+	for i, w := range want {
+		fe := p.InvalidFiles[i]
+		if p.InvalidGoFiles[i] != w.name || fe.Name != w.name || fe.Kind != w.kind || fe.Pos.Line != w.line {
+			t.Errorf("InvalidFiles[%d] = %s: %v at line %d (%v); want %s: %v at line %d",
+				i, fe.Name, fe.Kind, fe.Pos.Line, fe, w.name, w.kind, w.line)
+		}
+		if fe.Pos.Filename != filepath.Join(dir, w.name) {
+			t.Errorf("InvalidFiles[%d].Pos.Filename = %q, want %q", i, fe.Pos.Filename, filepath.Join(dir, w.name))
+		}
+	}
+	if err != p.InvalidFiles[0].Err {
+		t.Errorf("ImportDir error = %v, want InvalidFiles[0].Err = %v", err, p.InvalidFiles[0].Err)
+	}
+}
+
+func TestPackageFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":       "//go:build !nosuchtag\n\npackage p\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n",
+		"b.go":       "// +build gc\n// +build !nosuchtag\n\npackage p\n\nimport (\n\t_ \"embed\"\n\t\"fmt\"\n)\n\n//go:embed x.txt\nvar s string\n",
+		"c_test.go":  "package p\n\nimport \"testing\"\n",
+		"d_test.go":  "package p_test\n\nimport \"p\"\n",
+		"ignored.go": "//go:build nosuchtag\n\npackage p\n\nimport \"strings\"\n",
+		"x.txt":      "x\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctxt := Default
+	ctxt.Compiler = "gc"
+	p, err := ctxt.ImportDir(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name       string
+		constraint string
+		imports    []string
+		embeds     []string
+	}{
+		{"a.go", "!nosuchtag", []string{"fmt", "os"}, nil},
+		{"b.go", "gc && !nosuchtag", []string{"embed", "fmt"}, []string{"x.txt"}},
+		{"c_test.go", "", []string{"testing"}, nil},
+		{"d_test.go", "", []string{"p"}, nil},
+	}
+	if len(p.Files) != len(want) {
+		t.Fatalf("got %d Files, want %d", len(p.Files), len(want))
+	}
+	for i, w := range want {
+		f := p.Files[i]
+		constraint := ""
+		if f.Constraint != nil {
+			constraint = f.Constraint.String()
+		}
+		if f.Name != w.name || constraint != w.constraint || !reflect.DeepEqual(f.Imports, w.imports) || !reflect.DeepEqual(f.EmbedPatterns, w.embeds) {
+			t.Errorf("Files[%d] = {%s, %q, %q, %q}, want {%s, %q, %q, %q}",
+				i, f.Name, constraint, f.Imports, f.EmbedPatterns, w.name, w.constraint, w.imports, w.embeds)
+		}
+		if len(f.ImportPos) != len(f.Imports) || len(f.EmbedPatternPos) != len(f.EmbedPatterns) {
+			t.Errorf("Files[%d] has %d ImportPos and %d EmbedPatternPos, want %d and %d",
+				i, len(f.ImportPos), len(f.EmbedPatternPos), len(f.Imports), len(f.EmbedPatterns))
+			continue
+		}
+		for _, pos := range append(f.ImportPos, f.EmbedPatternPos...) {
+			if pos.Filename != filepath.Join(dir, w.name) || pos.Line == 0 {
+				t.Errorf("Files[%d]: bad position %v", i, pos)
+			}
+		}
+	}
+	if pos := p.Files[0].ImportPos[1]; pos.Line != 7 {
+		t.Errorf("a.go: import %q at line %d, want 7", p.Files[0].Imports[1], pos.Line)
+	}
+}
+
+func TestImportModuleInProcess(t *testing.T) {
+	// Use a GOROOT without a go command, so that any attempt to fall back
+	// to go list fails.
+	goroot := t.TempDir()
+	modCache := t.TempDir()
+	t.Setenv("GO111MODULE", "on")
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOWORK", "off")
+	t.Setenv("GOENV", "off")
+	t.Setenv("GOMODCACHE", modCache)
+
+	modDir := t.TempDir()
+	writeFiles(t, modDir, map[string]string{
+		"go.mod": `module example.com/m
+
+go 1.17
+
+require (
+	example.com/Dep v1.0.0
+	example.com/nosum v1.0.0 // indirect
+	example.com/rep v1.2.3
+)
+
+replace example.com/rep => ./rep
+`,
+		"go.sum":            "example.com/Dep v1.0.0 h1:x=\nexample.com/Dep v1.0.0/go.mod h1:x=\nexample.com/nosum v1.0.0/go.mod h1:x=\n",
+		"inner/inner.go":    "package inner\n",
+		"nested/go.mod":     "module example.com/m/nested\n",
+		"nested/pkg/pkg.go": "package pkg\n",
+		"rep/go.mod":        "module example.com/rep\n\ngo 1.17\n",
+		"rep/x/x.go":        "package x\n",
+	})
+	writeFiles(t, modCache, map[string]string{
+		"cache/download/example.com/!dep/@v/v1.0.0.mod":  "module example.com/Dep\n\ngo 1.17\n\nrequire example.com/nosum v0.9.0\n",
+		"cache/download/example.com/nosum/@v/v1.0.0.mod": "module example.com/nosum\n\ngo 1.17\n",
+		"example.com/!dep@v1.0.0/sub/sub.go":             "package sub\n",
+		"example.com/nosum@v1.0.0/x/x.go":                "package x\n",
+	})
+
+	ctxt := Default
+	ctxt.GOROOT = goroot
+	ctxt.Dir = modDir
+	tests := []struct {
+		path string
+		dir  string // "" if the go command is needed
+		root string
+	}{
+		{"example.com/m/inner", filepath.Join(modDir, "inner"), modDir},
+		{"example.com/Dep/sub", filepath.Join(modCache, "example.com", "!dep@v1.0.0", "sub"), filepath.Join(modCache, "example.com", "!dep@v1.0.0")},
+		{"example.com/rep/x", filepath.Join(modDir, "rep", "x"), filepath.Join(modDir, "rep")},
+		{"example.com/m/nested/pkg", "", ""},
+		{"example.com/nosum/x", "", ""},
+		{"example.com/other", "", ""},
+	}
+	for _, tt := range tests {
+		p, err := ctxt.Import(tt.path, modDir, FindOnly)
+		if tt.dir == "" {
+			if err == nil {
+				t.Errorf("Import(%q) = %s, want error from go list", tt.path, p.Dir)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Import(%q): %v", tt.path, err)
+			continue
+		}
+		if p.Dir != tt.dir || p.Root != tt.root || p.ImportPath != tt.path {
+			t.Errorf("Import(%q) = {Dir: %s, Root: %s, ImportPath: %s}, want {%s, %s, %s}",
+				tt.path, p.Dir, p.Root, p.ImportPath, tt.dir, tt.root, tt.path)
+		}
+	}
+}
+
+func TestImportModuleInProcessGoEnv(t *testing.T) {
+	// The go command reads settings made by 'go env -w' from the file
+	// named by $GOENV, which the in-process resolver must honor too.
+	goroot := t.TempDir()
+	modCache := t.TempDir()
+	envFile := filepath.Join(t.TempDir(), "env")
+	for _, key := range []string{"GO111MODULE", "GOFLAGS", "GOWORK", "GOMODCACHE"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("GOENV", envFile)
+
+	modDir := t.TempDir()
+	writeFiles(t, modDir, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.17\n\nrequire example.com/dep v1.0.0\n",
+		"go.sum": "example.com/dep v1.0.0 h1:x=\nexample.com/dep v1.0.0/go.mod h1:x=\n",
+	})
+	writeFiles(t, modCache, map[string]string{
+		"cache/download/example.com/dep/@v/v1.0.0.mod": "module example.com/dep\n\ngo 1.17\n",
+		"example.com/dep@v1.0.0/sub/sub.go":            "package sub\n",
+	})
+
+	ctxt := Default
+	ctxt.GOROOT = goroot
+	ctxt.GOPATH = t.TempDir()
+	ctxt.Dir = modDir
+	dir := filepath.Join(modCache, "example.com", "dep@v1.0.0", "sub")
+	tests := []struct {
+		env string
+		dir string // "" if the go command is needed
+	}{
+		{"GOMODCACHE=" + modCache + "\n", dir},
+		{"# comment\nGOMODCACHE=" + modCache + "\n", dir},
+		{"", ""}, // module cache in GOPATH, where the module is missing
+		{"GOMODCACHE=" + modCache + "\nGOFLAGS=-modfile=other.mod\n", ""},
+		{"GOMODCACHE=" + modCache + "\nGO111MODULE=off\n", ""},
+		{"GOMODCACHE=" + modCache + "\nGOWORK=" + filepath.Join(modDir, "go.work") + "\n", ""},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile(envFile, []byte(tt.env), 0666); err != nil {
+			t.Fatal(err)
+		}
+		p, err := ctxt.Import("example.com/dep/sub", modDir, FindOnly)
+		if tt.dir == "" {
+			if err == nil {
+				t.Errorf("with go env file %q: Import = %s, want error from go list", tt.env, p.Dir)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("with go env file %q: Import: %v", tt.env, err)
+		} else if p.Dir != tt.dir {
+			t.Errorf("with go env file %q: Import = %s, want %s", tt.env, p.Dir, tt.dir)
+		}
+	}
+
+	// With ctxt.Env set but not GOENV, the go command could read a go env
+	// file that the in-process resolver cannot find.
+	ctxt.Env = []string{"GOMODCACHE=" + modCache}
+	if p, err := ctxt.Import("example.com/dep/sub", modDir, FindOnly); err == nil {
+		t.Errorf("with Env lacking GOENV: Import = %s, want error from go list", p.Dir)
+	}
+}
+
+func TestImportModuleInProcessUntidy(t *testing.T) {
+	// When the main module's go.mod file does not record the versions
+	// that minimal version selection would choose, or excludes versions,
+	// only the go command can tell how to resolve an import.
+	goroot := t.TempDir()
+	modCache := t.TempDir()
+	t.Setenv("GO111MODULE", "on")
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOWORK", "off")
+	t.Setenv("GOENV", "off")
+	t.Setenv("GOMODCACHE", modCache)
+
+	writeFiles(t, modCache, map[string]string{
+		"cache/download/example.com/a/@v/v1.0.0.mod": "module example.com/a\n\ngo 1.17\n\nrequire example.com/b v1.2.0\n",
+		"cache/download/example.com/a/@v/v1.1.0.mod": "module example.com/a\n\ngo 1.17\n\nrequire example.com/b v1.10.0\n",
+		"cache/download/example.com/a/@v/v1.2.0.mod": "module example.com/a\n\ngo 1.17\n\nrequire example.com/b v1.2.0-pre\n",
+		"cache/download/example.com/a/@v/v1.3.0.mod": "module example.com/a\n\ngo 1.16\n",
+		"cache/download/example.com/a/@v/v1.4.0.mod": "module example.com/a\n\ngo 1.17\n\nrequire example.com/b/sub v1.0.0\n",
+		"cache/download/example.com/b/@v/v1.2.0.mod": "module example.com/b\n\ngo 1.17\n",
+		"example.com/b@v1.2.0/sub/sub.go":            "package sub\n",
+	})
+	dir := filepath.Join(modCache, "example.com", "b@v1.2.0", "sub")
+
+	ctxt := Default
+	ctxt.GOROOT = goroot
+	tests := []struct {
+		aVersion string
+		extra    string // additional go.mod lines
+		dir      string // "" if the go command is needed
+	}{
+		{"v1.0.0", "", dir},
+		{"v1.2.0", "", dir}, // a requires an earlier prerelease of b
+		{"v1.1.0", "", ""},  // a requires a later version of b
+		{"v1.3.0", "", ""},  // a's module graph is not pruned
+		{"v1.4.0", "", ""},  // another module may provide the package
+		{"v1.0.0", "exclude example.com/b v1.2.0\n", ""}, // b v1.2.0 cannot be used
+		{"v1.0.0", "exclude (\n\texample.com/c v1.0.0\n)\n", ""},
+	}
+	for _, tt := range tests {
+		modDir := t.TempDir()
+		writeFiles(t, modDir, map[string]string{
+			"go.mod": "module example.com/m\n\ngo 1.17\n\nrequire example.com/a " + tt.aVersion + "\nrequire example.com/b v1.2.0\n" + tt.extra,
+			"go.sum": "example.com/a " + tt.aVersion + "/go.mod h1:x=\nexample.com/b v1.2.0 h1:x=\nexample.com/b v1.2.0/go.mod h1:x=\n",
+		})
+		ctxt.Dir = modDir
+		p, err := ctxt.Import("example.com/b/sub", modDir, FindOnly)
+		if tt.dir == "" {
+			if err == nil {
+				t.Errorf("with a@%s %q: Import = %s, want error from go list", tt.aVersion, tt.extra, p.Dir)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("with a@%s %q: Import: %v", tt.aVersion, tt.extra, err)
+		} else if p.Dir != tt.dir {
+			t.Errorf("with a@%s %q: Import = %s, want %s", tt.aVersion, tt.extra, p.Dir, tt.dir)
+		}
+	}
+}
+
+// writeFiles writes the files, named by slash-separated paths
+// relative to dir, creating any directories needed.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadGraph(t *testing.T) {
+	t.Setenv("GO111MODULE", "off")
+	gopath := t.TempDir()
+	files := map[string]string{
+		"a/a.go":      "package a\n\nimport (\n\t\"b\"\n\t\"c\"\n)\n",
+		"a/a_test.go": "package a\n\nimport \"d\"\n",
+		"b/b.go":      "package b\n\nimport \"c\"\n",
+		"c/c.go":      "package c\n",
+		"d/d.go":      "package d\n\nimport (\n\t\"c\"\n\t\"nosuchpkg\"\n)\n",
+		"x/x.go":      "package x\n\nimport \"y\"\n",
+		"y/y.go":      "package y\n\nimport \"x\"\n",
+	}
+	for name, data := range files {
+		name = filepath.Join(gopath, "src", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctxt := Default
+	ctxt.GOPATH = gopath
+
+	paths := func(pkgs []*Package) []string {
+		var list []string
+		for _, p := range pkgs {
+			list = append(list, p.ImportPath)
+		}
+		return list
+	}
+
+	g, err := LoadGraph(&ctxt, []string{"a", "b"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := paths(g.Roots), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Roots = %q, want %q", got, want)
+	}
+	if got, want := paths(g.Packages), []string{"c", "b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Packages = %q, want %q", got, want)
+	}
+	a, b := g.Roots[0], g.Roots[1]
+	if got, want := paths(g.Imports(a)), []string{"b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Imports(a) = %q, want %q", got, want)
+	}
+	if g.Imports(a)[0] != b || g.Imports(a)[1] != g.Imports(b)[0] {
+		t.Errorf("shared dependencies loaded more than once")
+	}
+
+	g, err = LoadGraph(&ctxt, []string{"d"}, 0)
+	if err == nil {
+		t.Errorf("LoadGraph(d) succeeded, want error for nosuchpkg")
+	}
+	if len(g.Packages) != 3 {
+		t.Fatalf("LoadGraph(d) loaded %q, want 3 packages", paths(g.Packages))
+	}
+	for _, p := range g.Packages {
+		if (g.Err(p) != nil) != (p.ImportPath == "nosuchpkg") {
+			t.Errorf("Err(%s) = %v", p.ImportPath, g.Err(p))
+		}
+	}
+
+	_, err = LoadGraph(&ctxt, []string{"x"}, 0)
+	if e, ok := err.(*ImportCycleError); !ok {
+		t.Errorf("LoadGraph(x) error = %v, want *ImportCycleError", err)
+	} else if want := []string{"x", "y", "x"}; !reflect.DeepEqual(e.Cycle, want) {
+		t.Errorf("Cycle = %q, want %q", e.Cycle, want)
+	}
+}
+
+func TestMatchPatternsStd(t *testing.T) {
+	testenv.MustHaveGoBuild(t)
+	out, err := exec.Command(testenv.GoToolPath(t), "list", "std", "net/...").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Fields(string(out))
+	got, err := Default.MatchPatterns("std", "net/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchPatterns(std, net/...) = %q\nwant (from go list) %q", got, want)
+	}
+}
+
+func TestMatchPatterns(t *testing.T) {
+	write := func(root string, files map[string]string) {
+		for name, data := range files {
+			name = filepath.Join(root, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(name, []byte(data), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	match := func(t *testing.T, ctxt *Context, patterns []string, want []string) {
+		t.Helper()
+		got, err := ctxt.MatchPatterns(patterns...)
+		if err != nil {
+			t.Errorf("MatchPatterns(%q): %v", patterns, err)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("MatchPatterns(%q) = %q, want %q", patterns, got, want)
+		}
+	}
+
+	t.Run("GOPATH", func(t *testing.T) {
+		t.Setenv("GO111MODULE", "off")
+		gopath := t.TempDir()
+		write(filepath.Join(gopath, "src"), map[string]string{
+			"a/a.go":                 "package a\n",
+			"a/b/b.go":               "package b\n",
+			"a/b/c/README":           "no Go files\n",
+			"a/d/d_windows.go":       "package d\n",
+			"a/e/e.go":               "//go:build ignore\n\npackage e\n",
+			"a/vendor/v/v.go":        "package v\n",
+			"a/testdata/t/t.go":      "package t\n",
+			"a/_x/x.go":              "package x\n",
+			"a/.y/y.go":              "package y\n",
+			"other/o.go":             "package other\n",
+			"other/a/nested/nest.go": "package nested\n",
+		})
+		ctxt := Default
+		ctxt.GOROOT = ""
+		ctxt.GOOS = "linux"
+		ctxt.GOPATH = gopath
+		ctxt.Dir = filepath.Join(gopath, "src", "a")
+		match(t, &ctxt, []string{"a/..."}, []string{"a", "a/b"})
+		match(t, &ctxt, []string{"a/b/...", "a/...", "fmt"}, []string{"a/b", "a", "fmt"})
+		match(t, &ctxt, []string{"a/vendor/..."}, []string{"a/vendor/v"})
+		match(t, &ctxt, []string{".../nested"}, []string{"other/a/nested"})
+		match(t, &ctxt, []string{"./...", "."}, []string{"a", "a/b"})
+		match(t, &ctxt, []string{"../other/..."}, []string{"other", "other/a/nested"})
+		ctxt.GOOS = "windows"
+		match(t, &ctxt, []string{"./..."}, []string{"a", "a/b", "a/d"})
+		if _, err := ctxt.MatchPatterns("all"); err == nil {
+			t.Errorf("MatchPatterns(all) succeeded, want error")
+		}
+		if _, err := ctxt.MatchPatterns("./nonexistent"); err == nil {
+			t.Errorf("MatchPatterns(./nonexistent) succeeded, want error")
+		}
+
+		// Like the go command, name packages outside GOPATH
+		// after their directories.
+		outside := filepath.Join(t.TempDir(), "x")
+		write(outside, map[string]string{
+			"a/a.go": "package a\n",
+			"b/b.go": "package b\n",
+		})
+		dirPath := func(dir string) string {
+			return pathpkg.Join("_", strings.ReplaceAll(filepath.ToSlash(dir), ":", "_"))
+		}
+		ctxt.GOOS = "linux"
+		ctxt.Dir = outside
+		match(t, &ctxt, []string{"./..."}, []string{dirPath(filepath.Join(outside, "a")), dirPath(filepath.Join(outside, "b"))})
+		match(t, &ctxt, []string{"./b"}, []string{dirPath(filepath.Join(outside, "b"))})
+	})
+
+	t.Run("modules", func(t *testing.T) {
+		modCache := t.TempDir()
+		t.Setenv("GO111MODULE", "on")
+		t.Setenv("GOMODCACHE", modCache)
+		modDir := t.TempDir()
+		write(modDir, map[string]string{
+			"go.mod":            "module example.com/m\n\ngo 1.17\n\nrequire example.com/Dep v1.0.0\n",
+			"m.go":              "package m\n",
+			"x/x.go":            "package x\n",
+			"nested/go.mod":     "module example.com/m/nested\n",
+			"nested/nested.go":  "package nested\n",
+			"x/testdata/t/t.go": "package t\n",
+		})
+		write(modCache, map[string]string{
+			"example.com/!dep@v1.0.0/dep.go":     "package dep\n",
+			"example.com/!dep@v1.0.0/sub/sub.go": "package sub\n",
+		})
+		ctxt := Default
+		ctxt.GOROOT = ""
+		ctxt.Dir = filepath.Join(modDir, "x")
+		match(t, &ctxt, []string{"example.com/..."}, []string{"example.com/Dep", "example.com/Dep/sub", "example.com/m", "example.com/m/x"})
+		match(t, &ctxt, []string{"example.com/m/..."}, []string{"example.com/m", "example.com/m/x"})
+		match(t, &ctxt, []string{"../..."}, []string{"example.com/m", "example.com/m/x"})
+		match(t, &ctxt, []string{"."}, []string{"example.com/m/x"})
+	})
+}
+
+func TestApplyEnv(t *testing.T) {
+	ctxt := Default
+	err := ctxt.ApplyEnv([]string{
+		"GOOS=linux",
+		"GOOS=windows",
+		"GOARCH=arm64",
+		"CGO_ENABLED=0",
+		"GOEXPERIMENT=fieldtrack",
+		"GOFLAGS=-mod=mod --tags=foo,,bar -race -installsuffix=x",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctxt.GOOS != "windows" || ctxt.GOARCH != "arm64" || ctxt.CgoEnabled {
+		t.Errorf("GOOS, GOARCH, CgoEnabled = %s, %s, %v; want windows, arm64, false", ctxt.GOOS, ctxt.GOARCH, ctxt.CgoEnabled)
+	}
+	if want := []string{"foo", "bar"}; !reflect.DeepEqual(ctxt.BuildTags, want) {
+		t.Errorf("BuildTags = %q, want %q", ctxt.BuildTags, want)
+	}
+	if ctxt.InstallSuffix != "x_race" {
+		t.Errorf("InstallSuffix = %q, want %q", ctxt.InstallSuffix, "x_race")
+	}
+	for _, tag := range []string{"goexperiment.fieldtrack", "race"} {
+		if ok, _ := ctxt.MatchConstraintString(tag); !ok {
+			t.Errorf("ToolTags = %q, want %s", ctxt.ToolTags, tag)
+		}
+	}
+
+	// Without GOEXPERIMENT, the default experiments are enabled.
+	if err := ctxt.ApplyEnv(nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := ctxt.MatchConstraintString("goexperiment.fieldtrack || race"); ok {
+		t.Errorf("ToolTags = %q after ApplyEnv(nil), want no fieldtrack or race", ctxt.ToolTags)
+	}
+
+	for _, env := range []string{"GOEXPERIMENT=nosuchexperiment", "GOFLAGS=tags", "GOFLAGS=-race=maybe", "CGO_ENABLED=yes"} {
+		c := ctxt
+		if err := c.ApplyEnv([]string{"GOOS=plan9", env}); err == nil {
+			t.Errorf("ApplyEnv(%s) succeeded, want error", env)
+		} else if c.GOOS != ctxt.GOOS {
+			t.Errorf("ApplyEnv(%s) changed GOOS despite error", env)
+		}
+	}
+}
+
+func TestResolveEmbeds(t *testing.T) {
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+	fsys := fstest.MapFS{
+		"src/p/p.go":                 file("package p\n\nimport \"embed\"\n\n//go:embed static a*.txt all:hidden\nvar f embed.FS\n"),
+		"src/p/p_test.go":            file("package p\n\nimport _ \"embed\"\n\n//go:embed testdata/golden.txt\nvar s string\n"),
+		"src/p/a1.txt":               file("a1\n"),
+		"src/p/a2.txt":               file("a2\n"),
+		"src/p/b.txt":                file("b\n"),
+		"src/p/static/index.html":    file("<html>\n"),
+		"src/p/static/.hidden":       file("\n"),
+		"src/p/static/_skip":         file("\n"),
+		"src/p/static/sub/x.css":     file("\n"),
+		"src/p/static/nested/go.mod": file("module nested\n"),
+		"src/p/static/nested/n.txt":  file("\n"),
+		"src/p/static/.git/config":   file("\n"),
+		"src/p/hidden/.dot":          file("\n"),
+		"src/p/hidden/_under":        file("\n"),
+		"src/p/hidden/.git/config":   file("\n"),
+		"src/p/empty/.only":          file("\n"),
+		"src/p/link":                 {Data: []byte("a1.txt"), Mode: fs.ModeSymlink},
+		"src/p/testdata/golden.txt":  file("golden\n"),
+	}
+	ctxt := Context{GOOS: "linux", GOARCH: "amd64", Compiler: "gc", FS: fsys}
+	p, err := ctxt.ImportDir("/src/p", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ctxt.ResolveEmbeds(p); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a1.txt", "a2.txt", "hidden/.dot", "hidden/_under", "static/index.html", "static/sub/x.css"}
+	if !reflect.DeepEqual(p.EmbedFiles, want) {
+		t.Errorf("EmbedFiles = %q, want %q", p.EmbedFiles, want)
+	}
+	if want := []string{"testdata/golden.txt"}; !reflect.DeepEqual(p.TestEmbedFiles, want) {
+		t.Errorf("TestEmbedFiles = %q, want %q", p.TestEmbedFiles, want)
+	}
+	if p.XTestEmbedFiles != nil {
+		t.Errorf("XTestEmbedFiles = %q, want nil", p.XTestEmbedFiles)
+	}
+
+	for _, tt := range []struct {
+		pattern string
+		err     string
+	}{
+		{"../x", "invalid pattern syntax"},
+		{"[", "syntax error in pattern"},
+		{"nosuch*", "no matching files found"},
+		{"empty", "cannot embed directory empty: contains no embeddable files"},
+		{"static/nested/n.txt", "cannot embed file static/nested/n.txt: in different module"},
+		{"static/nested", "cannot embed directory static/nested: in different module"},
+		{"static/.git/config", "cannot embed file static/.git/config: in invalid directory .git"},
+		{"link", "cannot embed irregular file link"},
+	} {
+		q := &Package{Dir: "/src/p", EmbedFiles: []string{"old"}, EmbedPatterns: []string{"b.txt", tt.pattern}}
+		err := ctxt.ResolveEmbeds(q)
+		if want := "pattern " + tt.pattern + ": " + tt.err; err == nil || err.Error() != want {
+			t.Errorf("ResolveEmbeds(%s) error = %v, want %s", tt.pattern, err, want)
+		}
+		if len(q.EmbedFiles) != 1 {
+			t.Errorf("ResolveEmbeds(%s) changed EmbedFiles to %q despite error", tt.pattern, q.EmbedFiles)
+		}
+	}
+}
+
+func TestReadDirEntries(t *testing.T) {
+	want, err := ImportDir("testdata/doc", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dirs []string
+	ctxt := Default
+	ctxt.ReadDirEntries = func(dir string) ([]fs.DirEntry, error) {
+		dirs = append(dirs, dir)
+		return os.ReadDir(dir)
+	}
+	ctxt.ReadDir = func(dir string) ([]fs.FileInfo, error) {
+		t.Errorf("ReadDir(%s) called, want ReadDirEntries", dir)
+		return nil, errors.New("unexpected ReadDir")
+	}
+	p, err := ctxt.ImportDir("testdata/doc", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.GoFiles, want.GoFiles) || !reflect.DeepEqual(p.TestGoFiles, want.TestGoFiles) {
+		t.Errorf("with ReadDirEntries: GoFiles, TestGoFiles = %q, %q; want %q, %q", p.GoFiles, p.TestGoFiles, want.GoFiles, want.TestGoFiles)
+	}
+	if len(dirs) != 1 || dirs[0] != "testdata/doc" {
+		t.Errorf("ReadDirEntries called for %q, want [testdata/doc]", dirs)
+	}
+
+	// A context with only ReadDir still works.
+	ctxt.ReadDirEntries = nil
+	ctxt.ReadDir = func(dir string) ([]fs.FileInfo, error) {
+		ents, err := os.ReadDir(dir)
+		var infos []fs.FileInfo
+		for _, ent := range ents {
+			info, err := ent.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		return infos, err
+	}
+	p, err = ctxt.ImportDir("testdata/doc", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.GoFiles, want.GoFiles) || !reflect.DeepEqual(p.TestGoFiles, want.TestGoFiles) {
+		t.Errorf("with ReadDir: GoFiles, TestGoFiles = %q, %q; want %q, %q", p.GoFiles, p.TestGoFiles, want.GoFiles, want.TestGoFiles)
+	}
+}
+
+func TestSubdir(t *testing.T) {
+	root := filepath.FromSlash("/root/src")
+	if rel, ok := Default.Subdir(root, filepath.Join(root, "a", "b")); !ok || rel != "a/b" {
+		t.Errorf("Subdir(%s, %s/a/b) = %q, %v; want a/b, true", root, root, rel, ok)
+	}
+	if rel, ok := Default.Subdir(root, root+"x"); ok {
+		t.Errorf("Subdir(%s, %sx) = %q, %v; want false", root, root, rel, ok)
+	}
+
+	testenv.MustHaveSymlink(t)
+	tmp := t.TempDir()
+	realDir := filepath.Join(tmp, "real")
+	other := filepath.Join(tmp, "other")
+	link := filepath.Join(tmp, "link")
+	for _, dir := range []string{filepath.Join(realDir, "sub"), filepath.Join(other, "sub")} {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(realDir, link); err != nil {
+		t.Fatal(err)
+	}
+
+	ctxt := Default
+	ctxt.Cache = new(Cache)
+	for i := 0; i < 2; i++ {
+		if rel, ok := ctxt.Subdir(realDir, filepath.Join(link, "sub")); !ok || rel != "sub" {
+			t.Fatalf("Subdir(real, link/sub) = %q, %v; want sub, true", rel, ok)
+		}
+	}
+
+	// Retarget the link. The Cache keeps the old target until invalidated.
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(other, link); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ctxt.Subdir(realDir, filepath.Join(link, "sub")); !ok {
+		t.Errorf("Subdir(real, link/sub) = false after retargeting link, want cached true")
+	}
+	ctxt.Cache.Invalidate(link)
+	if rel, ok := ctxt.Subdir(realDir, filepath.Join(link, "sub")); ok {
+		t.Errorf("Subdir(real, link/sub) = %q, true after Invalidate, want false", rel)
+	}
+	if _, ok := Default.Subdir(realDir, filepath.Join(link, "sub")); ok {
+		t.Errorf("Subdir without Cache used a stale link target")
+	}
+}
+
+func TestImportOpts(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":     "package p\n",
+		"foo.go":   "//go:build foo\n\npackage p\n",
+		"go99.go":  "//go:build go1.99\n\npackage p\n",
+		"cgo.go":   "package p\n\nimport \"C\"\n",
+		"nocgo.go": "//go:build !cgo\n\npackage p\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctxt := Default
+	ctxt.CgoEnabled = true
+	yes, no := true, false
+	for _, tt := range []struct {
+		opts    ImportOptions
+		goFiles []string
+	}{
+		{ImportOptions{}, []string{"a.go"}},
+		{ImportOptions{BuildTags: []string{"foo"}}, []string{"a.go", "foo.go"}},
+		{ImportOptions{ReleaseTags: []string{"go1.1", "go1.99"}}, []string{"a.go", "go99.go"}},
+		{ImportOptions{CgoEnabled: &no}, []string{"a.go", "nocgo.go"}},
+		{ImportOptions{CgoEnabled: &yes, BuildTags: []string{}}, []string{"a.go"}},
+	} {
+		p, err := ctxt.ImportOpts(".", dir, tt.opts)
+		if err != nil {
+			t.Errorf("ImportOpts(%+v): %v", tt.opts, err)
+			continue
+		}
+		if !reflect.DeepEqual(p.GoFiles, tt.goFiles) {
+			t.Errorf("ImportOpts(%+v): GoFiles = %q, want %q", tt.opts, p.GoFiles, tt.goFiles)
+		}
+	}
+	if !ctxt.CgoEnabled || ctxt.BuildTags != nil {
+		t.Errorf("ImportOpts modified the Context")
+	}
+
+	p, err := ctxt.ImportOpts(".", dir, ImportOptions{Mode: FindOnly, BuildTags: []string{"foo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Dir != dir || p.GoFiles != nil {
+		t.Errorf("ImportOpts with FindOnly: Dir = %s, GoFiles = %q; want %s, none", p.Dir, p.GoFiles, dir)
+	}
+}
+
+func TestImportParallelScan(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"bad.go":     "package p\n\nimport (\n",
+		"binary.go":  "//go:binary-only-package\n\npackage p\n",
+		"c.c":        "// C source\n",
+		"cgo.go":     "package p\n\nimport \"C\"\n",
+		"badtag.go":  "//go:build (\n\npackage p\n",
+		"x_test.go":  "package p_test\n\nimport \"testing\"\n",
+		"_hidden.go": "package p\n",
+	}
+	for i := 0; i < 2*minParallelScan; i++ {
+		files[fmt.Sprintf("f%d.go", i)] = fmt.Sprintf("package p\n\nimport \"f%d\"\n", i)
+		files[fmt.Sprintf("t%d.go", i)] = fmt.Sprintf("//go:build tag%d\n\npackage p\n", i)
+		files[fmt.Sprintf("f%d_windows.go", i)] = "package p\n"
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctxt := Default
+	ctxt.GOOS = "linux"
+	ctxt.CgoEnabled = true
+	ctxt.BuildTags = []string{"tag3"}
+	parallel, err1 := ctxt.ImportDir(dir, 0)
+
+	// Reading files through OpenFile disables concurrent scanning.
+	ctxt.OpenFile = func(name string) (io.ReadCloser, error) { return os.Open(name) }
+	sequential, err2 := ctxt.ImportDir(dir, 0)
+
+	if fmt.Sprint(err1) != fmt.Sprint(err2) {
+		t.Errorf("errors differ:\nparallel:   %v\nsequential: %v", err1, err2)
+	}
+	if !reflect.DeepEqual(parallel, sequential) {
+		t.Errorf("packages differ:\nparallel:   %+v\nsequential: %+v", parallel, sequential)
+	}
+	if !parallel.BinaryOnly || len(parallel.GoFiles) != 2*minParallelScan+3 || len(parallel.InvalidGoFiles) != 2 {
+		t.Errorf("BinaryOnly = %v, GoFiles = %q, InvalidGoFiles = %q", parallel.BinaryOnly, parallel.GoFiles, parallel.InvalidGoFiles)
+	}
+}
+
+func TestLazyLoad(t *testing.T) {
+	ctxt := Default
+	want, wantErr := ctxt.ImportDir("testdata/multi", 0)
+	if wantErr == nil {
+		t.Fatal("ImportDir(testdata/multi) succeeded, want error")
+	}
+
+	p, err := ctxt.ImportDir("testdata/multi", LazyLoad)
+	if err != nil {
+		t.Fatalf("ImportDir(testdata/multi, LazyLoad): %v", err)
+	}
+	if p.Dir != want.Dir || p.Name != "" || len(p.GoFiles) != 0 {
+		t.Fatalf("lazy package has Dir = %q, Name = %q, GoFiles = %q; want only Dir = %q", p.Dir, p.Name, p.GoFiles, want.Dir)
+	}
+	for i := 0; i < 2; i++ {
+		if err := p.Complete(&ctxt); fmt.Sprint(err) != fmt.Sprint(wantErr) {
+			t.Errorf("Complete #%d = %v, want %v", i+1, err, wantErr)
+		}
+	}
+	p.lazy = nil
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("completed package differs:\nhave %+v\nwant %+v", p, want)
+	}
+
+	// Complete is a no-op for packages imported without LazyLoad.
+	p, err = ctxt.Import("go/build", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := p.GoFiles
+	if err := p.Complete(&ctxt); err != nil || !reflect.DeepEqual(p.GoFiles, files) {
+		t.Errorf("Complete on eager package = %v, GoFiles %q, want nil, %q", err, p.GoFiles, files)
+	}
+}
+
+func TestIgnoredReasons(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":         "package p\n",
+		"a_windows.go": "package p\n",
+		"b.go":         "//go:build never\n\npackage p\n",
+		"c.go":         "package p\n\nimport \"C\"\n",
+		"d.go":         "package documentation\n",
+		"e.S":          "// assembly\n",
+		"_f.go":        "package p\n",
+		"_g.txt":       "not a source file\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctxt := Default
+	ctxt.GOOS = "linux"
+	ctxt.CgoEnabled = false
+	p, err := ctxt.ImportDir(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]IgnoreReason{
+		"a_windows.go": IgnoredFileName,
+		"b.go":         IgnoredConstraint,
+		"c.go":         IgnoredCgo,
+		"d.go":         IgnoredDocumentation,
+		"e.S":          IgnoredCgo,
+		"_f.go":        IgnoredPrefix,
+	}
+	if !reflect.DeepEqual(p.IgnoredReasons, want) {
+		t.Errorf("IgnoredReasons = %v, want %v", p.IgnoredReasons, want)
+	}
+	for _, name := range append(p.IgnoredGoFiles, p.IgnoredOtherFiles...) {
+		if _, ok := p.IgnoredReasons[name]; !ok {
+			t.Errorf("no IgnoredReasons entry for %s", name)
+		}
+	}
+	if got := IgnoredConstraint.String(); got != "excluded by build constraints" {
+		t.Errorf("IgnoredConstraint.String() = %q", got)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	ctxt := Default
+	ctxt.GOPATH = t.TempDir()
+	t.Setenv("GO111MODULE", "off")
+
+	tests := []struct {
+		path, srcDir string
+		want         error
+	}{
+		{"", "", ErrInvalidImportPath},
+		{"./x", "", ErrInvalidImportPath},
+		{"/x", "", ErrInvalidImportPath},
+		{"example.com/doesnotexist", "", ErrNotFound},
+		{"./doesnotexist", ctxt.GOPATH, ErrNotFound},
+	}
+	for _, tt := range tests {
+		_, err := ctxt.Import(tt.path, tt.srcDir, FindOnly)
+		var ie *ImportError
+		if !errors.Is(err, tt.want) || !errors.As(err, &ie) || ie.ImportPath != tt.path {
+			t.Errorf("Import(%q, %q) = %v, want *ImportError for %q wrapping %v", tt.path, tt.srcDir, err, tt.path, tt.want)
+		}
+	}
+}
+
+func TestResolveCgoFlags(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skipf("skipping on %s: test uses a shell script as pkg-config", runtime.GOOS)
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("skipping test: no sh")
+	}
+	dir := t.TempDir()
+	src := `package p
+
+// #cgo CFLAGS: -I${SRCDIR}/include
+// #cgo LDFLAGS: -lm
+// #cgo pkg-config: --static foo
+import "C"
+`
+	pkgConfig := `#!/bin/sh
+case "$1" in
+--cflags) echo "-I/opt/foo/include \"-DNAME=a b\" $PKG_CONFIG_TEST_FLAG" ;;
+--libs) echo '-L/opt/foo/lib -lfoo' ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pkg-config"), []byte(pkgConfig), 0777); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PKG_CONFIG", filepath.Join(dir, "pkg-config"))
+	t.Setenv("CGO_CPPFLAGS", "")
+	t.Setenv("CGO_CFLAGS", "-O1")
+	t.Setenv("CGO_LDFLAGS", "")
+
+	ctxt := Default
+	ctxt.CgoEnabled = true
+	p, err := ctxt.ImportDir(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ctxt.ResolveCgoFlags(p); err != nil {
+		t.Fatal(err)
+	}
+	check := func(name string, have, want []string) {
+		t.Helper()
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("%s = %q, want %q", name, have, want)
+		}
+	}
+	check("ResolvedCgoCPPFLAGS", p.ResolvedCgoCPPFLAGS, []string{"-I/opt/foo/include", "-DNAME=a b"})
+	check("ResolvedCgoCFLAGS", p.ResolvedCgoCFLAGS, []string{"-O1", "-I" + dir + "/include"})
+	check("ResolvedCgoLDFLAGS", p.ResolvedCgoLDFLAGS, []string{"-g", "-O2", "-lm", "-L/opt/foo/lib", "-lfoo"})
+
+	// Flags that could run arbitrary code are rejected
+	// unless explicitly allowed.
+	p.CgoCFLAGS = []string{"-fplugin=evil.so"}
+	if err := ctxt.ResolveCgoFlags(p); err == nil || !strings.Contains(err.Error(), "invalid flag in #cgo CFLAGS") {
+		t.Errorf("ResolveCgoFlags with -fplugin = %v, want invalid flag error", err)
+	}
+	t.Setenv("CGO_CFLAGS_ALLOW", "-fplugin=.*")
+	if err := ctxt.ResolveCgoFlags(p); err != nil {
+		t.Errorf("ResolveCgoFlags with CGO_CFLAGS_ALLOW: %v", err)
+	}
+
+	// With ctxt.Env set, the process environment is not consulted,
+	// and pkg-config runs in ctxt.Env.
+	ctxt.Env = []string{
+		"PKG_CONFIG=" + filepath.Join(dir, "pkg-config"),
+		"CGO_CFLAGS=-O3",
+		"PKG_CONFIG_TEST_FLAG=-DENV",
+	}
+	if err := ctxt.ResolveCgoFlags(p); err == nil || !strings.Contains(err.Error(), "invalid flag in #cgo CFLAGS") {
+		t.Errorf("ResolveCgoFlags with -fplugin and ctxt.Env = %v, want invalid flag error", err)
+	}
+	p.CgoCFLAGS = nil
+	if err := ctxt.ResolveCgoFlags(p); err != nil {
+		t.Fatal(err)
+	}
+	check("ResolvedCgoCPPFLAGS", p.ResolvedCgoCPPFLAGS, []string{"-I/opt/foo/include", "-DNAME=a b", "-DENV"})
+	check("ResolvedCgoCFLAGS", p.ResolvedCgoCFLAGS, []string{"-O3"})
+	check("ResolvedCgoLDFLAGS", p.ResolvedCgoLDFLAGS, []string{"-g", "-O2", "-lm", "-L/opt/foo/lib", "-lfoo"})
+}
+
+func TestScanDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":         "package p\n\nimport \"fmt\"\n",
+		"b.go":         "package q\n",
+		"a_test.go":    "package p\n",
+		"x_test.go":    "package p_test\n",
+		"c.go":         "package p\n\nimport \"C\"\n",
+		"d_windows.go": "package p\n",
+		"bad.go":       "package p\n\nimport (\n",
+		"e.c":          "// C\n",
+		"f.S":          "// assembly\n",
+		"_g.go":        "package p\n",
+		"README":       "read me\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	ctxt := Default
+	ctxt.GOOS = "linux"
+	ctxt.CgoEnabled = true
+	scan, err := ctxt.ScanDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type verdict struct {
+		kind   ScanKind
+		pkg    string
+		reason IgnoreReason
+	}
+	want := map[string]verdict{
+		"README":       {ScanUnknownFile, "", 0},
+		"_g.go":        {ScanIgnoredFile, "", IgnoredPrefix},
+		"a.go":         {ScanGoFile, "p", 0},
+		"a_test.go":    {ScanTestGoFile, "p", 0},
+		"b.go":         {ScanGoFile, "q", 0},
+		"bad.go":       {ScanInvalidFile, "p", 0},
+		"c.go":         {ScanCgoFile, "p", 0},
+		"d_windows.go": {ScanIgnoredFile, "", IgnoredFileName},
+		"e.c":          {ScanOtherFile, "", 0},
+		"f.S":          {ScanOtherFile, "", 0},
+		"x_test.go":    {ScanXTestGoFile, "p_test", 0},
+	}
+	have := make(map[string]verdict)
+	for _, f := range scan.Files {
+		have[f.Name] = verdict{f.Kind, f.Package, f.Reason}
+		if (f.Kind == ScanInvalidFile) != (f.Err != nil) {
+			t.Errorf("%s: Kind = %v, Err = %v", f.Name, f.Kind, f.Err)
+		}
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("ScanDir verdicts:\nhave %v\nwant %v", have, want)
+	}
+	for _, f := range scan.Files {
+		if f.Name == "a.go" && (f.Go == nil || !reflect.DeepEqual(f.Go.Imports, []string{"fmt"})) {
+			t.Errorf("a.go: Go = %+v, want imports [fmt]", f.Go)
+		}
+		if f.Name == "bad.go" && f.Err.Kind != FileSyntaxError {
+			t.Errorf("bad.go: Err.Kind = %v, want %v", f.Err.Kind, FileSyntaxError)
+		}
+	}
+
+	// Without cgo, cgo files and .S files are ignored.
+	ctxt.CgoEnabled = false
+	scan, err = ctxt.ScanDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range scan.Files {
+		if (f.Name == "c.go" || f.Name == "f.S") && (f.Kind != ScanIgnoredFile || f.Reason != IgnoredCgo) {
+			t.Errorf("%s without cgo: Kind = %v, Reason = %v, want ignored for cgo", f.Name, f.Kind, f.Reason)
+		}
+	}
+}
+
+func TestCheckConstraints(t *testing.T) {
+	tests := []struct {
+		name, content string
+		want          []string
+	}{
+		{"ok.go", "//go:build linux && amd64\n// +build linux,amd64\n\npackage p\n", nil},
+		{"ok2.go", "//go:build linux\n\npackage p\n", nil},
+		{"none.go", "package p\n", nil},
+		{"toclose.go", "// +build yes\npackage p\n", []string{"toclose.go:1: misplaced +build comment"}},
+		{"mismatch.go", "//go:build linux\n// +build windows\n\npackage p\n", []string{"mismatch.go:2: +build lines do not match //go:build condition"}},
+		{"extra.go", "//go:build linux\n//go:build amd64\n\npackage p\n", []string{"extra.go:2: unexpected extra //go:build line"}},
+		{"syntax.go", "//go:build linux &&\n\npackage p\n", []string{"syntax.go:1: unexpected end of expression"}},
+		{"space.go", "// go:build linux\n\npackage p\n", []string{"space.go:1: malformed //go:build line (space between // and go:build)"}},
+		{"late.go", "package p\n\n//go:build linux\n", []string{"late.go:3: misplaced //go:build comment"}},
+		{"never.go", "//go:build linux && windows\n\npackage p\n", []string{"never.go:1: build constraint can never be satisfied"}},
+		{"never2.go", "//go:build foo && !foo\n\npackage p\n", []string{"never2.go:1: build constraint can never be satisfied"}},
+		{"x_windows.go", "//go:build linux\n\npackage p\n", []string{"x_windows.go:1: build constraint can never be satisfied"}},
+		{"x_linux.go", "//go:build unix && arm64\n\npackage p\n", nil},
+		{"x_android.go", "//go:build linux\n\npackage p\n", nil},
+		{"x.s", "// +build amd64,!amd64\n\nTEXT ·f(SB),0,$0\n", []string{"x.s:1: build constraint can never be satisfied"}},
+	}
+	for _, tt := range tests {
+		var have []string
+		for _, p := range CheckConstraints(tt.name, []byte(tt.content)) {
+			have = append(have, p.String())
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("CheckConstraints(%q):\nhave %q\nwant %q", tt.name, have, tt.want)
+		}
+	}
+}
+
+func TestImportEnv(t *testing.T) {
+	testenv.MustHaveGoBuild(t)
+
+	gopath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(gopath, "src/example.com/p"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gopath, "src/example.com/p/p.go"), []byte("package p"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// The environment in ctxt.Env, not that of the process,
+	// decides whether Import uses modules.
+	t.Setenv("GO111MODULE", "off")
+	ctxt := Default
+	ctxt.GOPATH = gopath
+	ctxt.Dir = filepath.Join(gopath, "src/example.com/p")
+	ctxt.Env = append(os.Environ(), "GO111MODULE=on", "GOPROXY=off", "GOFLAGS=")
+	if _, err := ctxt.Import("example.com/p", gopath, FindOnly); !errors.Is(err, ErrNoGoMod) {
+		t.Errorf("Import with GO111MODULE=on in Env: %v, want ErrNoGoMod", err)
+	}
+
+	t.Setenv("GO111MODULE", "on")
+	ctxt.Env = append(os.Environ(), "GO111MODULE=off")
+	p, err := ctxt.Import("example.com/p", gopath, FindOnly)
+	if err != nil {
+		t.Fatalf("Import with GO111MODULE=off in Env: %v", err)
+	}
+	if want := filepath.Join(gopath, "src/example.com/p"); p.Dir != want {
+		t.Errorf("Import with GO111MODULE=off in Env: Dir = %q, want %q", p.Dir, want)
+	}
+}
+
+func TestGoList(t *testing.T) {
+	testenv.MustHaveGoBuild(t)
+
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/m\n\ngo 1.16\n",
+		"a/a.go":  "package a\n",
+		"b/b.go":  "package b\n",
+		"c/c.go":  "package c\n",
+		"d/d.go":  "package d\n",
+		"e/e.go":  "package e\n",
+		"f/f.go":  "package f\n",
+		"g/g.go":  "package g\n",
+		"h/h.go":  "package h\n",
+		"i/i.go":  "package i\n",
+		"j/j1.go": "package j\n",
+	}
+	for name, data := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctxt := Default
+	ctxt.Dir = root
+	ctxt.Env = append(os.Environ(), "GO111MODULE=on", "GOPROXY=off", "GOFLAGS=-mod=mod", "GOWORK=off")
+	ctxt.GoList = new(GoList)
+
+	var specs []ImportSpec
+	for _, elem := range strings.Split("a b c d e f g h i j missing", " ") {
+		specs = append(specs, ImportSpec{"example.com/m/" + elem, root})
+	}
+	pkgs, errs := ctxt.importPackages(context.Background(), specs, FindOnly)
+	for i, spec := range specs {
+		if strings.HasSuffix(spec.Path, "/missing") {
+			var ie *ImportError
+			if !errors.As(errs[i], &ie) {
+				t.Errorf("Import(%s) = %v, want *ImportError", spec.Path, errs[i])
+			}
+			continue
+		}
+		if errs[i] != nil {
+			t.Errorf("Import(%s): %v", spec.Path, errs[i])
+			continue
+		}
+		want := filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(spec.Path, "example.com/m/")))
+		if pkgs[i].Dir != want || pkgs[i].ImportPath != spec.Path {
+			t.Errorf("Import(%s) = Dir %q, ImportPath %q; want %q, %q", spec.Path, pkgs[i].Dir, pkgs[i].ImportPath, want, spec.Path)
+		}
+	}
+
+	// GoList remembers its answers until Reset.
+	if err := os.RemoveAll(filepath.Join(root, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctxt.Import("example.com/m/a", root, FindOnly); err != nil {
+		t.Errorf("Import of removed package before Reset: %v, want remembered answer", err)
+	}
+	ctxt.GoList.Reset()
+	if _, err := ctxt.Import("example.com/m/a", root, FindOnly); err == nil {
+		t.Errorf("Import of removed package after Reset succeeded, want error")
+	}
+}
+
+func TestMatchDir(t *testing.T) {
+	dirs := []string{"testdata/empty", "testdata/multi", "testdata/doc", "testdata/cgo_disabled", "testdata/other", "testdata"}
+	err := filepath.WalkDir(filepath.Join(testenv.GOROOT(t), "src/go"), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cgo := range []bool{false, true} {
+		ctxt := Default
+		ctxt.CgoEnabled = cgo
+		for _, dir := range dirs {
+			_, err := ctxt.ImportDir(dir, 0)
+			want := !isNoGoError(err)
+			have, err := ctxt.MatchDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != want {
+				t.Errorf("MatchDir(%s) with CgoEnabled=%v = %v, want %v", dir, cgo, have, want)
+			}
+		}
+	}
+	if _, err := Default.MatchDir("testdata/doesnotexist"); err == nil {
+		t.Errorf("MatchDir of missing directory succeeded")
+	}
+}
+
+func TestParseFileConstraints(t *testing.T) {
+	tests := []struct {
+		content    string
+		expr       string
+		legacy     []string
+		binaryOnly bool
+		err        bool
+	}{
+		{content: "package p\n"},
+		{content: "//go:build linux && !cgo\n\npackage p\n", expr: "linux && !cgo"},
+		{content: "// +build linux darwin\n// +build amd64\n\npackage p\n", expr: "(linux || darwin) && amd64", legacy: []string{"// +build linux darwin", "// +build amd64"}},
+		{content: "//go:build linux\n// +build darwin\n\npackage p\n", expr: "linux", legacy: []string{"// +build darwin"}},
+		{content: "// +build linux\npackage p\n"},
+		{content: "//go:binary-only-package\n\n//go:build ignore\n\npackage p\n", expr: "ignore", binaryOnly: true},
+		{content: "//go:build linux &&\n\npackage p\n", err: true},
+		{content: "//go:build linux\n//go:build darwin\n\npackage p\n", err: true},
+	}
+	for _, tt := range tests {
+		expr, legacy, binaryOnly, err := ParseFileConstraints(strings.NewReader(tt.content))
+		if (err != nil) != tt.err {
+			t.Errorf("ParseFileConstraints(%q): err = %v, want error %v", tt.content, err, tt.err)
+			continue
+		}
+		var exprStr string
+		if expr != nil {
+			exprStr = expr.String()
+		}
+		if exprStr != tt.expr || !reflect.DeepEqual(legacy, tt.legacy) || binaryOnly != tt.binaryOnly {
+			t.Errorf("ParseFileConstraints(%q) = %q, %q, %v; want %q, %q, %v", tt.content, exprStr, legacy, binaryOnly, tt.expr, tt.legacy, tt.binaryOnly)
+		}
+	}
+}
+
+func TestImportModuleZip(t *testing.T) {
+	goroot := t.TempDir()
+	modCache := t.TempDir()
+	t.Setenv("GO111MODULE", "on")
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOWORK", "off")
+	t.Setenv("GOENV", "off")
+	t.Setenv("GOMODCACHE", modCache)
+
+	modDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.17\n\nrequire example.com/Zip v1.0.0\n",
+		"go.sum": "example.com/Zip v1.0.0 h1:x=\nexample.com/Zip v1.0.0/go.mod h1:x=\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(modDir, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	zipDir := filepath.Join(modCache, "cache", "download", "example.com", "!zip", "@v")
+	if err := os.MkdirAll(zipDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string]string{
+		"go.mod":          "module example.com/Zip\n",
+		"sub/sub.go":      "package sub\n\nimport \"fmt\"\n",
+		"sub/sub_test.go": "package sub\n",
+		"sub/x_linux.go":  "package sub\n",
+	} {
+		w, err := zw.Create("example.com/Zip@v1.0.0/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(zipDir, "v1.0.0.zip"), buf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(zipDir, "v1.0.0.mod"), []byte("module example.com/Zip\n\ngo 1.17\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	ctxt := Default
+	ctxt.GOROOT = goroot
+	ctxt.GOOS = "linux"
+	ctxt.Dir = modDir
+	if p, err := ctxt.Import("example.com/Zip/sub", modDir, 0); err == nil {
+		t.Fatalf("Import without OpenModuleZip = %s, want error from go list", p.Dir)
+	}
+
+	ctxt.OpenModuleZip = func(file string) (fs.FS, error) {
+		zr, err := zip.OpenReader(file)
+		if err != nil {
+			return nil, err
+		}
+		return zr, nil
+	}
+	root := filepath.Join(modCache, "example.com", "!zip@v1.0.0")
+	dir := filepath.Join(root, "sub")
+	for _, cache := range []*Cache{nil, new(Cache)} {
+		ctxt.Cache = cache
+		p, err := ctxt.Import("example.com/Zip/sub", modDir, 0)
+		if err != nil {
+			t.Fatalf("Import: %v", err)
+		}
+		if p.Dir != dir || p.Root != root || p.Name != "sub" {
+			t.Errorf("Import = {Dir: %s, Root: %s, Name: %s}, want {%s, %s, sub}", p.Dir, p.Root, p.Name, dir, root)
+		}
+		if want := []string{"sub.go", "x_linux.go"}; !reflect.DeepEqual(p.GoFiles, want) {
+			t.Errorf("GoFiles = %q, want %q", p.GoFiles, want)
+		}
+		if want := []string{"sub_test.go"}; !reflect.DeepEqual(p.TestGoFiles, want) {
+			t.Errorf("TestGoFiles = %q, want %q", p.TestGoFiles, want)
+		}
+		if want := []string{"fmt"}; !reflect.DeepEqual(p.Imports, want) {
+			t.Errorf("Imports = %q, want %q", p.Imports, want)
+		}
+		if _, err := ctxt.ImportDir(dir, 0); err != nil {
+			t.Errorf("ImportDir: %v", err)
+		}
+	}
+
+	// An extracted module takes precedence over its zip file.
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "extracted.go"), []byte("package sub\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	ctxt.Cache = nil
+	p, err := ctxt.ImportDir(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"extracted.go"}; !reflect.DeepEqual(p.GoFiles, want) {
+		t.Errorf("GoFiles of extracted module = %q, want %q", p.GoFiles, want)
+	}
+}
+
+func TestSrcDirsSeq(t *testing.T) {
+	t.Setenv("GO111MODULE", "off")
+	var gopath []string
+	for i := 0; i < 3; i++ {
+		dir := t.TempDir()
+		gopath = append(gopath, dir)
+		if i == 1 {
+			continue // no src directory
+		}
+		pkg := filepath.Join(dir, "src", "p")
+		if err := os.MkdirAll(pkg, 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(pkg, "p.go"), []byte("package p\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctxt := Default
+	ctxt.GOROOT = ""
+	ctxt.GOPATH = strings.Join(append([]string{""}, gopath...), string(filepath.ListSeparator))
+	if got := ctxt.GOPATHDirs(); !reflect.DeepEqual(got, gopath) {
+		t.Errorf("GOPATHDirs() = %q, want %q", got, gopath)
+	}
+	src := func(i int) string { return filepath.Join(gopath[i], "src") }
+	if got, want := ctxt.SrcDirs(), []string{src(0), src(2)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SrcDirs() = %q, want %q", got, want)
+	}
+
+	ctxt.FilterGOPATH = func(dirs []string) []string {
+		for i, j := 0, len(dirs)-1; i < j; i, j = i+1, j-1 {
+			dirs[i], dirs[j] = dirs[j], dirs[i]
+		}
+		return dirs
+	}
+	if got, want := ctxt.GOPATHDirs(), []string{gopath[2], gopath[1], gopath[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GOPATHDirs() with FilterGOPATH = %q, want %q", got, want)
+	}
+	if got, want := ctxt.SrcDirs(), []string{src(2), src(0)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SrcDirs() with FilterGOPATH = %q, want %q", got, want)
+	}
+	p, err := ctxt.Import("p", "", FindOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(src(2), "p"); p.Dir != want {
+		t.Errorf("Import(%q).Dir = %s, want %s", "p", p.Dir, want)
+	}
+
+	// Stopping the iteration early avoids checking the later directories.
+	var checked []string
+	ctxt.IsDir = func(path string) bool {
+		checked = append(checked, path)
+		fi, err := os.Stat(path)
+		return err == nil && fi.IsDir()
+	}
+	var got []string
+	ctxt.SrcDirsSeq()(func(dir string) bool {
+		got = append(got, dir)
+		return false
+	})
+	if want := []string{src(2)}; !reflect.DeepEqual(got, want) || !reflect.DeepEqual(checked, want) {
+		t.Errorf("SrcDirsSeq() stopped after first: yielded %q and checked %q, want %q", got, checked, want)
+	}
+}
+
+func TestPackageJSON(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":      "//go:build linux && !cgo\n\npackage p\n\nimport (\n\t\"embed\"\n\t\"fmt\"\n)\n\n//go:embed a.txt\nvar f embed.FS\n",
+		"b.go":      "package q\n",
+		"c.go":      "//go:build ignore\n\npackage p\n",
+		"p_test.go": "package p\n\nimport \"testing\"\n",
+		"_x.go":     "package p\n",
+		"a.txt":     "",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctxt := Default
+	ctxt.GOOS = "linux"
+	ctxt.CgoEnabled = false
+	p, err := ctxt.ImportDir(dir, 0)
+	if err == nil || len(p.InvalidFiles) != 1 || len(p.Files) == 0 {
+		t.Fatalf("ImportDir = %d invalid files, %d files, err %v; want 1 invalid file, some files and an error", len(p.InvalidFiles), len(p.Files), err)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var q Package
+	if err := json.Unmarshal(data, &q); err != nil {
+		t.Fatal(err)
+	}
+	if data2, err := json.Marshal(&q); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(data, data2) {
+		t.Errorf("encoding changed after round trip:\n%s\n%s", data, data2)
+	}
+	if got := q.InvalidFiles[0]; got.Name != "b.go" || got.Kind != p.InvalidFiles[0].Kind || got.Err.Error() != p.InvalidFiles[0].Err.Error() {
+		t.Errorf("InvalidFiles[0] = %+v, want %+v", got, p.InvalidFiles[0])
+	}
+	if got, want := q.Files[0].Constraint.String(), "linux && !cgo"; q.Files[0].Name != "a.go" || got != want {
+		t.Errorf("Files[0] = %s with constraint %q, want a.go with %q", q.Files[0].Name, got, want)
+	}
+
+	// Apart from the errors and constraint expressions,
+	// which are compared above, the packages are equal.
+	p.InvalidFiles, q.InvalidFiles = nil, nil
+	p.Files, q.Files = nil, nil
+	if !reflect.DeepEqual(p, &q) {
+		t.Errorf("round trip changed Package:\nhave %+v\nwant %+v", &q, p)
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["Version"] != 1.0 {
+		t.Errorf("Version = %v, want 1", m["Version"])
+	}
+	for _, bad := range []string{`{"Name": "p"}`, `{"Version": 2}`, `{"Version": 1, "Files": [{"Constraint": "linux &&"}]}`} {
+		if err := json.Unmarshal([]byte(bad), &q); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want error", bad)
+		}
+	}
+
+	lazy, err := ctxt.ImportDir(dir, LazyLoad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := json.Marshal(lazy); err == nil {
+		t.Errorf("Marshal of incomplete LazyLoad package succeeded, want error")
+	}
+}
+
+func TestImportPathClassification(t *testing.T) {
+	tests := []struct {
+		path               string
+		standard, relative bool
+		meta               bool
+	}{
+		{path: "fmt", standard: true},
+		{path: "net/http", standard: true},
+		{path: "cmd/internal/obj", standard: true},
+		{path: "vendor/golang.org/x/net/route", standard: true},
+		{path: "std", standard: true, meta: true},
+		{path: "cmd", standard: true, meta: true},
+		{path: "all", standard: true, meta: true},
+		{path: "golang.org/x/net/route"},
+		{path: "example.com"},
+		{path: ".", relative: true},
+		{path: "..", relative: true},
+		{path: "./x", relative: true},
+		{path: "../x/y", relative: true},
+		{path: ".x"},
+	}
+	for _, tt := range tests {
+		if got := IsStandardImportPath(tt.path); got != tt.standard {
+			t.Errorf("IsStandardImportPath(%q) = %v, want %v", tt.path, got, tt.standard)
+		}
+		if got := IsRelativeImportPath(tt.path); got != tt.relative {
+			t.Errorf("IsRelativeImportPath(%q) = %v, want %v", tt.path, got, tt.relative)
+		}
+		if got := IsMetaPackage(tt.path); got != tt.meta {
+			t.Errorf("IsMetaPackage(%q) = %v, want %v", tt.path, got, tt.meta)
+		}
+	}
+}
+
+func TestIgnoreDirs(t *testing.T) {
+	t.Setenv("GO111MODULE", "off")
+	gopath := t.TempDir()
+	for _, dir := range []string{"a", "a/node_modules/x", "bazel-out/y", "b", "b/bazel/z"} {
+		dir = filepath.Join(gopath, "src", "example.com", filepath.FromSlash(dir))
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "x.go"), []byte("package x\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctxt := Default
+	ctxt.GOROOT = ""
+	ctxt.GOPATH = gopath
+	ctxt.IgnoreDirs = []string{"node_modules", "bazel-*", "[bad"}
+	root := filepath.Join(gopath, "src", "example.com")
+	var walked []string
+	err := ctxt.Walk(root, func(p *Package, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p.Dir)
+		walked = append(walked, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a", "b", "b/bazel/z"}
+	if !reflect.DeepEqual(walked, want) {
+		t.Errorf("Walk visited %q, want %q", walked, want)
+	}
+
+	matched, err := ctxt.MatchPatterns("example.com/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, dir := range want {
+		want[i] = "example.com/" + dir
+	}
+	if !reflect.DeepEqual(matched, want) {
+		t.Errorf("MatchPatterns matched %q, want %q", matched, want)
+	}
+
+	// The root of the walk is walked whatever its name.
+	walked = nil
+	err = ctxt.Walk(filepath.Join(root, "a", "node_modules"), func(p *Package, err error) error {
+		walked = append(walked, filepath.Base(p.Dir))
+		return err
+	})
+	if err != nil || !reflect.DeepEqual(walked, []string{"x"}) {
+		t.Errorf("Walk from ignored root visited %q, %v; want [x], nil", walked, err)
+	}
+}
+
+func TestScanStats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":      "//go:build linux\n\npackage p\n",
+		"b.go":      "// +build ignore\n\npackage p\n",
+		"c.go":      "package p\n",
+		"p_test.go": "package p\n",
+	}
+	var size int64
+	for name, data := range files {
+		size += int64(len(data))
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctxt := Default
+	ctxt.GOOS = "linux"
+	ctxt.Stats = new(ScanStats)
+	if _, err := ctxt.ImportDir(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	want := ScanStats{ReadDirs: 1, FilesRead: 4, BytesRead: size, ConstraintEvals: 2}
+	if *ctxt.Stats != want {
+		t.Errorf("Stats = %+v, want %+v", *ctxt.Stats, want)
+	}
+
+	ctxt.Stats = new(ScanStats)
+	ctxt.Cache = new(Cache)
+	for i := 0; i < 2; i++ {
+		if _, err := ctxt.ImportDir(dir, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want = ScanStats{ReadDirs: 2, FilesRead: 4, BytesRead: size, ConstraintEvals: 4, CacheHits: 5, CacheMisses: 5}
+	if *ctxt.Stats != want {
+		t.Errorf("Stats with Cache = %+v, want %+v", *ctxt.Stats, want)
+	}
+}
+
+func TestResolveDeps(t *testing.T) {
+	t.Setenv("GO111MODULE", "off")
+	gopath := t.TempDir()
+	files := map[string]string{
+		"a/a.go":          "package a\n\nimport (\n\t\"b\"\n\t\"v\"\n)\n",
+		"a/a_test.go":     "package a\n\nimport \"t\"\n",
+		"a/vendor/v/v.go": "package v\n\nimport \"c\"\n",
+		"b/b.go":          "package b\n\nimport (\n\t\"C\"\n\t\"c\"\n\t\"missing\"\n)\n",
+		"c/c.go":          "package c\n\nimport \"a\"\n",
+		"t/t.go":          "package t\n",
+	}
+	for name, data := range files {
+		name = filepath.Join(gopath, "src", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctxt := Default
+	ctxt.GOPATH = gopath
+	ctxt.CgoEnabled = true
+	want := []string{"a/vendor/v", "b", "c", "missing"}
+	p, err := ctxt.Import("a", "", ResolveDeps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Deps, want) {
+		t.Errorf("Deps = %q, want %q", p.Deps, want)
+	}
+
+	p, err = ctxt.Import("a", "", ResolveDeps|LazyLoad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Deps != nil {
+		t.Errorf("Deps before Complete = %q, want nil", p.Deps)
+	}
+	if err := p.Complete(&ctxt); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Deps, want) {
+		t.Errorf("Deps after Complete = %q, want %q", p.Deps, want)
+	}
+
+	for _, mode := range []ImportMode{0, FindOnly | ResolveDeps} {
+		p, err := ctxt.Import("a", "", mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Deps != nil {
+			t.Errorf("Import with mode %d: Deps = %q, want nil", mode, p.Deps)
+		}
+	}
+}
+
+func TestPkgTarget(t *testing.T) {
+	t.Setenv("GO111MODULE", "off")
+	gopath := t.TempDir()
+	pkgDir := filepath.Join(gopath, "src", "example.com", "p")
+	if err := os.MkdirAll(pkgDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkgDir, "p.go"), []byte("package p\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	objDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(objDir, "q.a"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	ctxt := Default
+	ctxt.GOROOT = ""
+	ctxt.GOPATH = gopath
+	ctxt.Compiler = "gc"
+	ctxt.InstallSuffix = "race"
+	p, err := ctxt.Import("example.com/p", "", FindOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetRoot := filepath.Join(gopath, "pkg", ctxt.GOOS+"_"+ctxt.GOARCH+"_race")
+	if obj := filepath.Join(targetRoot, "example.com", "p.a"); p.PkgTargetRoot != targetRoot || p.PkgObj != obj {
+		t.Errorf("default PkgTargetRoot, PkgObj = %s, %s; want %s, %s", p.PkgTargetRoot, p.PkgObj, targetRoot, obj)
+	}
+
+	var calls []string
+	ctxt.PkgTarget = func(root, importPath string) (string, string) {
+		calls = append(calls, root+" "+importPath)
+		return objDir, filepath.Join(objDir, pathpkg.Base(importPath)+".a")
+	}
+	p, err = ctxt.Import("example.com/p", "", FindOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj := filepath.Join(objDir, "p.a"); p.PkgTargetRoot != objDir || p.PkgObj != obj {
+		t.Errorf("PkgTargetRoot, PkgObj = %s, %s; want %s, %s", p.PkgTargetRoot, p.PkgObj, objDir, obj)
+	}
+	if want := []string{gopath + " example.com/p"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("PkgTarget calls = %q, want %q", calls, want)
+	}
+
+	// With AllowBinary, a package without sources is found
+	// where PkgTarget places its archive.
+	p, err = ctxt.Import("example.com/q", "", AllowBinary|FindOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj := filepath.Join(objDir, "q.a"); p.PkgObj != obj {
+		t.Errorf("binary-only PkgObj = %s, want %s", p.PkgObj, obj)
+	}
+
+	// Local imports outside GOPATH have no install location.
+	calls = nil
+	p, err = ctxt.ImportDir(objDir, FindOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.PkgObj != "" || len(calls) != 0 {
+		t.Errorf("ImportDir: PkgObj = %q after PkgTarget calls %q, want none", p.PkgObj, calls)
+	}
+}
+
+func TestToolDirEnv(t *testing.T) {
+	if runtime.Compiler != "gc" {
+		t.Skip("GOTOOLDIR applies to the gc toolchain only")
+	}
+	dir := t.TempDir()
+	t.Setenv("GOTOOLDIR", dir)
+	if got := getToolDir(); got != dir {
+		t.Errorf("getToolDir() with GOTOOLDIR=%s = %s", dir, got)
+	}
+}
+
+func TestImportDocModes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":      "package p\n",
+		"b.go":      "// Package p does things.\n// More about p.\npackage p\n",
+		"c.go":      "// Another comment about p.\npackage p\n",
+		"p_test.go": "// Package p is tested.\npackage p\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	docs := func(p *Package) map[string]string {
+		m := make(map[string]string)
+		for _, f := range p.Files {
+			if f.Doc != "" {
+				m[f.Name] = f.Doc
+			}
+		}
+		return m
+	}
+	fileDocs := map[string]string{
+		"b.go":      "Package p does things.\nMore about p.\n",
+		"c.go":      "Another comment about p.\n",
+		"p_test.go": "Package p is tested.\n",
+	}
+	tests := []struct {
+		mode ImportMode
+		doc  string
+		docs map[string]string
+	}{
+		{0, "Package p does things.", map[string]string{}},
+		{SkipDoc, "", map[string]string{}},
+		{FileDocs, "Package p does things.", fileDocs},
+		{SkipDoc | FileDocs, "", fileDocs},
+	}
+	for _, tt := range tests {
+		p, err := Default.ImportDir(dir, tt.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Doc != tt.doc {
+			t.Errorf("mode %d: Doc = %q, want %q", tt.mode, p.Doc, tt.doc)
+		}
+		if got := docs(p); !reflect.DeepEqual(got, tt.docs) {
+			t.Errorf("mode %d: file docs = %q, want %q", tt.mode, got, tt.docs)
+		}
+	}
+}
+
+func TestNegatedBuildTags(t *testing.T) {
+	ctxt := Default
+	ctxt.GOOS = "linux"
+	ctxt.GOARCH = "amd64"
+	ctxt.CgoEnabled = true
+	ctxt.BuildTags = []string{"netgo", "!netgo", "!cgo", "!linux", "foo", "!"}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"netgo", false},
+		{"!netgo", true},
+		{"cgo", false},
+		{"linux", false},
+		{"unix", true},
+		{"amd64", true},
+		{"foo", true},
+		{"!bar", true},
+	}
+	for _, tt := range tests {
+		got, err := ctxt.MatchConstraintString(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("MatchConstraintString(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+	for name, want := range map[string]bool{"x_linux.go": false, "x_amd64.go": true, "x_linux_amd64.go": false} {
+		if got := ctxt.MatchFileName(name, nil); got != want {
+			t.Errorf("MatchFileName(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if got, want := goCommandTags(&ctxt), []string{"netgo", "foo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("goCommandTags(%q) = %q, want %q", ctxt.BuildTags, got, want)
+	}
+}
+
+func TestTagExpr(t *testing.T) {
+	ctxt := Default
+	ctxt.GOOS = "linux"
+	ctxt.GOARCH = "amd64"
+	ctxt.CgoEnabled = true
+	ctxt.BuildTags = []string{"foo", "netgo", "!bar"}
+	x, err := constraint.Parse("//go:build bar && !cgo && !linux && !foo && (a || b) && !(c && d)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctxt.TagExpr = x
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"bar", true},
+		{"cgo", false},
+		{"linux", false},
+		{"unix", true},
+		{"foo", false},
+		{"netgo", true},
+		{"a || b", false},
+		{"!c && !d", true},
+	}
+	for _, tt := range tests {
+		got, err := ctxt.MatchConstraintString(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("MatchConstraintString(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+	if got := ctxt.MatchFileName("x_linux.go", nil); got {
+		t.Errorf("MatchFileName(x_linux.go) = true, want false")
+	}
+	if got, want := goCommandTags(&ctxt), []string{"netgo", "bar"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("goCommandTags = %q, want %q", got, want)
+	}
+}
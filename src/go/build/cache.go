@@ -0,0 +1,202 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Cache remembers the directories read and the source file headers
+// scanned by Import and ImportDir, so that contexts sharing the Cache
+// do not read and scan the same directories and files again.
+// See Context.Cache.
+//
+// Each cached result is keyed by the path and the modification time and
+// size of the directory or file. A change that preserves both, as can
+// happen on file systems with coarse timestamps, is not noticed until
+// the path is passed to Invalidate.
+//
+// A Cache keeps open the module zip files opened by
+// Context.OpenModuleZip. Module zip files in the module cache
+// never change once written.
+//
+// A Cache also remembers the symbolic links it resolves to decide
+// whether one directory is within another (see Context.Subdir).
+// As links carry no modification time of their own, they are
+// resolved again only after a call to Invalidate, for any path.
+//
+// The zero value is an empty Cache ready to use.
+// A Cache is safe for concurrent use by multiple goroutines.
+type Cache struct {
+	mu    sync.Mutex
+	dirs  map[string]*cacheDir
+	files map[string]*cacheFile
+	zips  map[string]fs.FS
+	links filepath.SymlinkCache
+}
+
+// A cacheStamp identifies the version of a directory or file.
+type cacheStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(fi fs.FileInfo) cacheStamp {
+	return cacheStamp{fi.ModTime(), fi.Size()}
+}
+
+func (s cacheStamp) matches(fi fs.FileInfo) bool {
+	return s.modTime.Equal(fi.ModTime()) && s.size == fi.Size()
+}
+
+type cacheDir struct {
+	stamp cacheStamp
+	ents  []fs.DirEntry
+}
+
+type cacheFile struct {
+	stamp  cacheStamp
+	header []byte
+	embeds []fileEmbed
+	err    error
+}
+
+// Invalidate discards everything c has cached about path
+// and, if path is a directory, about the files beneath it,
+// as well as all of the symbolic links c has resolved.
+func (c *Cache) Invalidate(path string) {
+	c.links.Reset()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prefix := path
+	if !strings.HasSuffix(prefix, string(os.PathSeparator)) {
+		prefix += string(os.PathSeparator)
+	}
+	for dir := range c.dirs {
+		if dir == path || strings.HasPrefix(dir, prefix) {
+			delete(c.dirs, dir)
+		}
+	}
+	for file := range c.files {
+		if file == path || strings.HasPrefix(file, prefix) {
+			delete(c.files, file)
+		}
+	}
+	// Files already opened from a zip file may still be in use,
+	// so the zip file is left for the garbage collector to close.
+	for file := range c.zips {
+		if file == path || strings.HasPrefix(file, prefix) {
+			delete(c.zips, file)
+		}
+	}
+}
+
+// openZip returns the open zip file with the given name,
+// opening it with open if c does not already hold it open.
+func (c *Cache) openZip(name string, open func(string) (fs.FS, error)) (fs.FS, error) {
+	c.mu.Lock()
+	zr := c.zips[name]
+	c.mu.Unlock()
+	if zr != nil {
+		return zr, nil
+	}
+	zr, err := open(name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old := c.zips[name]; old != nil {
+		closeFS(zr)
+		return old, nil
+	}
+	if c.zips == nil {
+		c.zips = make(map[string]fs.FS)
+	}
+	c.zips[name] = zr
+	return zr, nil
+}
+
+// readDir is like os.ReadDir but answers from c
+// if the directory has not changed since it was last read.
+// It counts its hits and misses in stats, if not nil.
+func (c *Cache) readDir(path string, stats *ScanStats) ([]fs.DirEntry, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return os.ReadDir(path)
+	}
+	c.mu.Lock()
+	d := c.dirs[path]
+	c.mu.Unlock()
+	if d != nil && d.stamp.matches(fi) {
+		stats.countCache(true)
+		return d.ents, nil
+	}
+	stats.countCache(false)
+	ents, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.dirs == nil {
+		c.dirs = make(map[string]*cacheDir)
+	}
+	c.dirs[path] = &cacheDir{stamp: stampOf(fi), ents: ents}
+	c.mu.Unlock()
+	return ents, nil
+}
+
+// readFileInfo is like the package-level readFileInfo using os.Open
+// but answers from c if the file has not changed since it was last
+// scanned. The header is parsed again, using info.fset, on every call.
+// It counts its hits and misses, and the files it reads, in stats,
+// if not nil.
+func (c *Cache) readFileInfo(info *fileInfo, isGo bool, stats *ScanStats) error {
+	open := stats.countReads(openOSFile)
+	fi, err := os.Stat(info.name)
+	if err != nil || !fi.Mode().IsRegular() {
+		return readFileInfo(open, info, isGo)
+	}
+	c.mu.Lock()
+	f := c.files[info.name]
+	c.mu.Unlock()
+	stats.countCache(f != nil && f.stamp.matches(fi))
+	if f == nil || !f.stamp.matches(fi) {
+		// Scan with a file set of our own
+		// so that the embed patterns are recorded.
+		scan := &fileInfo{name: info.name}
+		if isGo {
+			scan.fset = token.NewFileSet()
+		}
+		f = &cacheFile{stamp: stampOf(fi)}
+		f.err = readFileInfo(open, scan, isGo)
+		f.header, f.embeds = scan.header, scan.embeds
+		c.mu.Lock()
+		if c.files == nil {
+			c.files = make(map[string]*cacheFile)
+		}
+		c.files[info.name] = f
+		c.mu.Unlock()
+	}
+	if f.err != nil {
+		return f.err
+	}
+	info.header = f.header
+	if isGo && info.fset != nil {
+		if _, err := parseGoInfo(info); err != nil {
+			return err
+		}
+		if info.parseErr == nil {
+			info.embeds = f.embeds
+		}
+	}
+	return nil
+}
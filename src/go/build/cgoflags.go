@@ -0,0 +1,165 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"internal/cgoflags"
+	exec "internal/execabs"
+	"strings"
+)
+
+// ResolveCgoFlags sets p.ResolvedCgoCPPFLAGS, p.ResolvedCgoCFLAGS,
+// p.ResolvedCgoCXXFLAGS, p.ResolvedCgoFFLAGS and p.ResolvedCgoLDFLAGS
+// to the flags that the go command would pass to the C, C++ and Fortran
+// compilers and to the linker when building p's cgo files.
+//
+// Each list holds the flags from the corresponding CGO_CPPFLAGS,
+// CGO_CFLAGS, CGO_CXXFLAGS, CGO_FFLAGS or CGO_LDFLAGS variable in
+// ctxt.Env, or in the process environment if ctxt.Env is nil, followed
+// by those from the package's #cgo directives, in which Import has
+// already expanded ${SRCDIR}. When unset or empty, each of the variables
+// but CGO_CPPFLAGS defaults to "-g -O2". If p.CgoPkgConfig is not empty,
+// ResolveCgoFlags then runs pkg-config (or the command named by the
+// PKG_CONFIG variable) in p.Dir, in the same environment, and appends
+// the output of its --cflags and --libs options to the preprocessor and
+// linker flags.
+//
+// Like the go command, ResolveCgoFlags returns an error if a #cgo
+// directive or pkg-config yields a flag that could be used to run
+// arbitrary code during the build, unless the flag is permitted by the
+// CGO_CFLAGS_ALLOW, CGO_LDFLAGS_ALLOW or similar variables.
+// On error, the resolved flag lists are left unchanged.
+func (ctxt *Context) ResolveCgoFlags(p *Package) error {
+	const defaults = "-g -O2"
+	cppflags, err := ctxt.cgoFlags("CPPFLAGS", "", p.CgoCPPFLAGS, ctxt.checkCompilerFlags)
+	if err != nil {
+		return err
+	}
+	cflags, err := ctxt.cgoFlags("CFLAGS", defaults, p.CgoCFLAGS, ctxt.checkCompilerFlags)
+	if err != nil {
+		return err
+	}
+	cxxflags, err := ctxt.cgoFlags("CXXFLAGS", defaults, p.CgoCXXFLAGS, ctxt.checkCompilerFlags)
+	if err != nil {
+		return err
+	}
+	fflags, err := ctxt.cgoFlags("FFLAGS", defaults, p.CgoFFLAGS, ctxt.checkCompilerFlags)
+	if err != nil {
+		return err
+	}
+	ldflags, err := ctxt.cgoFlags("LDFLAGS", defaults, p.CgoLDFLAGS, ctxt.checkLinkerFlags)
+	if err != nil {
+		return err
+	}
+
+	pcCFLAGS, pcLDFLAGS, err := ctxt.pkgConfigFlags(p)
+	if err != nil {
+		return err
+	}
+	p.ResolvedCgoCPPFLAGS = append(cppflags, pcCFLAGS...)
+	p.ResolvedCgoCFLAGS = cflags
+	p.ResolvedCgoCXXFLAGS = cxxflags
+	p.ResolvedCgoFFLAGS = fflags
+	p.ResolvedCgoLDFLAGS = append(ldflags, pcLDFLAGS...)
+	return nil
+}
+
+// checkCompilerFlags and checkLinkerFlags check flags by the same rules
+// as the go command, which shares them in internal/cgoflags, reading
+// CGO_<name>_ALLOW and CGO_<name>_DISALLOW from ctxt's environment.
+func (ctxt *Context) checkCompilerFlags(name, source string, list []string) error {
+	return cgoflags.CheckCompilerFlags(name, source, list, ctxt.getenv)
+}
+
+func (ctxt *Context) checkLinkerFlags(name, source string, list []string) error {
+	return cgoflags.CheckLinkerFlags(name, source, list, ctxt.getenv)
+}
+
+// cgoFlags returns the flags from the variable CGO_<name> in ctxt's
+// environment, or from defaults if it is empty, followed by the flags
+// from #cgo directives, which must pass check.
+func (ctxt *Context) cgoFlags(name, defaults string, fromPackage []string, check func(string, string, []string) error) ([]string, error) {
+	if err := check(name, "#cgo "+name, fromPackage); err != nil {
+		return nil, err
+	}
+	env := ctxt.getenv("CGO_" + name)
+	if env == "" {
+		env = defaults
+	}
+	flags, err := splitQuoted(env)
+	if err != nil {
+		return nil, fmt.Errorf("parsing $CGO_%s: %v", name, err)
+	}
+	return append(flags, fromPackage...), nil
+}
+
+// pkgConfigFlags runs pkg-config for the packages named in
+// p.CgoPkgConfig and returns the compiler and linker flags it reports.
+func (ctxt *Context) pkgConfigFlags(p *Package) (cflags, ldflags []string, err error) {
+	if len(p.CgoPkgConfig) == 0 {
+		return nil, nil, nil
+	}
+	// pkg-config permits arguments to appear anywhere in
+	// the command line. Move them all to the front, before --.
+	var pcflags, pkgs []string
+	for _, arg := range p.CgoPkgConfig {
+		if arg == "--" {
+			// We're going to add our own "--" argument.
+		} else if strings.HasPrefix(arg, "--") {
+			pcflags = append(pcflags, arg)
+		} else {
+			pkgs = append(pkgs, arg)
+		}
+	}
+	for _, pkg := range pkgs {
+		if !cgoflags.SafeArg(pkg) {
+			return nil, nil, fmt.Errorf("invalid pkg-config package name: %s", pkg)
+		}
+	}
+
+	cmd := "pkg-config"
+	if env, err := splitQuoted(ctxt.getenv("PKG_CONFIG")); err == nil && len(env) > 0 {
+		cmd = env[0]
+	}
+	run := func(mode string) ([]byte, error) {
+		args := append(append([]string{mode}, pcflags...), "--")
+		args = append(args, pkgs...)
+		var stdout, stderr bytes.Buffer
+		c := exec.Command(cmd, args...)
+		c.Dir = p.Dir
+		c.Env = ctxt.environ()
+		c.Stdout = &stdout
+		c.Stderr = &stderr
+		if err := c.Run(); err != nil {
+			return nil, fmt.Errorf("go/build: %s %s: %v\n%s", cmd, strings.Join(args, " "), err, stderr.Bytes())
+		}
+		return stdout.Bytes(), nil
+	}
+
+	out, err := run("--cflags")
+	if err != nil {
+		return nil, nil, err
+	}
+	if cflags, err = cgoflags.SplitPkgConfigOutput(out); err != nil {
+		return nil, nil, err
+	}
+	if err := ctxt.checkCompilerFlags("CFLAGS", "pkg-config --cflags", cflags); err != nil {
+		return nil, nil, err
+	}
+
+	out, err = run("--libs")
+	if err != nil {
+		return nil, nil, err
+	}
+	// NOTE: we don't attempt to parse quotes and unescapes here. pkg-config
+	// is typically used within shell backticks, which treats quotes literally.
+	ldflags = strings.Fields(string(out))
+	if err := ctxt.checkLinkerFlags("LDFLAGS", "pkg-config --libs", ldflags); err != nil {
+		return nil, nil, err
+	}
+	return cflags, ldflags, nil
+}
@@ -0,0 +1,369 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"fmt"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A Problem describes a problem with the build constraints
+// of a file, as reported by CheckConstraints.
+type Problem struct {
+	Pos     token.Position // Filename is the name passed to CheckConstraints
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Pos.String() + ": " + p.Message
+}
+
+// CheckConstraints reports problems with the build constraints in the
+// file with the given name and content, following the rules applied
+// by the buildtag check of go vet. It reports:
+//
+//   - //go:build and // +build lines that cannot be parsed, as well as
+//     comments that look like malformed constraints;
+//   - more than one //go:build line;
+//   - constraint lines that are ignored because of where they appear,
+//     such as a // +build line not followed by a blank line, or any
+//     constraint after the package clause of a Go file;
+//   - // +build lines that do not match the //go:build line;
+//   - constraints that no build configuration can satisfy, taking
+//     into account the GOOS and GOARCH suffixes of the file name.
+//
+// The problems are sorted by line number.
+func CheckConstraints(name string, content []byte) []Problem {
+	check := &constraintChecker{name: name, goBuildOK: true, crossCheck: true}
+	check.header(string(content))
+	if strings.HasSuffix(name, ".go") {
+		check.afterPackage(content)
+	}
+	check.finish()
+	sort.SliceStable(check.problems, func(i, j int) bool {
+		return check.problems[i].Pos.Line < check.problems[j].Pos.Line
+	})
+	return check.problems
+}
+
+// A constraintChecker accumulates the problems found by CheckConstraints.
+// It is adapted from the buildtag analyzer used by go vet.
+type constraintChecker struct {
+	name          string
+	problems      []Problem
+	plusBuildOK   bool            // "+build" lines still OK
+	goBuildOK     bool            // "go:build" lines still OK
+	crossCheck    bool            // cross-check go:build and +build lines when done reading file
+	goBuildLine   int             // line of first go:build line found
+	plusBuildLine int             // line of first "+build" line found
+	goBuild       constraint.Expr // go:build constraint found
+	plusBuild     constraint.Expr // AND of +build constraints found
+}
+
+func (check *constraintChecker) report(line int, format string, args ...any) {
+	check.problems = append(check.problems, Problem{
+		Pos:     token.Position{Filename: check.name, Line: line},
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// header checks the constraints in the leading comments of text,
+// stopping at the first line that is not blank or a comment.
+func (check *constraintChecker) header(text string) {
+	// Determine cutpoint where +build comments are no longer valid.
+	// They are valid in leading // comments in the file followed by
+	// a blank line.
+	plusBuildCutoff := 0
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "//") && line != "" {
+			break
+		}
+		if line == "" {
+			plusBuildCutoff = i
+		}
+	}
+
+	inStar := false
+	for i, line := range lines {
+		check.plusBuildOK = i < plusBuildCutoff
+		if strings.HasPrefix(line, "//") {
+			check.comment(i+1, line, inStar)
+			continue
+		}
+
+		// Keep looking for the point at which //go:build comments
+		// stop being allowed. Skip over, cut out any /* */ comments.
+		for {
+			line = strings.TrimSpace(line)
+			if inStar {
+				j := strings.Index(line, "*/")
+				if j < 0 {
+					line = ""
+					break
+				}
+				line = line[j+len("*/"):]
+				inStar = false
+				continue
+			}
+			if strings.HasPrefix(line, "/*") {
+				inStar = true
+				line = line[len("/*"):]
+				continue
+			}
+			break
+		}
+		if line != "" {
+			// Found non-comment non-blank line. From this point on we
+			// cannot tell comments from, say, multiline string constants.
+			break
+		}
+	}
+}
+
+// afterPackage reports constraint comments after the package
+// clause of a Go file, which are always ignored.
+func (check *constraintChecker) afterPackage(content []byte) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, check.name, content, parser.ParseComments)
+	if err != nil {
+		// Not valid Go source code - not our job to diagnose.
+		return
+	}
+	check.plusBuildOK, check.goBuildOK = false, false
+	for _, group := range f.Comments {
+		if group.Pos() < f.Package {
+			continue
+		}
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, "//") {
+				check.comment(fset.Position(c.Slash).Line, c.Text, false)
+			}
+		}
+	}
+}
+
+func (check *constraintChecker) comment(line int, text string, inStar bool) {
+	text = strings.TrimRight(text, "\r\n")
+	if strings.Contains(text, "+build") {
+		check.plusBuildComment(line, text, inStar)
+	}
+	if strings.Contains(text, "go:build") {
+		check.goBuildComment(line, text, inStar)
+	}
+}
+
+func (check *constraintChecker) goBuildComment(line int, text string, inStar bool) {
+	if !constraint.IsGoBuild(text) {
+		if !strings.HasPrefix(text, "//go:build") && constraint.IsGoBuild("//"+strings.TrimSpace(text[len("//"):])) {
+			check.report(line, "malformed //go:build line (space between // and go:build)")
+		}
+		return
+	}
+	if !check.goBuildOK || inStar {
+		check.report(line, "misplaced //go:build comment")
+		check.crossCheck = false
+		return
+	}
+
+	if check.goBuildLine == 0 {
+		check.goBuildLine = line
+	} else {
+		check.report(line, "unexpected extra //go:build line")
+		check.crossCheck = false
+	}
+
+	x, err := constraint.Parse(text)
+	if err != nil {
+		check.report(line, "%v", err)
+		check.crossCheck = false
+		return
+	}
+	if check.goBuild == nil {
+		check.goBuild = x
+	}
+}
+
+func (check *constraintChecker) plusBuildComment(line int, text string, inStar bool) {
+	text = strings.TrimSpace(text)
+	if !constraint.IsPlusBuild(text) {
+		// Comment with +build but not at beginning.
+		// Only report early in file.
+		if check.plusBuildOK {
+			check.report(line, "possible malformed +build comment")
+		}
+		return
+	}
+	if !check.plusBuildOK || inStar {
+		check.report(line, "misplaced +build comment")
+		check.crossCheck = false
+	}
+
+	if check.plusBuildLine == 0 {
+		check.plusBuildLine = line
+	}
+
+	fields := strings.Fields(text[len("//"):])
+	// IsPlusBuild check above implies fields[0] == "+build"
+	for _, arg := range fields[1:] {
+		for _, elem := range strings.Split(arg, ",") {
+			if strings.HasPrefix(elem, "!!") {
+				check.report(line, "invalid double negative in build constraint: %s", arg)
+				check.crossCheck = false
+				continue
+			}
+			elem = strings.TrimPrefix(elem, "!")
+			for _, c := range elem {
+				if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
+					check.report(line, "invalid non-alphanumeric build constraint: %s", arg)
+					check.crossCheck = false
+					break
+				}
+			}
+		}
+	}
+
+	if check.crossCheck {
+		y, err := constraint.Parse(text)
+		if err != nil {
+			check.report(line, "%v", err)
+			check.crossCheck = false
+			return
+		}
+		if check.plusBuild == nil {
+			check.plusBuild = y
+		} else {
+			check.plusBuild = &constraint.AndExpr{X: check.plusBuild, Y: y}
+		}
+	}
+}
+
+func (check *constraintChecker) finish() {
+	if !check.crossCheck {
+		return
+	}
+	x, line := check.goBuild, check.goBuildLine
+	if x == nil {
+		x, line = check.plusBuild, check.plusBuildLine
+	}
+	if x == nil {
+		return
+	}
+	if !satisfiable(check.name, x) {
+		check.report(line, "build constraint can never be satisfied")
+		return
+	}
+	if check.goBuild == nil || check.plusBuild == nil {
+		return
+	}
+
+	// Have both //go:build and // +build. Check they match.
+	var want constraint.Expr
+	lines, err := constraint.PlusBuildLines(check.goBuild)
+	if err != nil {
+		check.report(check.goBuildLine, "%v", err)
+		return
+	}
+	for _, line := range lines {
+		y, err := constraint.Parse(line)
+		if err != nil {
+			// Definitely should not happen, but not the user's fault.
+			return
+		}
+		if want == nil {
+			want = y
+		} else {
+			want = &constraint.AndExpr{X: want, Y: y}
+		}
+	}
+	if want.String() != check.plusBuild.String() {
+		check.report(check.plusBuildLine, "+build lines do not match //go:build condition")
+	}
+}
+
+// maxFreeTags bounds the number of tags other than operating systems,
+// architectures and compilers for which satisfiable tries every
+// combination of values.
+const maxFreeTags = 12
+
+// satisfiable reports whether some build configuration satisfies
+// both x and the GOOS and GOARCH suffixes of the file name. If x uses
+// too many tags to decide, satisfiable reports true.
+func satisfiable(name string, x constraint.Expr) bool {
+	var free []string
+	var arches []string
+	seen := make(map[string]bool)
+	collectTags(x, func(tag string) {
+		if seen[tag] {
+			return
+		}
+		seen[tag] = true
+		switch {
+		case knownOS[tag] || tag == "unix" || tag == "gc" || tag == "gccgo":
+		case knownArch[tag]:
+			arches = append(arches, tag)
+		default:
+			free = append(free, tag)
+		}
+	})
+	if len(free) > maxFreeTags {
+		return true
+	}
+	// Any architecture that x does not mention behaves like any other.
+	for _, arch := range KnownArchList() {
+		if !seen[arch] {
+			arches = append(arches, arch)
+			break
+		}
+	}
+
+	ctxt := &Context{}
+	for _, goos := range KnownOSList() {
+		for _, goarch := range arches {
+			ctxt.GOOS, ctxt.GOARCH = goos, goarch
+			ctxt.CgoEnabled = false
+			if !ctxt.goodOSArchFile(name, nil) {
+				continue
+			}
+			for _, compiler := range []string{"gc", "gccgo"} {
+				ctxt.Compiler = compiler
+				for bits := 0; bits < 1<<len(free); bits++ {
+					ctxt.BuildTags = ctxt.BuildTags[:0]
+					for i, tag := range free {
+						if bits&(1<<i) != 0 {
+							ctxt.BuildTags = append(ctxt.BuildTags, tag)
+						}
+					}
+					if ctxt.eval(x, nil) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// collectTags calls f for each tag in x.
+func collectTags(x constraint.Expr, f func(string)) {
+	switch x := x.(type) {
+	case *constraint.TagExpr:
+		f(x.Tag)
+	case *constraint.NotExpr:
+		collectTags(x.X, f)
+	case *constraint.AndExpr:
+		collectTags(x.X, f)
+		collectTags(x.Y, f)
+	case *constraint.OrExpr:
+		collectTags(x.X, f)
+		collectTags(x.Y, f)
+	}
+}
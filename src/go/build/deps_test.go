@@ -74,7 +74,7 @@ var depsRules = `
 	  internal/cfg, internal/cpu, internal/goarch,
 	  internal/goexperiment, internal/goos,
 	  internal/goversion, internal/nettrace,
-	  unicode/utf8, unicode/utf16, unicode,
+	  unicode/utf16, unicode,
 	  unsafe;
 
 	# These packages depend only on internal/goarch and unsafe.
@@ -100,6 +100,10 @@ var depsRules = `
 	< internal/oserror, math/bits
 	< RUNTIME;
 
+	# unicode/utf8 validates a block of bytes at a time with internal/bytealg.
+	internal/bytealg
+	< unicode/utf8;
+
 	RUNTIME
 	< sort
 	< container/heap;
@@ -307,7 +311,16 @@ var depsRules = `
 	FMT, internal/goexperiment
 	< internal/buildcfg;
 
-	go/build/constraint, go/doc, go/parser, internal/buildcfg, internal/goroot, internal/goversion
+	FMT, internal/lazyregexp
+	< internal/cgoflags;
+
+	# go/build implements json.Marshaler and json.Unmarshaler for Package,
+	# which must be done in go/build itself. Beyond what go/doc already
+	# needs, encoding/json adds only encoding/base64, encoding/binary and
+	# unicode/utf16. go/build also uses regexp directly, through the cgo
+	# flag checks in internal/cgoflags that it shares with cmd/go.
+	encoding/json, go/build/constraint, go/doc, go/parser,
+	internal/buildcfg, internal/cgoflags, internal/goroot, internal/goversion
 	< go/build;
 
 	# databases
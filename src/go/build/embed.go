@@ -0,0 +1,211 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ResolveEmbeds sets p.EmbedFiles, p.TestEmbedFiles and p.XTestEmbedFiles
+// to the files matched by the //go:embed patterns in p.EmbedPatterns,
+// p.TestEmbedPatterns and p.XTestEmbedPatterns, reading the directory p.Dir
+// using ctxt's file system hooks. The file lists are sorted and hold
+// slash-separated paths relative to p.Dir.
+//
+// ResolveEmbeds applies the rules of the go command, as described in the
+// documentation for package embed: a pattern must be a valid path that
+// stays within p.Dir and must match at least one file or non-empty
+// directory. A directory matched by a pattern stands for the files in
+// the tree below it, except those whose names begin with "." or "_"
+// unless the pattern begins with "all:". Files and directories in other
+// modules (below a directory containing a go.mod file), version control
+// directories such as .git, and irregular files such as symbolic links
+// cannot be embedded.
+//
+// If a pattern is invalid or matches something that cannot be embedded,
+// ResolveEmbeds returns an error describing the first such pattern, and
+// the file lists are left unchanged.
+func (ctxt *Context) ResolveEmbeds(p *Package) error {
+	files, err := ctxt.resolveEmbed(p.Dir, p.EmbedPatterns)
+	if err != nil {
+		return err
+	}
+	testFiles, err := ctxt.resolveEmbed(p.Dir, p.TestEmbedPatterns)
+	if err != nil {
+		return err
+	}
+	xtestFiles, err := ctxt.resolveEmbed(p.Dir, p.XTestEmbedPatterns)
+	if err != nil {
+		return err
+	}
+	p.EmbedFiles, p.TestEmbedFiles, p.XTestEmbedFiles = files, testFiles, xtestFiles
+	return nil
+}
+
+// resolveEmbed returns the sorted list of files in dir
+// matched by the //go:embed patterns.
+func (ctxt *Context) resolveEmbed(dir string, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	have := make(map[string]bool)
+	var list []string
+	for _, pattern := range patterns {
+		files, err := ctxt.resolveEmbedPattern(dir, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %s: %v", pattern, err)
+		}
+		for _, file := range files {
+			if !have[file] {
+				have[file] = true
+				list = append(list, file)
+			}
+		}
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// resolveEmbedPattern returns the files in dir matched by a single pattern.
+func (ctxt *Context) resolveEmbedPattern(dir, pattern string) ([]string, error) {
+	glob, all := pattern, false
+	if strings.HasPrefix(pattern, "all:") {
+		glob, all = pattern[len("all:"):], true
+	}
+	if glob == "." || !fs.ValidPath(glob) {
+		return nil, errors.New("invalid pattern syntax")
+	}
+	if _, err := pathpkg.Match(glob, ""); err != nil {
+		return nil, err
+	}
+
+	matches := ctxt.globEmbed(dir, ".", strings.Split(glob, "/"))
+	if len(matches) == 0 {
+		return nil, errors.New("no matching files found")
+	}
+
+	var files []string
+	for _, m := range matches {
+		what := "file"
+		if m.ent.IsDir() {
+			what = "directory"
+		}
+		// Check that no directory along the path begins a new module
+		// or is a version control directory.
+		for d := m.rel; d != "."; d = pathpkg.Dir(d) {
+			if (d != m.rel || m.ent.IsDir()) && ctxt.isFile(ctxt.joinPath(dir, fromSlash(ctxt, d), "go.mod")) {
+				return nil, fmt.Errorf("cannot embed %s %s: in different module", what, m.rel)
+			}
+			if elem := pathpkg.Base(d); isBadEmbedName(elem) {
+				if d == m.rel {
+					return nil, fmt.Errorf("cannot embed %s %s: invalid name %s", what, m.rel, elem)
+				}
+				return nil, fmt.Errorf("cannot embed %s %s: in invalid directory %s", what, m.rel, elem)
+			}
+		}
+
+		switch {
+		case m.ent.Type().IsRegular():
+			files = append(files, m.rel)
+		case m.ent.IsDir():
+			n := len(files)
+			var err error
+			files, err = ctxt.walkEmbed(dir, m.rel, all, files)
+			if err != nil {
+				return nil, err
+			}
+			if len(files) == n {
+				return nil, fmt.Errorf("cannot embed directory %s: contains no embeddable files", m.rel)
+			}
+		default:
+			return nil, fmt.Errorf("cannot embed irregular file %s", m.rel)
+		}
+	}
+	return files, nil
+}
+
+// An embedMatch is a file or directory matched by a //go:embed pattern.
+type embedMatch struct {
+	rel string // slash-separated path relative to the package directory
+	ent fs.DirEntry
+}
+
+// globEmbed returns the files and directories below dir/rel whose paths
+// relative to dir/rel match the sequence of path.Match patterns in elems.
+func (ctxt *Context) globEmbed(dir, rel string, elems []string) []embedMatch {
+	ents, err := ctxt.readDir(ctxt.joinPath(dir, fromSlash(ctxt, rel)))
+	if err != nil {
+		return nil
+	}
+	var matches []embedMatch
+	for _, ent := range ents {
+		if ok, _ := pathpkg.Match(elems[0], ent.Name()); !ok {
+			continue
+		}
+		name := pathpkg.Join(rel, ent.Name())
+		if len(elems) == 1 {
+			matches = append(matches, embedMatch{name, ent})
+		} else if ent.IsDir() {
+			matches = append(matches, ctxt.globEmbed(dir, name, elems[1:])...)
+		}
+	}
+	return matches
+}
+
+// walkEmbed appends to files the embeddable files
+// in the tree rooted at the directory dir/rel.
+func (ctxt *Context) walkEmbed(dir, rel string, all bool, files []string) ([]string, error) {
+	ents, err := ctxt.readDir(ctxt.joinPath(dir, fromSlash(ctxt, rel)))
+	if err != nil {
+		return nil, err
+	}
+	for _, ent := range ents {
+		elem := ent.Name()
+		name := pathpkg.Join(rel, elem)
+		if isBadEmbedName(elem) || !all && (strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_")) {
+			continue
+		}
+		switch {
+		case ent.IsDir():
+			if ctxt.isFile(ctxt.joinPath(dir, fromSlash(ctxt, name), "go.mod")) {
+				continue
+			}
+			files, err = ctxt.walkEmbed(dir, name, all, files)
+			if err != nil {
+				return nil, err
+			}
+		case ent.Type().IsRegular():
+			files = append(files, name)
+		default:
+			return nil, fmt.Errorf("cannot embed irregular file %s", name)
+		}
+	}
+	return files, nil
+}
+
+// fromSlash converts the slash-separated relative path rel into
+// a path to be joined by ctxt.joinPath.
+func fromSlash(ctxt *Context, rel string) string {
+	if ctxt.JoinPath != nil || ctxt.FS != nil {
+		return rel
+	}
+	return filepath.FromSlash(rel)
+}
+
+// isBadEmbedName reports whether name is the name of
+// a version control directory, which cannot be embedded.
+func isBadEmbedName(name string) bool {
+	switch name {
+	case ".bzr", ".hg", ".git", ".svn":
+		return true
+	}
+	return false
+}
@@ -13,5 +13,5 @@ import (
 
 // getToolDir returns the default value of ToolDir.
 func getToolDir() string {
-	return filepath.Join(runtime.GOROOT(), "pkg/tool/"+runtime.GOOS+"_"+runtime.GOARCH)
+	return envOr("GOTOOLDIR", filepath.Join(runtime.GOROOT(), "pkg/tool/"+runtime.GOOS+"_"+runtime.GOARCH))
 }
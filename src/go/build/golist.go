@@ -0,0 +1,264 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"fmt"
+	"go/build/constraint"
+	exec "internal/execabs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// A GoList runs the go command to locate packages in module mode on
+// behalf of Import, amortizing the cost of starting the go command and
+// loading the module graph across many imports. It remembers the
+// directory, or the error, that the go command reports for each import
+// path, and it answers the lookups made while the go command is already
+// running with a single further invocation.
+//
+// The answers are kept until Reset is called, so Reset must be called
+// after changes to go.mod files or the module cache that could affect
+// them. A GoList may be shared by contexts with different settings:
+// it keeps their answers apart.
+//
+// The zero value is an empty GoList ready to use.
+// A GoList is safe for concurrent use by multiple goroutines.
+type GoList struct {
+	mu      sync.Mutex
+	calls   map[goListKey]*goListCall
+	pending map[string][]string // config -> paths waiting for the next invocation
+	running map[string]bool     // config -> an invocation is in progress
+}
+
+// A goListKey identifies a lookup: an import path in a configuration,
+// as returned by goListConfig.
+type goListKey struct {
+	config, path string
+}
+
+// A goListCall is a lookup that is in progress or complete.
+type goListCall struct {
+	done chan struct{} // closed when res and err are set
+	res  goListResult
+	err  error
+}
+
+// A goListResult is what the go command reports for one import path.
+type goListResult struct {
+	dir        string
+	importPath string
+	root       string
+	goroot     bool
+	err        string // error, if any, from the go command
+}
+
+// Reset discards all of the answers that g remembers.
+func (g *GoList) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for key, call := range g.calls {
+		select {
+		case <-call.done:
+			delete(g.calls, key)
+		default:
+			// Keep lookups in progress, so that their waiters
+			// and the invocation that answers them agree.
+		}
+	}
+}
+
+// lookup returns the go command's answer for path in ctxt.
+func (g *GoList) lookup(ctxt *Context, path string) (goListResult, error) {
+	config := goListConfig(ctxt)
+	key := goListKey{config, path}
+
+	g.mu.Lock()
+	call := g.calls[key]
+	if call == nil {
+		if g.calls == nil {
+			g.calls = make(map[goListKey]*goListCall)
+			g.pending = make(map[string][]string)
+			g.running = make(map[string]bool)
+		}
+		call = &goListCall{done: make(chan struct{})}
+		g.calls[key] = call
+		g.pending[config] = append(g.pending[config], path)
+		if !g.running[config] {
+			g.running[config] = true
+			c := *ctxt
+			go g.run(&c, config)
+		}
+	}
+	g.mu.Unlock()
+
+	<-call.done
+	return call.res, call.err
+}
+
+// run invokes the go command for the paths pending in config
+// until there are none left.
+func (g *GoList) run(ctxt *Context, config string) {
+	for {
+		g.mu.Lock()
+		paths := g.pending[config]
+		delete(g.pending, config)
+		if len(paths) == 0 {
+			delete(g.running, config)
+			g.mu.Unlock()
+			return
+		}
+		g.mu.Unlock()
+
+		results, err := ctxt.runGoList(paths)
+		if err != nil && len(paths) > 1 {
+			// A problem with one path might have spoiled the whole
+			// invocation. Look up each path alone to find out.
+			results, err = make(map[string]goListResult), nil
+			errs := make(map[string]error)
+			for _, path := range paths {
+				r, err := ctxt.runGoList([]string{path})
+				results[path], errs[path] = r[path], err
+			}
+			g.finish(config, paths, results, func(path string) error { return errs[path] })
+			continue
+		}
+		g.finish(config, paths, results, func(string) error { return err })
+	}
+}
+
+// finish records the results of looking up paths in config.
+func (g *GoList) finish(config string, paths []string, results map[string]goListResult, errFor func(string) error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, path := range paths {
+		call := g.calls[goListKey{config, path}]
+		call.res, call.err = results[path], errFor(path)
+		close(call.done)
+	}
+}
+
+// goListConfig returns a string identifying the settings of ctxt
+// that affect the answers of the go command.
+func goListConfig(ctxt *Context) string {
+	fields := []string{
+		ctxt.GOROOT, ctxt.GOPATH, ctxt.GOOS, ctxt.GOARCH, ctxt.Dir,
+		strconv.FormatBool(ctxt.CgoEnabled), ctxt.Compiler, ctxt.InstallSuffix,
+		strings.Join(ctxt.BuildTags, ","), fmt.Sprint(ctxt.TagExpr),
+		strconv.FormatBool(ctxt.Env != nil),
+	}
+	fields = append(fields, ctxt.Env...)
+	return strings.Join(fields, "\x00")
+}
+
+// goCommandTags returns the tags of ctxt to pass to the go command:
+// the elements of ctxt.BuildTags and the tags that ctxt.TagExpr
+// requires, omitting the negated ones, which it does not understand,
+// and the tags that ctxt.TagExpr negates.
+func goCommandTags(ctxt *Context) []string {
+	var list []string
+	for _, tag := range ctxt.BuildTags {
+		if !strings.HasPrefix(tag, "!") {
+			if value, ok := settledTag(ctxt.TagExpr, tag); !ok || value {
+				list = append(list, tag)
+			}
+		}
+	}
+	var add func(x constraint.Expr)
+	add = func(x constraint.Expr) {
+		switch x := x.(type) {
+		case *constraint.TagExpr:
+			for _, tag := range list {
+				if tag == x.Tag {
+					return
+				}
+			}
+			list = append(list, x.Tag)
+		case *constraint.AndExpr:
+			add(x.X)
+			add(x.Y)
+		}
+	}
+	add(ctxt.TagExpr)
+	return list
+}
+
+// goListFormat is the template for the go list output parsed by
+// runGoList. It separates the fields of each package with NUL bytes,
+// which cannot appear in any of them.
+const goListFormat = `{{.Dir}}{{"\x00"}}{{.ImportPath}}{{"\x00"}}{{.Root}}{{"\x00"}}{{.Goroot}}{{"\x00"}}{{if .Error}}{{.Error}}{{end}}{{"\x00"}}`
+
+// runGoList runs 'go list' in ctxt to locate the packages with
+// the given import paths, and returns what it reports for each.
+func (ctxt *Context) runGoList(paths []string) (map[string]goListResult, error) {
+	goCmd := filepath.Join(ctxt.GOROOT, "bin", "go")
+	args := []string{"list", "-e", "-compiler=" + ctxt.Compiler, "-tags=" + strings.Join(goCommandTags(ctxt), ","), "-installsuffix=" + ctxt.InstallSuffix, "-f=" + goListFormat, "--"}
+	cmd := exec.Command(goCmd, append(args, paths...)...)
+	if s := ctxt.Stats; s != nil {
+		atomic.AddInt64(&s.GoListRuns, 1)
+	}
+
+	if ctxt.Dir != "" {
+		cmd.Dir = ctxt.Dir
+	}
+
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	cgo := "0"
+	if ctxt.CgoEnabled {
+		cgo = "1"
+	}
+	env := ctxt.environ()
+	cmd.Env = append(env[:len(env):len(env)],
+		"GOOS="+ctxt.GOOS,
+		"GOARCH="+ctxt.GOARCH,
+		"GOROOT="+ctxt.GOROOT,
+		"GOPATH="+ctxt.GOPATH,
+		"CGO_ENABLED="+cgo,
+	)
+	if cmd.Dir != "" {
+		// If possible, set PWD: if an error occurs and PWD includes a symlink, we
+		// want the error to refer to Dir, not some other name for it.
+		if abs, err := filepath.Abs(cmd.Dir); err == nil {
+			cmd.Env = append(cmd.Env, "PWD="+abs)
+		}
+	}
+
+	what := strings.Join(paths, " ")
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go/build: go list %s: %v\n%s\n", what, err, stderr.String())
+	}
+
+	results := make(map[string]goListResult)
+	out := stdout.String()
+	for out != "" {
+		var f [5]string
+		for i := range f {
+			var ok bool
+			if f[i], out, ok = strings.Cut(out, "\x00"); !ok {
+				return nil, fmt.Errorf("go/build: importGo %s: unexpected output:\n%s\n", what, stdout.String())
+			}
+		}
+		out = strings.TrimPrefix(out, "\n")
+		results[f[1]] = goListResult{
+			dir:        f[0],
+			importPath: f[1],
+			root:       f[2],
+			goroot:     f[3] == "true",
+			err:        strings.TrimSpace(f[4]),
+		}
+	}
+	for _, path := range paths {
+		if _, ok := results[path]; !ok {
+			return nil, fmt.Errorf("go/build: importGo %s: unexpected output:\n%s\n", what, stdout.String())
+		}
+	}
+	return results, nil
+}
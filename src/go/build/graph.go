@@ -0,0 +1,240 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A Graph is the transitive import graph of a set of packages,
+// as loaded by LoadGraph.
+type Graph struct {
+	// Roots holds the packages named in the call to LoadGraph, in order.
+	Roots []*Package
+
+	// Packages holds every package in the graph, including the roots,
+	// in topological order: each package appears after all the packages
+	// it imports, except for those that import it in turn through an
+	// import cycle.
+	Packages []*Package
+
+	imports map[*Package][]*Package
+	errs    map[*Package]error
+}
+
+// Imports returns the packages in g that p imports directly,
+// in the order of p.Imports, omitting the pseudo-package "C".
+func (g *Graph) Imports(p *Package) []*Package {
+	return g.imports[p]
+}
+
+// Err returns the error that Import returned for p, if any.
+func (g *Graph) Err(p *Package) error {
+	return g.errs[p]
+}
+
+// resolveDeps sets p.Deps, as described for the ResolveDeps mode,
+// importing the packages p depends on with the rest of mode.
+func (ctxt *Context) resolveDeps(p *Package, mode ImportMode) {
+	c := *ctxt
+	if c.Cache == nil && c.FS == nil && c.ReadDir == nil && c.ReadDirEntries == nil && c.OpenFile == nil {
+		c.Cache = new(Cache)
+	}
+	mode = mode&^(FindOnly|ImportComment|LazyLoad|ResolveDeps|FileDocs) | SkipTestFiles | SkipDoc
+
+	deps := make(map[string]bool)
+	seen := make(map[ImportSpec]bool)
+	frontier := []*Package{p}
+	for len(frontier) > 0 {
+		var specs []ImportSpec
+		for _, q := range frontier {
+			for _, path := range q.Imports {
+				spec := ImportSpec{path, q.Dir}
+				if path == "C" || seen[spec] {
+					continue
+				}
+				seen[spec] = true
+				specs = append(specs, spec)
+			}
+		}
+		pkgs, _ := c.importPackages(context.Background(), specs, mode)
+		frontier = nil
+		for _, q := range pkgs {
+			if deps[q.ImportPath] || q.ImportPath == p.ImportPath {
+				continue
+			}
+			deps[q.ImportPath] = true
+			frontier = append(frontier, q)
+		}
+	}
+
+	p.Deps = make([]string, 0, len(deps))
+	for path := range deps {
+		p.Deps = append(p.Deps, path)
+	}
+	sort.Strings(p.Deps)
+}
+
+// An ImportCycleError describes an import cycle found by LoadGraph.
+type ImportCycleError struct {
+	Cycle []string // import paths along the cycle; the first and last are the same
+}
+
+func (e *ImportCycleError) Error() string {
+	return "import cycle not allowed: " + strings.Join(e.Cycle, " -> ")
+}
+
+// LoadGraph imports the packages named by the import paths in roots,
+// and then, transitively, the packages they import, and returns the
+// resulting import graph. Relative import paths in roots are interpreted
+// relative to ctxt.Dir or, if it is empty, the current directory.
+//
+// Each package is imported with the given mode, and the imports that
+// are followed are those listed in Package.Imports, so test imports are
+// not included. A package imported by several others, even through
+// different import paths (such as vendored ones), appears in the graph
+// only once. The packages at each depth of the graph are imported
+// concurrently, as by ImportPackages.
+//
+// LoadGraph returns the graph even if some packages could not be
+// imported, along with an error: an *ImportCycleError if the graph
+// contains a cycle, and otherwise the first error, in the order of
+// Graph.Packages, that Import returned.
+func LoadGraph(ctxt *Context, roots []string, mode ImportMode) (*Graph, error) {
+	srcDir := ctxt.Dir
+	if srcDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		srcDir = wd
+	}
+
+	c := *ctxt
+	if c.Cache == nil && c.FS == nil && c.ReadDir == nil && c.ReadDirEntries == nil && c.OpenFile == nil {
+		c.Cache = new(Cache)
+	}
+
+	g := &Graph{
+		imports: make(map[*Package][]*Package),
+		errs:    make(map[*Package]error),
+	}
+	nodes := make(map[string]*Package)
+	resolved := make(map[ImportSpec]*Package)
+
+	// load imports the packages named by specs and returns
+	// those that were not already in the graph.
+	load := func(specs []ImportSpec) []*Package {
+		var added []*Package
+		pkgs, errs := c.importPackages(context.Background(), specs, mode)
+		for i, p := range pkgs {
+			key := p.Dir
+			if key == "" {
+				key = "\x00" + p.ImportPath
+			}
+			if q, ok := nodes[key]; ok {
+				p = q
+			} else {
+				nodes[key] = p
+				if errs[i] != nil {
+					g.errs[p] = errs[i]
+				}
+				added = append(added, p)
+			}
+			resolved[specs[i]] = p
+		}
+		return added
+	}
+
+	specs := make([]ImportSpec, len(roots))
+	for i, path := range roots {
+		specs[i] = ImportSpec{path, srcDir}
+	}
+	frontier := load(specs)
+	for _, spec := range specs {
+		g.Roots = append(g.Roots, resolved[spec])
+	}
+
+	for len(frontier) > 0 {
+		specs = specs[:0]
+		seen := make(map[ImportSpec]bool)
+		for _, p := range frontier {
+			for _, path := range p.Imports {
+				spec := ImportSpec{path, p.Dir}
+				if path == "C" || resolved[spec] != nil || seen[spec] {
+					continue
+				}
+				seen[spec] = true
+				specs = append(specs, spec)
+			}
+		}
+		next := load(specs)
+		for _, p := range frontier {
+			for _, path := range p.Imports {
+				if path != "C" {
+					g.imports[p] = append(g.imports[p], resolved[ImportSpec{path, p.Dir}])
+				}
+			}
+		}
+		frontier = next
+	}
+
+	cycle := g.sort()
+	if cycle != nil {
+		return g, &ImportCycleError{Cycle: cycle}
+	}
+	for _, p := range g.Packages {
+		if err := g.errs[p]; err != nil {
+			return g, err
+		}
+	}
+	return g, nil
+}
+
+// sort sets g.Packages to the packages reachable from g.Roots in
+// topological order. It returns the import paths along the first
+// import cycle it finds, or nil if there is none.
+func (g *Graph) sort() (cycle []string) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*Package]int)
+	var stack []*Package
+	var visit func(p *Package)
+	visit = func(p *Package) {
+		switch state[p] {
+		case visiting:
+			if cycle == nil {
+				i := len(stack) - 1
+				for stack[i] != p {
+					i--
+				}
+				for _, q := range stack[i:] {
+					cycle = append(cycle, q.ImportPath)
+				}
+				cycle = append(cycle, p.ImportPath)
+			}
+			return
+		case visited:
+			return
+		}
+		state[p] = visiting
+		stack = append(stack, p)
+		for _, q := range g.imports[p] {
+			visit(q)
+		}
+		stack = stack[:len(stack)-1]
+		state[p] = visited
+		g.Packages = append(g.Packages, p)
+	}
+	for _, p := range g.Roots {
+		visit(p)
+	}
+	return cycle
+}
@@ -0,0 +1,156 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build/constraint"
+	"go/token"
+)
+
+// packageJSONVersion is the version of the JSON encoding of a Package
+// written by MarshalJSON. It must be incremented whenever a change to
+// Package would make an older encoding decode differently.
+const packageJSONVersion = 1
+
+// packageJSON is the JSON encoding of a Package.
+// The fields of Package that cannot be encoded as they are
+// shadow the embedded ones.
+type packageJSON struct {
+	Version int
+	*packageFields
+	InvalidFiles []*fileErrorJSON
+	Files        []*goFileJSON
+}
+
+// packageFields has the fields of Package but not its methods,
+// so that encoding it does not recur into MarshalJSON.
+type packageFields Package
+
+// fileErrorJSON is the JSON encoding of a FileError.
+type fileErrorJSON struct {
+	Name string
+	Kind FileErrorKind
+	Pos  token.Position
+	Err  string
+}
+
+// goFileJSON is the JSON encoding of a GoFile.
+type goFileJSON struct {
+	Name            string
+	Constraint      string // expression, without the //go:build prefix; "" if none
+	Imports         []string
+	ImportPos       []token.Position
+	EmbedPatterns   []string
+	EmbedPatternPos []token.Position
+	Doc             string
+}
+
+// MarshalJSON encodes p as a JSON object, so that the result of Import
+// can be stored or passed to another process and decoded again by
+// UnmarshalJSON.
+//
+// The object has a member for each exported field of Package, named
+// after the field, together with a "Version" member identifying the
+// encoding, currently 1. The encoding of a given version is stable:
+// later versions of this package decode it to the same Package, and the
+// version changes if a change to Package would prevent that. Fields of
+// Package that are not plain data are encoded as follows: each element
+// of InvalidFiles has its Err encoded as the error's message, and each
+// element of Files has its Constraint encoded as the text of the
+// expression, such as "linux && !cgo", or as "" if it is nil.
+//
+// A Package imported with the LazyLoad mode cannot be encoded
+// until Complete has returned.
+func (p *Package) MarshalJSON() ([]byte, error) {
+	if p.lazy != nil && !p.lazy.done {
+		return nil, errors.New("go/build: cannot marshal incomplete Package imported with LazyLoad")
+	}
+	enc := packageJSON{
+		Version:       packageJSONVersion,
+		packageFields: (*packageFields)(p),
+	}
+	if p.InvalidFiles != nil {
+		enc.InvalidFiles = make([]*fileErrorJSON, len(p.InvalidFiles))
+		for i, e := range p.InvalidFiles {
+			enc.InvalidFiles[i] = &fileErrorJSON{Name: e.Name, Kind: e.Kind, Pos: e.Pos}
+			if e.Err != nil {
+				enc.InvalidFiles[i].Err = e.Err.Error()
+			}
+		}
+	}
+	if p.Files != nil {
+		enc.Files = make([]*goFileJSON, len(p.Files))
+		for i, f := range p.Files {
+			enc.Files[i] = &goFileJSON{
+				Name:            f.Name,
+				Imports:         f.Imports,
+				ImportPos:       f.ImportPos,
+				EmbedPatterns:   f.EmbedPatterns,
+				EmbedPatternPos: f.EmbedPatternPos,
+				Doc:             f.Doc,
+			}
+			if f.Constraint != nil {
+				enc.Files[i].Constraint = f.Constraint.String()
+			}
+		}
+	}
+	return json.Marshal(&enc)
+}
+
+// UnmarshalJSON sets *p to the Package encoded in data by MarshalJSON.
+// It returns an error if data was written by a later version of this
+// package using an encoding it does not know. Each element of
+// InvalidFiles has an Err with the original error's message, but not
+// its type, so that errors.Is and errors.As no longer see through it.
+func (p *Package) UnmarshalJSON(data []byte) error {
+	var dec packageJSON
+	var fields packageFields
+	dec.packageFields = &fields
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return err
+	}
+	if dec.Version < 1 || dec.Version > packageJSONVersion {
+		return fmt.Errorf("go/build: unsupported Package encoding version %d", dec.Version)
+	}
+	if dec.InvalidFiles != nil {
+		fields.InvalidFiles = make([]*FileError, len(dec.InvalidFiles))
+		for i, e := range dec.InvalidFiles {
+			if e == nil {
+				return fmt.Errorf("go/build: null element %d in InvalidFiles", i)
+			}
+			fields.InvalidFiles[i] = &FileError{Name: e.Name, Kind: e.Kind, Pos: e.Pos, Err: errors.New(e.Err)}
+		}
+	}
+	if dec.Files != nil {
+		fields.Files = make([]*GoFile, len(dec.Files))
+		for i, f := range dec.Files {
+			if f == nil {
+				return fmt.Errorf("go/build: null element %d in Files", i)
+			}
+			var x constraint.Expr
+			if f.Constraint != "" {
+				var err error
+				x, err = constraint.Parse("//go:build " + f.Constraint)
+				if err != nil {
+					return fmt.Errorf("go/build: file %s: invalid constraint %q: %v", f.Name, f.Constraint, err)
+				}
+			}
+			fields.Files[i] = &GoFile{
+				Name:            f.Name,
+				Constraint:      x,
+				Imports:         f.Imports,
+				ImportPos:       f.ImportPos,
+				EmbedPatterns:   f.EmbedPatterns,
+				EmbedPatternPos: f.EmbedPatternPos,
+				Doc:             f.Doc,
+			}
+		}
+	}
+	*p = Package(fields)
+	return nil
+}
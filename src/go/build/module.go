@@ -0,0 +1,676 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// importModule tries to locate the package with the given import path
+// in module mode without running the go command, by consulting the
+// go.mod and go.sum files of the main module and the module cache.
+// It reports whether it succeeded; if not, the caller must fall back
+// to asking the go command, which may need to consult the network.
+//
+// importModule only answers when it can tell that the go command would
+// give the same answer: when the main module's go.mod file declares
+// go 1.17 or later, so that it lists every module providing a package
+// in the build, when the versions it lists are the ones minimal version
+// selection would choose, and when no vendor directory, workspace,
+// exclude directive or GOFLAGS could change how the go command would
+// resolve the import.
+func (ctxt *Context) importModule(p *Package, path string) bool {
+	getenv, ok := ctxt.goEnv()
+	if !ok {
+		return false
+	}
+	if getenv("GOFLAGS") != "" || getenv("GO111MODULE") == "off" {
+		return false
+	}
+	gowork := getenv("GOWORK")
+	if gowork != "" && gowork != "off" {
+		return false
+	}
+
+	var dir string
+	if ctxt.Dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return false
+		}
+		dir = wd
+	} else {
+		abs, err := filepath.Abs(ctxt.Dir)
+		if err != nil {
+			return false
+		}
+		dir = abs
+	}
+	modRoot, data := findGoMod(dir)
+	if modRoot == "" {
+		return false
+	}
+	if gowork == "" {
+		if workRoot, _ := findParentFile(dir, "go.work"); workRoot != "" {
+			return false
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(modRoot, "vendor")); err == nil && fi.IsDir() {
+		return false
+	}
+	mf, err := parseGoMod(data)
+	if err != nil || !goVersionAtLeast(mf.goVersion, 17) || mf.exclude {
+		return false
+	}
+	// Without a go.sum file, only the main module's own packages resolve.
+	sums, _ := os.ReadFile(filepath.Join(modRoot, "go.sum"))
+	cache := goModCache(ctxt, getenv)
+	if cache == "" {
+		return false
+	}
+
+	// Find the module providing path: the main module or exactly one of
+	// its requirements. If more than one module could provide it,
+	// let the go command sort out (and report) the ambiguity.
+	var (
+		modPath, modVersion string
+		found               bool
+	)
+	if inModule(path, mf.module) {
+		modPath, found = mf.module, true
+	}
+	for _, r := range mf.require {
+		if !inModule(path, r.path) {
+			continue
+		}
+		if found {
+			return false
+		}
+		modPath, modVersion, found = r.path, r.version, true
+	}
+	if !found {
+		return false
+	}
+	if !ctxt.checkModGraph(mf, modRoot, sums, cache, path, modPath) {
+		return false
+	}
+
+	var modDir string
+	local := false
+	switch {
+	case modVersion == "":
+		modDir, local = modRoot, true
+	default:
+		rep, ok := mf.replacement(modPath, modVersion)
+		if ok && rep.version == "" {
+			modDir, local = rep.path, true
+			if !filepath.IsAbs(modDir) {
+				modDir = filepath.Join(modRoot, modDir)
+			}
+			break
+		}
+		sumPath, sumVersion := modPath, modVersion
+		if ok {
+			sumPath, sumVersion = rep.path, rep.version
+		}
+		if !hasGoSum(sums, sumPath, sumVersion) {
+			return false
+		}
+		modDir, ok = moduleCacheDir(ctxt, cache, sumPath, sumVersion)
+		if !ok {
+			return false
+		}
+	}
+
+	pkgDir := modDir
+	if path != modPath {
+		pkgDir = filepath.Join(modDir, filepath.FromSlash(path[len(modPath)+1:]))
+	}
+	if !ctxt.isDir(pkgDir) {
+		return false
+	}
+	if local {
+		// A go.mod file between modDir and pkgDir starts a nested module,
+		// which does not provide its packages to the enclosing one.
+		// (Module zip files in the module cache never contain nested modules.)
+		for d := pkgDir; d != modDir && len(d) > len(modDir); d = filepath.Dir(d) {
+			if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+				return false
+			}
+		}
+	}
+
+	p.Dir = pkgDir
+	p.ImportPath = path
+	p.Root = modDir
+	p.Goroot = false
+	return true
+}
+
+// findGoMod returns the directory containing the go.mod file governing
+// dir, and the contents of that file. It returns "" if there is none.
+func findGoMod(dir string) (root string, data []byte) {
+	return findParentFile(dir, "go.mod")
+}
+
+// findParentFile returns the nearest of dir and its parents containing
+// a regular file with the given name, and the contents of that file.
+// It returns "" if there is none.
+func findParentFile(dir, name string) (root string, data []byte) {
+	for {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			return dir, data
+		}
+		d := filepath.Dir(dir)
+		if len(d) >= len(dir) {
+			return "", nil
+		}
+		dir = d
+	}
+}
+
+// inModule reports whether the import path belongs to the module path mod,
+// disregarding any nested modules.
+func inModule(path, mod string) bool {
+	return mod != "" && (path == mod || strings.HasPrefix(path, mod) && path[len(mod)] == '/')
+}
+
+// goVersionAtLeast reports whether the go version v, of the form "1.N"
+// or "1.N.P", is at least 1.minor.
+func goVersionAtLeast(v string, minor int) bool {
+	if !strings.HasPrefix(v, "1.") {
+		return false
+	}
+	rest := v[len("1."):]
+	if i := strings.IndexByte(rest, '.'); i >= 0 {
+		rest = rest[:i]
+	}
+	n, err := strconv.Atoi(rest)
+	return err == nil && n >= minor
+}
+
+// moduleCacheDir returns the directory holding the extracted module
+// mod@version in the module cache rooted at cache, reporting whether it
+// exists, or, if ctxt.OpenModuleZip is set, whether the module's zip file does.
+func moduleCacheDir(ctxt *Context, cache, mod, version string) (string, bool) {
+	emod, ok := escapeModulePath(mod)
+	if !ok {
+		return "", false
+	}
+	ever, ok := escapeModulePath(version)
+	if !ok {
+		return "", false
+	}
+	dir := filepath.Join(cache, filepath.FromSlash(emod+"@"+ever))
+	if !ctxt.isDir(dir) {
+		return "", false
+	}
+	return dir, true
+}
+
+// goModCache returns the root of the module cache as the go command
+// would find it, given its configuration getenv, or "" if there is none.
+func goModCache(ctxt *Context, getenv func(key string) string) string {
+	if cache := getenv("GOMODCACHE"); cache != "" {
+		return cache
+	}
+	return ctxt.modCacheRoot()
+}
+
+// escapeModulePath returns s with each upper-case letter replaced by an
+// exclamation mark followed by the letter's lower-case equivalent, as in
+// the file names of the module cache. It reports false if s cannot be
+// escaped because it already contains an exclamation mark or is not
+// valid ASCII.
+func escapeModulePath(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '!' || c >= utf8.RuneSelf:
+			return "", false
+		case 'A' <= c && c <= 'Z':
+			b.WriteByte('!')
+			b.WriteByte(c + 'a' - 'A')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
+
+// hasGoSum reports whether the go.sum file contents sums record the
+// hash of mod@version, without which the go command refuses to use the
+// module. To check for the hash of the module's go.mod file instead,
+// version should have the suffix "/go.mod".
+func hasGoSum(sums []byte, mod, version string) bool {
+	prefix := []byte(mod + " " + version + " ")
+	for len(sums) > 0 {
+		var line []byte
+		line, sums, _ = bytes.Cut(sums, newline)
+		if bytes.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkModGraph reports whether the module graph of the main module,
+// whose go.mod file is mf, agrees with mf: whether minimal version
+// selection would choose the versions of modules that mf requires,
+// and whether modPath is the only module in the graph that could
+// provide the package path.
+//
+// Since the main module is at go 1.17 or later, its module graph is
+// pruned: it holds the modules the main module requires and their
+// own requirements, and requirements further removed only for modules
+// not themselves at go 1.17 or later. checkModGraph does not follow
+// requirements that far, and reports false for such modules instead.
+func (ctxt *Context) checkModGraph(mf *modFile, modRoot string, sums []byte, cache, path, modPath string) bool {
+	selected := make(map[string]string)
+	for _, r := range mf.require {
+		selected[r.path] = r.version
+	}
+	for _, r := range mf.require {
+		dep, ok := depGoMod(mf, modRoot, sums, cache, r)
+		if !ok || !goVersionAtLeast(dep.goVersion, 17) {
+			return false
+		}
+		for _, rr := range dep.require {
+			if rr.path == mf.module {
+				// The main module's requirements on itself are ignored.
+				continue
+			}
+			if rr.path != modPath && inModule(path, rr.path) {
+				return false
+			}
+			v, ok := selected[rr.path]
+			if !ok {
+				continue
+			}
+			if cmp, ok := semverCompare(rr.version, v); !ok || cmp > 0 {
+				// The go command would use a later version of rr.path than
+				// mf requires, and report that go.mod needs updating.
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// depGoMod returns the go.mod file of the module required by r in the
+// main module's go.mod file mf, taking replacements into account.
+func depGoMod(mf *modFile, modRoot string, sums []byte, cache string, r modVersion) (*modFile, bool) {
+	var (
+		data []byte
+		err  error
+	)
+	mod, version := r.path, r.version
+	if rep, ok := mf.replacement(mod, version); ok {
+		mod, version = rep.path, rep.version
+	}
+	if version == "" {
+		dir := mod
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(modRoot, dir)
+		}
+		data, err = os.ReadFile(filepath.Join(dir, "go.mod"))
+	} else {
+		if !hasGoSum(sums, mod, version+"/go.mod") {
+			return nil, false
+		}
+		emod, ok1 := escapeModulePath(mod)
+		ever, ok2 := escapeModulePath(version)
+		if !ok1 || !ok2 {
+			return nil, false
+		}
+		data, err = os.ReadFile(filepath.Join(cache, "cache", "download", filepath.FromSlash(emod), "@v", ever+".mod"))
+	}
+	if err != nil {
+		return nil, false
+	}
+	dep, err := parseGoMod(data)
+	if err != nil {
+		return nil, false
+	}
+	return dep, true
+}
+
+// semverCompare returns -1, 0 or +1 according to whether the semantic
+// version v is less than, equal to or greater than w. It reports false
+// if either is not a canonical semantic version as found in go.mod files.
+func semverCompare(v, w string) (int, bool) {
+	vm, vp, ok1 := splitSemver(v)
+	wm, wp, ok2 := splitSemver(w)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	for i := range vm {
+		if c := compareNum(vm[i], wm[i]); c != 0 {
+			return c, true
+		}
+	}
+	// A version with a prerelease precedes the same version without one.
+	switch {
+	case vp == wp:
+		return 0, true
+	case vp == "":
+		return +1, true
+	case wp == "":
+		return -1, true
+	}
+	vs, ws := strings.Split(vp, "."), strings.Split(wp, ".")
+	for i := 0; i < len(vs) && i < len(ws); i++ {
+		if vs[i] == ws[i] {
+			continue
+		}
+		vnum, wnum := isNum(vs[i]), isNum(ws[i])
+		switch {
+		case vnum && wnum:
+			return compareNum(vs[i], ws[i]), true
+		case vnum:
+			return -1, true
+		case wnum:
+			return +1, true
+		case vs[i] < ws[i]:
+			return -1, true
+		default:
+			return +1, true
+		}
+	}
+	switch {
+	case len(vs) < len(ws):
+		return -1, true
+	case len(vs) > len(ws):
+		return +1, true
+	}
+	return 0, true
+}
+
+// splitSemver splits the semantic version v, of the form
+// vMAJOR.MINOR.PATCH[-PRERELEASE][+BUILD], into its numbers and
+// prerelease, discarding the build metadata.
+func splitSemver(v string) (nums [3]string, pre string, ok bool) {
+	if !strings.HasPrefix(v, "v") {
+		return nums, "", false
+	}
+	v, _, _ = strings.Cut(v[1:], "+")
+	v, pre, hasPre := strings.Cut(v, "-")
+	if hasPre && pre == "" {
+		return nums, "", false
+	}
+	for i := range nums {
+		var n string
+		if i < len(nums)-1 {
+			n, v, ok = strings.Cut(v, ".")
+		} else {
+			n, ok = v, true
+		}
+		if !ok || !isNum(n) || len(n) > 1 && n[0] == '0' {
+			return nums, "", false
+		}
+		nums[i] = n
+	}
+	return nums, pre, true
+}
+
+// isNum reports whether s is a non-empty string of decimal digits.
+func isNum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// compareNum compares the decimal numbers x and y, which have no
+// leading zeros.
+func compareNum(x, y string) int {
+	switch {
+	case len(x) != len(y):
+		if len(x) < len(y) {
+			return -1
+		}
+		return +1
+	case x < y:
+		return -1
+	case x > y:
+		return +1
+	}
+	return 0
+}
+
+// goEnv returns a function reporting the value the go command would
+// use for a configuration variable: its value in the environment or,
+// if that is empty, the value set by 'go env -w' in the user's go env
+// file or, failing that, in $GOROOT/go.env. It reports false if it
+// cannot tell which go env file the go command would read.
+func (ctxt *Context) goEnv() (getenv func(key string) string, ok bool) {
+	file := ctxt.getenv("GOENV")
+	if file == "" {
+		if ctxt.Env != nil {
+			// The go command would look for the file in the configuration
+			// directory given by ctxt.Env, which os.UserConfigDir ignores.
+			return nil, false
+		}
+		if dir, err := os.UserConfigDir(); err == nil {
+			file = filepath.Join(dir, "go", "env")
+		}
+	}
+	vars := make(map[string]string)
+	if ctxt.GOROOT != "" {
+		readGoEnvFile(vars, filepath.Join(ctxt.GOROOT, "go.env"), true)
+	}
+	if file != "" && file != "off" {
+		readGoEnvFile(vars, file, false)
+	}
+	return func(key string) string {
+		if v := ctxt.getenv(key); v != "" {
+			return v
+		}
+		return vars[key]
+	}, true
+}
+
+// readGoEnvFile adds the variables set in the go env file to vars,
+// as the go command reads them. Values in $GOROOT/go.env may be quoted.
+func readGoEnvFile(vars map[string]string, file string, goroot bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	for len(data) > 0 {
+		var line []byte
+		line, data, _ = bytes.Cut(data, newline)
+		key, val, ok := strings.Cut(string(line), "=")
+		if !ok || key == "" || key[0] < 'A' || key[0] > 'Z' {
+			// A comment or not a variable setting.
+			continue
+		}
+		if goroot {
+			if unq, err := strconv.Unquote(val); err == nil {
+				val = unq
+			}
+		}
+		vars[key] = val
+	}
+}
+
+// A modFile holds the parts of a go.mod file needed to resolve imports.
+type modFile struct {
+	module    string
+	goVersion string
+	require   []modVersion
+	replace   []modReplace
+	exclude   bool // whether there are exclude directives
+}
+
+// A modVersion is a module path and version, such as in a require
+// directive. The version is empty for a replacement directory.
+type modVersion struct {
+	path, version string
+}
+
+// A modReplace is a replace directive. An empty old.version
+// replaces all versions of old.path.
+type modReplace struct {
+	old, new modVersion
+}
+
+// replacement returns the replacement for mod@version, if any.
+func (mf *modFile) replacement(mod, version string) (modVersion, bool) {
+	var rep modVersion
+	found := false
+	for _, r := range mf.replace {
+		if r.old.path != mod {
+			continue
+		}
+		if r.old.version == version {
+			return r.new, true
+		}
+		if r.old.version == "" {
+			rep, found = r.new, true
+		}
+	}
+	return rep, found
+}
+
+var errGoModSyntax = errors.New("go.mod: unsupported syntax")
+
+// parseGoMod parses the module, go, require and replace directives of
+// a go.mod file and notes whether it has exclude directives. Other
+// directives are ignored. parseGoMod is stricter
+// than the go command: it returns an error for anything it does not
+// understand, so that the caller can defer to the go command instead.
+func parseGoMod(data []byte) (*modFile, error) {
+	mf := new(modFile)
+	block := ""
+	for len(data) > 0 {
+		var line []byte
+		line, data, _ = bytes.Cut(data, newline)
+		if i := bytes.Index(line, bSlashSlash); i >= 0 {
+			line = line[:i]
+		}
+		args, err := goModFields(string(line))
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if block != "" {
+			if len(args) == 1 && args[0] == ")" {
+				block = ""
+				continue
+			}
+			args = append([]string{block}, args...)
+		} else if len(args) == 2 && args[1] == "(" {
+			block = args[0]
+			continue
+		} else if strings.ContainsAny(args[0], "()") {
+			return nil, errGoModSyntax
+		}
+		switch verb := args[0]; verb {
+		case "module":
+			if len(args) != 2 || mf.module != "" {
+				return nil, errGoModSyntax
+			}
+			mf.module = args[1]
+		case "go":
+			if len(args) != 2 {
+				return nil, errGoModSyntax
+			}
+			mf.goVersion = args[1]
+		case "require":
+			if len(args) != 3 {
+				return nil, errGoModSyntax
+			}
+			mf.require = append(mf.require, modVersion{args[1], args[2]})
+		case "replace":
+			var r modReplace
+			switch {
+			case len(args) == 4 && args[2] == "=>":
+				r = modReplace{modVersion{args[1], ""}, modVersion{args[3], ""}}
+			case len(args) == 5 && args[2] == "=>":
+				r = modReplace{modVersion{args[1], ""}, modVersion{args[3], args[4]}}
+			case len(args) == 5 && args[3] == "=>":
+				r = modReplace{modVersion{args[1], args[2]}, modVersion{args[4], ""}}
+			case len(args) == 6 && args[3] == "=>":
+				r = modReplace{modVersion{args[1], args[2]}, modVersion{args[4], args[5]}}
+			default:
+				return nil, errGoModSyntax
+			}
+			if r.new.version == "" && !isLocalModReplacement(r.new.path) {
+				return nil, errGoModSyntax
+			}
+			mf.replace = append(mf.replace, r)
+		case "exclude":
+			mf.exclude = true
+		}
+	}
+	if block != "" || mf.module == "" {
+		return nil, errGoModSyntax
+	}
+	return mf, nil
+}
+
+// isLocalModReplacement reports whether the replacement path, which has
+// no version, names a directory in the local file system.
+func isLocalModReplacement(path string) bool {
+	return strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") ||
+		strings.HasPrefix(path, `.\`) || strings.HasPrefix(path, `..\`) ||
+		path == "." || path == ".." || filepath.IsAbs(path)
+}
+
+// goModFields splits a go.mod line into fields, unquoting
+// interpreted and raw string literals.
+func goModFields(line string) ([]string, error) {
+	var args []string
+	for {
+		line = strings.TrimLeft(line, " \t\r")
+		if line == "" {
+			return args, nil
+		}
+		switch line[0] {
+		case '"', '`':
+			end := strings.IndexByte(line[1:], line[0])
+			if line[0] == '"' {
+				// Find the closing quote, skipping escaped characters.
+				end = -1
+				for i := 1; i < len(line); i++ {
+					if line[i] == '\\' {
+						i++
+					} else if line[i] == '"' {
+						end = i - 1
+						break
+					}
+				}
+			}
+			if end < 0 {
+				return nil, errGoModSyntax
+			}
+			s, err := strconv.Unquote(line[:end+2])
+			if err != nil {
+				return nil, errGoModSyntax
+			}
+			args = append(args, s)
+			line = line[end+2:]
+		default:
+			end := strings.IndexAny(line, " \t\r\"`")
+			if end < 0 {
+				end = len(line)
+			}
+			args = append(args, line[:end])
+			line = line[end:]
+		}
+	}
+}
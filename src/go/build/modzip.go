@@ -0,0 +1,188 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+)
+
+// moduleZip reports whether path, a directory or file in the local file
+// system, lies within a module in the module cache that has been
+// downloaded but not extracted, and so must be read from the module's
+// zip file when ctxt.OpenModuleZip is set. If so, it returns the name of
+// the zip file and the name of path within it.
+func (ctxt *Context) moduleZip(path string) (zipFile, name string, ok bool) {
+	if ctxt.OpenModuleZip == nil || ctxt.FS != nil {
+		return "", "", false
+	}
+	cache := ctxt.modCacheRoot()
+	if cache == "" {
+		return "", "", false
+	}
+	rel, ok := hasSubdir(cache, path)
+	if !ok || rel == "" {
+		return "", "", false
+	}
+
+	// The module's directory is the first element of rel
+	// containing an @, as in "example.com/!m@v1.2.3/pkg".
+	elems := strings.Split(rel, "/")
+	if elems[0] == "cache" {
+		return "", "", false
+	}
+	i := 0
+	for i < len(elems) && !strings.Contains(elems[i], "@") {
+		i++
+	}
+	if i == len(elems) {
+		return "", "", false
+	}
+	last, ever, _ := strings.Cut(elems[i], "@")
+	emod := pathpkg.Join(append(elems[:i:i], last)...)
+	mod, ok1 := unescapeModulePath(emod)
+	version, ok2 := unescapeModulePath(ever)
+	if !ok1 || !ok2 || emod == "" || ever == "" {
+		return "", "", false
+	}
+
+	// An extracted module takes precedence over its zip file.
+	if _, err := os.Stat(filepath.Join(cache, filepath.FromSlash(pathpkg.Join(elems[:i+1]...)))); err == nil {
+		return "", "", false
+	}
+
+	zipFile = filepath.Join(cache, "cache", "download", filepath.FromSlash(emod), "@v", ever+".zip")
+	name = pathpkg.Join(append([]string{mod + "@" + version}, elems[i+1:]...)...)
+	return zipFile, name, true
+}
+
+// modCacheRoot returns the root directory of the module cache:
+// $GOMODCACHE or, if that is not set, the pkg/mod directory in the
+// first GOPATH entry. It returns "" if there is no module cache.
+func (ctxt *Context) modCacheRoot() string {
+	if cache := ctxt.getenv("GOMODCACHE"); cache != "" {
+		return cache
+	}
+	list := filepath.SplitList(ctxt.GOPATH)
+	if len(list) == 0 || list[0] == "" {
+		return ""
+	}
+	return filepath.Join(list[0], "pkg", "mod")
+}
+
+// unescapeModulePath reverses escapeModulePath, reporting false
+// if s is not a valid escaped path.
+func unescapeModulePath(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z' || c >= 0x80:
+			return "", false
+		case c == '!':
+			if i+1 == len(s) || s[i+1] < 'a' || s[i+1] > 'z' {
+				return "", false
+			}
+			i++
+			b.WriteByte(s[i] - 'a' + 'A')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
+
+// openModuleZip opens the named module zip file with ctxt.OpenModuleZip.
+// When ctxt.Cache is set, the zip file is opened once and kept open by
+// the Cache, and the returned Closer does nothing.
+func (ctxt *Context) openModuleZip(file string) (fs.FS, io.Closer, error) {
+	if c := ctxt.Cache; c != nil {
+		zr, err := c.openZip(file, ctxt.OpenModuleZip)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, nopCloser{}, nil
+	}
+	zr, err := ctxt.OpenModuleZip(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return zr, fsCloser{zr}, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// An fsCloser closes its file system if it implements io.Closer.
+type fsCloser struct {
+	fsys fs.FS
+}
+
+func (c fsCloser) Close() error {
+	return closeFS(c.fsys)
+}
+
+// closeFS closes fsys if it implements io.Closer.
+func closeFS(fsys fs.FS) error {
+	if c, ok := fsys.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// readZipDir is like readDir for the directory name in the module zip file.
+func (ctxt *Context) readZipDir(file, name string) ([]fs.DirEntry, error) {
+	zr, c, err := ctxt.openModuleZip(file)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return fs.ReadDir(zr, name)
+}
+
+// openZipFile is like openFile for the file name in the module zip file.
+func (ctxt *Context) openZipFile(file, name string) (io.ReadCloser, error) {
+	zr, c, err := ctxt.openModuleZip(file)
+	if err != nil {
+		return nil, err
+	}
+	f, err := zr.Open(name)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return &zipFileCloser{f, c}, nil
+}
+
+// isZipDir is like isDir for the path name in the module zip file.
+func (ctxt *Context) isZipDir(file, name string) bool {
+	zr, c, err := ctxt.openModuleZip(file)
+	if err != nil {
+		return false
+	}
+	defer c.Close()
+	fi, err := fs.Stat(zr, name)
+	return err == nil && fi.IsDir()
+}
+
+// A zipFileCloser is a file in a module zip file
+// that closes the zip file too when it is closed.
+type zipFileCloser struct {
+	fs.File
+	zip io.Closer
+}
+
+func (f *zipFileCloser) Close() error {
+	err := f.File.Close()
+	if err1 := f.zip.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
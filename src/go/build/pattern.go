@@ -0,0 +1,402 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"fmt"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MatchPatterns expands package patterns, as accepted by the go command,
+// into the import paths of the packages they match, using the GOROOT,
+// GOPATH and file system settings of ctxt. The result lists the matches
+// of each pattern in turn, sorted and without duplicates.
+//
+// The supported patterns are:
+//
+//   - "std" and "cmd", which match the packages of the standard library
+//     and of the Go commands in GOROOT;
+//   - import path patterns containing "...", such as "net/..." or
+//     "example.com/m/...", as described by 'go help packages';
+//   - local patterns, which begin with "./" or "../" or are absolute
+//     directory names, such as "./..." and "./cmd/...", interpreted
+//     relative to ctxt.Dir or, if it is empty, the current directory;
+//   - any other import path, which is returned unchanged.
+//
+// In module mode, which MatchPatterns assumes when GO111MODULE is not
+// "off" and the directory for local patterns is inside a module, import
+// path patterns match packages in GOROOT, in the main module and in the
+// modules it requires, which must already be present in the module cache
+// or replaced by local directories. Otherwise they match packages in
+// GOROOT and GOPATH.
+//
+// As with the go command, the "..." wildcard does not match directories
+// named testdata or beginning with "." or "_", nor, unless the pattern
+// names vendor directories explicitly, those named vendor. Nor does it
+// match directories whose names match ctxt.IgnoreDirs. A directory
+// matches only if it holds a package, that is, if ImportDir does not
+// report a *NoGoError for it.
+//
+// The pattern "all", whose meaning depends on the package import graph,
+// is not supported.
+func (ctxt *Context) MatchPatterns(patterns ...string) ([]string, error) {
+	m := &patternMatcher{ctxt: ctxt, seen: make(map[string]bool)}
+	m.dir = ctxt.Dir
+	if m.dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		m.dir = wd
+	}
+	if ctxt.FS == nil && ctxt.getenv("GO111MODULE") != "off" {
+		// The standard library and commands in GOROOT form modules of their own,
+		// but they are matched by walking GOROOT as in GOPATH mode.
+		root, data := findGoMod(m.dir)
+		if root != "" && ctxt.GOROOT != "" {
+			src := ctxt.joinPath(ctxt.GOROOT, "src")
+			if _, ok := ctxt.hasSubdir(src, root); ok || root == src {
+				root = ""
+			}
+		}
+		if root != "" {
+			mf, err := parseGoMod(data)
+			if err != nil {
+				return nil, fmt.Errorf("go/build: cannot parse %s: %v", filepath.Join(root, "go.mod"), err)
+			}
+			m.modRoot, m.mod = root, mf
+			m.vendor = goVersionAtLeast(mf.goVersion, 14) && ctxt.isDir(filepath.Join(root, "vendor"))
+		}
+	}
+
+	for _, pattern := range patterns {
+		var err error
+		switch {
+		case pattern == "all":
+			err = fmt.Errorf("go/build: pattern %q is not supported", pattern)
+		case pattern == "std" || pattern == "cmd":
+			m.matchGoroot(pattern)
+		case IsLocalImport(pattern) || ctxt.isAbsPath(pattern):
+			err = m.matchLocal(pattern)
+		case !strings.Contains(pattern, "..."):
+			m.matches = append(m.matches, pattern)
+		default:
+			err = m.matchImportPath(pattern)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.flush()
+	}
+	return m.list, nil
+}
+
+// A patternMatcher holds the state of a call to MatchPatterns.
+type patternMatcher struct {
+	ctxt    *Context
+	dir     string   // directory for local patterns
+	modRoot string   // root of the main module, in module mode
+	mod     *modFile // go.mod of the main module, in module mode
+	vendor  bool     // whether the main module vendors its requirements
+
+	matches []string // matches of the current pattern
+	list    []string // matches of the previous patterns
+	seen    map[string]bool
+}
+
+// flush appends the matches of the current pattern to the result.
+func (m *patternMatcher) flush() {
+	sort.Strings(m.matches)
+	for _, path := range m.matches {
+		if !m.seen[path] {
+			m.seen[path] = true
+			m.list = append(m.list, path)
+		}
+	}
+	m.matches = m.matches[:0]
+}
+
+// matchGoroot adds the packages matched by "std" or "cmd".
+func (m *patternMatcher) matchGoroot(pattern string) {
+	if m.ctxt.GOROOT == "" {
+		return
+	}
+	src := m.ctxt.joinPath(m.ctxt.GOROOT, "src")
+	if pattern == "cmd" {
+		m.walk(m.ctxt.joinPath(src, "cmd"), "cmd", false,
+			func(string) bool { return true },
+			func(dir, path string) { m.matches = append(m.matches, path) })
+		return
+	}
+	m.walk(src, "", false,
+		func(path string) bool { return path != "cmd" && !strings.HasPrefix(path, "cmd/") },
+		func(dir, path string) {
+			if path != "" && path != "builtin" {
+				m.matches = append(m.matches, path)
+			}
+		})
+}
+
+// matchImportPath adds the packages matched by an import path pattern.
+func (m *patternMatcher) matchImportPath(pattern string) error {
+	match := matchPattern(pattern)
+	canMatch := treeCanMatchPattern(pattern)
+	add := func(dir, path string) {
+		if match(path) {
+			m.matches = append(m.matches, path)
+		}
+	}
+
+	if m.ctxt.GOROOT != "" {
+		m.walk(m.ctxt.joinPath(m.ctxt.GOROOT, "src"), "", false, canMatch, add)
+	}
+	if m.mod == nil {
+		for _, root := range m.ctxt.gopath() {
+			m.walk(m.ctxt.joinPath(root, "src"), "", false, canMatch, add)
+		}
+		return nil
+	}
+
+	if canMatch(m.mod.module) {
+		m.walk(m.modRoot, m.mod.module, true, canMatch, add)
+	}
+	cache := ""
+	if getenv, ok := m.ctxt.goEnv(); ok {
+		cache = goModCache(m.ctxt, getenv)
+	}
+	for _, r := range m.mod.require {
+		if !canMatch(r.path) {
+			continue
+		}
+		dir, ok := "", false
+		rep, replaced := m.mod.replacement(r.path, r.version)
+		switch {
+		case m.vendor:
+			// Only the vendored packages of the module are available.
+			dir, ok = filepath.Join(m.modRoot, "vendor", filepath.FromSlash(r.path)), true
+		case replaced && rep.version == "":
+			dir, ok = rep.path, true
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(m.modRoot, dir)
+			}
+		case cache == "":
+		case replaced:
+			dir, ok = moduleCacheDir(m.ctxt, cache, rep.path, rep.version)
+		default:
+			dir, ok = moduleCacheDir(m.ctxt, cache, r.path, r.version)
+		}
+		if !ok {
+			return fmt.Errorf("go/build: matching %s: module %s@%s is not in the module cache", pattern, r.path, r.version)
+		}
+		m.walk(dir, r.path, true, canMatch, add)
+	}
+	return nil
+}
+
+// matchLocal adds the packages matched by a local pattern.
+func (m *patternMatcher) matchLocal(pattern string) error {
+	dirPattern := pattern
+	if !m.ctxt.isAbsPath(pattern) {
+		dirPattern = m.ctxt.joinPath(m.dir, pattern)
+	}
+	dirPattern = filepath.ToSlash(filepath.Clean(dirPattern))
+
+	var err error
+	add := func(dir, _ string) {
+		if err != nil {
+			return
+		}
+		var path string
+		path, err = m.localImportPath(dir)
+		m.matches = append(m.matches, path)
+	}
+
+	if !strings.Contains(dirPattern, "...") {
+		if !m.ctxt.isDir(filepath.FromSlash(dirPattern)) {
+			return fmt.Errorf("go/build: directory %s not found", pattern)
+		}
+		add(filepath.FromSlash(dirPattern), "")
+		return err
+	}
+
+	// Walk the tree rooted at the longest directory prefix of the
+	// pattern that contains no wildcard, matching directory names.
+	root := dirPattern[:strings.Index(dirPattern, "...")]
+	if i := strings.LastIndex(root, "/"); i >= 0 {
+		root = root[:i]
+	}
+	if root == "" {
+		root = "/"
+	}
+	match := matchPattern(dirPattern)
+	m.walk(filepath.FromSlash(root), root, m.mod != nil, treeCanMatchPattern(dirPattern),
+		func(dir, name string) {
+			if match(name) {
+				add(dir, name)
+			}
+		})
+	return err
+}
+
+// localImportPath returns the import path of the package in dir.
+func (m *patternMatcher) localImportPath(dir string) (string, error) {
+	if m.mod == nil {
+		p, err := m.ctxt.ImportDir(dir, FindOnly)
+		if err != nil {
+			return "", err
+		}
+		if p.ImportPath == "." {
+			// dir is outside GOROOT and GOPATH.
+			return dirToImportPath(dir), nil
+		}
+		return p.ImportPath, nil
+	}
+	rel, err := filepath.Rel(m.modRoot, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("go/build: directory %s is outside main module %s", dir, m.modRoot)
+	}
+	if rel == "." {
+		return m.mod.module, nil
+	}
+	return m.mod.module + "/" + filepath.ToSlash(rel), nil
+}
+
+// walk calls found(dir, path) for each directory in the tree rooted at
+// root that holds a Go package, where path is the slash-separated name
+// of the directory formed by joining name and its path relative to root.
+// It descends only into the directories whose names satisfy canMatch
+// and whose last elements are not ignored by m.ctxt.IgnoreDirs.
+// If module is set, walk does not descend into vendor directories or
+// into nested modules, whose roots contain a go.mod file.
+func (m *patternMatcher) walk(root, name string, module bool, canMatch func(string) bool, found func(dir, path string)) {
+	if !canMatch(name) {
+		return
+	}
+	ents, err := m.ctxt.readDir(root)
+	if err != nil {
+		return
+	}
+	if m.ctxt.matchDirEntries(root, ents) {
+		found(root, name)
+	}
+	for _, ent := range ents {
+		elem := ent.Name()
+		if !ent.IsDir() || elem == "testdata" || strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") ||
+			m.ctxt.ignoreDir(elem) {
+			continue
+		}
+		dir := m.ctxt.joinPath(root, elem)
+		if module {
+			if elem == "vendor" || m.ctxt.isFile(m.ctxt.joinPath(dir, "go.mod")) {
+				continue
+			}
+		}
+		path := elem
+		if name != "" {
+			path = pathpkg.Join(name, elem)
+		}
+		m.walk(dir, path, module, canMatch, found)
+	}
+}
+
+func isNoGoError(err error) bool {
+	_, ok := err.(*NoGoError)
+	return ok
+}
+
+// dirToImportPath returns the import path the go command gives to the
+// package in dir, a directory outside GOROOT and GOPATH in GOPATH mode.
+func dirToImportPath(dir string) string {
+	return pathpkg.Join("_", strings.Map(makeImportValid, filepath.ToSlash(dir)))
+}
+
+func makeImportValid(r rune) rune {
+	// Should match Go spec, compilers, and ../parser/parser.go:/isValidImport.
+	const illegalChars = `!"#$%&'()*,:;<=>?[\]^{|}` + "`\uFFFD"
+	if !unicode.IsGraphic(r) || unicode.IsSpace(r) || strings.ContainsRune(illegalChars, r) {
+		return '_'
+	}
+	return r
+}
+
+// matchPattern returns a function reporting whether a name matches
+// a pattern in which "..." matches any string, as described by
+// 'go help packages'. A trailing "/..." also matches the empty string,
+// so that "net/..." matches both "net" and the packages below it.
+// A "..." never matches a vendor path element, so names in vendor
+// directories match only patterns that spell out the "vendor" element.
+func matchPattern(pattern string) func(name string) bool {
+	pattern = replaceVendor(pattern)
+	parts := strings.Split(pattern, "...")
+	var optional []string
+	if strings.HasSuffix(pattern, "/...") {
+		prefix := pattern[:len(pattern)-len("/...")]
+		if prefix != vendorElem && !strings.HasSuffix(prefix, "/"+vendorElem) {
+			optional = strings.Split(prefix, "...")
+		}
+	}
+	return func(name string) bool {
+		name = replaceVendor(name)
+		return matchParts(parts, name) || optional != nil && matchParts(optional, name)
+	}
+}
+
+// vendorElem replaces vendor path elements in patterns and names
+// matched by matchPattern. It cannot occur in an import path.
+const vendorElem = "\x00"
+
+// replaceVendor returns s with each vendor path element replaced by vendorElem.
+func replaceVendor(s string) string {
+	if !strings.Contains(s, "vendor") {
+		return s
+	}
+	elems := strings.Split(s, "/")
+	for i, elem := range elems {
+		if elem == "vendor" {
+			elems[i] = vendorElem
+		}
+	}
+	return strings.Join(elems, "/")
+}
+
+// matchParts reports whether name consists of the literal parts in order,
+// separated by arbitrary strings that contain no vendorElem.
+func matchParts(parts []string, name string) bool {
+	if len(parts) == 1 {
+		return name == parts[0]
+	}
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 || strings.Contains(name[:i], vendorElem) {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	last := parts[len(parts)-1]
+	if !strings.HasSuffix(name, last) {
+		return false
+	}
+	return !strings.Contains(name[:len(name)-len(last)], vendorElem)
+}
+
+// treeCanMatchPattern returns a function reporting whether a directory
+// with the given slash-separated name, or one of its subdirectories,
+// can match pattern.
+func treeCanMatchPattern(pattern string) func(name string) bool {
+	prefix, _, wildcard := strings.Cut(pattern, "...")
+	return func(name string) bool {
+		return name == "" || len(name) <= len(prefix) && strings.HasPrefix(prefix, name) && (len(name) == len(prefix) || prefix[len(name)] == '/' || name == "/") ||
+			wildcard && strings.HasPrefix(name, prefix)
+	}
+}
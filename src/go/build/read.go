@@ -437,38 +437,9 @@ func readGoInfo(f io.Reader, info *fileInfo) error {
 		return nil
 	}
 
-	// Parse file header & record imports.
-	info.parsed, info.parseErr = parser.ParseFile(info.fset, info.name, info.header, parser.ImportsOnly|parser.ParseComments)
-	if info.parseErr != nil {
-		return nil
-	}
-
-	hasEmbed := false
-	for _, decl := range info.parsed.Decls {
-		d, ok := decl.(*ast.GenDecl)
-		if !ok {
-			continue
-		}
-		for _, dspec := range d.Specs {
-			spec, ok := dspec.(*ast.ImportSpec)
-			if !ok {
-				continue
-			}
-			quoted := spec.Path.Value
-			path, err := strconv.Unquote(quoted)
-			if err != nil {
-				return fmt.Errorf("parser returned invalid quoted string: <%s>", quoted)
-			}
-			if path == "embed" {
-				hasEmbed = true
-			}
-
-			doc := spec.Doc
-			if doc == nil && len(d.Specs) == 1 {
-				doc = d.Doc
-			}
-			info.imports = append(info.imports, fileImport{path, spec.Pos(), doc})
-		}
+	hasEmbed, err := parseGoInfo(info)
+	if err != nil || info.parseErr != nil {
+		return err
 	}
 
 	// If the file imports "embed",
@@ -504,6 +475,48 @@ func readGoInfo(f io.Reader, info *fileInfo) error {
 	return nil
 }
 
+// parseGoInfo parses info.header, which must hold the header of a Go
+// file as read by readGoInfo, using info.fset. It sets info.parsed,
+// info.parseErr, and info.imports, and it reports whether the file
+// imports "embed".
+//
+// It only returns an error if the parser misbehaves,
+// not for syntax errors in the file itself.
+func parseGoInfo(info *fileInfo) (hasEmbed bool, err error) {
+	info.parsed, info.parseErr = parser.ParseFile(info.fset, info.name, info.header, parser.ImportsOnly|parser.ParseComments)
+	if info.parseErr != nil {
+		return false, nil
+	}
+
+	for _, decl := range info.parsed.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, dspec := range d.Specs {
+			spec, ok := dspec.(*ast.ImportSpec)
+			if !ok {
+				continue
+			}
+			quoted := spec.Path.Value
+			path, err := strconv.Unquote(quoted)
+			if err != nil {
+				return false, fmt.Errorf("parser returned invalid quoted string: <%s>", quoted)
+			}
+			if path == "embed" {
+				hasEmbed = true
+			}
+
+			doc := spec.Doc
+			if doc == nil && len(d.Specs) == 1 {
+				doc = d.Doc
+			}
+			info.imports = append(info.imports, fileImport{path, spec.Pos(), doc})
+		}
+	}
+	return hasEmbed, nil
+}
+
 // parseGoEmbed parses the text following "//go:embed" to extract the glob patterns.
 // It accepts unquoted space-separated patterns as well as double-quoted and back-quoted Go strings.
 // This is based on a similar function in cmd/compile/internal/gc/noder.go;
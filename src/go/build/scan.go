@@ -0,0 +1,203 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// A DirScan describes how each file in a directory
+// would be treated by Import, as reported by ScanDir.
+type DirScan struct {
+	Dir   string         // directory scanned
+	Files []*ScannedFile // files in Dir, in directory order
+}
+
+// A ScannedFile describes how a single file would be treated by Import.
+type ScannedFile struct {
+	Name    string       // file name, relative to DirScan.Dir
+	Kind    ScanKind     // how the file is classified
+	Package string       // name in the package clause, for Go files that could be parsed
+	Reason  IgnoreReason // why the file is ignored, if Kind is ScanIgnoredFile
+	Err     *FileError   // the problem with the file, if Kind is ScanInvalidFile
+
+	// Go describes the constraint, imports and embed patterns of a
+	// Go file that could be parsed. It is nil for other files.
+	Go *GoFile
+}
+
+// A ScanKind classifies a file in a directory scanned by ScanDir.
+type ScanKind int
+
+const (
+	ScanGoFile      ScanKind = iota // a Go file, as listed in Package.GoFiles
+	ScanCgoFile                     // a Go file that imports "C", as listed in Package.CgoFiles
+	ScanTestGoFile                  // a _test.go file in the package, as listed in Package.TestGoFiles
+	ScanXTestGoFile                 // a _test.go file in the external test package, as listed in Package.XTestGoFiles
+	ScanOtherFile                   // a non-Go source file, such as one listed in Package.CFiles or Package.SFiles
+	ScanIgnoredFile                 // a source file left out of the build
+	ScanInvalidFile                 // a Go file with a problem, as listed in Package.InvalidGoFiles
+	ScanUnknownFile                 // a file that is not source code
+)
+
+var scanKinds = [...]string{
+	ScanGoFile:      "Go file",
+	ScanCgoFile:     "cgo file",
+	ScanTestGoFile:  "test file",
+	ScanXTestGoFile: "external test file",
+	ScanOtherFile:   "other source file",
+	ScanIgnoredFile: "ignored file",
+	ScanInvalidFile: "invalid file",
+	ScanUnknownFile: "not a source file",
+}
+
+func (k ScanKind) String() string {
+	if 0 <= k && int(k) < len(scanKinds) {
+		return scanKinds[k]
+	}
+	return "ScanKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// ScanDir reports how Import would treat each file in the directory dir,
+// without assembling the files into a Package. Unlike Import, ScanDir
+// does not require the Go files to belong to a single package: it
+// records each file's package name, considering a _test.go file to be
+// in an external test package when its package name ends in "_test",
+// and does not treat files from different packages as invalid.
+//
+// ScanDir returns an error only if the directory cannot be read.
+// Problems with individual files are reported in their ScannedFile.
+func (ctxt *Context) ScanDir(dir string) (*DirScan, error) {
+	ents, err := ctxt.readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	scan := &DirScan{Dir: dir}
+	fset := token.NewFileSet()
+	haveCgo := false
+	var sfiles []*ScannedFile // .S and .sx files, which need cgo
+	for _, d := range ents {
+		if d.IsDir() {
+			continue
+		}
+		if d.Type()&fs.ModeSymlink != 0 && ctxt.isDir(ctxt.joinPath(dir, d.Name())) {
+			continue
+		}
+		f := ctxt.scanFile(dir, d.Name(), fset)
+		switch f.Kind {
+		case ScanCgoFile:
+			haveCgo = true
+		case ScanOtherFile:
+			if ext := nameExt(f.Name); ext == ".S" || ext == ".sx" {
+				sfiles = append(sfiles, f)
+			}
+		}
+		scan.Files = append(scan.Files, f)
+	}
+	if !haveCgo {
+		for _, f := range sfiles {
+			f.Kind, f.Reason = ScanIgnoredFile, IgnoredCgo
+		}
+	}
+	return scan, nil
+}
+
+// scanFile classifies the file dir/name for ScanDir.
+func (ctxt *Context) scanFile(dir, name string, fset *token.FileSet) *ScannedFile {
+	f := &ScannedFile{Name: name}
+	invalid := func(kind FileErrorKind, pos token.Position, err error) {
+		fe, ok := err.(*FileError)
+		if !ok {
+			fe = &FileError{Name: name, Kind: kind, Pos: pos, Err: err}
+		}
+		if f.Err == nil {
+			f.Kind, f.Err = ScanInvalidFile, fe
+		}
+	}
+
+	ext := nameExt(name)
+	info, reason, err := ctxt.matchFile(dir, name, nil, nil, fset)
+	switch {
+	case err != nil:
+		invalid(FileReadError, token.Position{Filename: ctxt.joinPath(dir, name)}, err)
+		return f
+	case ext != ".go" && fileListForExt(&dummyPkg, ext) == nil:
+		f.Kind = ScanUnknownFile
+		return f
+	case info == nil:
+		f.Kind, f.Reason = ScanIgnoredFile, reason
+		return f
+	case ext != ".go":
+		f.Kind = ScanOtherFile
+		return f
+	}
+
+	if info.parseErr != nil {
+		pos := token.Position{Filename: info.name}
+		if list, ok := info.parseErr.(scanner.ErrorList); ok && len(list) > 0 {
+			pos = list[0].Pos
+		}
+		invalid(FileSyntaxError, pos, info.parseErr)
+	}
+	if info.parsed == nil {
+		return f
+	}
+	f.Package = info.parsed.Name.Name
+	if f.Package == "documentation" {
+		f.Kind, f.Reason = ScanIgnoredFile, IgnoredDocumentation
+		return f
+	}
+
+	isTest := strings.HasSuffix(name, "_test.go")
+	isCgo := false
+	for _, imp := range info.imports {
+		if imp.path != "C" {
+			continue
+		}
+		if isTest {
+			invalid(FileCgoError, fset.Position(imp.pos), fmt.Errorf("use of cgo in test %s not supported", info.name))
+			continue
+		}
+		isCgo = true
+		if imp.doc != nil {
+			if err := ctxt.saveCgo(info.name, &Package{Dir: dir}, imp.doc); err != nil {
+				invalid(FileCgoError, fset.Position(imp.doc.Pos()), err)
+			}
+		}
+	}
+
+	g := &GoFile{Name: name, Constraint: fileConstraint(info.header)}
+	for _, imp := range info.imports {
+		g.Imports = append(g.Imports, imp.path)
+		g.ImportPos = append(g.ImportPos, fset.Position(imp.pos))
+	}
+	for _, emb := range info.embeds {
+		g.EmbedPatterns = append(g.EmbedPatterns, emb.pattern)
+		g.EmbedPatternPos = append(g.EmbedPatternPos, emb.pos)
+	}
+	f.Go = g
+
+	if f.Kind == ScanInvalidFile {
+		return f
+	}
+	switch {
+	case isCgo && !ctxt.CgoEnabled:
+		f.Kind, f.Reason = ScanIgnoredFile, IgnoredCgo
+	case isCgo:
+		f.Kind = ScanCgoFile
+	case isTest && strings.HasSuffix(f.Package, "_test"):
+		f.Kind = ScanXTestGoFile
+	case isTest:
+		f.Kind = ScanTestGoFile
+	default:
+		f.Kind = ScanGoFile
+	}
+	return f
+}
@@ -0,0 +1,66 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// ScanStats counts the work done by Import and the other methods of
+// a Context whose Stats field points to it. See Context.Stats.
+//
+// The counters are updated atomically, so that a ScanStats can be
+// shared by contexts used concurrently. While any of them may be in
+// use, the counters must be read with the functions of sync/atomic.
+type ScanStats struct {
+	ReadDirs        int64 // directories read
+	FilesRead       int64 // source files opened to read their headers
+	BytesRead       int64 // bytes read from those files
+	ConstraintEvals int64 // build constraint expressions evaluated
+	GoListRuns      int64 // go list commands run
+	CacheHits       int64 // directories and file headers found in Context.Cache
+	CacheMisses     int64 // directories and file headers missing from or stale in Context.Cache
+}
+
+// countReads returns a function that calls open, counting the files
+// it opens and the bytes read from them in s. It returns open itself
+// if s is nil.
+func (s *ScanStats) countReads(open func(string) (io.ReadCloser, error)) func(string) (io.ReadCloser, error) {
+	if s == nil {
+		return open
+	}
+	return func(name string) (io.ReadCloser, error) {
+		f, err := open(name)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&s.FilesRead, 1)
+		return &countingReader{f, s}, nil
+	}
+}
+
+// A countingReader counts the bytes read through it in stats.BytesRead.
+type countingReader struct {
+	io.ReadCloser
+	stats *ScanStats
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	atomic.AddInt64(&r.stats.BytesRead, int64(n))
+	return n, err
+}
+
+// countCache counts a hit or miss in Context.Cache, if s is not nil.
+func (s *ScanStats) countCache(hit bool) {
+	switch {
+	case s == nil:
+	case hit:
+		atomic.AddInt64(&s.CacheHits, 1)
+	default:
+		atomic.AddInt64(&s.CacheMisses, 1)
+	}
+}
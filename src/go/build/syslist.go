@@ -4,6 +4,8 @@
 
 package build
 
+import "sort"
+
 // knownOS is the list of past, present, and future known GOOS values.
 // Do not remove from this list, as it is used for filename matching.
 // If you add an entry to this list, look at unixOS, below.
@@ -73,3 +75,61 @@ var knownArch = map[string]bool{
 	"sparc64":     true,
 	"wasm":        true,
 }
+
+// KnownOSList returns the sorted list of GOOS values known to go/build:
+// those that select files by name, as in "_linux.go", and that match the
+// build constraint of the same name. The list includes past and future
+// operating systems as well as those the toolchain currently supports.
+func KnownOSList() []string {
+	return sortedKeys(knownOS)
+}
+
+// KnownArchList returns the sorted list of GOARCH values known to
+// go/build: those that select files by name, as in "_amd64.go", and
+// that match the build constraint of the same name. The list includes
+// past and future architectures as well as those the toolchain
+// currently supports.
+func KnownArchList() []string {
+	return sortedKeys(knownArch)
+}
+
+// IsUnixOS reports whether the "unix" build constraint
+// is satisfied when building for goos.
+func IsUnixOS(goos string) bool {
+	return unixOS[goos]
+}
+
+// ValidOSArch reports whether the toolchain supports building for the
+// operating system goos on the architecture goarch: whether goos/goarch
+// is one of the ports listed by 'go tool dist list'. Unlike KnownOSList
+// and KnownArchList, which include values the toolchain no longer or
+// does not yet support, ValidOSArch reflects the ports of the current
+// toolchain.
+func ValidOSArch(goos, goarch string) bool {
+	_, ok := distPorts[goos+"/"+goarch]
+	return ok
+}
+
+// FirstClassPorts returns the sorted list of the first class ports of
+// the toolchain, in the form "goos/goarch", as marked by
+// 'go tool dist list -json'. A broken build on a first class port
+// blocks a release.
+func FirstClassPorts() []string {
+	var list []string
+	for port, firstClass := range distPorts {
+		if firstClass {
+			list = append(list, port)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+func sortedKeys(m map[string]bool) []string {
+	list := make([]string, 0, len(m))
+	for k := range m {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -6,6 +6,8 @@ package build
 
 import (
 	"runtime"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -60,3 +62,73 @@ func TestGoodOSArch(t *testing.T) {
 		}
 	}
 }
+
+func TestKnownLists(t *testing.T) {
+	for _, x := range []struct {
+		name  string
+		list  []string
+		this  string
+		field func(*Context) *string
+	}{
+		{"KnownOSList", KnownOSList(), thisOS, func(c *Context) *string { return &c.GOOS }},
+		{"KnownArchList", KnownArchList(), thisArch, func(c *Context) *string { return &c.GOARCH }},
+	} {
+		if !sort.StringsAreSorted(x.list) {
+			t.Errorf("%s() is not sorted: %q", x.name, x.list)
+		}
+		found := false
+		for _, v := range x.list {
+			found = found || v == x.this
+			ctxt := Default
+			*x.field(&ctxt) = v
+			if !ctxt.goodOSArchFile("file_"+v+".go", nil) {
+				t.Errorf("%s: file_%s.go does not match when building for %s", x.name, v, v)
+			}
+		}
+		if !found {
+			t.Errorf("%s() = %q, missing %s", x.name, x.list, x.this)
+		}
+	}
+
+	for goos := range unixOS {
+		if !knownOS[goos] {
+			t.Errorf("IsUnixOS(%q) is true, but %s is not in KnownOSList()", goos, goos)
+		}
+	}
+	if !IsUnixOS("linux") || IsUnixOS("windows") || IsUnixOS("nosuchos") {
+		t.Errorf("IsUnixOS(linux, windows, nosuchos) = %v, %v, %v; want true, false, false",
+			IsUnixOS("linux"), IsUnixOS("windows"), IsUnixOS("nosuchos"))
+	}
+}
+
+func TestValidOSArch(t *testing.T) {
+	if !ValidOSArch(thisOS, thisArch) {
+		t.Errorf("ValidOSArch(%q, %q) = false, want true", thisOS, thisArch)
+	}
+	for _, port := range [][2]string{{"linux", "amd64"}, {"js", "wasm"}, {"plan9", "386"}} {
+		if !ValidOSArch(port[0], port[1]) {
+			t.Errorf("ValidOSArch(%q, %q) = false, want true", port[0], port[1])
+		}
+	}
+	for _, port := range [][2]string{{"linux", "wasm"}, {"nacl", "amd64p32"}, {"linux", "sparc64"}, {"", ""}, {"linux/amd64", ""}} {
+		if ValidOSArch(port[0], port[1]) {
+			t.Errorf("ValidOSArch(%q, %q) = true, want false", port[0], port[1])
+		}
+	}
+
+	list := FirstClassPorts()
+	if !sort.StringsAreSorted(list) {
+		t.Errorf("FirstClassPorts() is not sorted: %q", list)
+	}
+	found := false
+	for _, port := range list {
+		goos, goarch, _ := strings.Cut(port, "/")
+		if !ValidOSArch(goos, goarch) {
+			t.Errorf("FirstClassPorts() lists %s, for which ValidOSArch is false", port)
+		}
+		found = found || port == "linux/amd64"
+	}
+	if !found {
+		t.Errorf("FirstClassPorts() = %q, missing linux/amd64", list)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// Walk walks the directory tree rooted at root, using ctxt to read
+// directories and files, and calls fn with the result of ImportDir
+// for each directory that contains a Go package, in lexical order
+// with each directory before its subdirectories.
+//
+// Like the "..." pattern of the go command, Walk does not descend into
+// directories named "testdata" or "vendor" or whose names begin with
+// "." or "_", other than root itself, and it does not follow symbolic
+// links. Nor does it descend into directories whose names match
+// ctxt.IgnoreDirs. Directories without Go files, for which ImportDir reports a
+// *NoGoError, are not passed to fn, but their subdirectories are walked.
+//
+// If ImportDir fails for another reason, fn is called with the partial
+// Package and the error. If a directory cannot be read, that error is
+// passed to fn, as filepath.WalkDir does, and Walk does not descend
+// into the directory. If fn returns fs.SkipDir, Walk skips the
+// subdirectories of the directory; if fn returns any other non-nil
+// error, Walk stops and returns that error.
+func (ctxt *Context) Walk(root string, fn func(*Package, error) error) error {
+	err := ctxt.walkPackages(root, fn)
+	if err == fs.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func (ctxt *Context) walkPackages(dir string, fn func(*Package, error) error) error {
+	// Import lazily, so that the directory is read only once,
+	// here, and its entries shared with Import.
+	p, err := ctxt.ImportDir(dir, LazyLoad)
+	ents, readErr := ctxt.readDir(dir)
+	if p.lazy != nil {
+		p.complete(ctxt, ents, readErr)
+		err = p.lazy.err
+	} else if err == nil {
+		err = readErr
+	}
+	if _, noGo := err.(*NoGoError); !noGo {
+		if err := fn(p, err); err != nil {
+			return err
+		}
+	}
+	if readErr != nil {
+		return nil
+	}
+
+	for _, ent := range ents {
+		name := ent.Name()
+		if !ent.IsDir() || name == "testdata" || name == "vendor" ||
+			strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || ctxt.ignoreDir(name) {
+			continue
+		}
+		err := ctxt.walkPackages(ctxt.joinPath(dir, name), fn)
+		if err != nil && err != fs.SkipDir {
+			return err
+		}
+	}
+	return nil
+}
@@ -24,9 +24,17 @@ const (
 // If MaxLen is not 0, make sure MaxLen >= 4.
 var MaxLen int
 
+// MaxWideLen is the maximum length of the string to be searched for (argument b)
+// in IndexWide. If MaxWideLen is 0, IndexWide is not available.
+var MaxWideLen int
+
+// MinWideSearch is the minimum length of the string to be searched (argument a)
+// for which IndexWide is worth using instead of Index.
+var MinWideSearch int
+
 // FIXME: the logic of HashStrBytes, HashStrRevBytes, IndexRabinKarpBytes and HashStr, HashStrRev,
 // IndexRabinKarp are exactly the same, except that the types are different. Can we eliminate
-// three of them without causing allocation?
+// three of them without causing allocation? The same goes for their 64-bit counterparts.
 
 // PrimeRK is the prime base used in Rabin-Karp algorithm.
 const PrimeRK = 16777619
@@ -99,6 +107,84 @@ func HashStrRev(sep string) (uint32, uint32) {
 	return hash, pow
 }
 
+// PrimeRK64 is the prime base used in the 64-bit Rabin-Karp functions.
+//
+// The 32-bit hash of a long or repetitive needle often equals that of
+// a window of s that differs from it, and each such false positive costs
+// a full comparison. A 64-bit hash makes that far less likely. For
+// instance, with any odd base, a Thue-Morse string over two bytes
+// collides with its complement under the 32-bit hash if it is at least
+// 128 bytes long, but under the 64-bit hash only from 1024 bytes.
+const PrimeRK64 = 1099511628211
+
+// HashStrBytes64 returns the 64-bit hash and the appropriate
+// multiplicative factor for use in Rabin-Karp algorithm.
+func HashStrBytes64(sep []byte) (uint64, uint64) {
+	hash := uint64(0)
+	for i := 0; i < len(sep); i++ {
+		hash = hash*PrimeRK64 + uint64(sep[i])
+	}
+	var pow, sq uint64 = 1, PrimeRK64
+	for i := len(sep); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			pow *= sq
+		}
+		sq *= sq
+	}
+	return hash, pow
+}
+
+// HashStr64 returns the 64-bit hash and the appropriate
+// multiplicative factor for use in Rabin-Karp algorithm.
+func HashStr64(sep string) (uint64, uint64) {
+	hash := uint64(0)
+	for i := 0; i < len(sep); i++ {
+		hash = hash*PrimeRK64 + uint64(sep[i])
+	}
+	var pow, sq uint64 = 1, PrimeRK64
+	for i := len(sep); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			pow *= sq
+		}
+		sq *= sq
+	}
+	return hash, pow
+}
+
+// HashStrRevBytes64 returns the 64-bit hash of the reverse of sep and
+// the appropriate multiplicative factor for use in Rabin-Karp algorithm.
+func HashStrRevBytes64(sep []byte) (uint64, uint64) {
+	hash := uint64(0)
+	for i := len(sep) - 1; i >= 0; i-- {
+		hash = hash*PrimeRK64 + uint64(sep[i])
+	}
+	var pow, sq uint64 = 1, PrimeRK64
+	for i := len(sep); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			pow *= sq
+		}
+		sq *= sq
+	}
+	return hash, pow
+}
+
+// HashStrRev64 returns the 64-bit hash of the reverse of sep and the
+// appropriate multiplicative factor for use in Rabin-Karp algorithm.
+func HashStrRev64(sep string) (uint64, uint64) {
+	hash := uint64(0)
+	for i := len(sep) - 1; i >= 0; i-- {
+		hash = hash*PrimeRK64 + uint64(sep[i])
+	}
+	var pow, sq uint64 = 1, PrimeRK64
+	for i := len(sep); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			pow *= sq
+		}
+		sq *= sq
+	}
+	return hash, pow
+}
+
 // IndexRabinKarpBytes uses the Rabin-Karp search algorithm to return the index of the
 // first occurrence of substr in s, or -1 if not present.
 func IndexRabinKarpBytes(s, sep []byte) int {
@@ -148,3 +234,60 @@ func IndexRabinKarp(s, substr string) int {
 	}
 	return -1
 }
+
+// IndexRabinKarpBytes64 is like IndexRabinKarpBytes but uses a 64-bit
+// rolling hash, which makes false positives much less likely.
+func IndexRabinKarpBytes64(s, sep []byte) int {
+	// Rabin-Karp search
+	hashsep, pow := HashStrBytes64(sep)
+	n := len(sep)
+	var h uint64
+	for i := 0; i < n; i++ {
+		h = h*PrimeRK64 + uint64(s[i])
+	}
+	if h == hashsep && Equal(s[:n], sep) {
+		return 0
+	}
+	for i := n; i < len(s); {
+		h *= PrimeRK64
+		h += uint64(s[i])
+		h -= pow * uint64(s[i-n])
+		i++
+		if h == hashsep && Equal(s[i-n:i], sep) {
+			return i - n
+		}
+	}
+	return -1
+}
+
+// IndexRabinKarp64 is like IndexRabinKarp but uses a 64-bit
+// rolling hash, which makes false positives much less likely.
+func IndexRabinKarp64(s, substr string) int {
+	// Rabin-Karp search
+	hashss, pow := HashStr64(substr)
+	n := len(substr)
+	var h uint64
+	for i := 0; i < n; i++ {
+		h = h*PrimeRK64 + uint64(s[i])
+	}
+	if h == hashss && s[:n] == substr {
+		return 0
+	}
+	for i := n; i < len(s); {
+		h *= PrimeRK64
+		h += uint64(s[i])
+		h -= pow * uint64(s[i-n])
+		i++
+		if h == hashss && s[i-n:i] == substr {
+			return i - n
+		}
+	}
+	return -1
+}
+
+// MakeNoZero makes a slice of length and capacity n without zeroing the bytes.
+// It is the caller's responsibility to ensure uninitialized bytes
+// do not leak to the end user.
+//
+// It is implemented in the runtime.
+func MakeNoZero(n int) []byte
@@ -0,0 +1,62 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytealg
+
+// The case functions deal with the 26 ASCII letters starting at lo,
+// which is 'A' for the upper-case letters or 'a' for the lower-case
+// ones. Changing the case of one of them flips its 0x20 bit.
+
+// isCaseASCII reports whether c is not ASCII or is one of the 26
+// ASCII letters starting at lo.
+func isCaseASCII(c, lo byte) bool {
+	return c >= 0x80 || c-lo < 26
+}
+
+func indexCaseASCIIGeneric(b []byte, lo byte) int {
+	for i, c := range b {
+		if isCaseASCII(c, lo) {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexCaseASCIIGenericString(s string, lo byte) int {
+	for i := 0; i < len(s); i++ {
+		if isCaseASCII(s[i], lo) {
+			return i
+		}
+	}
+	return -1
+}
+
+func changeCaseASCIIGeneric(dst, src []byte, lo byte) int {
+	dst = dst[:len(src)]
+	for i, c := range src {
+		if c >= 0x80 {
+			return i
+		}
+		if c-lo < 26 {
+			c ^= 0x20
+		}
+		dst[i] = c
+	}
+	return len(src)
+}
+
+func changeCaseASCIIGenericString(dst []byte, src string, lo byte) int {
+	dst = dst[:len(src)]
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if c >= 0x80 {
+			return i
+		}
+		if c-lo < 26 {
+			c ^= 0x20
+		}
+		dst[i] = c
+	}
+	return len(src)
+}
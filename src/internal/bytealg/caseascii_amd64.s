@@ -0,0 +1,224 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "go_asm.h"
+#include "textflag.h"
+
+// A byte c is one of the 26 letters starting at lo if c+(0x80-lo),
+// as a signed byte, is less than -128+26, that is, caselimit<>.
+// The bytes that are not ASCII are negative as signed bytes.
+DATA caselimit<>+0x00(SB)/8, $0x9a9a9a9a9a9a9a9a
+DATA caselimit<>+0x08(SB)/8, $0x9a9a9a9a9a9a9a9a
+GLOBL caselimit<>(SB), RODATA, $16
+
+DATA casebit<>+0x00(SB)/8, $0x2020202020202020
+DATA casebit<>+0x08(SB)/8, $0x2020202020202020
+GLOBL casebit<>(SB), RODATA, $16
+
+TEXT ·indexCaseASCII(SB),NOSPLIT,$0-40
+	MOVQ	b_base+0(FP), SI
+	MOVQ	b_len+8(FP), BX
+	MOVBLZX	lo+24(FP), AX
+	LEAQ	ret+32(FP), R8
+	JMP	indexcasebody<>(SB)
+
+TEXT ·indexCaseASCIIString(SB),NOSPLIT,$0-32
+	MOVQ	s_base+0(FP), SI
+	MOVQ	s_len+8(FP), BX
+	MOVBLZX	lo+16(FP), AX
+	LEAQ	ret+24(FP), R8
+	JMP	indexcasebody<>(SB)
+
+TEXT ·changeCaseASCII(SB),NOSPLIT,$0-64
+	MOVQ	dst_base+0(FP), R10
+	MOVQ	src_base+24(FP), SI
+	MOVQ	src_len+32(FP), BX
+	MOVBLZX	lo+48(FP), AX
+	LEAQ	ret+56(FP), R8
+	JMP	changecasebody<>(SB)
+
+TEXT ·changeCaseASCIIString(SB),NOSPLIT,$0-56
+	MOVQ	dst_base+0(FP), R10
+	MOVQ	src_base+24(FP), SI
+	MOVQ	src_len+32(FP), BX
+	MOVBLZX	lo+40(FP), AX
+	LEAQ	ret+48(FP), R8
+	JMP	changecasebody<>(SB)
+
+// CASESETUP sets each byte of X1 to 0x80-lo, given lo in AX, and
+// loads caselimit<> into X2. It clobbers CX.
+#define CASESETUP \
+	MOVL	$0x80, CX \
+	SUBL	AX, CX \
+	MOVQ	CX, X1 \
+	PUNPCKLBW	X1, X1 \
+	PUNPCKLBW	X1, X1 \
+	PSHUFL	$0, X1, X1 \
+	MOVOU	caselimit<>(SB), X2
+
+// CASE16 loads the 16 bytes at (SI)(DI*1) into X3 and sets the high
+// bit of each byte of X5 that is one of the letters and of no other.
+// It clobbers X4.
+#define CASE16 \
+	MOVOU	(SI)(DI*1), X3 \
+	MOVOU	X3, X4 \
+	PADDB	X1, X4 \
+	MOVOU	X2, X5 \
+	PCMPGTB	X4, X5
+
+// CASE32 is CASE16 for the 32 bytes at (SI)(DI*1), with Y registers
+// in place of X registers.
+#define CASE32 \
+	VMOVDQU	(SI)(DI*1), Y3 \
+	VPADDB	Y1, Y3, Y4 \
+	VPCMPGTB	Y4, Y2, Y5
+
+// input:
+//   SI: data
+//   BX: data len, at least 16
+//   AX: lo
+//   R8: address to put result
+TEXT indexcasebody<>(SB),NOSPLIT,$0
+	CASESETUP
+	XORQ	DI, DI
+	CMPQ	BX, $32
+	JLT	sse
+	CMPB	internal∕cpu·X86+const_offsetX86HasAVX2(SB), $1
+	JNE	sse
+
+	VPBROADCASTB	X1, Y1
+	VPBROADCASTB	X2, Y2
+	LEAQ	-32(BX), R9	// R9 = index of the last 32 bytes
+avx2_loop:
+	CASE32
+	VPOR	Y3, Y5, Y5
+	VPMOVMSKB	Y5, CX
+	TESTL	CX, CX
+	JNZ	avx2_found
+	ADDQ	$32, DI
+	CMPQ	DI, R9
+	JLT	avx2_loop
+
+	// Check the last 32 bytes, which may overlap
+	// bytes already checked.
+	MOVQ	R9, DI
+	CASE32
+	VPOR	Y3, Y5, Y5
+	VPMOVMSKB	Y5, CX
+	TESTL	CX, CX
+	JNZ	avx2_found
+	VZEROUPPER
+	MOVQ	$-1, (R8)
+	RET
+avx2_found:
+	VZEROUPPER
+	JMP	found
+
+sse:
+	LEAQ	-16(BX), R9	// R9 = index of the last 16 bytes
+sse_loop:
+	CASE16
+	POR	X3, X5
+	PMOVMSKB	X5, CX
+	TESTL	CX, CX
+	JNZ	found
+	ADDQ	$16, DI
+	CMPQ	DI, R9
+	JLT	sse_loop
+
+	// Check the last 16 bytes, which may overlap
+	// bytes already checked.
+	MOVQ	R9, DI
+	CASE16
+	POR	X3, X5
+	PMOVMSKB	X5, CX
+	TESTL	CX, CX
+	JNZ	found
+	MOVQ	$-1, (R8)
+	RET
+found:
+	BSFL	CX, CX
+	ADDQ	CX, DI
+	MOVQ	DI, (R8)
+	RET
+
+// input:
+//   SI: source
+//   R10: destination, at least as long as the source
+//   BX: source len, at least 16
+//   AX: lo
+//   R8: address to put result
+TEXT changecasebody<>(SB),NOSPLIT,$0
+	CASESETUP
+	MOVOU	casebit<>(SB), X6
+	XORQ	DI, DI
+	CMPQ	BX, $32
+	JLT	sse
+	CMPB	internal∕cpu·X86+const_offsetX86HasAVX2(SB), $1
+	JNE	sse
+
+	VPBROADCASTB	X1, Y1
+	VPBROADCASTB	X2, Y2
+	VPBROADCASTB	X6, Y6
+	LEAQ	-32(BX), R9	// R9 = index of the last 32 bytes
+avx2_loop:
+	CASE32
+	VPAND	Y6, Y5, Y5
+	VPXOR	Y3, Y5, Y5
+	VMOVDQU	Y5, (R10)(DI*1)
+	VPMOVMSKB	Y3, CX
+	TESTL	CX, CX
+	JNZ	avx2_found
+	ADDQ	$32, DI
+	CMPQ	DI, R9
+	JLT	avx2_loop
+
+	// Change the last 32 bytes, which may overlap bytes
+	// already changed, from the source again.
+	MOVQ	R9, DI
+	CASE32
+	VPAND	Y6, Y5, Y5
+	VPXOR	Y3, Y5, Y5
+	VMOVDQU	Y5, (R10)(DI*1)
+	VPMOVMSKB	Y3, CX
+	TESTL	CX, CX
+	JNZ	avx2_found
+	VZEROUPPER
+	MOVQ	BX, (R8)
+	RET
+avx2_found:
+	VZEROUPPER
+	JMP	found
+
+sse:
+	LEAQ	-16(BX), R9	// R9 = index of the last 16 bytes
+sse_loop:
+	CASE16
+	PAND	X6, X5
+	PXOR	X3, X5
+	MOVOU	X5, (R10)(DI*1)
+	PMOVMSKB	X3, CX
+	TESTL	CX, CX
+	JNZ	found
+	ADDQ	$16, DI
+	CMPQ	DI, R9
+	JLT	sse_loop
+
+	// Change the last 16 bytes, which may overlap bytes
+	// already changed, from the source again.
+	MOVQ	R9, DI
+	CASE16
+	PAND	X6, X5
+	PXOR	X3, X5
+	MOVOU	X5, (R10)(DI*1)
+	PMOVMSKB	X3, CX
+	TESTL	CX, CX
+	JNZ	found
+	MOVQ	BX, (R8)
+	RET
+found:
+	BSFL	CX, CX
+	ADDQ	CX, DI
+	MOVQ	DI, (R8)
+	RET
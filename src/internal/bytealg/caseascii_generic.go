@@ -0,0 +1,39 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !amd64
+
+package bytealg
+
+// IndexCaseASCII returns the index of the first byte of b that is not
+// ASCII or is one of the 26 ASCII letters starting at lo, which is 'A'
+// or 'a', or -1 if there is none.
+func IndexCaseASCII(b []byte, lo byte) int {
+	return indexCaseASCIIGeneric(b, lo)
+}
+
+// IndexCaseASCIIString returns the index of the first byte of s that is
+// not ASCII or is one of the 26 ASCII letters starting at lo, which is
+// 'A' or 'a', or -1 if there is none.
+func IndexCaseASCIIString(s string, lo byte) int {
+	return indexCaseASCIIGenericString(s, lo)
+}
+
+// ChangeCaseASCII copies src to dst up to its first byte that is not
+// ASCII, changing the case of the 26 ASCII letters starting at lo,
+// which is 'A' or 'a'. It returns the number of bytes copied.
+// dst must be at least as long as src. The bytes of dst after
+// those copied may be overwritten.
+func ChangeCaseASCII(dst, src []byte, lo byte) int {
+	return changeCaseASCIIGeneric(dst, src, lo)
+}
+
+// ChangeCaseASCIIString copies src to dst up to its first byte that is
+// not ASCII, changing the case of the 26 ASCII letters starting at lo,
+// which is 'A' or 'a'. It returns the number of bytes copied.
+// dst must be at least as long as src. The bytes of dst after
+// those copied may be overwritten.
+func ChangeCaseASCIIString(dst []byte, src string, lo byte) int {
+	return changeCaseASCIIGenericString(dst, src, lo)
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64
+
+package bytealg
+
+// The assembly versions work on 32 bytes at a time with AVX2 and
+// otherwise on 16 bytes at a time, and require len(src) >= 16.
+
+//go:noescape
+func indexCaseASCII(b []byte, lo byte) int
+
+//go:noescape
+func indexCaseASCIIString(s string, lo byte) int
+
+//go:noescape
+func changeCaseASCII(dst, src []byte, lo byte) int
+
+//go:noescape
+func changeCaseASCIIString(dst []byte, src string, lo byte) int
+
+// IndexCaseASCII returns the index of the first byte of b that is not
+// ASCII or is one of the 26 ASCII letters starting at lo, which is 'A'
+// or 'a', or -1 if there is none.
+func IndexCaseASCII(b []byte, lo byte) int {
+	if len(b) >= 16 {
+		return indexCaseASCII(b, lo)
+	}
+	return indexCaseASCIIGeneric(b, lo)
+}
+
+// IndexCaseASCIIString returns the index of the first byte of s that is
+// not ASCII or is one of the 26 ASCII letters starting at lo, which is
+// 'A' or 'a', or -1 if there is none.
+func IndexCaseASCIIString(s string, lo byte) int {
+	if len(s) >= 16 {
+		return indexCaseASCIIString(s, lo)
+	}
+	return indexCaseASCIIGenericString(s, lo)
+}
+
+// ChangeCaseASCII copies src to dst up to its first byte that is not
+// ASCII, changing the case of the 26 ASCII letters starting at lo,
+// which is 'A' or 'a'. It returns the number of bytes copied.
+// dst must be at least as long as src. The bytes of dst after
+// those copied may be overwritten.
+func ChangeCaseASCII(dst, src []byte, lo byte) int {
+	if len(src) >= 16 {
+		_ = dst[len(src)-1]
+		return changeCaseASCII(dst, src, lo)
+	}
+	return changeCaseASCIIGeneric(dst, src, lo)
+}
+
+// ChangeCaseASCIIString copies src to dst up to its first byte that is
+// not ASCII, changing the case of the 26 ASCII letters starting at lo,
+// which is 'A' or 'a'. It returns the number of bytes copied.
+// dst must be at least as long as src. The bytes of dst after
+// those copied may be overwritten.
+func ChangeCaseASCIIString(dst []byte, src string, lo byte) int {
+	if len(src) >= 16 {
+		_ = dst[len(src)-1]
+		return changeCaseASCIIString(dst, src, lo)
+	}
+	return changeCaseASCIIGenericString(dst, src, lo)
+}
@@ -31,43 +31,97 @@ TEXT runtime·cmpstring<ABIInternal>(SB),NOSPLIT|NOFRAME,$0-40
 //
 // On exit:
 // R0 is the result
-// R4, R5, R6, R8, R9 and R10 are clobbered
+// R4 through R15 are clobbered
 TEXT cmpbody<>(SB),NOSPLIT|NOFRAME,$0-0
 	CMP	R0, R2
 	BEQ	samebytes         // same starting pointers; compare lengths
 	CMP	R1, R3
 	CSEL	LT, R3, R1, R6    // R6 is min(R1, R3)
 
-	CBZ	R6, samebytes
-	BIC	$0xf, R6, R10
-	CBZ	R10, small        // length < 16
-	ADD	R0, R10           // end of chunk16
-	// length >= 16
-chunk16_loop:
-	LDP.P	16(R0), (R4, R8)
-	LDP.P	16(R2), (R5, R9)
+	CMP	$16, R6
+	BLT	small             // length < 16
+	MOVBU	·useSVE(SB), R7
+	CBNZ	R7, sve
+	CMP	$32, R6
+	BLT	mid               // length < 32
+	// length >= 32
+	LSR	$6, R6, R10       // number of 64-byte chunks
+	CBZ	R10, chunk32
+chunk64_loop:
+	LDP	(R0), (R4, R8)
+	LDP	16(R0), (R11, R12)
+	LDP	(R2), (R5, R9)
+	LDP	16(R2), (R13, R14)
 	CMP	R4, R5
-	BNE	cmp
-	CMP	R8, R9
-	BNE	cmpnext
-	CMP	R10, R0
-	BNE	chunk16_loop
-	AND	$0xf, R6, R6
-	CBZ	R6, samebytes
-	SUBS	$8, R6
-	BLT	tail
-	// the length of tail > 8 bytes
-	MOVD.P	8(R0), R4
-	MOVD.P	8(R2), R5
+	CCMP	EQ, R8, R9, $0
+	CCMP	EQ, R11, R13, $0
+	CCMP	EQ, R12, R14, $0
+	BNE	found
+	LDP	32(R0), (R4, R8)
+	LDP	48(R0), (R11, R12)
+	LDP	32(R2), (R5, R9)
+	LDP	48(R2), (R13, R14)
+	ADD	$64, R0
+	ADD	$64, R2
+	SUB	$1, R10
 	CMP	R4, R5
-	BNE	cmp
-	SUB	$8, R6
-	// compare last 8 bytes
+	CCMP	EQ, R8, R9, $0
+	CCMP	EQ, R11, R13, $0
+	CCMP	EQ, R12, R14, $0
+	BNE	found
+	CBNZ	R10, chunk64_loop
+chunk32:
+	TBZ	$5, R6, tail
+	LDP	(R0), (R4, R8)
+	LDP	16(R0), (R11, R12)
+	LDP	(R2), (R5, R9)
+	LDP	16(R2), (R13, R14)
+	ADD	$32, R0
+	ADD	$32, R2
+	CMP	R4, R5
+	CCMP	EQ, R8, R9, $0
+	CCMP	EQ, R11, R13, $0
+	CCMP	EQ, R12, R14, $0
+	BNE	found
 tail:
-	MOVD	(R0)(R6), R4
-	MOVD	(R2)(R6), R5
+	ANDS	$0x1f, R6, R6
+	BEQ	samebytes
+	// Compare the last 32 bytes, which overlap those already compared.
+	SUB	$32, R6
+	ADD	R6, R0
+	ADD	R6, R2
+	ADD	$16, R0, R10
+	ADD	$16, R2, R15
+	B	last
+mid:
+	// 16 <= length < 32: compare the first 16 bytes and the last 16,
+	// which overlap.
+	SUB	$16, R6
+	ADD	R0, R6, R10
+	ADD	R2, R6, R15
+last:
+	// Compare 16 bytes at R0 and 16 at R10 with those at R2 and R15.
+	LDP	(R0), (R4, R8)
+	LDP	(R10), (R11, R12)
+	LDP	(R2), (R5, R9)
+	LDP	(R15), (R13, R14)
 	CMP	R4, R5
+	CCMP	EQ, R8, R9, $0
+	CCMP	EQ, R11, R13, $0
+	CCMP	EQ, R12, R14, $0
 	BEQ	samebytes
+found:
+	// Some pair of the words in R4 and R5, R8 and R9, R11 and R13,
+	// and R12 and R14 differs. Move the first such pair to R4 and R5.
+	CMP	R4, R5
+	CSEL	EQ, R8, R4, R4
+	CSEL	EQ, R9, R5, R5
+	CMP	R4, R5
+	CSEL	EQ, R11, R4, R4
+	CSEL	EQ, R13, R5, R5
+	CMP	R4, R5
+	CSEL	EQ, R12, R4, R4
+	CSEL	EQ, R14, R5, R5
 cmp:
 	REV	R4, R4
 	REV	R5, R5
@@ -77,41 +131,50 @@ ret:
 	CNEG	HI, R0, R0
 	RET
 small:
+	CBZ	R6, samebytes
 	TBZ	$3, R6, lt_8
+	// 8 <= length < 16: compare the first 8 bytes and the last 8,
+	// which overlap.
+	SUB	$8, R6
 	MOVD	(R0), R4
 	MOVD	(R2), R5
+	MOVD	(R0)(R6), R8
+	MOVD	(R2)(R6), R9
 	CMP	R4, R5
 	BNE	cmp
-	SUBS	$8, R6
-	BEQ	samebytes
-	ADD	$8, R0
-	ADD	$8, R2
-	SUB	$8, R6
-	B	tail
+	CMP	R8, R9
+	BNE	cmpnext
+	B	samebytes
 lt_8:
 	TBZ	$2, R6, lt_4
+	// 4 <= length < 8: compare the first 4 bytes and the last 4,
+	// which overlap, as one 8-byte word.
+	SUB	$4, R6
 	MOVWU	(R0), R4
 	MOVWU	(R2), R5
-	CMPW	R4, R5
+	MOVWU	(R0)(R6), R8
+	MOVWU	(R2)(R6), R9
+	ORR	R8<<32, R4
+	ORR	R9<<32, R5
+	CMP	R4, R5
 	BNE	cmp
-	SUBS	$4, R6
-	BEQ	samebytes
-	ADD	$4, R0
-	ADD	$4, R2
+	B	samebytes
 lt_4:
-	TBZ	$1, R6, lt_2
-	MOVHU	(R0), R4
-	MOVHU	(R2), R5
-	CMPW	R4, R5
-	BNE	cmp
-	ADD	$2, R0
-	ADD	$2, R2
-lt_2:
-	TBZ	$0, R6, samebytes
-one:
+	// 1 <= length < 4: compare the first byte, the middle one and
+	// the last, which may be the same, as one big-endian word.
+	LSR	$1, R6, R7
+	SUB	$1, R6
 	MOVBU	(R0), R4
 	MOVBU	(R2), R5
-	CMPW	R4, R5
+	MOVBU	(R0)(R7), R8
+	MOVBU	(R2)(R7), R9
+	MOVBU	(R0)(R6), R10
+	MOVBU	(R2)(R6), R11
+	ORR	R4<<16, R10, R4
+	ORR	R5<<16, R11, R5
+	ORR	R8<<8, R4
+	ORR	R9<<8, R5
+	CMP	R4, R5
 	BNE	ret
 samebytes:
 	CMP	R3, R1
@@ -123,3 +186,28 @@ cmpnext:
 	REV	R9, R5
 	CMP	R4, R5
 	B	ret
+sve:
+	// Compare one vector at a time, with the predicate P0 selecting
+	// the bytes of the vector that are within the shorter input. The
+	// SVE instructions are encoded by hand.
+	MOVD	ZR, R7            // R7 is the offset of the vector
+	WORD	$0x25261ce0       // whilelo p0.b, x7, x6
+sve_loop:
+	WORD	$0xa4074000       // ld1b {z0.b}, p0/z, [x0, x7]
+	WORD	$0xa4074041       // ld1b {z1.b}, p0/z, [x2, x7]
+	WORD	$0x2401a011       // cmpne p1.b, p0/z, z0.b, z1.b
+	BNE	sve_found         // some bytes differ
+	WORD	$0x0430e3e7       // incb x7
+	WORD	$0x25261ce0       // whilelo p0.b, x7, x6
+	BMI	sve_loop          // some bytes are left
+	B	samebytes
+sve_found:
+	// Count the bytes before the first difference
+	// and compare the differing bytes.
+	WORD	$0x25904021       // brkb p1.b, p0/z, p1.b
+	WORD	$0x25208024       // cntp x4, p0, p1.b
+	ADD	R7, R4, R6
+	MOVBU	(R0)(R6), R4
+	MOVBU	(R2)(R6), R5
+	CMP	R4, R5
+	B	ret
@@ -29,6 +29,8 @@ TEXT countbytebody<>(SB),NOSPLIT,$0
 	MOVD	$0, R11
 	// short path to handle 0-byte case
 	CBZ	R2, done
+	MOVBU	·useSVE(SB), R9
+	CBNZ	R9, sve
 	CMP	$0x20, R2
 	// jump directly to tail if length < 32
 	BLO	tail
@@ -88,3 +90,18 @@ tail:
 done:
 	MOVD	R11, (R8)
 	RET
+sve:
+	// Count one vector at a time, with the predicate P0 selecting the
+	// bytes of the vector that are within the data. The SVE
+	// instructions are encoded by hand.
+	MOVD	ZR, R3			// R3 is the offset of the vector
+	WORD	$0x05203820		// mov z0.b, w1
+	WORD	$0x25221c60		// whilelo p0.b, x3, x2
+sve_loop:
+	WORD	$0xa4034001		// ld1b {z1.b}, p0/z, [x0, x3]
+	WORD	$0x2400a021		// cmpeq p1.b, p0/z, z1.b, z0.b
+	WORD	$0x252c882b		// incp x11, p1.b
+	WORD	$0x0430e3e3		// incb x3
+	WORD	$0x25221c60		// whilelo p0.b, x3, x2
+	BMI	sve_loop		// some bytes are left
+	B	done
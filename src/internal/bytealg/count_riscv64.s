@@ -5,32 +5,79 @@
 #include "go_asm.h"
 #include "textflag.h"
 
-TEXT ·Count(SB),NOSPLIT,$0-40
+TEXT ·Count(SB),NOSPLIT|NOFRAME,$0-40
 	MOV	b_base+0(FP), A1
 	MOV	b_len+8(FP), A2
 	MOVBU	c+24(FP), A3	// byte to count
+	MOV	$ret+32(FP), A7
+	JMP	countbody<>(SB)
+
+TEXT ·CountString(SB),NOSPLIT|NOFRAME,$0-32
+	MOV	s_base+0(FP), A1
+	MOV	s_len+8(FP), A2
+	MOVBU	c+16(FP), A3	// byte to count
+	MOV	$ret+24(FP), A7
+	JMP	countbody<>(SB)
+
+// On entry:
+//   A1: data
+//   A2: data len
+//   A3: byte to count
+//   A7: address to put result
+// RISC-V has no vector instructions in the base ISA, so this counts
+// eight bytes at a time in a general purpose register once the data
+// is aligned.
+TEXT countbody<>(SB),NOSPLIT|NOFRAME,$0
 	MOV	ZERO, A4	// count
-	ADD	A1, A2		// end
 
-loop:
-	BEQ	A1, A2, done
+	MOV	$16, T0
+	BLT	A2, T0, tail
+
+	// Count one byte at a time until A1 is 8 byte aligned.
+	AND	$7, A1, T0
+	BEQZ	T0, words
+	MOV	$8, T1
+	SUB	T0, T1, T0	// bytes to alignment
+	SUB	T0, A2, A2
+align:
 	MOVBU	(A1), A5
 	ADD	$1, A1
-	BNE	A3, A5, loop
-	ADD	$1, A4
-	JMP	loop
+	ADD	$-1, T0
+	XOR	A3, A5, A5
+	SEQZ	A5, A5
+	ADD	A5, A4
+	BNEZ	T0, align
 
-done:
-	MOV	A4, ret+32(FP)
-	RET
+words:
+	// T2 has 0x01 in each byte, T3 the byte to count in each
+	// byte and T4 0x7f in each byte.
+	MOV	$0x0101010101010101, T2
+	MUL	A3, T2, T3
+	MOV	$0x7f7f7f7f7f7f7f7f, T4
+	MOV	$8, T5
+loop8:
+	// A5 has a zero byte for each byte equal to A3. Adding 0x7f
+	// to its low seven bits sets the top bit of each nonzero byte,
+	// without a carry into the next byte. Inverting that leaves
+	// 0x80 in each byte equal to A3 and 0x00 in the others; the
+	// multiplication sums the bytes, shifted down, into the top byte.
+	MOV	(A1), A5
+	XOR	T3, A5, A5
+	AND	T4, A5, A6
+	ADD	T4, A6
+	OR	A5, A6
+	OR	T4, A6
+	NOT	A6, A6
+	SRL	$7, A6
+	MUL	T2, A6, A6
+	SRL	$56, A6
+	ADD	A6, A4
+	ADD	$8, A1
+	ADD	$-8, A2
+	BGE	A2, T5, loop8
 
-TEXT ·CountString(SB),NOSPLIT,$0-32
-	MOV	s_base+0(FP), A1
-	MOV	s_len+8(FP), A2
-	MOVBU	c+16(FP), A3	// byte to count
-	MOV	ZERO, A4	// count
+tail:
 	ADD	A1, A2		// end
-
 loop:
 	BEQ	A1, A2, done
 	MOVBU	(A1), A5
@@ -40,5 +87,5 @@ loop:
 	JMP	loop
 
 done:
-	MOV	A4, ret+24(FP)
+	MOV	A4, (A7)
 	RET
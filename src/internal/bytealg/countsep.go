@@ -0,0 +1,161 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytealg
+
+// CountSep returns the number of non-overlapping instances of sep in b.
+// It requires len(sep) >= 2.
+//
+// It chooses how to search for sep once, as Index does, and then counts
+// each instance and moves past it in the same loop. Where Index looks for
+// the first byte of sep with IndexByte, CountSep does too, until IndexByte
+// finds too many bytes that do not begin an instance; it counts the rest
+// of b with the search Index would have switched to.
+func CountSep(b, sep []byte) int {
+	n := len(sep)
+	count := 0
+	if n <= MaxWideLen {
+		for len(b) >= MinWideSearch {
+			// Instances often follow one another closely, so check
+			// for one at the start before calling IndexWide.
+			i := 0
+			if b[0] != sep[0] || b[1] != sep[1] || !Equal(b[:n], sep) {
+				if i = IndexWide(b, sep); i < 0 {
+					return count
+				}
+			}
+			count++
+			b = b[i+n:]
+		}
+	}
+	if n <= MaxLen && len(b) <= MaxBruteForce {
+		return count + countIndex(b, sep)
+	}
+	c0, c1 := sep[0], sep[1]
+	fails := 0
+	i, t := 0, len(b)-n+1
+	for i < t {
+		if b[i] != c0 {
+			o := IndexByte(b[i+1:t], c0)
+			if o < 0 {
+				break
+			}
+			i += o + 1
+		}
+		if b[i+1] == c1 && Equal(b[i:i+n], sep) {
+			count++
+			i += n
+			continue
+		}
+		i++
+		fails++
+		if i < t && (n <= MaxLen && fails > Cutover(i) || n > MaxLen && fails >= 4+i>>4) {
+			return count + countIndex(b[i:], sep)
+		}
+	}
+	return count
+}
+
+// CountSepString returns the number of non-overlapping instances of sep
+// in s. It requires len(sep) >= 2.
+//
+// It chooses how to search for sep as CountSep does.
+func CountSepString(s, sep string) int {
+	n := len(sep)
+	count := 0
+	if n <= MaxWideLen {
+		for len(s) >= MinWideSearch {
+			// Instances often follow one another closely, so check
+			// for one at the start before calling IndexWide.
+			i := 0
+			if s[0] != sep[0] || s[1] != sep[1] || s[:n] != sep {
+				if i = IndexWideString(s, sep); i < 0 {
+					return count
+				}
+			}
+			count++
+			s = s[i+n:]
+		}
+	}
+	if n <= MaxLen && len(s) <= MaxBruteForce {
+		return count + countIndexString(s, sep)
+	}
+	c0, c1 := sep[0], sep[1]
+	fails := 0
+	i, t := 0, len(s)-n+1
+	for i < t {
+		if s[i] != c0 {
+			o := IndexByteString(s[i+1:t], c0)
+			if o < 0 {
+				break
+			}
+			i += o + 1
+		}
+		if s[i+1] == c1 && s[i:i+n] == sep {
+			count++
+			i += n
+			continue
+		}
+		i++
+		fails++
+		if i < t && (n <= MaxLen && fails > Cutover(i) || n > MaxLen && fails >= 4+i>>4) {
+			return count + countIndexString(s[i:], sep)
+		}
+	}
+	return count
+}
+
+// countIndex returns the number of non-overlapping instances of sep in b,
+// found with Index if sep is short enough for it and otherwise with the
+// Two-Way algorithm, whose factorization of sep it makes only once.
+func countIndex(b, sep []byte) int {
+	n, count := len(sep), 0
+	if n <= MaxLen {
+		for len(b) >= n {
+			i := Index(b, sep)
+			if i < 0 {
+				break
+			}
+			count++
+			b = b[i+n:]
+		}
+		return count
+	}
+	tw := MakeTwoWayBytes(sep)
+	for len(b) >= n {
+		i := tw.Index(b, sep)
+		if i < 0 {
+			break
+		}
+		count++
+		b = b[i+n:]
+	}
+	return count
+}
+
+// countIndexString is countIndex for strings.
+func countIndexString(s, sep string) int {
+	n, count := len(sep), 0
+	if n <= MaxLen {
+		for len(s) >= n {
+			i := IndexString(s, sep)
+			if i < 0 {
+				break
+			}
+			count++
+			s = s[i+n:]
+		}
+		return count
+	}
+	tw := MakeTwoWay(sep)
+	for len(s) >= n {
+		i := tw.IndexString(s, sep)
+		if i < 0 {
+			break
+		}
+		count++
+		s = s[i+n:]
+	}
+	return count
+}
@@ -37,6 +37,8 @@ TEXT memeqbody<>(SB),NOSPLIT,$0
 	CMP	$16, R2
 	// handle specially if length < 16
 	BLO	tail
+	MOVBU	·useSVE(SB), R3
+	CBNZ	R3, sve
 	BIC	$0x3f, R2, R3
 	CBZ	R3, chunk16
 	// work with 64-byte chunks
@@ -119,3 +121,18 @@ equal:
 not_equal:
 	MOVB	ZR, R0
 	RET
+sve:
+	// Compare one vector at a time, with the predicate P0 selecting
+	// the bytes of the vector that are within the data. The SVE
+	// instructions are encoded by hand.
+	MOVD	ZR, R3			// R3 is the offset of the vector
+	WORD	$0x25221c60		// whilelo p0.b, x3, x2
+sve_loop:
+	WORD	$0xa4034000		// ld1b {z0.b}, p0/z, [x0, x3]
+	WORD	$0xa4034021		// ld1b {z1.b}, p0/z, [x1, x3]
+	WORD	$0x2401a011		// cmpne p1.b, p0/z, z0.b, z1.b
+	BNE	not_equal		// some bytes differ
+	WORD	$0x0430e3e3		// incb x3
+	WORD	$0x25221c60		// whilelo p0.b, x3, x2
+	BMI	sve_loop		// some bytes are left
+	B	equal
@@ -4,15 +4,29 @@
 
 package bytealg
 
+import "internal/cpu"
+
 // Empirical data shows that using Index can get better
 // performance when len(s) <= 16.
 const MaxBruteForce = 16
 
+// useSVE reports whether IndexByte, Count, Equal and Compare use
+// their SVE loops, which handle one vector per iteration. Vectors of
+// 16 bytes, the shortest SVE allows, cover no more per iteration than
+// the NEON loops, so SVE is used only when vectors are longer.
+var useSVE bool
+
 func init() {
 	// Optimize cases where the length of the substring is less than 32 bytes
 	MaxLen = 32
+
+	useSVE = cpu.ARM64.HasSVE && sveBytes() >= 32
 }
 
+// sveBytes returns the length in bytes of the SVE vector registers.
+// It must only be called if cpu.ARM64.HasSVE is set.
+func sveBytes() int
+
 // Cutover reports the number of failures of IndexByte we should tolerate
 // before switching over to Index.
 // n is the number of bytes processed so far.
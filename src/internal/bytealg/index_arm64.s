@@ -204,3 +204,9 @@ loop_25_32:
 	CMP	R10, R12	// compare the last 8 bytes
 	BNE	loop_25_32
 	B	found
+
+// func sveBytes() int
+TEXT ·sveBytes(SB),NOSPLIT,$0-8
+	WORD	$0x0420e3e0	// cntb x0
+	MOVD	R0, ret+0(FP)
+	RET
@@ -0,0 +1,67 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytealg
+
+// An ASCIISet is a set of ASCII bytes, in the form used by IndexAny and
+// LastIndexAny. Element l holds the high nibbles of the members whose low
+// nibble is l: bit h is set if the byte h<<4|l is in the set. A byte is
+// then in the set if as[c&15] has bit c>>4 set, which can be tested for
+// 16 bytes at once with two table lookups (PSHUFB on amd64).
+type ASCIISet [16]byte
+
+// MakeASCIISet returns the set of the bytes in chars and reports
+// whether they are all ASCII.
+func MakeASCIISet(chars string) (as ASCIISet, ok bool) {
+	for i := 0; i < len(chars); i++ {
+		c := chars[i]
+		if c >= 0x80 {
+			return as, false
+		}
+		as[c&15] |= 1 << (c >> 4)
+	}
+	return as, true
+}
+
+// Contains reports whether c is in the set.
+func (as *ASCIISet) Contains(c byte) bool {
+	// For c >= 0x80, the shift clears the byte.
+	return as[c&15]&(1<<(c>>4)) != 0
+}
+
+func indexAnyGeneric(b []byte, as *ASCIISet) int {
+	for i, c := range b {
+		if as.Contains(c) {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexAnyGenericString(s string, as *ASCIISet) int {
+	for i := 0; i < len(s); i++ {
+		if as.Contains(s[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func lastIndexAnyGeneric(b []byte, as *ASCIISet) int {
+	for i := len(b) - 1; i >= 0; i-- {
+		if as.Contains(b[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func lastIndexAnyGenericString(s string, as *ASCIISet) int {
+	for i := len(s) - 1; i >= 0; i-- {
+		if as.Contains(s[i]) {
+			return i
+		}
+	}
+	return -1
+}
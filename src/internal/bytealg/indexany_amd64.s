@@ -0,0 +1,134 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "go_asm.h"
+#include "textflag.h"
+
+// Bit h of byte h of anyhigh<> is set, for h < 8: the high nibble
+// table for an ASCIISet. Bytes with a high nibble of 8 or more
+// are not ASCII and so not in any set.
+DATA anyhigh<>+0x00(SB)/8, $0x8040201008040201
+DATA anyhigh<>+0x08(SB)/8, $0x0000000000000000
+GLOBL anyhigh<>(SB), RODATA, $16
+
+DATA anynibble<>+0x00(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA anynibble<>+0x08(SB)/8, $0x0f0f0f0f0f0f0f0f
+GLOBL anynibble<>(SB), RODATA, $16
+
+TEXT ·indexAny(SB),NOSPLIT,$0-40
+	MOVQ	b_base+0(FP), SI
+	MOVQ	b_len+8(FP), BX
+	MOVQ	as+24(FP), DX
+	LEAQ	ret+32(FP), R8
+	JMP	indexanybody<>(SB)
+
+TEXT ·indexAnyString(SB),NOSPLIT,$0-32
+	MOVQ	s_base+0(FP), SI
+	MOVQ	s_len+8(FP), BX
+	MOVQ	as+16(FP), DX
+	LEAQ	ret+24(FP), R8
+	JMP	indexanybody<>(SB)
+
+TEXT ·lastIndexAny(SB),NOSPLIT,$0-40
+	MOVQ	b_base+0(FP), SI
+	MOVQ	b_len+8(FP), BX
+	MOVQ	as+24(FP), DX
+	LEAQ	ret+32(FP), R8
+	JMP	lastindexanybody<>(SB)
+
+TEXT ·lastIndexAnyString(SB),NOSPLIT,$0-32
+	MOVQ	s_base+0(FP), SI
+	MOVQ	s_len+8(FP), BX
+	MOVQ	as+16(FP), DX
+	LEAQ	ret+24(FP), R8
+	JMP	lastindexanybody<>(SB)
+
+// MATCH16 sets the bits of CX for the bytes of the 16 at (SI)(DI*1)
+// that are in the set whose low nibble table is in X0, given the high
+// nibble table in X1 and 0x0f in each byte of X2. It clobbers X3-X5.
+#define MATCH16 \
+	MOVOU	(SI)(DI*1), X3 \
+	MOVOU	X3, X4 \
+	PSRLW	$4, X4 \
+	PAND	X2, X3 \
+	PAND	X2, X4 \
+	MOVOU	X0, X5 \
+	PSHUFB	X3, X5 \
+	MOVOU	X1, X3 \
+	PSHUFB	X4, X3 \
+	PAND	X3, X5 \
+	PXOR	X3, X3 \
+	PCMPEQB	X3, X5 \
+	PMOVMSKB	X5, CX \
+	XORL	$0xffff, CX
+
+// input:
+//   SI: data
+//   BX: data len, at least 16
+//   DX: address of the ASCIISet
+//   R8: address to put result
+// This function requires the PSHUFB instruction.
+TEXT indexanybody<>(SB),NOSPLIT,$0
+	MOVOU	(DX), X0
+	MOVOU	anyhigh<>(SB), X1
+	MOVOU	anynibble<>(SB), X2
+
+	XORQ	DI, DI
+	LEAQ	-16(BX), R9	// R9 = index of the last 16 bytes
+loop:
+	MATCH16
+	JNZ	found
+	ADDQ	$16, DI
+	CMPQ	DI, R9
+	JLT	loop
+
+	// Check the last 16 bytes, which may overlap
+	// bytes already known not to match.
+	CMPQ	DI, BX
+	JGE	notfound
+	MOVQ	R9, DI
+	MATCH16
+	JNZ	found
+notfound:
+	MOVQ	$-1, (R8)
+	RET
+found:
+	BSFL	CX, CX
+	ADDQ	CX, DI
+	MOVQ	DI, (R8)
+	RET
+
+// input:
+//   SI: data
+//   BX: data len, at least 16
+//   DX: address of the ASCIISet
+//   R8: address to put result
+// This function requires the PSHUFB instruction.
+TEXT lastindexanybody<>(SB),NOSPLIT,$0
+	MOVOU	(DX), X0
+	MOVOU	anyhigh<>(SB), X1
+	MOVOU	anynibble<>(SB), X2
+
+	LEAQ	-16(BX), DI
+loop:
+	MATCH16
+	JNZ	found
+	SUBQ	$16, DI
+	JGT	loop
+
+	// Check the first 16 bytes, which may overlap
+	// bytes already known not to match.
+	CMPQ	DI, $-16
+	JLE	notfound
+	XORQ	DI, DI
+	MATCH16
+	JNZ	found
+notfound:
+	MOVQ	$-1, (R8)
+	RET
+found:
+	BSRL	CX, CX
+	ADDQ	CX, DI
+	MOVQ	DI, (R8)
+	RET
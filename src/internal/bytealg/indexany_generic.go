@@ -0,0 +1,27 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !amd64
+
+package bytealg
+
+// IndexAny returns the index of the first byte of b in as, or -1.
+func IndexAny(b []byte, as *ASCIISet) int {
+	return indexAnyGeneric(b, as)
+}
+
+// IndexAnyString returns the index of the first byte of s in as, or -1.
+func IndexAnyString(s string, as *ASCIISet) int {
+	return indexAnyGenericString(s, as)
+}
+
+// LastIndexAny returns the index of the last byte of b in as, or -1.
+func LastIndexAny(b []byte, as *ASCIISet) int {
+	return lastIndexAnyGeneric(b, as)
+}
+
+// LastIndexAnyString returns the index of the last byte of s in as, or -1.
+func LastIndexAnyString(s string, as *ASCIISet) int {
+	return lastIndexAnyGenericString(s, as)
+}
@@ -0,0 +1,56 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64
+
+package bytealg
+
+import "internal/cpu"
+
+// The assembly versions search 16 bytes at a time and
+// require len(b) >= 16 and the SSSE3 instruction PSHUFB.
+
+//go:noescape
+func indexAny(b []byte, as *ASCIISet) int
+
+//go:noescape
+func indexAnyString(s string, as *ASCIISet) int
+
+//go:noescape
+func lastIndexAny(b []byte, as *ASCIISet) int
+
+//go:noescape
+func lastIndexAnyString(s string, as *ASCIISet) int
+
+// IndexAny returns the index of the first byte of b in as, or -1.
+func IndexAny(b []byte, as *ASCIISet) int {
+	if len(b) >= 16 && cpu.X86.HasSSSE3 {
+		return indexAny(b, as)
+	}
+	return indexAnyGeneric(b, as)
+}
+
+// IndexAnyString returns the index of the first byte of s in as, or -1.
+func IndexAnyString(s string, as *ASCIISet) int {
+	if len(s) >= 16 && cpu.X86.HasSSSE3 {
+		return indexAnyString(s, as)
+	}
+	return indexAnyGenericString(s, as)
+}
+
+// LastIndexAny returns the index of the last byte of b in as, or -1.
+func LastIndexAny(b []byte, as *ASCIISet) int {
+	if len(b) >= 16 && cpu.X86.HasSSSE3 {
+		return lastIndexAny(b, as)
+	}
+	return lastIndexAnyGeneric(b, as)
+}
+
+// LastIndexAnyString returns the index of the last byte of s in as, or -1.
+func LastIndexAnyString(s string, as *ASCIISet) int {
+	if len(s) >= 16 && cpu.X86.HasSSSE3 {
+		return lastIndexAnyString(s, as)
+	}
+	return lastIndexAnyGenericString(s, as)
+}
@@ -34,6 +34,8 @@ TEXT indexbytebody<>(SB),NOSPLIT,$0
 	// identify exactly which byte has matched.
 
 	CBZ	R2, fail
+	MOVBU	·useSVE(SB), R9
+	CBNZ	R9, sve
 	MOVD	R0, R11
 	// Magic constant 0x40100401 allows us to identify
 	// which lane matches the requested byte.
@@ -124,3 +126,27 @@ fail:
 	MOVD	$-1, R0
 	MOVD	R0, (R8)
 	RET
+
+sve:
+	// Compare one vector at a time, with the predicate P0 selecting
+	// the bytes of the vector that are within the data. The Go
+	// assembler does not know SVE instructions, so they are encoded
+	// by hand.
+	MOVD	ZR, R3			// R3 is the offset of the vector
+	WORD	$0x05203820		// mov z0.b, w1
+	WORD	$0x25221c60		// whilelo p0.b, x3, x2
+sve_loop:
+	WORD	$0xa4034001		// ld1b {z1.b}, p0/z, [x0, x3]
+	WORD	$0x2400a021		// cmpeq p1.b, p0/z, z1.b, z0.b
+	BNE	sve_found		// some byte matched
+	WORD	$0x0430e3e3		// incb x3
+	WORD	$0x25221c60		// whilelo p0.b, x3, x2
+	BMI	sve_loop		// some bytes are left
+	B	fail
+sve_found:
+	// Count the bytes before the first match.
+	WORD	$0x25904021		// brkb p1.b, p0/z, p1.b
+	WORD	$0x25208024		// cntp x4, p0, p1.b
+	ADD	R3, R4, R0
+	MOVD	R0, (R8)
+	RET
@@ -0,0 +1,100 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "go_asm.h"
+#include "textflag.h"
+
+TEXT ·IndexFoldPairString(SB),NOSPLIT,$0-32
+	MOVQ	s_base+0(FP), SI
+	MOVQ	s_len+8(FP), BX
+	MOVBLZX	c0+16(FP), AX
+	MOVBLZX	c1+17(FP), DX
+	ORL	$0x20, AX
+	ORL	$0x20, DX
+
+	// DI is the index of the current pair. There are BX-1 pairs.
+	XORQ	DI, DI
+	DECQ	BX
+	JLE	notfound
+
+	// Fewer than 16 pairs: no full 17-byte window to load.
+	CMPQ	BX, $16
+	JLT	tail
+
+	// Broadcast c0|0x20 to X0, c1|0x20 to X1 and 0x20 to X2.
+	MOVQ	AX, X0
+	PUNPCKLBW	X0, X0
+	PUNPCKLBW	X0, X0
+	PSHUFL	$0, X0, X0
+	MOVQ	DX, X1
+	PUNPCKLBW	X1, X1
+	PUNPCKLBW	X1, X1
+	PSHUFL	$0, X1, X1
+	MOVQ	$0x20, CX
+	MOVQ	CX, X2
+	PUNPCKLBW	X2, X2
+	PUNPCKLBW	X2, X2
+	PSHUFL	$0, X2, X2
+
+	// R8 is the index of the last block of 16 pairs.
+	LEAQ	-16(BX), R8
+
+loop:
+	// Compare the first and second bytes of 16 pairs at once.
+	MOVOU	(SI)(DI*1), X3
+	MOVOU	1(SI)(DI*1), X4
+	POR	X2, X3
+	POR	X2, X4
+	PCMPEQB	X0, X3
+	PCMPEQB	X1, X4
+	PAND	X4, X3
+	PMOVMSKB	X3, CX
+	TESTL	CX, CX
+	JNZ	found
+	ADDQ	$16, DI
+	CMPQ	DI, R8
+	JLE	loop
+
+	// Handle the remaining pairs by rechecking the last 16,
+	// which overlap pairs already known not to match.
+	CMPQ	DI, BX
+	JGE	notfound
+	MOVQ	R8, DI
+	MOVOU	(SI)(DI*1), X3
+	MOVOU	1(SI)(DI*1), X4
+	POR	X2, X3
+	POR	X2, X4
+	PCMPEQB	X0, X3
+	PCMPEQB	X1, X4
+	PAND	X4, X3
+	PMOVMSKB	X3, CX
+	TESTL	CX, CX
+	JNZ	found
+	JMP	notfound
+
+found:
+	BSFL	CX, CX
+	ADDQ	CX, DI
+	MOVQ	DI, ret+24(FP)
+	RET
+
+tail:
+	MOVBLZX	(SI)(DI*1), CX
+	ORL	$0x20, CX
+	CMPL	CX, AX
+	JNE	next
+	MOVBLZX	1(SI)(DI*1), CX
+	ORL	$0x20, CX
+	CMPL	CX, DX
+	JNE	next
+	MOVQ	DI, ret+24(FP)
+	RET
+next:
+	INCQ	DI
+	CMPQ	DI, BX
+	JLT	tail
+
+notfound:
+	MOVQ	$-1, ret+24(FP)
+	RET
@@ -0,0 +1,16 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64
+
+package bytealg
+
+// IndexFoldPairString returns the index of the first byte pair in s
+// that matches c0, c1 ignoring bit 0x20: the smallest i such that
+// s[i]|0x20 == c0|0x20 and s[i+1]|0x20 == c1|0x20, or -1 if there is
+// none. For ASCII letters this is a case-insensitive match; for other
+// bytes it may report false positives, which the caller must reject.
+//
+//go:noescape
+func IndexFoldPairString(s string, c0, c1 byte) int
@@ -0,0 +1,31 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytealg
+
+import "internal/cpu"
+
+func init() {
+	// IndexWide uses 512-bit vectors, which on some processors lower
+	// the clock frequency of the core for a while after their use, and
+	// take some time to become available at full speed. Use them only
+	// for inputs long enough that the faster search outweighs that.
+	// They can be disabled with GODEBUG=cpu.avx512bw=off.
+	if cpu.X86.HasAVX512BW && cpu.X86.HasBMI1 && cpu.X86.HasBMI2 {
+		MaxWideLen = 64
+		MinWideSearch = 4096
+	}
+}
+
+//go:noescape
+
+// IndexWide returns the index of the first instance of b in a, or -1 if b is not present in a.
+// Requires 2 <= len(b) <= MaxWideLen.
+func IndexWide(a, b []byte) int
+
+//go:noescape
+
+// IndexWideString returns the index of the first instance of b in a, or -1 if b is not present in a.
+// Requires 2 <= len(b) <= MaxWideLen.
+func IndexWideString(a, b string) int
@@ -0,0 +1,112 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "go_asm.h"
+#include "textflag.h"
+
+TEXT ·IndexWide(SB),NOSPLIT,$0-56
+	MOVQ	a_base+0(FP), DI
+	MOVQ	a_len+8(FP), DX
+	MOVQ	b_base+24(FP), R8
+	MOVQ	b_len+32(FP), AX
+	LEAQ	ret+48(FP), R11
+	JMP	indexwidebody<>(SB)
+
+TEXT ·IndexWideString(SB),NOSPLIT,$0-40
+	MOVQ	a_base+0(FP), DI
+	MOVQ	a_len+8(FP), DX
+	MOVQ	b_base+16(FP), R8
+	MOVQ	b_len+24(FP), AX
+	LEAQ	ret+32(FP), R11
+	JMP	indexwidebody<>(SB)
+
+// Compare the first and last bytes of b with 64 consecutive
+// positions of a at once, and then b as a whole with a at each
+// position where both match. Loads that would read past the end
+// of a or b are masked, so there is no scalar head or tail loop.
+//
+// input:
+//   DI: pointer to string, in which we are searching
+//   DX: length of string, in which we are searching
+//   R8: pointer to string, that we are searching for
+//   AX: length of string, that we are searching for, 2 to 64
+//   R11: address, where to put return value
+// This function requires AVX-512BW, BMI1 and BMI2.
+TEXT indexwidebody<>(SB),NOSPLIT,$0
+	CMPQ	AX, DX
+	JA	fail
+
+	// Load b into Z0, zeroing the bytes past its end,
+	// and broadcast its first and last bytes into Z1 and Z2.
+	MOVQ	$-1, R14
+	BZHIQ	AX, R14, R14
+	KMOVQ	R14, K7
+	VMOVDQU8.Z	(R8), K7, Z0
+	VPBROADCASTB	(R8), Z1
+	VPBROADCASTB	-1(R8)(AX*1), Z2
+
+	MOVQ	DX, R9
+	SUBQ	AX, R9
+	INCQ	R9	// R9 = number of positions at which b may start
+	LEAQ	-1(DI)(AX*1), R10	// R10 = address of a[len(b)-1]
+	XORQ	SI, SI	// SI = first position of the current block
+	XORQ	R12, R12	// R12 = 1 in the last, partial block
+
+loop:
+	MOVQ	R9, CX
+	SUBQ	SI, CX	// CX = positions left
+	CMPQ	CX, $64
+	JB	tail
+	VPCMPEQB	(DI)(SI*1), Z1, K1
+	VPCMPEQB	(R10)(SI*1), Z2, K2
+	KANDQ	K1, K2, K1
+	KMOVQ	K1, BX
+	TESTQ	BX, BX
+	JNZ	candidates
+next:
+	ADDQ	$64, SI
+	JMP	loop
+
+tail:
+	// Fewer than 64 positions are left, and there may be fewer
+	// than 64 bytes left to load: mask the loads.
+	TESTQ	CX, CX
+	JZ	fail
+	MOVQ	$-1, R14
+	BZHIQ	CX, R14, R14
+	KMOVQ	R14, K6
+	VMOVDQU8.Z	(DI)(SI*1), K6, Z3
+	VMOVDQU8.Z	(R10)(SI*1), K6, Z4
+	VPCMPEQB	Z3, Z1, K6, K1
+	VPCMPEQB	Z4, Z2, K6, K2
+	KANDQ	K1, K2, K1
+	KMOVQ	K1, BX
+	MOVQ	$1, R12
+	TESTQ	BX, BX
+	JZ	fail
+
+candidates:
+	// BX has a bit set for each position in the block where
+	// the first and last bytes of b match.
+	TZCNTQ	BX, CX
+	ADDQ	SI, CX
+	VMOVDQU8.Z	(DI)(CX*1), K7, Z3
+	VPCMPEQB	Z3, Z0, K3
+	KORTESTQ	K3, K3
+	JCS	found
+	BLSRQ	BX, BX
+	JNZ	candidates
+	TESTQ	R12, R12
+	JNZ	fail
+	JMP	next
+
+found:
+	MOVQ	CX, (R11)
+	VZEROUPPER
+	RET
+
+fail:
+	MOVQ	$-1, (R11)
+	VZEROUPPER
+	RET
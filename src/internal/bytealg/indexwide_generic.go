@@ -0,0 +1,19 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !amd64
+
+package bytealg
+
+// IndexWide returns the index of the first instance of b in a, or -1 if b is not present in a.
+// Requires 2 <= len(b) <= MaxWideLen.
+func IndexWide(a, b []byte) int {
+	panic("unimplemented")
+}
+
+// IndexWideString returns the index of the first instance of b in a, or -1 if b is not present in a.
+// Requires 2 <= len(b) <= MaxWideLen.
+func IndexWideString(a, b string) int {
+	panic("unimplemented")
+}
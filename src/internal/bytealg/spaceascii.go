@@ -0,0 +1,47 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytealg
+
+// isSpaceASCII reports whether c is one of the ASCII white space
+// bytes '\t', '\n', '\v', '\f', '\r' and ' '.
+func isSpaceASCII(c byte) bool {
+	return c == ' ' || c-'\t' < 5
+}
+
+func indexNonSpaceASCIIGeneric(b []byte) int {
+	for i, c := range b {
+		if !isSpaceASCII(c) {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexNonSpaceASCIIGenericString(s string) int {
+	for i := 0; i < len(s); i++ {
+		if !isSpaceASCII(s[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func lastIndexNonSpaceASCIIGeneric(b []byte) int {
+	for i := len(b) - 1; i >= 0; i-- {
+		if !isSpaceASCII(b[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func lastIndexNonSpaceASCIIGenericString(s string) int {
+	for i := len(s) - 1; i >= 0; i-- {
+		if !isSpaceASCII(s[i]) {
+			return i
+		}
+	}
+	return -1
+}
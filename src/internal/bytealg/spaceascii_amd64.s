@@ -0,0 +1,121 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "go_asm.h"
+#include "textflag.h"
+
+// A byte c is one of '\t', '\n', '\v', '\f' and '\r' if c+(0x80-'\t'),
+// as a signed byte, is less than -128+5, that is, spacelimit<>.
+DATA spacebias<>+0x00(SB)/8, $0x7777777777777777
+DATA spacebias<>+0x08(SB)/8, $0x7777777777777777
+GLOBL spacebias<>(SB), RODATA, $16
+
+DATA spacelimit<>+0x00(SB)/8, $0x8585858585858585
+DATA spacelimit<>+0x08(SB)/8, $0x8585858585858585
+GLOBL spacelimit<>(SB), RODATA, $16
+
+DATA spaceblank<>+0x00(SB)/8, $0x2020202020202020
+DATA spaceblank<>+0x08(SB)/8, $0x2020202020202020
+GLOBL spaceblank<>(SB), RODATA, $16
+
+TEXT ·indexNonSpaceASCII(SB),NOSPLIT,$0-32
+	MOVQ	b_base+0(FP), SI
+	MOVQ	b_len+8(FP), BX
+	LEAQ	ret+24(FP), R8
+	JMP	indexnonspacebody<>(SB)
+
+TEXT ·indexNonSpaceASCIIString(SB),NOSPLIT,$0-24
+	MOVQ	s_base+0(FP), SI
+	MOVQ	s_len+8(FP), BX
+	LEAQ	ret+16(FP), R8
+	JMP	indexnonspacebody<>(SB)
+
+TEXT ·lastIndexNonSpaceASCII(SB),NOSPLIT,$0-32
+	MOVQ	b_base+0(FP), SI
+	MOVQ	b_len+8(FP), BX
+	LEAQ	ret+24(FP), R8
+	JMP	lastindexnonspacebody<>(SB)
+
+TEXT ·lastIndexNonSpaceASCIIString(SB),NOSPLIT,$0-24
+	MOVQ	s_base+0(FP), SI
+	MOVQ	s_len+8(FP), BX
+	LEAQ	ret+16(FP), R8
+	JMP	lastindexnonspacebody<>(SB)
+
+// NONSPACE16 sets the bits of CX for the bytes of the 16 at (SI)(DI*1)
+// that are not ASCII white space, given spacebias<> in X0, spacelimit<>
+// in X1 and spaceblank<> in X2. It clobbers X3-X5.
+#define NONSPACE16 \
+	MOVOU	(SI)(DI*1), X3 \
+	MOVOU	X3, X4 \
+	PADDB	X0, X4 \
+	MOVOU	X1, X5 \
+	PCMPGTB	X4, X5 \
+	PCMPEQB	X2, X3 \
+	POR	X3, X5 \
+	PMOVMSKB	X5, CX \
+	XORL	$0xffff, CX
+
+// input:
+//   SI: data
+//   BX: data len, at least 16
+//   R8: address to put result
+TEXT indexnonspacebody<>(SB),NOSPLIT,$0
+	MOVOU	spacebias<>(SB), X0
+	MOVOU	spacelimit<>(SB), X1
+	MOVOU	spaceblank<>(SB), X2
+
+	XORQ	DI, DI
+	LEAQ	-16(BX), R9	// R9 = index of the last 16 bytes
+loop:
+	NONSPACE16
+	JNZ	found
+	ADDQ	$16, DI
+	CMPQ	DI, R9
+	JLT	loop
+
+	// Check the last 16 bytes, which may overlap
+	// bytes already known to be white space.
+	MOVQ	R9, DI
+	NONSPACE16
+	JNZ	found
+	MOVQ	$-1, (R8)
+	RET
+found:
+	BSFL	CX, CX
+	ADDQ	CX, DI
+	MOVQ	DI, (R8)
+	RET
+
+// input:
+//   SI: data
+//   BX: data len, at least 16
+//   R8: address to put result
+TEXT lastindexnonspacebody<>(SB),NOSPLIT,$0
+	MOVOU	spacebias<>(SB), X0
+	MOVOU	spacelimit<>(SB), X1
+	MOVOU	spaceblank<>(SB), X2
+
+	LEAQ	-16(BX), DI
+loop:
+	NONSPACE16
+	JNZ	found
+	SUBQ	$16, DI
+	JGT	loop
+
+	// Check the first 16 bytes, which may overlap
+	// bytes already known to be white space.
+	CMPQ	DI, $-16
+	JLE	notfound
+	XORQ	DI, DI
+	NONSPACE16
+	JNZ	found
+notfound:
+	MOVQ	$-1, (R8)
+	RET
+found:
+	BSRL	CX, CX
+	ADDQ	CX, DI
+	MOVQ	DI, (R8)
+	RET
@@ -0,0 +1,35 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !amd64
+
+package bytealg
+
+// IndexNonSpaceASCII returns the index of the first byte of b that is
+// not ASCII white space, or -1 if there is none. Bytes that are not
+// ASCII are not ASCII white space.
+func IndexNonSpaceASCII(b []byte) int {
+	return indexNonSpaceASCIIGeneric(b)
+}
+
+// IndexNonSpaceASCIIString returns the index of the first byte of s
+// that is not ASCII white space, or -1 if there is none. Bytes that
+// are not ASCII are not ASCII white space.
+func IndexNonSpaceASCIIString(s string) int {
+	return indexNonSpaceASCIIGenericString(s)
+}
+
+// LastIndexNonSpaceASCII returns the index of the last byte of b that
+// is not ASCII white space, or -1 if there is none. Bytes that are not
+// ASCII are not ASCII white space.
+func LastIndexNonSpaceASCII(b []byte) int {
+	return lastIndexNonSpaceASCIIGeneric(b)
+}
+
+// LastIndexNonSpaceASCIIString returns the index of the last byte of s
+// that is not ASCII white space, or -1 if there is none. Bytes that
+// are not ASCII are not ASCII white space.
+func LastIndexNonSpaceASCIIString(s string) int {
+	return lastIndexNonSpaceASCIIGenericString(s)
+}
@@ -0,0 +1,62 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64
+
+package bytealg
+
+// The assembly versions classify 16 bytes at a time
+// and require len(b) >= 16.
+
+//go:noescape
+func indexNonSpaceASCII(b []byte) int
+
+//go:noescape
+func indexNonSpaceASCIIString(s string) int
+
+//go:noescape
+func lastIndexNonSpaceASCII(b []byte) int
+
+//go:noescape
+func lastIndexNonSpaceASCIIString(s string) int
+
+// IndexNonSpaceASCII returns the index of the first byte of b that is
+// not ASCII white space, or -1 if there is none. Bytes that are not
+// ASCII are not ASCII white space.
+func IndexNonSpaceASCII(b []byte) int {
+	if len(b) >= 16 {
+		return indexNonSpaceASCII(b)
+	}
+	return indexNonSpaceASCIIGeneric(b)
+}
+
+// IndexNonSpaceASCIIString returns the index of the first byte of s
+// that is not ASCII white space, or -1 if there is none. Bytes that
+// are not ASCII are not ASCII white space.
+func IndexNonSpaceASCIIString(s string) int {
+	if len(s) >= 16 {
+		return indexNonSpaceASCIIString(s)
+	}
+	return indexNonSpaceASCIIGenericString(s)
+}
+
+// LastIndexNonSpaceASCII returns the index of the last byte of b that
+// is not ASCII white space, or -1 if there is none. Bytes that are not
+// ASCII are not ASCII white space.
+func LastIndexNonSpaceASCII(b []byte) int {
+	if len(b) >= 16 {
+		return lastIndexNonSpaceASCII(b)
+	}
+	return lastIndexNonSpaceASCIIGeneric(b)
+}
+
+// LastIndexNonSpaceASCIIString returns the index of the last byte of s
+// that is not ASCII white space, or -1 if there is none. Bytes that
+// are not ASCII are not ASCII white space.
+func LastIndexNonSpaceASCIIString(s string) int {
+	if len(s) >= 16 {
+		return lastIndexNonSpaceASCIIString(s)
+	}
+	return lastIndexNonSpaceASCIIGenericString(s)
+}
@@ -0,0 +1,591 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytealg
+
+// The Two-Way algorithm of Crochemore and Perrin finds a needle of
+// length m in a haystack of length n in O(n+m) time and O(1) space,
+// whatever the contents of either. It splits the needle x at a critical
+// factorization x = u v, where ell = len(u)-1, and at each alignment
+// compares v left to right and then, if v matches, u right to left. A
+// mismatch in v shifts the needle past the mismatched byte; a mismatch
+// in u shifts it by the period of x. When x is periodic, the prefix
+// already known to match after a shift by the period is not compared
+// again.
+//
+// As with the Rabin-Karp functions, the code is duplicated for strings
+// and byte slices to avoid conversions.
+
+// maxSuffix returns the start of the maximal suffix of x under the
+// byte order, or under the reverse order if rev is set, less one, and
+// the period of that suffix.
+func maxSuffix(x string, rev bool) (ms, p int) {
+	ms, j, k, p := -1, 0, 1, 1
+	for j+k < len(x) {
+		a, b := x[j+k], x[ms+k]
+		if rev {
+			a, b = b, a
+		}
+		switch {
+		case a < b:
+			// The suffix at j+k is smaller; the period
+			// of the maximal suffix extends to it.
+			j += k
+			k = 1
+			p = j - ms
+		case a == b:
+			if k != p {
+				k++
+			} else {
+				j += p
+				k = 1
+			}
+		default:
+			// The suffix at j is greater: it starts a new maximal suffix.
+			ms = j
+			j = ms + 1
+			k, p = 1, 1
+		}
+	}
+	return ms, p
+}
+
+// maxSuffixBytes is like maxSuffix for a byte slice.
+func maxSuffixBytes(x []byte, rev bool) (ms, p int) {
+	ms, j, k, p := -1, 0, 1, 1
+	for j+k < len(x) {
+		a, b := x[j+k], x[ms+k]
+		if rev {
+			a, b = b, a
+		}
+		switch {
+		case a < b:
+			j += k
+			k = 1
+			p = j - ms
+		case a == b:
+			if k != p {
+				k++
+			} else {
+				j += p
+				k = 1
+			}
+		default:
+			ms = j
+			j = ms + 1
+			k, p = 1, 1
+		}
+	}
+	return ms, p
+}
+
+// A TwoWay is the critical factorization of a string x used by the
+// Two-Way search for x, which depends only on x and so can be computed
+// once and used to search for x in any number of strings.
+type TwoWay struct {
+	ell      int  // x = x[:ell+1] x[ell+1:]
+	per      int  // the period of x if periodic, otherwise the shift after a mismatch in x[:ell+1]
+	periodic bool // whether x[:ell+1] occurs in x per bytes later
+}
+
+// MakeTwoWay returns the critical factorization of x.
+func MakeTwoWay(x string) TwoWay {
+	// Find a critical factorization: the later of the two maximal suffixes.
+	ell, per := maxSuffix(x, false)
+	if ms, p := maxSuffix(x, true); ms > ell {
+		ell, per = ms, p
+	}
+	if ell+1+per <= len(x) && x[:ell+1] == x[per:per+ell+1] {
+		return TwoWay{ell: ell, per: per, periodic: true}
+	}
+	return TwoWay{ell: ell, per: max(ell+1, len(x)-ell-1) + 1}
+}
+
+// MakeTwoWayBytes returns the critical factorization of x.
+func MakeTwoWayBytes(x []byte) TwoWay {
+	ell, per := maxSuffixBytes(x, false)
+	if ms, p := maxSuffixBytes(x, true); ms > ell {
+		ell, per = ms, p
+	}
+	if ell+1+per <= len(x) && Equal(x[:ell+1], x[per:per+ell+1]) {
+		return TwoWay{ell: ell, per: per, periodic: true}
+	}
+	return TwoWay{ell: ell, per: max(ell+1, len(x)-ell-1) + 1}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// IndexTwoWay uses the Two-Way search algorithm to return the index of
+// the first occurrence of substr in s, or -1 if not present.
+// It runs in time linear in len(s)+len(substr) and constant space.
+func IndexTwoWay(s, substr string) int {
+	tw := MakeTwoWay(substr)
+	return tw.IndexString(s, substr)
+}
+
+// IndexTwoWayBytes uses the Two-Way search algorithm to return the index
+// of the first occurrence of sep in s, or -1 if not present.
+// It runs in time linear in len(s)+len(sep) and constant space.
+func IndexTwoWayBytes(s, sep []byte) int {
+	tw := MakeTwoWayBytes(sep)
+	return tw.Index(s, sep)
+}
+
+// IndexString is like IndexTwoWay, for the string substr
+// whose factorization tw is.
+func (tw *TwoWay) IndexString(s, substr string) int {
+	m := len(substr)
+	switch {
+	case m == 0:
+		return 0
+	case m > len(s):
+		return -1
+	}
+	ell, per := tw.ell, tw.per
+
+	if tw.periodic {
+		// substr is periodic with period per. After a shift by per,
+		// the first m-per bytes of substr are known to match already;
+		// memory is the index of the last of them, or -1 if none.
+		memory := -1
+		for j := 0; j <= len(s)-m; {
+			i := ell + 1
+			if memory >= i {
+				i = memory + 1
+			}
+			for i < m && substr[i] == s[i+j] {
+				i++
+			}
+			if i < m {
+				j += i - ell
+				memory = -1
+				continue
+			}
+			i = ell
+			for i > memory && substr[i] == s[i+j] {
+				i--
+			}
+			if i <= memory {
+				return j
+			}
+			j += per
+			memory = m - per - 1
+		}
+		return -1
+	}
+
+	// The halves of the factorization do not overlap in any occurrence,
+	// so a mismatch in the left half allows a shift past it.
+	for j := 0; j <= len(s)-m; {
+		i := ell + 1
+		for i < m && substr[i] == s[i+j] {
+			i++
+		}
+		if i < m {
+			j += i - ell
+			continue
+		}
+		i = ell
+		for i >= 0 && substr[i] == s[i+j] {
+			i--
+		}
+		if i < 0 {
+			return j
+		}
+		j += per
+	}
+	return -1
+}
+
+// Index is like IndexTwoWayBytes, for the byte slice sep
+// whose factorization tw is.
+func (tw *TwoWay) Index(s, sep []byte) int {
+	m := len(sep)
+	switch {
+	case m == 0:
+		return 0
+	case m > len(s):
+		return -1
+	}
+	ell, per := tw.ell, tw.per
+
+	if tw.periodic {
+		memory := -1
+		for j := 0; j <= len(s)-m; {
+			i := ell + 1
+			if memory >= i {
+				i = memory + 1
+			}
+			for i < m && sep[i] == s[i+j] {
+				i++
+			}
+			if i < m {
+				j += i - ell
+				memory = -1
+				continue
+			}
+			i = ell
+			for i > memory && sep[i] == s[i+j] {
+				i--
+			}
+			if i <= memory {
+				return j
+			}
+			j += per
+			memory = m - per - 1
+		}
+		return -1
+	}
+
+	for j := 0; j <= len(s)-m; {
+		i := ell + 1
+		for i < m && sep[i] == s[i+j] {
+			i++
+		}
+		if i < m {
+			j += i - ell
+			continue
+		}
+		i = ell
+		for i >= 0 && sep[i] == s[i+j] {
+			i--
+		}
+		if i < 0 {
+			return j
+		}
+		j += per
+	}
+	return -1
+}
+
+// The reverse search for x in s, which finds the last instance, is the
+// forward search for the reverse of x in the reverse of s. It uses the
+// critical factorization of the reverse of x, in a TwoWay made by
+// MakeTwoWayRev, and reads x and s from their ends rather than
+// reversing them.
+
+// maxSuffixRev is like maxSuffix for the reverse of x.
+func maxSuffixRev(x string, rev bool) (ms, p int) {
+	last := len(x) - 1
+	ms, j, k, p := -1, 0, 1, 1
+	for j+k < len(x) {
+		a, b := x[last-(j+k)], x[last-(ms+k)]
+		if rev {
+			a, b = b, a
+		}
+		switch {
+		case a < b:
+			j += k
+			k = 1
+			p = j - ms
+		case a == b:
+			if k != p {
+				k++
+			} else {
+				j += p
+				k = 1
+			}
+		default:
+			ms = j
+			j = ms + 1
+			k, p = 1, 1
+		}
+	}
+	return ms, p
+}
+
+// maxSuffixRevBytes is like maxSuffixRev for a byte slice.
+func maxSuffixRevBytes(x []byte, rev bool) (ms, p int) {
+	last := len(x) - 1
+	ms, j, k, p := -1, 0, 1, 1
+	for j+k < len(x) {
+		a, b := x[last-(j+k)], x[last-(ms+k)]
+		if rev {
+			a, b = b, a
+		}
+		switch {
+		case a < b:
+			j += k
+			k = 1
+			p = j - ms
+		case a == b:
+			if k != p {
+				k++
+			} else {
+				j += p
+				k = 1
+			}
+		default:
+			ms = j
+			j = ms + 1
+			k, p = 1, 1
+		}
+	}
+	return ms, p
+}
+
+// MakeTwoWayRev returns the critical factorization of the reverse of x.
+func MakeTwoWayRev(x string) TwoWay {
+	ell, per := maxSuffixRev(x, false)
+	if ms, p := maxSuffixRev(x, true); ms > ell {
+		ell, per = ms, p
+	}
+	// The first ell+1 bytes of the reverse of x are the last ell+1
+	// bytes of x, reversed.
+	m := len(x)
+	if ell+1+per <= m && x[m-1-ell:] == x[m-1-ell-per:m-per] {
+		return TwoWay{ell: ell, per: per, periodic: true}
+	}
+	return TwoWay{ell: ell, per: max(ell+1, m-ell-1) + 1}
+}
+
+// MakeTwoWayRevBytes returns the critical factorization of the reverse of x.
+func MakeTwoWayRevBytes(x []byte) TwoWay {
+	ell, per := maxSuffixRevBytes(x, false)
+	if ms, p := maxSuffixRevBytes(x, true); ms > ell {
+		ell, per = ms, p
+	}
+	m := len(x)
+	if ell+1+per <= m && Equal(x[m-1-ell:], x[m-1-ell-per:m-per]) {
+		return TwoWay{ell: ell, per: per, periodic: true}
+	}
+	return TwoWay{ell: ell, per: max(ell+1, m-ell-1) + 1}
+}
+
+// LastIndexTwoWay uses the Two-Way search algorithm to return the index
+// of the last occurrence of substr in s, or -1 if not present.
+// It runs in time linear in len(s)+len(substr) and constant space.
+func LastIndexTwoWay(s, substr string) int {
+	tw := MakeTwoWayRev(substr)
+	return tw.LastIndexString(s, substr)
+}
+
+// LastIndexTwoWayBytes uses the Two-Way search algorithm to return the
+// index of the last occurrence of sep in s, or -1 if not present.
+// It runs in time linear in len(s)+len(sep) and constant space.
+func LastIndexTwoWayBytes(s, sep []byte) int {
+	tw := MakeTwoWayRevBytes(sep)
+	return tw.LastIndex(s, sep)
+}
+
+// LastIndexString is like LastIndexTwoWay, for the string substr
+// whose reverse's factorization tw is.
+func (tw *TwoWay) LastIndexString(s, substr string) int {
+	m := len(substr)
+	switch {
+	case m == 0:
+		return len(s)
+	case m > len(s):
+		return -1
+	}
+	ell, per := tw.ell, tw.per
+	// At each alignment, e is the index in s of the byte aligned with
+	// the last byte of substr, and byte i of the reverse of substr,
+	// x[last-i], is compared with s[e-i].
+	x, last := substr, m-1
+
+	if tw.periodic {
+		memory := -1
+		for e := len(s) - 1; e >= last; {
+			i := ell + 1
+			if memory >= i {
+				i = memory + 1
+			}
+			for i < m && x[last-i] == s[e-i] {
+				i++
+			}
+			if i < m {
+				e -= i - ell
+				memory = -1
+				continue
+			}
+			i = ell
+			for i > memory && x[last-i] == s[e-i] {
+				i--
+			}
+			if i <= memory {
+				return e - last
+			}
+			e -= per
+			memory = m - per - 1
+		}
+		return -1
+	}
+
+	for e := len(s) - 1; e >= last; {
+		i := ell + 1
+		for i < m && x[last-i] == s[e-i] {
+			i++
+		}
+		if i < m {
+			e -= i - ell
+			continue
+		}
+		i = ell
+		for i >= 0 && x[last-i] == s[e-i] {
+			i--
+		}
+		if i < 0 {
+			return e - last
+		}
+		e -= per
+	}
+	return -1
+}
+
+// LastIndex is like LastIndexTwoWayBytes, for the byte slice sep
+// whose reverse's factorization tw is.
+func (tw *TwoWay) LastIndex(s, sep []byte) int {
+	m := len(sep)
+	switch {
+	case m == 0:
+		return len(s)
+	case m > len(s):
+		return -1
+	}
+	ell, per := tw.ell, tw.per
+	x, last := sep, m-1
+
+	if tw.periodic {
+		memory := -1
+		for e := len(s) - 1; e >= last; {
+			i := ell + 1
+			if memory >= i {
+				i = memory + 1
+			}
+			for i < m && x[last-i] == s[e-i] {
+				i++
+			}
+			if i < m {
+				e -= i - ell
+				memory = -1
+				continue
+			}
+			i = ell
+			for i > memory && x[last-i] == s[e-i] {
+				i--
+			}
+			if i <= memory {
+				return e - last
+			}
+			e -= per
+			memory = m - per - 1
+		}
+		return -1
+	}
+
+	for e := len(s) - 1; e >= last; {
+		i := ell + 1
+		for i < m && x[last-i] == s[e-i] {
+			i++
+		}
+		if i < m {
+			e -= i - ell
+			continue
+		}
+		i = ell
+		for i >= 0 && x[last-i] == s[e-i] {
+			i--
+		}
+		if i < 0 {
+			return e - last
+		}
+		e -= per
+	}
+	return -1
+}
+
+// MinSkipLen is the minimum length of the string to be searched for
+// for which LastIndexSkip is worth using instead of Rabin-Karp.
+const MinSkipLen = 8
+
+// LastIndexSkip returns the index of the last occurrence of substr in s,
+// or -1 if not present. It is the reverse of Horspool's search: it tries
+// each alignment of substr from the end of s, and after a mismatch shifts
+// substr left until a byte of it lines up with the byte of s that was
+// under its first, or past that byte if there is none. The shifts are
+// long when few of the bytes of s occur in substr. If they turn out to
+// be short, it finishes with the reverse Two-Way search, so it runs in
+// time linear in len(s)+len(substr) in any case.
+// It requires 2 <= len(substr) <= len(s).
+func LastIndexSkip(s, substr string) int {
+	m := len(substr)
+	c0, c1 := substr[0], substr[1]
+	last := len(s) - m
+	// Try the last few alignments directly: when there is an instance
+	// near the end of s, that is quicker than making the table.
+	i := last
+	for ; i >= 0 && i > last-16; i-- {
+		if s[i] == c0 && s[i+1] == c1 && s[i:i+m] == substr {
+			return i
+		}
+	}
+	// The shift after a mismatch with c under substr[0] is the least
+	// k >= 1 such that substr[k] == c, or len(substr) if there is none,
+	// capped at 255; skip[c] is top less that shift, so that the table
+	// starts out zero.
+	var skip [256]uint8
+	top := m
+	if top > 255 {
+		top = 255
+	}
+	for k := top - 1; k > 0; k-- {
+		skip[substr[k]] = uint8(top - k)
+	}
+	tries := 0
+	for i >= 0 {
+		c := s[i]
+		if c == c0 && s[i+1] == c1 && s[i:i+m] == substr {
+			return i
+		}
+		i -= top - int(skip[c])
+		tries++
+		if tries >= 4+(last-i)>>3 && i >= 0 {
+			// The shifts average less than 8 bytes: use the
+			// Two-Way search on what remains.
+			return LastIndexTwoWay(s[:i+m], substr)
+		}
+	}
+	return -1
+}
+
+// LastIndexSkipBytes is like LastIndexSkip for byte slices.
+// It requires 2 <= len(sep) <= len(s).
+func LastIndexSkipBytes(s, sep []byte) int {
+	m := len(sep)
+	c0, c1 := sep[0], sep[1]
+	last := len(s) - m
+	i := last
+	for ; i >= 0 && i > last-16; i-- {
+		if s[i] == c0 && s[i+1] == c1 && Equal(s[i:i+m], sep) {
+			return i
+		}
+	}
+	var skip [256]uint8
+	top := m
+	if top > 255 {
+		top = 255
+	}
+	for k := top - 1; k > 0; k-- {
+		skip[sep[k]] = uint8(top - k)
+	}
+	tries := 0
+	for i >= 0 {
+		c := s[i]
+		if c == c0 && s[i+1] == c1 && Equal(s[i:i+m], sep) {
+			return i
+		}
+		i -= top - int(skip[c])
+		tries++
+		if tries >= 4+(last-i)>>3 && i >= 0 {
+			return LastIndexTwoWayBytes(s[:i+m], sep)
+		}
+	}
+	return -1
+}
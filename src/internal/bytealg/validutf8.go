@@ -0,0 +1,28 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytealg
+
+// runeStartBefore returns the index of the start of the rune that
+// b[:n] may end within, or n if b[:n] ends with a complete rune or
+// an ASCII byte. b[:n] must be valid UTF-8 apart from its last rune.
+func runeStartBefore(b []byte, n int) int {
+	for i := 0; i < 3 && n > 0 && b[n-1]&0xc0 == 0x80; i++ {
+		n--
+	}
+	if n > 0 && b[n-1] >= 0xc0 {
+		n--
+	}
+	return n
+}
+
+func runeStartBeforeString(s string, n int) int {
+	for i := 0; i < 3 && n > 0 && s[n-1]&0xc0 == 0x80; i++ {
+		n--
+	}
+	if n > 0 && s[n-1] >= 0xc0 {
+		n--
+	}
+	return n
+}
@@ -0,0 +1,139 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "go_asm.h"
+#include "textflag.h"
+
+// The validation follows "Validating UTF-8 In Less Than One Instruction
+// Per Byte" by John Keiser and Daniel Lemire. Each byte is classified
+// by three table lookups: on the high and low nibbles of the byte
+// before it and on its own high nibble. Each table entry is a set of
+// the errors that the nibble allows, so the AND of the three is the
+// set of errors that the pair of bytes has:
+//
+//	0x01 too short: a lead byte not followed by a continuation byte
+//	0x02 too long: an ASCII byte followed by a continuation byte
+//	0x04 overlong 3: E0 followed by 80-9F
+//	0x08 too large: F4 followed by 90-BF, or F5-FF
+//	0x10 surrogate: ED followed by A0-BF
+//	0x20 overlong 2: C0 or C1 followed by a continuation byte
+//	0x40 too large or overlong 4: F5-FF or F0 followed by 80-8F
+//	0x80 two continuation bytes in a row
+//
+// Two continuation bytes in a row are an error unless the byte two
+// before is a three- or four-byte lead, or the byte three before is
+// a four-byte lead, and otherwise they must be.
+DATA utf8byte1high<>+0x00(SB)/8, $0x0202020202020202
+DATA utf8byte1high<>+0x08(SB)/8, $0x4915012180808080
+GLOBL utf8byte1high<>(SB), RODATA, $16
+
+DATA utf8byte1low<>+0x00(SB)/8, $0xcbcbcb8b8383a3e7
+DATA utf8byte1low<>+0x08(SB)/8, $0xcbcbdbcbcbcbcbcb
+GLOBL utf8byte1low<>(SB), RODATA, $16
+
+DATA utf8byte2high<>+0x00(SB)/8, $0x0101010101010101
+DATA utf8byte2high<>+0x08(SB)/8, $0x01010101babaaee6
+GLOBL utf8byte2high<>(SB), RODATA, $16
+
+DATA utf8nibble<>+0x00(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA utf8nibble<>+0x08(SB)/8, $0x0f0f0f0f0f0f0f0f
+GLOBL utf8nibble<>(SB), RODATA, $16
+
+// A byte is at least E0, a three- or four-byte lead, if subtracting
+// 0x60 with unsigned saturation leaves its high bit set, and at least
+// F0, a four-byte lead, if subtracting 0x70 does.
+DATA utf8third<>+0x00(SB)/8, $0x6060606060606060
+DATA utf8third<>+0x08(SB)/8, $0x6060606060606060
+GLOBL utf8third<>(SB), RODATA, $16
+
+DATA utf8fourth<>+0x00(SB)/8, $0x7070707070707070
+DATA utf8fourth<>+0x08(SB)/8, $0x7070707070707070
+GLOBL utf8fourth<>(SB), RODATA, $16
+
+DATA utf8high<>+0x00(SB)/8, $0x8080808080808080
+DATA utf8high<>+0x08(SB)/8, $0x8080808080808080
+GLOBL utf8high<>(SB), RODATA, $16
+
+TEXT ·validUTF8(SB),NOSPLIT,$0-32
+	MOVQ	b_base+0(FP), SI
+	MOVQ	b_len+8(FP), BX
+	LEAQ	ret+24(FP), R8
+	JMP	validutf8body<>(SB)
+
+TEXT ·validUTF8String(SB),NOSPLIT,$0-24
+	MOVQ	s_base+0(FP), SI
+	MOVQ	s_len+8(FP), BX
+	LEAQ	ret+16(FP), R8
+	JMP	validutf8body<>(SB)
+
+// input:
+//   SI: data
+//   BX: data len
+//   R8: address to put result
+// This function requires the PSHUFB and PALIGNR instructions.
+TEXT validutf8body<>(SB),NOSPLIT,$0
+	MOVOU	utf8byte1high<>(SB), X0
+	MOVOU	utf8byte1low<>(SB), X1
+	MOVOU	utf8byte2high<>(SB), X2
+	MOVOU	utf8nibble<>(SB), X3
+	MOVOU	utf8third<>(SB), X4
+	MOVOU	utf8fourth<>(SB), X5
+	MOVOU	utf8high<>(SB), X6
+	PXOR	X7, X7	// X7 = previous block, as if ASCII at first
+	PXOR	X15, X15
+
+	XORQ	DI, DI
+	ANDQ	$~15, BX	// BX = length of the whole blocks
+	JZ	done
+loop:
+	MOVOU	(SI)(DI*1), X8
+
+	// Skip a block of ASCII after another.
+	PMOVMSKB	X8, AX
+	PMOVMSKB	X7, CX
+	ORL	AX, CX
+	JZ	next
+
+	// X11 = errors of each byte and the byte before it.
+	MOVOU	X8, X9
+	PALIGNR	$15, X7, X9	// X9 = bytes before
+	MOVOU	X9, X10
+	PSRLW	$4, X10
+	PAND	X3, X10
+	PAND	X3, X9
+	MOVOU	X0, X11
+	PSHUFB	X10, X11
+	MOVOU	X1, X12
+	PSHUFB	X9, X12
+	PAND	X12, X11
+	MOVOU	X8, X10
+	PSRLW	$4, X10
+	PAND	X3, X10
+	MOVOU	X2, X12
+	PSHUFB	X10, X12
+	PAND	X12, X11
+
+	// Flip the two continuation bytes error where they must be.
+	MOVOU	X8, X9
+	PALIGNR	$14, X7, X9	// X9 = bytes two before
+	PSUBUSB	X4, X9
+	MOVOU	X8, X10
+	PALIGNR	$13, X7, X10	// X10 = bytes three before
+	PSUBUSB	X5, X10
+	POR	X10, X9
+	PAND	X6, X9
+	PXOR	X9, X11
+
+	PCMPEQB	X15, X11
+	PMOVMSKB	X11, CX
+	CMPL	CX, $0xffff
+	JNE	done
+next:
+	MOVOU	X8, X7
+	ADDQ	$16, DI
+	CMPQ	DI, BX
+	JLT	loop
+done:
+	MOVQ	DI, (R8)
+	RET
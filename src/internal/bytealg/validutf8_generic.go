@@ -0,0 +1,23 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !amd64
+
+package bytealg
+
+// ValidUTF8Prefix returns the length of a prefix of b that is valid
+// UTF-8 and ends at the start of a rune, found a block of bytes at a
+// time. It may stop short of the longest such prefix, so the caller
+// must check the rest of b itself.
+func ValidUTF8Prefix(b []byte) int {
+	return 0
+}
+
+// ValidUTF8PrefixString returns the length of a prefix of s that is
+// valid UTF-8 and ends at the start of a rune, found a block of bytes
+// at a time. It may stop short of the longest such prefix, so the
+// caller must check the rest of s itself.
+func ValidUTF8PrefixString(s string) int {
+	return 0
+}
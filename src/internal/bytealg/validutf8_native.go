@@ -0,0 +1,42 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64
+
+package bytealg
+
+import "internal/cpu"
+
+// The assembly versions check 16 bytes at a time and require the
+// SSSE3 instructions PSHUFB and PALIGNR. They return the length of
+// the whole blocks of 16 bytes before the first block with an error,
+// which may end within a rune.
+
+//go:noescape
+func validUTF8(b []byte) int
+
+//go:noescape
+func validUTF8String(s string) int
+
+// ValidUTF8Prefix returns the length of a prefix of b that is valid
+// UTF-8 and ends at the start of a rune, found a block of bytes at a
+// time. It may stop short of the longest such prefix, so the caller
+// must check the rest of b itself.
+func ValidUTF8Prefix(b []byte) int {
+	if len(b) < 16 || !cpu.X86.HasSSSE3 {
+		return 0
+	}
+	return runeStartBefore(b, validUTF8(b))
+}
+
+// ValidUTF8PrefixString returns the length of a prefix of s that is
+// valid UTF-8 and ends at the start of a rune, found a block of bytes
+// at a time. It may stop short of the longest such prefix, so the
+// caller must check the rest of s itself.
+func ValidUTF8PrefixString(s string) int {
+	if len(s) < 16 || !cpu.X86.HasSSSE3 {
+		return 0
+	}
+	return runeStartBeforeString(s, validUTF8String(s))
+}
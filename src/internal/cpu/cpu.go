@@ -21,7 +21,9 @@ var CacheLineSize uintptr = CacheLinePadSize
 
 // The booleans in X86 contain the correspondingly named cpuid feature bit.
 // HasAVX and HasAVX2 are only set if the OS does support XMM and YMM registers
-// in addition to the cpuid feature bit being set.
+// in addition to the cpuid feature bit being set. Likewise HasAVX512F,
+// HasAVX512BW and HasAVX512VL are only set if the OS also supports the
+// opmask and ZMM registers.
 // The struct is padded to avoid false sharing.
 var X86 struct {
 	_            CacheLinePad
@@ -29,6 +31,9 @@ var X86 struct {
 	HasADX       bool
 	HasAVX       bool
 	HasAVX2      bool
+	HasAVX512F   bool
+	HasAVX512BW  bool
+	HasAVX512VL  bool
 	HasBMI1      bool
 	HasBMI2      bool
 	HasERMS      bool
@@ -64,6 +69,7 @@ var ARM64 struct {
 	HasCRC32     bool
 	HasATOMICS   bool
 	HasCPUID     bool
+	HasSVE       bool
 	IsNeoverseN1 bool
 	IsZeus       bool
 	_            CacheLinePad
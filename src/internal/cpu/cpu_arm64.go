@@ -15,6 +15,7 @@ func doinit() {
 		{Name: "crc32", Feature: &ARM64.HasCRC32},
 		{Name: "atomics", Feature: &ARM64.HasATOMICS},
 		{Name: "cpuid", Feature: &ARM64.HasCPUID},
+		{Name: "sve", Feature: &ARM64.HasSVE},
 		{Name: "isNeoverseN1", Feature: &ARM64.IsNeoverseN1},
 		{Name: "isZeus", Feature: &ARM64.IsZeus},
 	}
@@ -0,0 +1,33 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build arm64
+
+package cpu_test
+
+import (
+	. "internal/cpu"
+	"internal/godebug"
+	"testing"
+)
+
+func TestDisableSVE(t *testing.T) {
+	if !ARM64.HasSVE {
+		t.Skip("skipping test: SVE not supported")
+	}
+	runDebugOptionsTest(t, "TestSVEDebugOption", "cpu.sve=off")
+}
+
+func TestSVEDebugOption(t *testing.T) {
+	MustHaveDebugOptionsSupport(t)
+
+	if godebug.Get("cpu.sve") != "off" {
+		t.Skipf("skipping test: GODEBUG=cpu.sve=off not set")
+	}
+
+	want := false
+	if got := ARM64.HasSVE; got != want {
+		t.Errorf("ARM64.HasSVE expected %v, got %v", want, got)
+	}
+}
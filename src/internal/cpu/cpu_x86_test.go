@@ -37,3 +37,9 @@ func TestSSE3DebugOption(t *testing.T) {
 		t.Errorf("X86.HasSSE3 expected %v, got %v", want, got)
 	}
 }
+
+func TestX86ifAVX512BWhasAVX512F(t *testing.T) {
+	if (X86.HasAVX512BW || X86.HasAVX512VL) && !X86.HasAVX512F {
+		t.Fatalf("HasAVX512F expected true when HasAVX512BW or HasAVX512VL is true, got false")
+	}
+}
@@ -155,3 +155,25 @@ func TestIssue51617(t *testing.T) {
 		t.Errorf("got directories %v, want %v", saw, want)
 	}
 }
+
+func TestWalkDirSkipAll(t *testing.T) {
+	fsys := makeTree(t)
+	var saw []string
+	err := WalkDir(fsys, ".", func(path string, d DirEntry, err error) error {
+		if err != nil {
+			t.Fatal(err)
+		}
+		saw = append(saw, path)
+		if path == "testdata/d/x" {
+			return SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir returned %v, want nil", err)
+	}
+	want := []string{".", "testdata", "testdata/a", "testdata/b", "testdata/c", "testdata/d", "testdata/d/x"}
+	if !reflect.DeepEqual(saw, want) {
+		t.Errorf("got %v, want %v", saw, want)
+	}
+}
@@ -44,6 +44,18 @@ func ExampleClean() {
 	// Clean("") = "."
 }
 
+func ExampleDepth() {
+	fmt.Println(path.Depth("a/b/c"))
+	fmt.Println(path.Depth("/a//b/./c/"))
+	fmt.Println(path.Depth("a/b/../c"))
+	fmt.Println(path.Depth("/"))
+	// Output:
+	// 3
+	// 3
+	// 2
+	// 0
+}
+
 func ExampleDir() {
 	fmt.Println(path.Dir("/a/b/c"))
 	fmt.Println(path.Dir("a/b/c"))
@@ -60,6 +72,16 @@ func ExampleDir() {
 	// .
 }
 
+func ExampleEqualClean() {
+	fmt.Println(path.EqualClean("a//b/./c/", "a/b/c"))
+	fmt.Println(path.EqualClean("a/b/../c", "a/c"))
+	fmt.Println(path.EqualClean("/a/b", "a/b"))
+	// Output:
+	// true
+	// true
+	// false
+}
+
 func ExampleExt() {
 	fmt.Println(path.Ext("/a/b/c/bar.css"))
 	fmt.Println(path.Ext("/"))
@@ -70,11 +92,35 @@ func ExampleExt() {
 	//
 }
 
+func ExampleHasExt() {
+	fmt.Println(path.HasExt("/a/b/c/bar.css", ".js", ".css"))
+	fmt.Println(path.HasExt("/a/b/c/bar.CSS", ".js", ".css"))
+	fmt.Println(path.HasExtFold("/a/b/c/bar.CSS", ".js", ".css"))
+	// Output:
+	// true
+	// false
+	// true
+}
+
 func ExampleIsAbs() {
 	fmt.Println(path.IsAbs("/dev/null"))
 	// Output: true
 }
 
+func ExampleIsLocal() {
+	fmt.Println(path.IsLocal("a/b/c"))
+	fmt.Println(path.IsLocal("a/../b"))
+	fmt.Println(path.IsLocal("a/../../b"))
+	fmt.Println(path.IsLocal("/a/b"))
+	fmt.Println(path.IsLocal(""))
+	// Output:
+	// true
+	// true
+	// false
+	// false
+	// false
+}
+
 func ExampleJoin() {
 	fmt.Println(path.Join("a", "b", "c"))
 	fmt.Println(path.Join("a", "b/c"))
@@ -106,6 +152,16 @@ func ExampleMatch() {
 	// false <nil>
 }
 
+func ExampleMatchFold() {
+	fmt.Println(path.MatchFold("*.jpg", "IMG_0042.JPG"))
+	fmt.Println(path.MatchFold("Docs/[a-c]*", "docs/Budget.xlsx"))
+	fmt.Println(path.MatchFold("*.jpg", "photos/IMG_0042.JPG"))
+	// Output:
+	// true <nil>
+	// true <nil>
+	// false <nil>
+}
+
 func ExampleSplit() {
 	split := func(s string) {
 		dir, file := path.Split(s)
@@ -119,3 +175,21 @@ func ExampleSplit() {
 	// path.Split("myfile.css") = dir: "", file: "myfile.css"
 	// path.Split("") = dir: "", file: ""
 }
+
+func ExampleSplitN() {
+	head, rest := path.SplitN("example.com/mod/sub/pkg", 2)
+	fmt.Printf("head: %q, rest: %q\n", head, rest)
+	head, rest = path.SplitN("/static/css/site.css", 1)
+	fmt.Printf("head: %q, rest: %q\n", head, rest)
+	// Output:
+	// head: "example.com/mod", rest: "sub/pkg"
+	// head: "/static", rest: "css/site.css"
+}
+
+func ExampleTrimElementPrefix() {
+	fmt.Println(path.TrimElementPrefix("/static/css/site.css", "/static"))
+	fmt.Println(path.TrimElementPrefix("/staticfiles/site.css", "/static"))
+	// Output:
+	// css/site.css true
+	// /staticfiles/site.css false
+}
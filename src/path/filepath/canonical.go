@@ -0,0 +1,92 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package filepath
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Canonical returns the canonical form of path: an absolute path with
+// no symbolic links in which each element is spelled with the case in
+// which it is stored in its directory. On a case-insensitive file
+// system, paths that name the same file through different cases have
+// the same canonical form, so canonical forms can be compared with ==.
+// Canonical calls Clean on the result.
+//
+// On macOS and iOS, Canonical reads each directory along the resolved
+// path to find the stored case of the next element. On Windows,
+// EvalSymlinks already reports the stored case. Elsewhere, file systems
+// are assumed to be case-sensitive and Canonical is equivalent to Abs
+// followed by EvalSymlinks.
+func Canonical(path string) (string, error) {
+	p, err := Abs(path)
+	if err != nil {
+		return "", err
+	}
+	p, err = EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		p = storedCase(p)
+	}
+	return p, nil
+}
+
+// storedCase returns the clean, absolute path with each element
+// replaced by the entry of its directory that has the same name
+// under Unicode case folding, preferring an exact match. Elements
+// whose directory cannot be read or has no such entry are left as is.
+func storedCase(path string) string {
+	vol := VolumeName(path)
+	var b strings.Builder
+	b.Grow(len(path))
+	b.WriteString(path[:len(vol)])
+	rest := path[len(vol):]
+	if rest != "" && os.IsPathSeparator(rest[0]) {
+		b.WriteByte(Separator)
+		rest = rest[1:]
+	}
+	for rest != "" {
+		elem := rest
+		if i := strings.IndexByte(rest, Separator); i >= 0 {
+			elem, rest = rest[:i], rest[i+1:]
+		} else {
+			rest = ""
+		}
+		dir := b.String()
+		if dir == vol {
+			dir += "."
+		}
+		b.WriteString(storedName(dir, elem))
+		if rest != "" {
+			b.WriteByte(Separator)
+		}
+	}
+	return b.String()
+}
+
+// storedName returns the name of the entry of dir
+// that matches name, as described for storedCase.
+func storedName(dir, name string) string {
+	f, err := os.Open(dir)
+	if err != nil {
+		return name
+	}
+	names, _ := f.Readdirnames(-1)
+	f.Close()
+	found := name
+	for _, n := range names {
+		if n == name {
+			return name
+		}
+		if found == name && strings.EqualFold(n, name) {
+			found = n
+		}
+	}
+	return found
+}
@@ -18,3 +18,15 @@ func ExampleExt() {
 	// One dot: ".js"
 	// Two dots: ".js"
 }
+
+func ExampleReplaceExt() {
+	fmt.Printf("No dots: %q\n", filepath.ReplaceExt("index", ".html"))
+	fmt.Printf("One dot: %q\n", filepath.ReplaceExt("index.js", ".ts"))
+	fmt.Printf("Two dots: %q\n", filepath.ReplaceExt("main.test.js", ".ts"))
+	fmt.Printf("Dotfile: %q\n", filepath.ReplaceExt(".eslintrc", ".json"))
+	// Output:
+	// No dots: "index.html"
+	// One dot: "index.ts"
+	// Two dots: "main.test.ts"
+	// Dotfile: ".eslintrc.json"
+}
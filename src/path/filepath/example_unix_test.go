@@ -38,6 +38,27 @@ func ExampleRel() {
 	// "./b/c": "" Rel: can't make ./b/c relative to /a
 }
 
+func ExampleIsWithin() {
+	paths := []string{
+		"/srv/www/index.html",
+		"/srv/www/../secret",
+		"/srv/wwwdata",
+	}
+	root := "/srv/www"
+
+	fmt.Println("On Unix:")
+	for _, p := range paths {
+		rel, ok := filepath.IsWithin(root, p)
+		fmt.Printf("%q: %q %v\n", p, rel, ok)
+	}
+
+	// Output:
+	// On Unix:
+	// "/srv/www/index.html": "index.html" true
+	// "/srv/www/../secret": "" false
+	// "/srv/wwwdata": "" false
+}
+
 func ExampleSplit() {
 	paths := []string{
 		"/home/arnie/amelia.jpg",
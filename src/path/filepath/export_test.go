@@ -5,3 +5,5 @@
 package filepath
 
 var LstatP = &lstat
+
+var StoredCase = storedCase
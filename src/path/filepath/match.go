@@ -6,6 +6,7 @@ package filepath
 
 import (
 	"errors"
+	"io/fs"
 	"os"
 	"runtime"
 	"sort"
@@ -232,6 +233,202 @@ func getEsc(chunk string) (r rune, nchunk string, err error) {
 	return
 }
 
+// A Matcher is a compiled shell file name pattern, as accepted by
+// Match. A Matcher is safe for concurrent use by multiple goroutines.
+type Matcher struct {
+	pattern string
+	chunks  []matchChunkOps
+}
+
+// A matchChunkOps is a compiled chunk of a pattern: a sequence of
+// single-character operators, possibly preceded by a star.
+type matchChunkOps struct {
+	star bool
+	ops  []matchOp
+}
+
+// A matchOp is a literal string, a '?' or a character class.
+type matchOp struct {
+	kind    matchOpKind
+	lit     string      // for matchLiteral
+	negated bool        // for matchClass
+	ranges  []runeRange // for matchClass
+}
+
+type matchOpKind uint8
+
+const (
+	matchLiteral matchOpKind = iota
+	matchAny
+	matchClass
+)
+
+type runeRange struct {
+	lo, hi rune
+}
+
+// CompileMatch parses pattern, using the syntax described for Match,
+// and returns a Matcher that reports whether names match it. Matching
+// a name with a Matcher is equivalent to calling Match with pattern,
+// but avoids parsing pattern again for each name.
+//
+// The only possible returned error is ErrBadPattern, when pattern is
+// malformed. Unlike Match, which may stop examining pattern once a
+// name fails to match, CompileMatch checks the whole pattern.
+func CompileMatch(pattern string) (*Matcher, error) {
+	m := &Matcher{pattern: pattern}
+	for rest := pattern; len(rest) > 0; {
+		var c matchChunkOps
+		var chunk string
+		c.star, chunk, rest = scanChunk(rest)
+		for len(chunk) > 0 {
+			var op matchOp
+			var err error
+			op, chunk, err = compileOp(chunk)
+			if err != nil {
+				return nil, err
+			}
+			if n := len(c.ops); op.kind == matchLiteral && n > 0 && c.ops[n-1].kind == matchLiteral {
+				// Merge adjacent literals.
+				c.ops[n-1].lit += op.lit
+				continue
+			}
+			c.ops = append(c.ops, op)
+		}
+		m.chunks = append(m.chunks, c)
+	}
+	return m, nil
+}
+
+// compileOp parses the first single-character operator in chunk.
+func compileOp(chunk string) (op matchOp, rest string, err error) {
+	switch chunk[0] {
+	case '[':
+		op.kind = matchClass
+		chunk = chunk[1:]
+		if len(chunk) > 0 && chunk[0] == '^' {
+			op.negated = true
+			chunk = chunk[1:]
+		}
+		for {
+			if len(chunk) > 0 && chunk[0] == ']' && len(op.ranges) > 0 {
+				return op, chunk[1:], nil
+			}
+			var r runeRange
+			if r.lo, chunk, err = getEsc(chunk); err != nil {
+				return op, "", err
+			}
+			r.hi = r.lo
+			if chunk[0] == '-' {
+				if r.hi, chunk, err = getEsc(chunk[1:]); err != nil {
+					return op, "", err
+				}
+			}
+			op.ranges = append(op.ranges, r)
+		}
+
+	case '?':
+		op.kind = matchAny
+		return op, chunk[1:], nil
+
+	case '\\':
+		if runtime.GOOS != "windows" {
+			chunk = chunk[1:]
+			if len(chunk) == 0 {
+				return op, "", ErrBadPattern
+			}
+		}
+	}
+	op.kind = matchLiteral
+	op.lit = chunk[:1]
+	return op, chunk[1:], nil
+}
+
+// String returns the source pattern of m.
+func (m *Matcher) String() string {
+	return m.pattern
+}
+
+// Match reports whether name matches the pattern of m.
+func (m *Matcher) Match(name string) bool {
+	chunks := m.chunks
+Pattern:
+	for len(chunks) > 0 {
+		c := &chunks[0]
+		chunks = chunks[1:]
+		if c.star && len(c.ops) == 0 {
+			// Trailing * matches rest of string unless it has a /.
+			return !strings.Contains(name, string(Separator))
+		}
+		// Look for match at current position.
+		t, ok := c.match(name)
+		// if we're the last chunk, make sure we've exhausted the name
+		// otherwise we'll give a false result even if we could still match
+		// using the star
+		if ok && (len(t) == 0 || len(chunks) > 0) {
+			name = t
+			continue
+		}
+		if c.star {
+			// Look for match skipping i+1 bytes.
+			// Cannot skip /.
+			for i := 0; i < len(name) && name[i] != Separator; i++ {
+				t, ok := c.match(name[i+1:])
+				if ok {
+					// if we're the last chunk, make sure we exhausted the name
+					if len(chunks) == 0 && len(t) > 0 {
+						continue
+					}
+					name = t
+					continue Pattern
+				}
+			}
+		}
+		return false
+	}
+	return len(name) == 0
+}
+
+// match checks whether c, ignoring its star, matches the beginning of s.
+// If so, it returns the remainder of s (after the match).
+func (c *matchChunkOps) match(s string) (rest string, ok bool) {
+	for i := range c.ops {
+		op := &c.ops[i]
+		switch op.kind {
+		case matchLiteral:
+			if !strings.HasPrefix(s, op.lit) {
+				return "", false
+			}
+			s = s[len(op.lit):]
+
+		case matchAny:
+			if len(s) == 0 || s[0] == Separator {
+				return "", false
+			}
+			_, n := utf8.DecodeRuneInString(s)
+			s = s[n:]
+
+		case matchClass:
+			if len(s) == 0 {
+				return "", false
+			}
+			r, n := utf8.DecodeRuneInString(s)
+			s = s[n:]
+			match := false
+			for _, rr := range op.ranges {
+				if rr.lo <= r && r <= rr.hi {
+					match = true
+					break
+				}
+			}
+			if match == op.negated {
+				return "", false
+			}
+		}
+	}
+	return s, true
+}
+
 // Glob returns the names of all files matching pattern or nil
 // if there is no matching file. The syntax of patterns is the same
 // as in Match. The pattern may describe hierarchical names such as
@@ -241,15 +438,50 @@ func getEsc(chunk string) (r rune, nchunk string, err error) {
 // The only possible returned error is ErrBadPattern, when pattern
 // is malformed.
 func Glob(pattern string) (matches []string, err error) {
+	_, err = globSeq(pattern, func(m string) bool {
+		matches = append(matches, m)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// GlobSeq returns an iterator over the names of all files matching
+// pattern. It yields the same names in the same order as Glob, but
+// yields each name as soon as it is found instead of collecting them
+// all first, so that the caller can start work on early matches or
+// stop once it has seen enough. Iteration stops early if yield
+// returns false.
+//
+// If pattern is malformed, GlobSeq yields a single pair with an
+// empty name and ErrBadPattern. Like Glob, it ignores file system
+// errors such as I/O errors reading directories.
+func GlobSeq(pattern string) func(yield func(string, error) bool) {
+	return func(yield func(string, error) bool) {
+		_, err := globSeq(pattern, func(m string) bool {
+			return yield(m, nil)
+		})
+		if err != nil {
+			yield("", err)
+		}
+	}
+}
+
+// globSeq calls fn for each name matching pattern, in the order
+// Glob returns them. It stops and reports false if fn returns false.
+// If pattern is malformed, it returns ErrBadPattern before calling fn.
+func globSeq(pattern string, fn func(string) bool) (more bool, err error) {
 	// Check pattern is well-formed.
 	if _, err := Match(pattern, ""); err != nil {
-		return nil, err
+		return false, err
 	}
 	if !hasMeta(pattern) {
 		if _, err = os.Lstat(pattern); err != nil {
-			return nil, nil
+			return true, nil
 		}
-		return []string{pattern}, nil
+		return fn(pattern), nil
 	}
 
 	dir, file := Split(pattern)
@@ -261,26 +493,357 @@ func Glob(pattern string) (matches []string, err error) {
 	}
 
 	if !hasMeta(dir[volumeLen:]) {
-		return glob(dir, file, nil)
+		return glob(dir, file, fn)
 	}
 
 	// Prevent infinite recursion. See issue 15879.
 	if dir == pattern {
-		return nil, ErrBadPattern
+		return false, ErrBadPattern
 	}
 
-	var m []string
-	m, err = Glob(dir)
+	var globErr error
+	more, err = globSeq(dir, func(d string) bool {
+		more, globErr = glob(d, file, fn)
+		return more && globErr == nil
+	})
 	if err != nil {
-		return
+		return false, err
 	}
-	for _, d := range m {
-		matches, err = glob(d, file, matches)
-		if err != nil {
-			return
+	if globErr != nil {
+		return false, globErr
+	}
+	return more, nil
+}
+
+// GlobFS is like Glob but matches pattern against the names in fsys
+// instead of the operating system's file system, applying the same
+// rules as Glob on the host: the syntax of patterns is that of Match,
+// so that on Windows both '\' and '/' separate elements and '\' does
+// not escape, and pattern elements without magic characters are looked
+// up case-insensitively on systems whose file systems usually ignore
+// case (Windows, macOS, and iOS). This gives a file system built on
+// os.DirFS, such as an overlay, the same matches as the directory it
+// reflects.
+//
+// The pattern must be relative to the root of fsys and, like the names
+// accepted by fs.ValidPath, must not contain empty, ".", or ".." elements;
+// GlobFS returns ErrBadPattern otherwise. An escaped '/' does not
+// separate elements, and as no name in fsys contains one, it matches
+// nothing. The returned names are slash-separated paths that can be
+// passed to fsys.Open.
+// An element without magic characters is returned as spelled in
+// pattern if fs.Stat finds it that way, and otherwise with the case
+// of the directory entry it matched. Matches are in lexical order
+// within each directory.
+//
+// GlobFS ignores file system errors such as I/O errors reading
+// directories. The only possible returned error is ErrBadPattern,
+// when pattern is malformed.
+func GlobFS(fsys fs.FS, pattern string) (matches []string, err error) {
+	// Check pattern is well-formed.
+	if _, err := Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if pattern == "." {
+		return []string{"."}, nil
+	}
+	if runtime.GOOS == "windows" {
+		pattern = strings.ReplaceAll(pattern, `\`, "/")
+	}
+	elems := splitFSPattern(pattern)
+	for _, e := range elems {
+		if e == "" || e == "." || e == ".." {
+			return nil, ErrBadPattern
 		}
 	}
-	return
+	return globFSElems(fsys, ".", elems, nil), nil
+}
+
+// splitFSPattern splits the slash-separated pattern into its elements.
+// A '/' that is escaped or inside a character class belongs to the
+// element around it.
+func splitFSPattern(pattern string) []string {
+	var elems []string
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if runtime.GOOS != "windows" {
+				i++
+			}
+		case '[':
+			for i++; i < len(pattern) && pattern[i] != ']'; i++ {
+				if pattern[i] == '\\' && runtime.GOOS != "windows" {
+					i++
+				}
+			}
+		case '/':
+			elems = append(elems, pattern[start:i])
+			start = i + 1
+		}
+	}
+	return append(elems, pattern[start:])
+}
+
+// caseInsensitiveOS reports whether file systems on this system
+// usually ignore case.
+const caseInsensitiveOS = runtime.GOOS == "windows" || runtime.GOOS == "darwin" || runtime.GOOS == "ios"
+
+// globFSElems appends to matches the names in fsys below the directory
+// dir that match the pattern elements elems.
+func globFSElems(fsys fs.FS, dir string, elems []string, matches []string) []string {
+	if len(elems) == 0 {
+		return append(matches, dir)
+	}
+	e := elems[0]
+	if !hasMeta(e) {
+		name, ok := lookupFS(fsys, dir, e)
+		if ok && (len(elems) == 1 || isDirFS(fsys, name)) {
+			matches = globFSElems(fsys, name, elems[1:], matches)
+		}
+		return matches
+	}
+	dirs, _ := fs.ReadDir(fsys, dir)
+	for _, d := range dirs {
+		if ok, _ := Match(e, d.Name()); !ok {
+			continue
+		}
+		name := joinFS(dir, d.Name())
+		if len(elems) == 1 || isDirFS(fsys, name) {
+			matches = globFSElems(fsys, name, elems[1:], matches)
+		}
+	}
+	return matches
+}
+
+// lookupFS returns the name of the entry elem of dir in fsys,
+// ignoring case if caseInsensitiveOS is set.
+func lookupFS(fsys fs.FS, dir, elem string) (name string, ok bool) {
+	name = joinFS(dir, elem)
+	if _, err := fs.Stat(fsys, name); err == nil {
+		return name, true
+	}
+	if !caseInsensitiveOS {
+		return "", false
+	}
+	dirs, _ := fs.ReadDir(fsys, dir)
+	for _, d := range dirs {
+		if strings.EqualFold(d.Name(), elem) {
+			return joinFS(dir, d.Name()), true
+		}
+	}
+	return "", false
+}
+
+// isDirFS reports whether name is a directory in fsys.
+func isDirFS(fsys fs.FS, name string) bool {
+	fi, err := fs.Stat(fsys, name)
+	return err == nil && fi.IsDir()
+}
+
+// joinFS joins the slash-separated path dir and the element elem.
+func joinFS(dir, elem string) string {
+	if dir == "." {
+		return elem
+	}
+	return dir + "/" + elem
+}
+
+// GlobStar is like Glob but also understands two extensions to the
+// pattern syntax of Match, as popularized by shells and build tools:
+//
+//	'**'        as an entire path element, matches zero or more
+//	            directories; as a final element, matches every file
+//	            and directory below the preceding path
+//	'{' alt { ',' alt } '}'
+//	            matches any of the comma-separated alternatives,
+//	            which may themselves contain patterns and nested
+//	            braces; '{a,b}c' is equivalent to the union of
+//	            'ac' and 'bc'
+//
+// Elsewhere in an element, '**' is equivalent to '*'. Except on Windows,
+// where escaping is disabled, a brace or comma can be matched literally
+// by preceding it with '\'.
+//
+// GlobStar does not follow symbolic links to directories when matching
+// '**', so that traversal of cyclic trees terminates. Matches are
+// returned in lexical order without duplicates.
+//
+// GlobStar ignores file system errors such as I/O errors reading
+// directories. The only possible returned error is ErrBadPattern, when
+// pattern is malformed or its braces expand to more than 10000
+// alternatives.
+func GlobStar(pattern string) (matches []string, err error) {
+	alts, err := expandBraces(pattern, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, alt := range alts {
+		// Check pattern is well-formed.
+		if _, err := Match(alt, ""); err != nil {
+			return nil, err
+		}
+	}
+	for _, alt := range alts {
+		vol := VolumeName(alt)
+		dir, rest := vol, alt[len(vol):]
+		if rest != "" && os.IsPathSeparator(rest[0]) {
+			dir += string(Separator)
+		}
+		if dir == "" {
+			dir = "."
+		}
+		matches = globElems(dir, splitPattern(rest), matches)
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	sort.Strings(matches)
+	// Remove duplicates produced by overlapping alternatives
+	// or consecutive '**' elements.
+	out := matches[:1]
+	for _, m := range matches[1:] {
+		if m != out[len(out)-1] {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// maxBraceExpansions limits the number of alternatives
+// a GlobStar pattern may expand to.
+const maxBraceExpansions = 10000
+
+// expandBraces appends to alts the patterns described by the
+// brace alternations in pattern.
+func expandBraces(pattern string, alts []string) ([]string, error) {
+	open, depth := -1, 0
+	var parts []string
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if runtime.GOOS != "windows" {
+				i++
+			}
+		case '[':
+			// Braces and commas are literal within a character class.
+			for i++; i < len(pattern) && pattern[i] != ']'; i++ {
+				if pattern[i] == '\\' && runtime.GOOS != "windows" {
+					i++
+				}
+			}
+		case '{':
+			if depth == 0 {
+				open, start = i, i+1
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				parts = append(parts, pattern[start:i])
+				start = i + 1
+			}
+		case '}':
+			if depth == 0 {
+				// A stray closing brace is an ordinary character.
+				continue
+			}
+			if depth--; depth == 0 {
+				parts = append(parts, pattern[start:i])
+				prefix, suffix := pattern[:open], pattern[i+1:]
+				for _, p := range parts {
+					var err error
+					alts, err = expandBraces(prefix+p+suffix, alts)
+					if err != nil {
+						return nil, err
+					}
+				}
+				return alts, nil
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, ErrBadPattern
+	}
+	if len(alts) >= maxBraceExpansions {
+		return nil, ErrBadPattern
+	}
+	// Any escaped braces and commas left in pattern
+	// match literally, as for any other escaped character.
+	return append(alts, pattern), nil
+}
+
+// splitPattern splits the relative pattern into its non-empty
+// elements, collapsing consecutive '**' elements into one.
+func splitPattern(pattern string) []string {
+	var elems []string
+	for i := 0; i < len(pattern); {
+		j := i
+		for j < len(pattern) && !os.IsPathSeparator(pattern[j]) {
+			j++
+		}
+		if e := pattern[i:j]; e != "" {
+			if !(e == "**" && len(elems) > 0 && elems[len(elems)-1] == "**") {
+				elems = append(elems, e)
+			}
+		}
+		i = j + 1
+	}
+	return elems
+}
+
+// globElems appends to matches the names below the existing directory
+// dir that match the pattern elements elems.
+func globElems(dir string, elems []string, matches []string) []string {
+	if len(elems) == 0 {
+		return append(matches, dir)
+	}
+	e := elems[0]
+	if e == "**" {
+		if len(elems) == 1 {
+			// A final '**' matches everything below dir.
+			WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && path != dir {
+					matches = append(matches, path)
+				}
+				return nil
+			})
+			return matches
+		}
+		// Match zero directories, then one or more.
+		matches = globElems(dir, elems[1:], matches)
+		dirs, _ := readDir(dir)
+		for _, d := range dirs {
+			if d.IsDir() {
+				matches = globElems(Join(dir, d.Name()), elems, matches)
+			}
+		}
+		return matches
+	}
+	if !hasMeta(e) {
+		path := Join(dir, e)
+		if len(elems) == 1 {
+			if _, err := os.Lstat(path); err == nil {
+				matches = append(matches, path)
+			}
+		} else if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			matches = globElems(path, elems[1:], matches)
+		}
+		return matches
+	}
+	dirs, _ := readDir(dir)
+	for _, d := range dirs {
+		if ok, _ := Match(e, d.Name()); !ok {
+			continue
+		}
+		path := Join(dir, d.Name())
+		if len(elems) == 1 {
+			matches = append(matches, path)
+		} else if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			matches = globElems(path, elems[1:], matches)
+		}
+	}
+	return matches
 }
 
 // cleanGlobPath prepares path for glob matching.
@@ -316,37 +879,70 @@ func cleanGlobPathWindows(path string) (prefixLen int, cleaned string) {
 }
 
 // glob searches for files matching pattern in the directory dir
-// and appends them to matches. If the directory cannot be
-// opened, it returns the existing matches. New matches are
-// added in lexicographical order.
-func glob(dir, pattern string, matches []string) (m []string, e error) {
-	m = matches
+// and calls fn for each, in lexicographical order. It stops and
+// reports false if fn returns false. If the directory cannot be
+// opened, it reports true without calling fn.
+func glob(dir, pattern string, fn func(string) bool) (more bool, err error) {
 	fi, err := os.Stat(dir)
 	if err != nil {
-		return // ignore I/O error
+		return true, nil // ignore I/O error
 	}
 	if !fi.IsDir() {
-		return // ignore I/O error
+		return true, nil // ignore I/O error
 	}
 	d, err := os.Open(dir)
 	if err != nil {
-		return // ignore I/O error
+		return true, nil // ignore I/O error
 	}
-	defer d.Close()
-
 	names, _ := d.Readdirnames(-1)
+	d.Close()
 	sort.Strings(names)
 
 	for _, n := range names {
 		matched, err := Match(pattern, n)
 		if err != nil {
-			return m, err
+			return false, err
 		}
-		if matched {
-			m = append(m, Join(dir, n))
+		if matched && !fn(Join(dir, n)) {
+			return false, nil
 		}
 	}
-	return
+	return true, nil
+}
+
+// EscapePattern returns a pattern that matches s literally when used
+// with Match, Glob, or the other functions that accept the same
+// patterns, by escaping the magic characters '*', '?', '[', ']', and
+// '\' in s. Separators in s still separate elements.
+//
+// On Windows, where escaping is disabled and '\' is a separator,
+// EscapePattern instead encloses '*', '?', and '[' in a character
+// class, as in "[*]", and leaves the other characters unchanged.
+func EscapePattern(s string) string {
+	if !hasMeta(s) && !strings.Contains(s, "]") {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) + 4)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if runtime.GOOS == "windows" {
+			switch c {
+			case '*', '?', '[':
+				b.WriteByte('[')
+				b.WriteByte(c)
+				b.WriteByte(']')
+				continue
+			}
+		} else {
+			switch c {
+			case '*', '?', '[', ']', '\\':
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
 }
 
 // hasMeta reports whether path contains any of the magic characters
@@ -7,6 +7,7 @@ package filepath_test
 import (
 	"fmt"
 	"internal/testenv"
+	"io/fs"
 	"os"
 	. "path/filepath"
 	"reflect"
@@ -14,6 +15,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 type MatchTest struct {
@@ -107,6 +109,72 @@ func TestMatch(t *testing.T) {
 	}
 }
 
+func TestCompileMatch(t *testing.T) {
+	for _, tt := range matchTests {
+		pattern := tt.pattern
+		s := tt.s
+		if runtime.GOOS == "windows" {
+			if strings.Contains(pattern, "\\") {
+				// no escape allowed on windows.
+				continue
+			}
+			pattern = Clean(pattern)
+			s = Clean(s)
+		}
+		m, err := CompileMatch(pattern)
+		if err != nil {
+			if tt.err == nil {
+				// Match may not examine all of a malformed pattern.
+				if _, err1 := Match(pattern, ""); err1 == nil {
+					t.Errorf("CompileMatch(%#q) = %q, want nil", pattern, errp(err))
+				}
+			}
+			continue
+		}
+		if tt.err != nil {
+			t.Errorf("CompileMatch(%#q) succeeded, want %q", pattern, errp(tt.err))
+			continue
+		}
+		if ok := m.Match(s); ok != tt.match {
+			t.Errorf("CompileMatch(%#q).Match(%#q) = %v want %v", pattern, s, ok, tt.match)
+		}
+		if m.String() != pattern {
+			t.Errorf("CompileMatch(%#q).String() = %#q", pattern, m.String())
+		}
+	}
+
+	// Errors in parts of the pattern that Match does not reach.
+	for _, pattern := range []string{"a*[", "x[]", "*x\\"} {
+		if runtime.GOOS == "windows" && strings.Contains(pattern, "\\") {
+			continue
+		}
+		if _, err := CompileMatch(pattern); err != ErrBadPattern {
+			t.Errorf("CompileMatch(%#q) = %q, want %q", pattern, errp(err), errp(ErrBadPattern))
+		}
+	}
+}
+
+func TestCompileMatchMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+	m, err := CompileMatch("*.[ch]*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		m.Match("match_test.go")
+		m.Match("foo.c")
+	})
+	if allocs > 0 {
+		t.Errorf("Matcher.Match: %v allocs, want zero", allocs)
+	}
+}
+
 // contains reports whether vector contains the string s.
 func contains(vector []string, s string) bool {
 	for _, elem := range vector {
@@ -164,6 +232,154 @@ func TestGlobError(t *testing.T) {
 	}
 }
 
+func TestGlobSeq(t *testing.T) {
+	patterns := []string{"no_match", "../*/no_match", "../*/*.go", "[mp]*.go"}
+	for _, tt := range globTests {
+		patterns = append(patterns, tt.pattern)
+	}
+	for _, pattern := range patterns {
+		if runtime.GOOS == "windows" {
+			pattern = Clean(pattern)
+		}
+		want, err := Glob(pattern)
+		if err != nil {
+			t.Fatalf("Glob error for %q: %s", pattern, err)
+		}
+		var got []string
+		GlobSeq(pattern)(func(m string, err error) bool {
+			if err != nil {
+				t.Errorf("GlobSeq error for %q: %s", pattern, err)
+			}
+			got = append(got, m)
+			return true
+		})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GlobSeq(%#q) yielded %#v, want %#v", pattern, got, want)
+		}
+	}
+
+	// Stop after the first match.
+	n := 0
+	GlobSeq("../*/*.go")(func(m string, err error) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("GlobSeq yielded %d matches after stopping, want 1", n)
+	}
+
+	for _, pattern := range []string{`[]`, `nonexist/[]`, `../*/[]`} {
+		var errs []error
+		GlobSeq(pattern)(func(m string, err error) bool {
+			if m != "" {
+				t.Errorf("GlobSeq(%#q) yielded %q", pattern, m)
+			}
+			errs = append(errs, err)
+			return true
+		})
+		if len(errs) != 1 || errs[0] != ErrBadPattern {
+			t.Errorf("GlobSeq(%#q) yielded errors %v, want [ErrBadPattern]", pattern, errs)
+		}
+	}
+}
+
+type globFSTest struct {
+	pattern string
+	want    []string
+}
+
+func TestGlobFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/B.txt":   {},
+		"a/b/c.go":  {},
+		"a/b/d.go":  {},
+		"x/y":       {},
+		"Mixed/Dir": {Mode: fs.ModeDir},
+	}
+	tests := []globFSTest{
+		{"a/*", []string{"a/B.txt", "a/b"}},
+		{"*/b/*.go", []string{"a/b/c.go", "a/b/d.go"}},
+		{"a/b/c.go", []string{"a/b/c.go"}},
+		{"*/y", []string{"x/y"}},
+		{"?/[bB]*", []string{"a/B.txt", "a/b"}},
+		{"no_match", nil},
+		{"*/no_match", nil},
+		{"x/y/*", nil},
+		{".", []string{"."}},
+	}
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios":
+		tests = append(tests, []globFSTest{
+			{"A/b.TXT", []string{"a/B.txt"}},
+			{"mixed/*", []string{"Mixed/Dir"}},
+			{"*/B/c.go", []string{"a/b/c.go"}},
+		}...)
+	default:
+		tests = append(tests, []globFSTest{
+			{"A/b.TXT", nil},
+			{"mixed/*", nil},
+			{`a/\B.txt`, []string{"a/B.txt"}},
+			{`a\/b/c.go`, nil},
+			{`a[\/]b/c.go`, nil},
+		}...)
+	}
+	if runtime.GOOS == "windows" {
+		tests = append(tests, []globFSTest{
+			{`a\b\*.go`, []string{"a/b/c.go", "a/b/d.go"}},
+		}...)
+	}
+	for _, tt := range tests {
+		matches, err := GlobFS(fsys, tt.pattern)
+		if err != nil {
+			t.Errorf("GlobFS(%#q) error: %v", tt.pattern, err)
+			continue
+		}
+		if !reflect.DeepEqual(matches, tt.want) {
+			t.Errorf("GlobFS(%#q) = %q, want %q", tt.pattern, matches, tt.want)
+		}
+	}
+	for _, pattern := range []string{"a/[]", "/a", "a//b", "a/", "a/./b", "../a", "a/.."} {
+		if _, err := GlobFS(fsys, pattern); err != ErrBadPattern {
+			t.Errorf("GlobFS(%#q) returned err=%v, want ErrBadPattern", pattern, err)
+		}
+	}
+}
+
+func TestEscapePattern(t *testing.T) {
+	tests := []struct{ s, unix, windows string }{
+		{"", "", ""},
+		{"abc", "abc", "abc"},
+		{"a*b", `a\*b`, "a[*]b"},
+		{"a?b", `a\?b`, "a[?]b"},
+		{"[ab]", `\[ab\]`, "[[]ab]"},
+		{"a]b", `a\]b`, "a]b"},
+		{"a-^!b", "a-^!b", "a-^!b"},
+		{`a\b`, `a\\b`, `a\b`},
+		{"*/[x]/?", `\*/\[x\]/\?`, "[*]/[[]x]/[?]"},
+	}
+	for _, tt := range tests {
+		want := tt.unix
+		if runtime.GOOS == "windows" {
+			want = tt.windows
+		}
+		pattern := EscapePattern(tt.s)
+		if pattern != want {
+			t.Errorf("EscapePattern(%#q) = %#q, want %#q", tt.s, pattern, want)
+		}
+		if ok, err := Match(pattern, tt.s); !ok || err != nil {
+			t.Errorf("Match(%#q, %#q) = %v, %v, want true, nil", pattern, tt.s, ok, err)
+		}
+	}
+	for _, name := range []string{"a*b", "a?b", "[ab]"} {
+		pattern := EscapePattern(name)
+		for _, other := range []string{"axb", "a", "b"} {
+			if ok, _ := Match(pattern, other); ok {
+				t.Errorf("Match(%#q, %#q) = true, want false", pattern, other)
+			}
+		}
+	}
+}
+
 func TestGlobUNC(t *testing.T) {
 	// Just make sure this runs without crashing for now.
 	// See issue 15879.
@@ -371,3 +587,99 @@ func TestNonWindowsGlobEscape(t *testing.T) {
 		t.Fatalf("Glob(%#q) = %v want %v", pattern, matches, want)
 	}
 }
+
+func TestGlobStar(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		"a.go",
+		"b.txt",
+		"x/a.go",
+		"x/c.md",
+		"x/y/a.go",
+		"x/y/z/b.txt",
+		"w/a.go",
+		"w/{lit}.go",
+	} {
+		name = Join(root, FromSlash(name))
+		if err := os.MkdirAll(Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.go", []string{"a.go"}},
+		{"**/a.go", []string{"a.go", "w/a.go", "x/a.go", "x/y/a.go"}},
+		{"**/**/a.go", []string{"a.go", "w/a.go", "x/a.go", "x/y/a.go"}},
+		{"x/**/*.txt", []string{"x/y/z/b.txt"}},
+		{"x/**", []string{"x/a.go", "x/c.md", "x/y", "x/y/a.go", "x/y/z", "x/y/z/b.txt"}},
+		{"x/**.go", []string{"x/a.go"}},
+		{"*.{go,txt}", []string{"a.go", "b.txt"}},
+		{"{x,w}/a.go", []string{"w/a.go", "x/a.go"}},
+		{"{x/{y,},w}/a.go", []string{"w/a.go", "x/a.go", "x/y/a.go"}},
+		{"{a,*}.go", []string{"a.go"}},
+		{"**/*.{md,txt}", []string{"b.txt", "x/c.md", "x/y/z/b.txt"}},
+		{"[{]*", nil},
+		{"nonexistent/**", nil},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			pattern string
+			want    []string
+		}{`w/\{lit\}.go`, []string{"w/{lit}.go"}})
+	}
+
+	chdir(t, root)
+	for _, tt := range tests {
+		matches, err := GlobStar(FromSlash(tt.pattern))
+		if err != nil {
+			t.Errorf("GlobStar(%#q) error: %v", tt.pattern, err)
+			continue
+		}
+		var want []string
+		for _, w := range tt.want {
+			want = append(want, FromSlash(w))
+		}
+		if !reflect.DeepEqual(matches, want) {
+			t.Errorf("GlobStar(%#q) = %q, want %q", tt.pattern, matches, want)
+		}
+	}
+
+	// Absolute patterns return absolute matches.
+	matches, err := GlobStar(Join(root, "x", "**", "b.txt"))
+	if want := []string{Join(root, "x", "y", "z", "b.txt")}; err != nil || !reflect.DeepEqual(matches, want) {
+		t.Errorf("GlobStar(abs) = %q, %v, want %q", matches, err, want)
+	}
+}
+
+func TestGlobStarError(t *testing.T) {
+	bad := []string{`[]`, `nonexist/[]`, `{a,b`, `{a,{b}`, `**/[]`, `{[],a}`,
+		strings.Repeat("{a,b,c,d,e,f,g,h,i,j}", 5)}
+	for _, pattern := range bad {
+		if _, err := GlobStar(pattern); err != ErrBadPattern {
+			t.Errorf("GlobStar(%#q) returned err=%v, want ErrBadPattern", pattern, err)
+		}
+	}
+}
+
+func TestGlobStarSymlinkCycle(t *testing.T) {
+	testenv.MustHaveSymlink(t)
+
+	root := t.TempDir()
+	if err := os.Mkdir(Join(root, "a"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..", Join(root, "a", "up")); err != nil {
+		t.Fatal(err)
+	}
+	matches, err := GlobStar(Join(root, "**", "up"))
+	want := []string{Join(root, "a", "up")}
+	if err != nil || !reflect.DeepEqual(matches, want) {
+		t.Errorf("GlobStar = %q, %v, want %q", matches, err, want)
+	}
+}
@@ -83,10 +83,19 @@ const (
 // If the result of this process is an empty string, Clean
 // returns the string ".".
 //
+// On Windows, Clean does not modify a path beginning with `\\?\`,
+// because Windows passes the rest of such a path to the file system
+// without processing it. The same rules apply to other device paths,
+// such as `\\.\C:\a`, as to other paths, except that the volume name,
+// `\\.\C:`, cannot be removed by a .. element.
+//
 // See also Rob Pike, ``Lexical File Names in Plan 9 or
 // Getting Dot-Dot Right,''
 // https://9p.io/sys/doc/lexnames.html
 func Clean(path string) string {
+	if isVerbatim(path) {
+		return path
+	}
 	originalPath := path
 	volLen := volumeNameLen(path)
 	path = path[volLen:]
@@ -97,6 +106,51 @@ func Clean(path string) string {
 		}
 		return originalPath + "."
 	}
+	out := lazybuf{path: path, volAndPath: originalPath, volLen: volLen}
+	cleanTo(&out, path)
+	return FromSlash(out.string())
+}
+
+// AppendClean appends the cleaned form of path, as returned by Clean,
+// to dst and returns the extended buffer. It does not allocate if dst
+// has room for the result.
+func AppendClean(dst []byte, path string) []byte {
+	if isVerbatim(path) {
+		return append(dst, path...)
+	}
+	volLen := volumeNameLen(path)
+	vol, rest := path[:volLen], path[volLen:]
+	if rest == "" {
+		if volLen > 1 && path[1] != ':' {
+			// should be UNC
+			return appendFromSlash(dst, vol)
+		}
+		return append(append(dst, vol...), '.')
+	}
+	dst = appendFromSlash(dst, vol)
+	n := len(dst)
+	// The result is no longer than rest.
+	dst = append(dst, make([]byte, len(rest))...)
+	out := lazybuf{path: rest, buf: dst[n:]}
+	cleanTo(&out, rest)
+	return dst[:n+out.w]
+}
+
+// appendFromSlash appends path to dst, as FromSlash would return it.
+func appendFromSlash(dst []byte, path string) []byte {
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c == '/' {
+			c = Separator
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// cleanTo writes the cleaned form of path, which has no volume name,
+// to out. Only Separator is written between elements.
+func cleanTo(out *lazybuf, path string) {
 	rooted := os.IsPathSeparator(path[0])
 
 	// Invariants:
@@ -105,7 +159,6 @@ func Clean(path string) string {
 	//	dotdot is index in buf where .. must stop, either because
 	//		it is the leading slash or it is a leading ../../.. prefix.
 	n := len(path)
-	out := lazybuf{path: path, volAndPath: originalPath, volLen: volLen}
 	r, dotdot := 0, 0
 	if rooted {
 		out.append(Separator)
@@ -156,8 +209,6 @@ func Clean(path string) string {
 	if out.w == 0 {
 		out.append('.')
 	}
-
-	return FromSlash(out.string())
 }
 
 // ToSlash returns the result of replacing each separator character
@@ -183,11 +234,28 @@ func FromSlash(path string) string {
 // SplitList splits a list of paths joined by the OS-specific ListSeparator,
 // usually found in PATH or GOPATH environment variables.
 // Unlike strings.Split, SplitList returns an empty slice when passed an empty
-// string.
+// string. On Windows, SplitList removes double quotes, which protect
+// a ListSeparator within a path from splitting it; within double quotes,
+// two double quotes stand for one. SplitList is the inverse of JoinList.
 func SplitList(path string) []string {
 	return splitList(path)
 }
 
+// JoinList joins any number of paths into a list separated by the
+// OS-specific ListSeparator, such as the value of the PATH environment
+// variable. It is the inverse of SplitList: SplitList(JoinList(elems...))
+// returns elems.
+//
+// On Windows, JoinList encloses paths that contain the ListSeparator or
+// double quotes in double quotes, doubling the double quotes within them,
+// and encloses a single empty path in double quotes so that the list is
+// not empty. Elsewhere, a list cannot
+// represent paths that contain the ListSeparator or a single empty
+// path, and JoinList joins the paths without changing them.
+func JoinList(elems ...string) string {
+	return joinList(elems)
+}
+
 // Split splits path immediately following the final Separator,
 // separating it into a directory and file name component.
 // If there is no Separator in path, Split returns an empty dir
@@ -202,6 +270,36 @@ func Split(path string) (dir, file string) {
 	return path[:i+1], path[i+1:]
 }
 
+// SplitSeq returns an iterator over the components of path. It yields
+// the volume name, if any, then the leading Separator if the rest of
+// path is rooted, and then each element between separators, in order.
+// Empty elements produced by repeated separators are skipped, but the
+// elements are otherwise yielded as they appear in path: SplitSeq does
+// not call Clean, so "." and ".." elements are yielded too. Every value
+// yielded is a substring of path.
+//
+// Iteration stops early if yield returns false.
+func SplitSeq(path string) func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		vol := VolumeName(path)
+		if vol != "" && !yield(vol) {
+			return
+		}
+		rest := path[len(vol):]
+		if rest != "" && os.IsPathSeparator(rest[0]) {
+			if !yield(rest[:1]) {
+				return
+			}
+		}
+		for i := 0; ; {
+			var elem string
+			if elem, i = nextElem(rest, i); elem == "" || !yield(elem) {
+				return
+			}
+		}
+	}
+}
+
 // Join joins any number of path elements into a single path,
 // separating them with an OS specific Separator. Empty elements
 // are ignored. The result is Cleaned. However, if the argument
@@ -213,6 +311,71 @@ func Join(elem ...string) string {
 	return join(elem)
 }
 
+// joinClean joins the elements of elem with Separator, provided that
+// the result is already Clean, so that Join may skip calling Clean.
+// It is conservative and reports false for empty elements, volume names,
+// slashes on systems where Separator is not a slash, and any . or ..
+// elements other than a leading ../../.. prefix of the first element.
+func joinClean(elem []string) (string, bool) {
+	size := len(elem) - 1
+	for i, e := range elem {
+		if e == "" || volumeNameLen(e) > 0 {
+			return "", false
+		}
+		size += len(e)
+		r := 0
+		if os.IsPathSeparator(e[0]) {
+			// Only the first element may be rooted.
+			if i > 0 || e[0] != Separator {
+				return "", false
+			}
+			if len(e) == 1 {
+				// The root already ends in a separator.
+				size--
+				continue
+			}
+			r = 1
+		}
+		dotdot := i == 0 && r == 0
+		for r < len(e) {
+			s := r
+			for r < len(e) && !os.IsPathSeparator(e[r]) {
+				r++
+			}
+			if r < len(e) && e[r] != Separator {
+				// A slash that Clean would replace.
+				return "", false
+			}
+			switch e[s:r] {
+			case "", ".":
+				return "", false
+			case "..":
+				if !dotdot {
+					return "", false
+				}
+			default:
+				dotdot = false
+			}
+			if r++; r == len(e) {
+				// Trailing separator.
+				return "", false
+			}
+		}
+	}
+	if len(elem) == 1 {
+		return elem[0], true
+	}
+	var b strings.Builder
+	b.Grow(size)
+	for i, e := range elem {
+		if i > 0 && !(i == 1 && len(elem[0]) == 1 && elem[0][0] == Separator) {
+			b.WriteByte(Separator)
+		}
+		b.WriteString(e)
+	}
+	return b.String(), true
+}
+
 // Ext returns the file name extension used by path.
 // The extension is the suffix beginning at the final dot
 // in the final element of path; it is empty if there is
@@ -226,13 +389,63 @@ func Ext(path string) string {
 	return ""
 }
 
+// TrimExt returns path without the extension of its final element.
+// The extension is the suffix beginning at the final dot in the final
+// element, as for Ext, except that a dot preceded in the element only
+// by other dots does not begin an extension, so that names such as
+// ".bashrc" and ".." are returned unchanged.
+func TrimExt(path string) string {
+	return path[:extIndex(path)]
+}
+
+// ReplaceExt returns path with the extension of its final element, as
+// defined by TrimExt, replaced by newExt, or with newExt appended if
+// the final element has no extension. newExt usually begins with a dot;
+// if newExt is empty, ReplaceExt is equivalent to TrimExt.
+func ReplaceExt(path, newExt string) string {
+	return path[:extIndex(path)] + newExt
+}
+
+// extIndex returns the index in path of the extension
+// described by TrimExt, or len(path) if there is none.
+func extIndex(path string) int {
+	dot := -1
+	for i := len(path) - 1; i >= 0 && !os.IsPathSeparator(path[i]); i-- {
+		if path[i] != '.' {
+			if dot >= 0 {
+				return dot
+			}
+		} else if dot < 0 {
+			dot = i
+		}
+	}
+	return len(path)
+}
+
 // EvalSymlinks returns the path name after the evaluation of any symbolic
 // links.
 // If path is relative the result will be relative to the current directory,
 // unless one of the components is an absolute symbolic link.
 // EvalSymlinks calls Clean on the result.
+//
+// To resolve many paths that share directories, use a SymlinkCache.
 func EvalSymlinks(path string) (string, error) {
-	return evalSymlinks(path)
+	return evalSymlinks(path, nil)
+}
+
+// EvalSymlinksPartial is like EvalSymlinks but does not fail if a
+// component of path does not exist. Instead, it evaluates the symbolic
+// links in the leading components that do exist and returns the result
+// as resolved, and returns the remainder of the path, beginning with
+// the first missing component, as unresolved. Join(resolved, unresolved)
+// names the file that path would name once the missing components are
+// created. If every component exists, unresolved is empty and resolved
+// is the result of EvalSymlinks.
+//
+// Errors other than a missing component, such as a component that is
+// not a directory, are returned as by EvalSymlinks.
+func EvalSymlinksPartial(path string) (resolved, unresolved string, err error) {
+	return evalSymlinksPartial(path)
 }
 
 // Abs returns an absolute representation of path.
@@ -244,6 +457,27 @@ func Abs(path string) (string, error) {
 	return abs(path)
 }
 
+// AbsFrom is like Abs but joins a relative path with wd instead of
+// the current working directory, so it does not need to ask the
+// operating system for the working directory. wd must be absolute.
+// On Windows, a path such as `C:a` that is relative to the working
+// directory of a drive other than the one wd is on cannot be made
+// absolute and results in an error.
+// AbsFrom calls Clean on the result.
+func AbsFrom(wd, path string) (string, error) {
+	if !IsAbs(wd) {
+		return "", errors.New("AbsFrom: working directory " + wd + " is not absolute")
+	}
+	return absFrom(wd, path)
+}
+
+func unixAbsFrom(wd, path string) (string, error) {
+	if IsAbs(path) {
+		return Clean(path), nil
+	}
+	return Join(wd, path), nil
+}
+
 func unixAbs(path string) (string, error) {
 	if IsAbs(path) {
 		return Clean(path), nil
@@ -262,15 +496,170 @@ func unixAbs(path string) (string, error) {
 // even if basepath and targpath share no elements.
 // An error is returned if targpath can't be made relative to basepath or if
 // knowing the current working directory would be necessary to compute it.
+// If the paths are on different volumes, the error is a *RelVolumeError.
 // Rel calls Clean on the result.
 func Rel(basepath, targpath string) (string, error) {
+	return rel(basepath, targpath, sameWord)
+}
+
+// RelFold is like Rel but compares the elements and volume names of
+// basepath and targpath without regard to case, under Unicode case
+// folding, as is appropriate for case-insensitive file systems such as
+// the defaults on Windows and macOS. Elements of the result that come
+// from targpath keep their case.
+func RelFold(basepath, targpath string) (string, error) {
+	return rel(basepath, targpath, strings.EqualFold)
+}
+
+// IsWithin reports whether path names root or a file lexically beneath
+// root and, if so, returns path relative to root, as Rel would. Both
+// paths are cleaned first, and their volume names and elements are
+// compared the way the operating system does; on Windows this means
+// without regard to case, and '/' and '\' are equivalent.
+//
+// IsWithin is purely lexical: it does not consult the file system, so
+// it does not account for symbolic links. A relative path is never
+// within an absolute root and vice versa, and ok is false whenever
+// knowing the current working directory would be needed to decide.
+func IsWithin(root, path string) (rel string, ok bool) {
+	rel, err := Rel(root, path)
+	if err != nil {
+		return "", false
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// JoinRoot joins any number of path elements to root, as Join does,
+// and reports an error if the result is not root or lexically beneath
+// it. This happens if an element is absolute or has a volume name, or
+// if ".." elements lead out of root. On Unix and Plan 9, this rejects
+// every element beginning with a Separator. On Windows, an element
+// such as `\a`, which begins with a Separator but has no volume name,
+// is not absolute and is joined as a relative path, while an element
+// that names a reserved device, such as NUL, is absolute and rejected.
+//
+// Like IsWithin, JoinRoot does not consult the file system, so the
+// result may still refer to a file outside root through a symbolic
+// link beneath it.
+func JoinRoot(root string, elem ...string) (string, error) {
+	for _, e := range elem {
+		if IsAbs(e) || VolumeName(e) != "" {
+			return "", errors.New("JoinRoot: " + e + " is not a relative path")
+		}
+	}
+	root = Clean(root)
+	p := Join(append([]string{root}, elem...)...)
+	if _, ok := IsWithin(root, p); !ok {
+		return "", errors.New("JoinRoot: " + p + " is outside " + root)
+	}
+	return p, nil
+}
+
+// A RelVolumeError is returned by Rel and RelFold when the target path
+// cannot be made relative to the base path because the two are on
+// different volumes, which can only happen on Windows.
+type RelVolumeError struct {
+	Base, Targ             string // the arguments to Rel
+	BaseVolume, TargVolume string // their volume names
+}
+
+func (e *RelVolumeError) Error() string {
+	return "Rel: can't make " + e.Targ + " relative to " + e.Base
+}
+
+// RelOrTarget is like Rel, except that if basepath and targpath are on
+// different volumes it returns the cleaned targpath instead of an error,
+// as is appropriate when a path is being shortened for display or
+// storage. The result is then absolute, unless targpath is relative
+// to the working directory of its drive, as in `D:a`.
+func RelOrTarget(basepath, targpath string) (string, error) {
+	rel, err := Rel(basepath, targpath)
+	if _, ok := err.(*RelVolumeError); ok {
+		return Clean(targpath), nil
+	}
+	return rel, err
+}
+
+// HasPrefixFold reports whether the path begins with the elements of
+// prefix, comparing volume names and elements without regard to case,
+// under Unicode case folding. Unlike strings.HasPrefix, it respects
+// element boundaries: "/A/b" has the prefix "/a", but "/ab" does not.
+// Repeated and trailing separators are ignored, and on Windows '/' and
+// '\' are equivalent, but a rooted path never has a relative prefix or
+// vice versa. An empty prefix is a prefix of every path.
+//
+// HasPrefixFold is purely lexical and does not call Clean, so "." and
+// ".." elements are compared like any other; clean both paths first
+// if they may contain such elements.
+func HasPrefixFold(path, prefix string) bool {
+	if prefix == "" {
+		return true
+	}
+	vol := VolumeName(path)
+	pvol := VolumeName(prefix)
+	if !strings.EqualFold(vol, pvol) {
+		return false
+	}
+	path, prefix = path[len(vol):], prefix[len(pvol):]
+	if prefix == "" {
+		return true
+	}
+	rooted := path != "" && os.IsPathSeparator(path[0])
+	if rooted != os.IsPathSeparator(prefix[0]) {
+		return false
+	}
+	i, j := 0, 0
+	for {
+		var e, pe string
+		pe, j = nextElem(prefix, j)
+		if pe == "" {
+			return true
+		}
+		e, i = nextElem(path, i)
+		if !strings.EqualFold(e, pe) {
+			return false
+		}
+	}
+}
+
+// nextElem returns the element of path that begins at or after i,
+// skipping any separators, and the index just past it. It returns
+// an empty element at the end of path.
+func nextElem(path string, i int) (elem string, next int) {
+	for i < len(path) && os.IsPathSeparator(path[i]) {
+		i++
+	}
+	j := i
+	for j < len(path) && !os.IsPathSeparator(path[j]) {
+		j++
+	}
+	return path[i:j], j
+}
+
+// rel implements Rel and RelFold, comparing elements with equal.
+func rel(basepath, targpath string, equal func(a, b string) bool) (string, error) {
 	baseVol := VolumeName(basepath)
 	targVol := VolumeName(targpath)
 	base := Clean(basepath)
 	targ := Clean(targpath)
-	if sameWord(targ, base) {
+	if equal(targ, base) {
 		return ".", nil
 	}
+	// Fast path: targ is inside base, as in the common case of
+	// computing a path relative to an enclosing directory.
+	if baseVol == "" && targVol == "" && base != "." && len(targ) > len(base) &&
+		equal(targ[:len(base)], base) {
+		if base[len(base)-1] == Separator {
+			// base is the root.
+			return targ[len(base):], nil
+		}
+		if targ[len(base)] == Separator {
+			return targ[len(base)+1:], nil
+		}
+	}
 	base = base[len(baseVol):]
 	targ = targ[len(targVol):]
 	if base == "." {
@@ -279,13 +668,30 @@ func Rel(basepath, targpath string) (string, error) {
 		// Treat any targetpath matching `\\host\share` basepath as absolute path.
 		base = string(Separator)
 	}
+	if targ == "" && volumeNameLen(targVol) > 2 /* isUNC */ {
+		// Likewise for a `\\host\share` targpath.
+		targ = string(Separator)
+	}
 
+	if !equal(baseVol, targVol) {
+		return "", &RelVolumeError{
+			Base:       basepath,
+			Targ:       targpath,
+			BaseVolume: baseVol,
+			TargVolume: targVol,
+		}
+	}
 	// Can't use IsAbs - `\a` and `a` are both relative in Windows.
 	baseSlashed := len(base) > 0 && base[0] == Separator
 	targSlashed := len(targ) > 0 && targ[0] == Separator
-	if baseSlashed != targSlashed || !sameWord(baseVol, targVol) {
+	if baseSlashed != targSlashed {
 		return "", errors.New("Rel: can't make " + targpath + " relative to " + basepath)
 	}
+	if equal(targ, base) {
+		// As for `\\host\share` and `\\host\share\`, which differ
+		// only before the volume names were removed.
+		return ".", nil
+	}
 	// Position base[b0:bi] and targ[t0:ti] at the first differing elements.
 	bl := len(base)
 	tl := len(targ)
@@ -297,7 +703,7 @@ func Rel(basepath, targpath string) (string, error) {
 		for ti < tl && targ[ti] != Separator {
 			ti++
 		}
-		if !sameWord(targ[t0:ti], base[b0:bi]) {
+		if !equal(targ[t0:ti], base[b0:bi]) {
 			break
 		}
 		if bi < bl {
@@ -340,6 +746,11 @@ func Rel(basepath, targpath string) (string, error) {
 // as an error by any function.
 var SkipDir error = fs.SkipDir
 
+// SkipAll is used as a return value from WalkFuncs to indicate that
+// all remaining files and directories are to be skipped. It is not returned
+// as an error by any function.
+var SkipAll error = fs.SkipAll
+
 // WalkFunc is the type of the function called by Walk to visit each
 // file or directory.
 //
@@ -358,8 +769,9 @@ var SkipDir error = fs.SkipDir
 // The error result returned by the function controls how Walk continues.
 // If the function returns the special value SkipDir, Walk skips the
 // current directory (path if info.IsDir() is true, otherwise path's
-// parent directory). Otherwise, if the function returns a non-nil error,
-// Walk stops entirely and returns that error.
+// parent directory). If the function returns the special value SkipAll,
+// Walk skips all remaining files and directories. Otherwise, if the function
+// returns a non-nil error, Walk stops entirely and returns that error.
 //
 // The err argument reports an error related to path, signaling that Walk
 // will not walk into that directory. The function can decide how to
@@ -381,8 +793,47 @@ type WalkFunc func(path string, info fs.FileInfo, err error) error
 
 var lstat = os.Lstat // for testing
 
-// walkDir recursively descends path, calling walkDirFn.
-func walkDir(path string, d fs.DirEntry, walkDirFn fs.WalkDirFunc) error {
+// A dirWalker holds the settings of a walk by walkDir.
+type dirWalker struct {
+	fn       fs.WalkDirFunc
+	read     func(string) ([]fs.DirEntry, error) // reads a directory
+	maxDepth int                                 // if positive, the depth of the deepest entries visited
+
+	// If follow is set, symbolic links to directories are walked
+	// and active holds the directories being walked, to break cycles.
+	follow bool
+	active map[fileKey]bool
+}
+
+// A fileKey identifies a directory by device and inode number,
+// or their equivalents on systems that lack them.
+type fileKey struct {
+	dev, ino uint64
+}
+
+// followLink returns the entry that w.walkDir should visit in place of d,
+// the entry for the symbolic link path. If path refers to a directory
+// that is not already being walked, followLink returns an entry for that
+// directory, under the name of the link. Otherwise it returns d.
+func (w *dirWalker) followLink(path string, d fs.DirEntry) fs.DirEntry {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return d
+	}
+	d1 := &statDirEntry{info}
+	if key, ok := dirKey(path, d1); ok && w.active[key] {
+		return d
+	}
+	return d1
+}
+
+// walkDir recursively descends path, which is depth levels below
+// the root of the walk, calling w.fn.
+func (w *dirWalker) walkDir(path string, d fs.DirEntry, depth int) error {
+	walkDirFn := w.fn
+	if w.follow && d.Type()&fs.ModeSymlink != 0 {
+		d = w.followLink(path, d)
+	}
 	if err := walkDirFn(path, d, nil); err != nil || !d.IsDir() {
 		if err == SkipDir && d.IsDir() {
 			// Successfully skipped directory.
@@ -390,8 +841,18 @@ func walkDir(path string, d fs.DirEntry, walkDirFn fs.WalkDirFunc) error {
 		}
 		return err
 	}
+	if w.maxDepth > 0 && depth >= w.maxDepth {
+		// Entries of path would be too deep.
+		return nil
+	}
+	if w.follow {
+		if key, ok := dirKey(path, d); ok {
+			w.active[key] = true
+			defer delete(w.active, key)
+		}
+	}
 
-	dirs, err := readDir(path)
+	dirs, err := w.read(path)
 	if err != nil {
 		// Second call, to report ReadDir error.
 		err = walkDirFn(path, d, err)
@@ -405,7 +866,7 @@ func walkDir(path string, d fs.DirEntry, walkDirFn fs.WalkDirFunc) error {
 
 	for _, d1 := range dirs {
 		path1 := Join(path, d1.Name())
-		if err := walkDir(path1, d1, walkDirFn); err != nil {
+		if err := w.walkDir(path1, d1, depth+1); err != nil {
 			if err == SkipDir {
 				break
 			}
@@ -465,13 +926,105 @@ func walk(path string, info fs.FileInfo, walkFn WalkFunc) error {
 //
 // WalkDir does not follow symbolic links.
 func WalkDir(root string, fn fs.WalkDirFunc) error {
-	info, err := os.Lstat(root)
+	return walkDirRoot(root, &dirWalker{fn: fn, read: readDir})
+}
+
+// WalkDirUnsorted is like WalkDir but visits the entries of each
+// directory in the order the operating system returns them instead of
+// in lexical order. This avoids sorting each directory, which can be a
+// significant part of the cost of walking large directories, but the
+// order of the walk is unspecified and may differ between calls.
+func WalkDirUnsorted(root string, fn fs.WalkDirFunc) error {
+	return walkDirRoot(root, &dirWalker{fn: fn, read: readDirUnsorted})
+}
+
+// WalkDirOptions controls the behavior of WalkDirWithOptions.
+// The zero value walks exactly like WalkDir.
+type WalkDirOptions struct {
+	// IgnorePermissionErrors skips files and directories that cannot
+	// be examined or read because permission is denied, instead of
+	// reporting the error to the walk function.
+	IgnorePermissionErrors bool
+
+	// IgnoreNotExistErrors skips files and directories that are
+	// removed while the walk is in progress, instead of reporting
+	// the error to the walk function.
+	IgnoreNotExistErrors bool
+
+	// OnSkip, if non-nil, is called with the path and error of each
+	// file or directory skipped because of an ignored error.
+	OnSkip func(path string, err error)
+
+	// MaxDepth, if positive, limits the walk to entries at most
+	// MaxDepth levels below root: the entries of root are at depth 1.
+	// Directories at depth MaxDepth are passed to the walk function
+	// but are not read, as if it had returned SkipDir for them.
+	MaxDepth int
+
+	// FollowSymlinks walks the directories that symbolic links refer
+	// to, including root, as if they were the directories themselves.
+	// Files in such a directory are reported under the path of the
+	// link, and the link itself is passed to the walk function as a
+	// directory. Links to directories that are already being walked,
+	// which would otherwise make the walk loop forever, are identified
+	// by device and inode number and reported as symbolic links
+	// without being followed, as are links that cannot be resolved
+	// and links to files other than directories.
+	FollowSymlinks bool
+}
+
+// ignore reports whether the walk should skip path because of err.
+func (o *WalkDirOptions) ignore(path string, err error) bool {
+	if !(o.IgnorePermissionErrors && errors.Is(err, fs.ErrPermission) ||
+		o.IgnoreNotExistErrors && errors.Is(err, fs.ErrNotExist)) {
+		return false
+	}
+	if o.OnSkip != nil {
+		o.OnSkip(path, err)
+	}
+	return true
+}
+
+// WalkDirWithOptions is like WalkDir but walks as directed by opts.
+//
+// If opts says to ignore an error that prevents reading a directory,
+// the directory is skipped, as if fn had returned SkipDir from the
+// second call for the directory, without fn being called with the
+// error. fn has already been called for the directory itself.
+func WalkDirWithOptions(root string, fn fs.WalkDirFunc, opts WalkDirOptions) error {
+	walkFn := fn
+	if opts.IgnorePermissionErrors || opts.IgnoreNotExistErrors {
+		walkFn = func(path string, d fs.DirEntry, err error) error {
+			if err != nil && opts.ignore(path, err) {
+				if d == nil {
+					// The root could not be examined.
+					return nil
+				}
+				return SkipDir
+			}
+			return fn(path, d, err)
+		}
+	}
+	w := &dirWalker{fn: walkFn, read: readDir, maxDepth: opts.MaxDepth}
+	if opts.FollowSymlinks {
+		w.follow = true
+		w.active = make(map[fileKey]bool)
+	}
+	return walkDirRoot(root, w)
+}
+
+func walkDirRoot(root string, w *dirWalker) error {
+	stat := os.Lstat
+	if w.follow {
+		stat = os.Stat
+	}
+	info, err := stat(root)
 	if err != nil {
-		err = fn(root, nil, err)
+		err = w.fn(root, nil, err)
 	} else {
-		err = walkDir(root, &statDirEntry{info}, fn)
+		err = w.walkDir(root, &statDirEntry{info}, 0)
 	}
-	if err == SkipDir {
+	if err == SkipDir || err == SkipAll {
 		return nil
 	}
 	return err
@@ -507,7 +1060,7 @@ func Walk(root string, fn WalkFunc) error {
 	} else {
 		err = walk(root, info, fn)
 	}
-	if err == SkipDir {
+	if err == SkipDir || err == SkipAll {
 		return nil
 	}
 	return err
@@ -516,6 +1069,17 @@ func Walk(root string, fn WalkFunc) error {
 // readDir reads the directory named by dirname and returns
 // a sorted list of directory entries.
 func readDir(dirname string) ([]fs.DirEntry, error) {
+	dirs, err := readDirUnsorted(dirname)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name() < dirs[j].Name() })
+	return dirs, nil
+}
+
+// readDirUnsorted reads the directory named by dirname and returns
+// its directory entries in directory order.
+func readDirUnsorted(dirname string) ([]fs.DirEntry, error) {
 	f, err := os.Open(dirname)
 	if err != nil {
 		return nil, err
@@ -525,7 +1089,6 @@ func readDir(dirname string) ([]fs.DirEntry, error) {
 	if err != nil {
 		return nil, err
 	}
-	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name() < dirs[j].Name() })
 	return dirs, nil
 }
 
@@ -558,13 +1121,9 @@ func Base(path string) string {
 		path = path[0 : len(path)-1]
 	}
 	// Throw away volume name
-	path = path[len(VolumeName(path)):]
+	path = path[volumeNameLen(path):]
 	// Find the last element
-	i := len(path) - 1
-	for i >= 0 && !os.IsPathSeparator(path[i]) {
-		i--
-	}
-	if i >= 0 {
+	if i := lastSlash(path); i >= 0 {
 		path = path[i+1:]
 	}
 	// If empty now, it had only slashes.
@@ -582,21 +1141,77 @@ func Base(path string) string {
 // The returned path does not end in a separator unless it is the root directory.
 func Dir(path string) string {
 	vol := VolumeName(path)
-	i := len(path) - 1
-	for i >= len(vol) && !os.IsPathSeparator(path[i]) {
-		i--
-	}
+	i := len(vol) + lastSlash(path[len(vol):])
 	dir := Clean(path[len(vol) : i+1])
 	if dir == "." && len(vol) > 2 {
 		// must be UNC
 		return vol
 	}
+	// If path was clean, vol and dir are its prefix:
+	// return that rather than allocating a copy.
+	if strings.HasPrefix(path, vol) && strings.HasPrefix(path[len(vol):], dir) {
+		return path[:len(vol)+len(dir)]
+	}
 	return vol + dir
 }
 
+// lastSlash returns the index of the last path separator in s,
+// or -1 if there is none.
+func lastSlash(s string) int {
+	if Separator == '/' {
+		return strings.LastIndexByte(s, '/')
+	}
+	i := len(s) - 1
+	for i >= 0 && !os.IsPathSeparator(s[i]) {
+		i--
+	}
+	return i
+}
+
+// errInvalidPath is returned by Localize for paths that cannot be
+// converted to operating system paths.
+var errInvalidPath = errors.New("invalid path")
+
+// Localize converts a slash-separated path, such as one accepted by
+// io/fs, into an operating system path. The path must be valid as
+// reported by fs.ValidPath, so it is never absolute and never contains
+// ".." elements.
+//
+// Localize returns an error if the path cannot be represented on the
+// operating system. For example, on Windows the path a\b is rejected,
+// because '\' is a separator there and cannot be part of a file name,
+// as are reserved device names such as NUL and COM1, with or without an
+// extension, and elements ending in a dot or space, which Windows would
+// silently strip. The result always names a file within the directory
+// it is interpreted relative to.
+func Localize(path string) (string, error) {
+	if !fs.ValidPath(path) {
+		return "", errInvalidPath
+	}
+	return localize(path)
+}
+
+// SplitVolume splits path into its leading volume name and the rest
+// of the path, so that path = volume+rest. On Windows, the volume name
+// is one of
+//
+//	a drive letter and colon        C:
+//	a UNC host and share            \\host\share
+//	a device path and its device    \\.\C:, \\?\C:, \\.\pipe
+//	a device UNC host and share     \\?\UNC\host\share
+//
+// in which either kind of slash may be used. A separator that follows
+// the volume name is part of rest, not volume. On other platforms,
+// volume is always empty.
+func SplitVolume(path string) (volume, rest string) {
+	n := volumeNameLen(path)
+	return path[:n], path[n:]
+}
+
 // VolumeName returns leading volume name.
 // Given "C:\foo\bar" it returns "C:" on Windows.
 // Given "\\host\share\foo" it returns "\\host\share".
+// Given "\\.\C:\foo" or "\\?\C:\foo" it returns "\\.\C:" or "\\?\C:".
 // On other platforms it returns "".
 func VolumeName(path string) string {
 	return path[:volumeNameLen(path)]
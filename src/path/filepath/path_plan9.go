@@ -4,19 +4,37 @@
 
 package filepath
 
-import "strings"
+import (
+	"io/fs"
+	"strings"
+	"syscall"
+)
 
 // IsAbs reports whether the path is absolute.
 func IsAbs(path string) bool {
 	return strings.HasPrefix(path, "/") || strings.HasPrefix(path, "#")
 }
 
+func localize(path string) (string, error) {
+	if path[0] == '#' || strings.IndexByte(path, 0) >= 0 {
+		// A leading # names a kernel device.
+		return "", errInvalidPath
+	}
+	return path, nil
+}
+
 // volumeNameLen returns length of the leading volume name on Windows.
 // It returns 0 elsewhere.
 func volumeNameLen(path string) int {
 	return 0
 }
 
+// isVerbatim reports whether path must not be cleaned. It is always
+// false except on Windows.
+func isVerbatim(path string) bool {
+	return false
+}
+
 // HasPrefix exists for historical compatibility and should not be used.
 //
 // Deprecated: HasPrefix does not respect path boundaries and
@@ -32,14 +50,25 @@ func splitList(path string) []string {
 	return strings.Split(path, string(ListSeparator))
 }
 
+func joinList(elems []string) string {
+	return strings.Join(elems, string(ListSeparator))
+}
+
 func abs(path string) (string, error) {
 	return unixAbs(path)
 }
 
+func absFrom(wd, path string) (string, error) {
+	return unixAbsFrom(wd, path)
+}
+
 func join(elem []string) string {
 	// If there's a bug here, fix the logic in ./path_unix.go too.
 	for i, e := range elem {
 		if e != "" {
+			if p, ok := joinClean(elem[i:]); ok {
+				return p
+			}
 			return Clean(strings.Join(elem[i:], string(Separator)))
 		}
 	}
@@ -49,3 +78,17 @@ func join(elem []string) string {
 func sameWord(a, b string) bool {
 	return a == b
 }
+
+// dirKey returns the identity of the directory d, found at path:
+// its device type and number and the path of its qid.
+func dirKey(path string, d fs.DirEntry) (fileKey, bool) {
+	info, err := d.Info()
+	if err != nil {
+		return fileKey{}, false
+	}
+	dir, ok := info.Sys().(*syscall.Dir)
+	if !ok {
+		return fileKey{}, false
+	}
+	return fileKey{dev: uint64(dir.Type)<<32 | uint64(dir.Dev), ino: dir.Qid.Path}, true
+}
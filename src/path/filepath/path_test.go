@@ -15,6 +15,8 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 )
@@ -93,6 +95,21 @@ var wincleantests = []PathTest{
 	{`//host/share/foo/../baz`, `\\host\share\baz`},
 	{`\\a\b\..\c`, `\\a\b\c`},
 	{`\\a\b`, `\\a\b`},
+
+	// Device paths.
+	{`\\.\C:\a\..\b`, `\\.\C:\b`},
+	{`\\.\C:\..\..`, `\\.\C:\`},
+	{`//./C:/a/./b/`, `\\.\C:\a\b`},
+	{`\\.\C:`, `\\.\C:`},
+	{`\\.\pipe\name`, `\\.\pipe\name`},
+	{`\\.\UNC\host\share\a\..\b`, `\\.\UNC\host\share\b`},
+	{`\\.\UNC\host\share\..`, `\\.\UNC\host\share\`},
+
+	// Verbatim paths are left alone.
+	{`\\?\C:\a\..\b`, `\\?\C:\a\..\b`},
+	{`\\?\C:\a\.\b\`, `\\?\C:\a\.\b\`},
+	{`\\?\C:\a/b\\c`, `\\?\C:\a/b\\c`},
+	{`\\?\UNC\host\share\a\..`, `\\?\UNC\host\share\a\..`},
 }
 
 func TestClean(t *testing.T) {
@@ -128,6 +145,51 @@ func TestClean(t *testing.T) {
 	}
 }
 
+func TestAppendClean(t *testing.T) {
+	tests := cleantests
+	if runtime.GOOS == "windows" {
+		for i := range tests {
+			tests[i].result = filepath.FromSlash(tests[i].result)
+		}
+		tests = append(tests, wincleantests...)
+	}
+	buf := make([]byte, 0, 64)
+	for _, test := range tests {
+		for _, path := range []string{test.path, test.result} {
+			want := filepath.Clean(path)
+			if got := string(filepath.AppendClean(nil, path)); got != want {
+				t.Errorf("AppendClean(nil, %q) = %q, want %q", path, got, want)
+			}
+			buf = append(buf[:0], "prefix"...)
+			if got := string(filepath.AppendClean(buf, path)); got != "prefix"+want {
+				t.Errorf("AppendClean(%q, %q) = %q, want %q", "prefix", path, got, "prefix"+want)
+			}
+		}
+	}
+}
+
+func TestAppendCleanMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+
+	tests := cleantests
+	if runtime.GOOS == "windows" {
+		tests = append(tests, wincleantests...)
+	}
+	buf := make([]byte, 0, 64)
+	for _, test := range tests {
+		allocs := testing.AllocsPerRun(100, func() { buf = filepath.AppendClean(buf[:0], test.path) })
+		if allocs > 0 {
+			t.Errorf("AppendClean(buf, %q): %v allocs, want zero", test.path, allocs)
+		}
+	}
+}
+
 const sep = filepath.Separator
 
 var slashtests = []PathTest{
@@ -148,6 +210,59 @@ func TestFromAndToSlash(t *testing.T) {
 	}
 }
 
+type SplitSeqTest struct {
+	path string
+	want []string
+}
+
+var splitseqtests = []SplitSeqTest{
+	{"", nil},
+	{"a", []string{"a"}},
+	{"a/b/c", []string{"a", "b", "c"}},
+	{"/", []string{"/"}},
+	{"/a/b", []string{"/", "a", "b"}},
+	{"//a//b//", []string{"/", "a", "b"}},
+	{"./a/../b", []string{".", "a", "..", "b"}},
+}
+
+var winsplitseqtests = []SplitSeqTest{
+	{`c:`, []string{`c:`}},
+	{`c:\`, []string{`c:`, `\`}},
+	{`c:a\b`, []string{`c:`, `a`, `b`}},
+	{`c:/a\b/`, []string{`c:`, `/`, `a`, `b`}},
+	{`\\host\share`, []string{`\\host\share`}},
+	{`\\host\share\a\b`, []string{`\\host\share`, `\`, `a`, `b`}},
+	{`//host/share/a`, []string{`//host/share`, `/`, `a`}},
+	{`\a`, []string{`\`, `a`}},
+}
+
+func TestSplitSeq(t *testing.T) {
+	tests := splitseqtests
+	if runtime.GOOS == "windows" {
+		tests = append(tests, winsplitseqtests...)
+	}
+	for _, test := range tests {
+		var got []string
+		filepath.SplitSeq(test.path)(func(s string) bool {
+			got = append(got, s)
+			return true
+		})
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SplitSeq(%q) yielded %q, want %q", test.path, got, test.want)
+		}
+	}
+
+	// Stopping early.
+	var got []string
+	filepath.SplitSeq("/a/b/c")(func(s string) bool {
+		got = append(got, s)
+		return len(got) < 2
+	})
+	if want := []string{"/", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitSeq stopped after 2 yielded %q, want %q", got, want)
+	}
+}
+
 type SplitListTest struct {
 	list   string
 	result []string
@@ -176,11 +291,16 @@ var winsplitlisttests = []SplitListTest{
 	{`a; ""b`, []string{`a`, ` b`}},
 	{`"a;b`, []string{`a;b`}},
 	{`""a;b`, []string{`a`, `b`}},
-	{`"""a;b`, []string{`a;b`}},
-	{`""""a;b`, []string{`a`, `b`}},
+	{`"""a;b`, []string{`"a;b`}},
+	{`""""a;b`, []string{`"a`, `b`}},
 	{`a";b`, []string{`a;b`}},
 	{`a;b";c`, []string{`a`, `b;c`}},
 	{`"a";b";c`, []string{`a`, `b;c`}},
+
+	// doubled quotes within quotes
+	{`"a""b"`, []string{`a"b`}},
+	{`"a"";b"`, []string{`a";b`}},
+	{`a""b`, []string{`ab`}},
 }
 
 func TestSplitList(t *testing.T) {
@@ -195,6 +315,60 @@ func TestSplitList(t *testing.T) {
 	}
 }
 
+var joinlisttests = []SplitListTest{
+	{"", []string{}},
+	{"a", []string{"a"}},
+	{string([]byte{'a', lsep, 'b'}), []string{"a", "b"}},
+	{string([]byte{lsep, 'a'}), []string{"", "a"}},
+	{string([]byte{'a', lsep}), []string{"a", ""}},
+}
+
+var winjoinlisttests = []SplitListTest{
+	{`""`, []string{``}},
+	{`;`, []string{``, ``}},
+	{`";"`, []string{`;`}},
+	{`"a;b";c`, []string{`a;b`, `c`}},
+	{`C:\a;"C:\b;c";`, []string{`C:\a`, `C:\b;c`, ``}},
+	{`""""`, []string{`"`}},
+	{`"a""b";"""c;d"""`, []string{`a"b`, `"c;d"`}},
+}
+
+func TestJoinList(t *testing.T) {
+	tests := joinlisttests
+	if runtime.GOOS == "windows" {
+		tests = append(tests, winjoinlisttests...)
+	}
+	for _, test := range tests {
+		if l := filepath.JoinList(test.result...); l != test.list {
+			t.Errorf("JoinList(%#q) = %#q, want %#q", test.result, l, test.list)
+		}
+		if l := filepath.SplitList(test.list); !reflect.DeepEqual(l, test.result) {
+			t.Errorf("SplitList(%#q) = %#q, want %#q", test.list, l, test.result)
+		}
+	}
+}
+
+func TestJoinListRoundTrip(t *testing.T) {
+	elems := []string{"", "a", "a b", "/a/b"}
+	if runtime.GOOS == "windows" {
+		elems = append(elems, `C:\a b`, `;`, `a;b`, `"`, `""`, `a"b`, `"a;b"`, `";"`, `a"";b`)
+	}
+	for _, e1 := range elems {
+		for _, e2 := range elems {
+			for _, list := range [][]string{{e1}, {e1, e2}, {e1, "x", e2}} {
+				if len(list) == 1 && list[0] == "" && runtime.GOOS != "windows" {
+					// Only Windows can represent a single empty path.
+					continue
+				}
+				joined := filepath.JoinList(list...)
+				if got := filepath.SplitList(joined); !reflect.DeepEqual(got, list) {
+					t.Errorf("SplitList(JoinList(%#q)) = SplitList(%#q) = %#q", list, joined, got)
+				}
+			}
+		}
+	}
+}
+
 type SplitTest struct {
 	path, dir, file string
 }
@@ -262,6 +436,23 @@ var jointests = []JoinTest{
 
 	// three parameters
 	{[]string{"/", "a", "b"}, "/a/b"},
+
+	// already clean elements
+	{[]string{"..", "a"}, "../a"},
+	{[]string{"../..", "a/b"}, "../../a/b"},
+	{[]string{"/..", "a"}, "/a"},
+	{[]string{"a", ".."}, "."},
+	{[]string{"a", "../b"}, "b"},
+	{[]string{"a/..", "b"}, "b"},
+	{[]string{"a", "..", "..", "b"}, "../b"},
+	{[]string{".", "a"}, "a"},
+	{[]string{"a", "."}, "a"},
+	{[]string{"a/.", "b"}, "a/b"},
+	{[]string{"a", "b/"}, "a/b"},
+	{[]string{"a", "/b"}, "a/b"},
+	{[]string{"a//b", "c"}, "a/b/c"},
+	{[]string{"a", "", "b"}, "a/b"},
+	{[]string{"a", "...", "b"}, "a/.../b"},
 }
 
 var winjointests = []JoinTest{
@@ -291,6 +482,15 @@ var winjointests = []JoinTest{
 	{[]string{`\`, `\\a\b`, `c`}, `\a\b\c`},
 	{[]string{`\\a`, `b`, `c`}, `\a\b\c`},
 	{[]string{`\\a\`, `b`, `c`}, `\a\b\c`},
+	{[]string{`..\..`, `a/b`}, `..\..\a\b`},
+	{[]string{`a/b`, `c`}, `a\b\c`},
+	{[]string{`\a`, `b/c`}, `\a\b\c`},
+	{[]string{`\\.\C:\a`, `..`, `b`}, `\\.\C:\b`},
+	{[]string{`\\.\C:`, `a`}, `\\.\C:\a`},
+	{[]string{`\\?\C:\a`, `..`, `b`}, `\\?\C:\a\..\b`},
+	{[]string{`\\?\C:\`, `a`}, `\\?\C:\a`},
+	{[]string{`\\?\C:`, ``, `a`, ``}, `\\?\C:\a`},
+	{[]string{`\\?\C:\a\`, ``}, `\\?\C:\a\`},
 }
 
 func TestJoin(t *testing.T) {
@@ -305,6 +505,30 @@ func TestJoin(t *testing.T) {
 	}
 }
 
+func TestJoinMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+
+	for _, elem := range [][]string{
+		{"a", "b"},
+		{"/", "a", "b/c"},
+		{"../..", "a"},
+	} {
+		for i := range elem {
+			elem[i] = filepath.FromSlash(elem[i])
+		}
+		allocs := testing.AllocsPerRun(100, func() { filepath.Join(elem...) })
+		if allocs > 1 {
+			t.Errorf("Join(%q): %v allocs, want at most one", elem, allocs)
+		}
+	}
+}
+
 type ExtTest struct {
 	path, ext string
 }
@@ -325,6 +549,58 @@ func TestExt(t *testing.T) {
 	}
 }
 
+func TestExtMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+
+	for _, test := range exttests {
+		allocs := testing.AllocsPerRun(100, func() { filepath.Ext(test.path) })
+		if allocs > 0 {
+			t.Errorf("Ext(%q): %v allocs, want zero", test.path, allocs)
+		}
+	}
+}
+
+type ReplaceExtTest struct {
+	path, newExt, trimmed, replaced string
+}
+
+var replaceexttests = []ReplaceExtTest{
+	{"path.go", ".o", "path", "path.o"},
+	{"path.pb.go", ".o", "path.pb", "path.pb.o"},
+	{"a.dir/b", ".o", "a.dir/b", "a.dir/b.o"},
+	{"a.dir/b.go", ".a", "a.dir/b", "a.dir/b.a"},
+	{"a.dir/b.go", "", "a.dir/b", "a.dir/b"},
+	{"a.dir/", ".o", "a.dir/", "a.dir/.o"},
+	{"b.", ".o", "b", "b.o"},
+	{".bashrc", ".bak", ".bashrc", ".bashrc.bak"},
+	{"a/.bashrc", ".bak", "a/.bashrc", "a/.bashrc.bak"},
+	{".config.json", ".yaml", ".config", ".config.yaml"},
+	{"..", ".o", "..", "...o"},
+	{"a/...x", ".o", "a/...x", "a/...x.o"},
+	{"a/b..go", ".o", "a/b.", "a/b..o"},
+	{"", ".o", "", ".o"},
+}
+
+func TestReplaceExt(t *testing.T) {
+	for _, test := range replaceexttests {
+		path := filepath.FromSlash(test.path)
+		trimmed := filepath.FromSlash(test.trimmed)
+		replaced := filepath.FromSlash(test.replaced)
+		if got := filepath.TrimExt(path); got != trimmed {
+			t.Errorf("TrimExt(%q) = %q, want %q", path, got, trimmed)
+		}
+		if got := filepath.ReplaceExt(path, test.newExt); got != replaced {
+			t.Errorf("ReplaceExt(%q, %q) = %q, want %q", path, test.newExt, got, replaced)
+		}
+	}
+}
+
 type Node struct {
 	name    string
 	entries []*Node // nil if the entry is a file
@@ -484,6 +760,98 @@ func TestWalkDir(t *testing.T) {
 	testWalk(t, filepath.WalkDir, 2)
 }
 
+func TestWalkDirUnsorted(t *testing.T) {
+	testWalk(t, filepath.WalkDirUnsorted, 2)
+}
+
+func TestWalkDirParallel(t *testing.T) {
+	walk := func(root string, fn fs.WalkDirFunc) error {
+		// With a single worker the walk is sequential,
+		// so the error tests in testWalk apply unchanged.
+		return filepath.WalkDirParallel(root, 1, fn)
+	}
+	testWalk(t, walk, 2)
+}
+
+func TestWalkDirParallelConcurrent(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			dir := filepath.Join(root, fmt.Sprintf("d%d", i), fmt.Sprintf("e%d", j))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			touch(t, filepath.Join(dir, "f1"))
+			touch(t, filepath.Join(dir, "f2"))
+		}
+	}
+
+	var want []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "e3" {
+			return filepath.SkipDir
+		}
+		want = append(want, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	err = filepath.WalkDirParallel(root, 4, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "e3" {
+			return filepath.SkipDir
+		}
+		mu.Lock()
+		got = append(got, path)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkDirParallel visited %d paths, want %d:\ngot  %q\nwant %q", len(got), len(want), got, want)
+	}
+
+	errStop := errors.New("stop")
+	err = filepath.WalkDirParallel(root, 4, func(path string, d fs.DirEntry, err error) error {
+		if d.Name() == "f2" {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("WalkDirParallel returned %v, want %v", err, errStop)
+	}
+
+	var n int32
+	err = filepath.WalkDirParallel(root, 4, func(path string, d fs.DirEntry, err error) error {
+		if atomic.AddInt32(&n, 1) == 10 {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("WalkDirParallel with SkipAll returned %v, want nil", err)
+	}
+	if max := int32(10 + 4); atomic.LoadInt32(&n) > max {
+		// Each worker may finish one call after SkipAll.
+		t.Errorf("WalkDirParallel called fn %d times after SkipAll, want at most %d", n, max)
+	}
+}
+
 func testWalk(t *testing.T, walk func(string, fs.WalkDirFunc) error, errVisit int) {
 	if runtime.GOOS == "ios" {
 		restore := chtmpdir(t)
@@ -635,92 +1003,352 @@ func TestWalkSkipDirOnFile(t *testing.T) {
 	})
 }
 
-func TestWalkFileError(t *testing.T) {
-	td := t.TempDir()
-
-	touch(t, filepath.Join(td, "foo"))
-	touch(t, filepath.Join(td, "bar"))
-	dir := filepath.Join(td, "dir")
-	if err := os.MkdirAll(filepath.Join(td, "dir"), 0755); err != nil {
-		t.Fatal(err)
+func TestWalkDirWithOptions(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", "gone/x", "locked/y"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0777); err != nil {
+			t.Fatal(err)
+		}
 	}
-	touch(t, filepath.Join(dir, "baz"))
-	touch(t, filepath.Join(dir, "stat-error"))
-	defer func() {
-		*filepath.LstatP = os.Lstat
-	}()
-	statErr := errors.New("some stat error")
-	*filepath.LstatP = func(path string) (fs.FileInfo, error) {
-		if strings.HasSuffix(path, "stat-error") {
-			return nil, statErr
+	gone := filepath.Join(root, "gone")
+	locked := filepath.Join(root, "locked")
+
+	walk := func(opts filepath.WalkDirOptions) (visited []string, errs []string, skipped []string, err error) {
+		opts.OnSkip = func(path string, err error) {
+			skipped = append(skipped, path)
 		}
-		return os.Lstat(path)
+		err = filepath.WalkDirWithOptions(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				errs = append(errs, path)
+				return nil
+			}
+			visited = append(visited, path)
+			if path == gone {
+				// Remove the directory before WalkDir reads it.
+				if err := os.RemoveAll(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return nil
+		}, opts)
+		return
 	}
-	got := map[string]error{}
-	err := filepath.Walk(td, func(path string, fi fs.FileInfo, err error) error {
-		rel, _ := filepath.Rel(td, path)
-		got[filepath.ToSlash(rel)] = err
-		return nil
-	})
-	if err != nil {
-		t.Errorf("Walk error: %v", err)
+
+	// By default, the error is reported to the walk function.
+	_, errs, skipped, err := walk(filepath.WalkDirOptions{})
+	if err != nil || !reflect.DeepEqual(errs, []string{gone}) || skipped != nil {
+		t.Errorf("WalkDirWithOptions with no options: errors at %q, skipped %q, %v; want errors at %q", errs, skipped, err, gone)
 	}
-	want := map[string]error{
-		".":              nil,
-		"foo":            nil,
-		"bar":            nil,
-		"dir":            nil,
-		"dir/baz":        nil,
-		"dir/stat-error": statErr,
+
+	if err := os.MkdirAll(filepath.Join(gone, "x"), 0777); err != nil {
+		t.Fatal(err)
 	}
-	if !reflect.DeepEqual(got, want) {
-		t.Errorf("Walked %#v; want %#v", got, want)
+	visited, errs, skipped, err := walk(filepath.WalkDirOptions{IgnoreNotExistErrors: true})
+	if err != nil || errs != nil || !reflect.DeepEqual(skipped, []string{gone}) {
+		t.Errorf("WalkDirWithOptions ignoring not exist: errors at %q, skipped %q, %v; want skipped %q", errs, skipped, err, gone)
+	}
+	want := []string{root, filepath.Join(root, "a"), gone, locked, filepath.Join(locked, "y")}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("WalkDirWithOptions visited %q, want %q", visited, want)
 	}
-}
-
-var basetests = []PathTest{
-	{"", "."},
-	{".", "."},
-	{"/.", "."},
-	{"/", "/"},
-	{"////", "/"},
-	{"x/", "x"},
-	{"abc", "abc"},
-	{"abc/def", "def"},
-	{"a/b/.x", ".x"},
-	{"a/b/c.", "c."},
-	{"a/b/c.x", "c.x"},
-}
 
-var winbasetests = []PathTest{
-	{`c:\`, `\`},
-	{`c:.`, `.`},
-	{`c:\a\b`, `b`},
-	{`c:a\b`, `b`},
-	{`c:a\b\c`, `c`},
-	{`\\host\share\`, `\`},
-	{`\\host\share\a`, `a`},
-	{`\\host\share\a\b`, `b`},
-}
+	// A missing root is skipped too.
+	var rootSkipped []string
+	missing := filepath.Join(root, "missing")
+	err = filepath.WalkDirWithOptions(missing, func(path string, d fs.DirEntry, err error) error {
+		t.Errorf("walk function called for %q with %v", path, err)
+		return err
+	}, filepath.WalkDirOptions{
+		IgnoreNotExistErrors: true,
+		OnSkip:               func(path string, err error) { rootSkipped = append(rootSkipped, path) },
+	})
+	if err != nil || !reflect.DeepEqual(rootSkipped, []string{missing}) {
+		t.Errorf("WalkDirWithOptions(%q) skipped %q, %v; want skipped %q", missing, rootSkipped, err, missing)
+	}
 
-func TestBase(t *testing.T) {
-	tests := basetests
-	if runtime.GOOS == "windows" {
-		// make unix tests work on windows
-		for i := range tests {
-			tests[i].result = filepath.Clean(tests[i].result)
+	t.Run("PermErr", func(t *testing.T) {
+		if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+			t.Skipf("skipping on %s", runtime.GOOS)
 		}
-		// add windows specific tests
-		tests = append(tests, winbasetests...)
-	}
-	for _, test := range tests {
-		if s := filepath.Base(test.path); s != test.result {
-			t.Errorf("Base(%q) = %q, want %q", test.path, s, test.result)
+		if os.Getuid() == 0 {
+			t.Skip("skipping as root")
 		}
-	}
-}
+		if err := os.Chmod(locked, 0); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chmod(locked, 0777)
 
-var dirtests = []PathTest{
+		if err := os.MkdirAll(filepath.Join(gone, "x"), 0777); err != nil {
+			t.Fatal(err)
+		}
+		_, errs, skipped, err := walk(filepath.WalkDirOptions{IgnorePermissionErrors: true})
+		if err != nil || !reflect.DeepEqual(errs, []string{gone}) || !reflect.DeepEqual(skipped, []string{locked}) {
+			t.Errorf("WalkDirWithOptions ignoring permission errors: errors at %q, skipped %q, %v; want errors at %q, skipped %q", errs, skipped, err, gone, locked)
+		}
+	})
+}
+
+func TestWalkDirMaxDepth(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a", "b", "c", "d"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"f", "a/f", "a/b/f"} {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(file)), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		maxDepth int
+		want     []string
+	}{
+		{1, []string{".", "a", "f"}},
+		{2, []string{".", "a", "a/b", "a/f", "f"}},
+		{3, []string{".", "a", "a/b", "a/b/c", "a/b/f", "a/f", "f"}},
+		{0, []string{".", "a", "a/b", "a/b/c", "a/b/c/d", "a/b/f", "a/f", "f"}},
+	}
+	for _, test := range tests {
+		var got []string
+		err := filepath.WalkDirWithOptions(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				t.Errorf("walk function called for %q with %v", path, err)
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, filepath.ToSlash(rel))
+			return nil
+		}, filepath.WalkDirOptions{MaxDepth: test.maxDepth})
+		if err != nil {
+			t.Errorf("WalkDirWithOptions with MaxDepth %d: %v", test.maxDepth, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("WalkDirWithOptions with MaxDepth %d visited %q, want %q", test.maxDepth, got, test.want)
+		}
+	}
+}
+
+func TestWalkDirFollowSymlinks(t *testing.T) {
+	testenv.MustHaveSymlink(t)
+
+	tmp := t.TempDir()
+	root := filepath.Join(tmp, "root")
+	if err := os.MkdirAll(filepath.Join(root, "a"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a", "file"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	links := []struct{ link, target string }{
+		{"root/a/loop", ".."},
+		{"root/b", "a"},
+		{"root/dangling", "missing"},
+		{"root/f", "a/file"},
+		{"rootlink", "root"},
+	}
+	for _, l := range links {
+		if err := os.Symlink(filepath.FromSlash(l.target), filepath.Join(tmp, filepath.FromSlash(l.link))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each visited path is followed by a letter for its type:
+	// d for a directory, l for a symbolic link, and f for a file.
+	want := []string{
+		". d",
+		"a d",
+		"a/file f",
+		"a/loop l",
+		"b d",
+		"b/file f",
+		"b/loop l",
+		"dangling l",
+		"f l",
+	}
+	for _, dir := range []string{"root", "rootlink"} {
+		start := filepath.Join(tmp, dir)
+		var got []string
+		err := filepath.WalkDirWithOptions(start, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				t.Errorf("walk function called for %q with %v", path, err)
+				return err
+			}
+			rel, err := filepath.Rel(start, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			typ := "f"
+			if d.IsDir() {
+				typ = "d"
+			} else if d.Type()&fs.ModeSymlink != 0 {
+				typ = "l"
+			}
+			got = append(got, filepath.ToSlash(rel)+" "+typ)
+			return nil
+		}, filepath.WalkDirOptions{FollowSymlinks: true})
+		if err != nil {
+			t.Errorf("WalkDirWithOptions(%q) with FollowSymlinks: %v", dir, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("WalkDirWithOptions(%q) with FollowSymlinks visited %q, want %q", dir, got, want)
+		}
+	}
+}
+
+func TestWalkSkipAll(t *testing.T) {
+	td := t.TempDir()
+
+	for _, dir := range []string{"a", "b", "c"} {
+		if err := os.Mkdir(filepath.Join(td, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		touch(t, filepath.Join(td, dir, "foo"))
+	}
+
+	var saw []string
+	walker := func(path string) error {
+		rel, _ := filepath.Rel(td, path)
+		saw = append(saw, filepath.ToSlash(rel))
+		if rel == filepath.Join("b", "foo") {
+			return filepath.SkipAll
+		}
+		return nil
+	}
+	walkFn := func(path string, _ fs.FileInfo, _ error) error { return walker(path) }
+	walkDirFn := func(path string, _ fs.DirEntry, _ error) error { return walker(path) }
+	want := []string{".", "a", "a/foo", "b", "b/foo"}
+
+	check := func(t *testing.T, walk func(root string) error) {
+		t.Helper()
+		saw = nil
+		if err := walk(td); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(saw, want) {
+			t.Errorf("visited %q, want %q", saw, want)
+		}
+	}
+
+	t.Run("Walk", func(t *testing.T) {
+		check(t, func(root string) error { return filepath.Walk(root, walkFn) })
+	})
+	t.Run("WalkDir", func(t *testing.T) {
+		check(t, func(root string) error { return filepath.WalkDir(root, walkDirFn) })
+	})
+	t.Run("WalkDirParallel", func(t *testing.T) {
+		check(t, func(root string) error { return filepath.WalkDirParallel(root, 1, walkDirFn) })
+	})
+}
+
+func TestWalkFileError(t *testing.T) {
+	td := t.TempDir()
+
+	touch(t, filepath.Join(td, "foo"))
+	touch(t, filepath.Join(td, "bar"))
+	dir := filepath.Join(td, "dir")
+	if err := os.MkdirAll(filepath.Join(td, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, filepath.Join(dir, "baz"))
+	touch(t, filepath.Join(dir, "stat-error"))
+	defer func() {
+		*filepath.LstatP = os.Lstat
+	}()
+	statErr := errors.New("some stat error")
+	*filepath.LstatP = func(path string) (fs.FileInfo, error) {
+		if strings.HasSuffix(path, "stat-error") {
+			return nil, statErr
+		}
+		return os.Lstat(path)
+	}
+	got := map[string]error{}
+	err := filepath.Walk(td, func(path string, fi fs.FileInfo, err error) error {
+		rel, _ := filepath.Rel(td, path)
+		got[filepath.ToSlash(rel)] = err
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Walk error: %v", err)
+	}
+	want := map[string]error{
+		".":              nil,
+		"foo":            nil,
+		"bar":            nil,
+		"dir":            nil,
+		"dir/baz":        nil,
+		"dir/stat-error": statErr,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Walked %#v; want %#v", got, want)
+	}
+}
+
+var basetests = []PathTest{
+	{"", "."},
+	{".", "."},
+	{"/.", "."},
+	{"/", "/"},
+	{"////", "/"},
+	{"x/", "x"},
+	{"abc", "abc"},
+	{"abc/def", "def"},
+	{"a/b/.x", ".x"},
+	{"a/b/c.", "c."},
+	{"a/b/c.x", "c.x"},
+}
+
+var winbasetests = []PathTest{
+	{`c:\`, `\`},
+	{`c:.`, `.`},
+	{`c:\a\b`, `b`},
+	{`c:a\b`, `b`},
+	{`c:a\b\c`, `c`},
+	{`\\host\share\`, `\`},
+	{`\\host\share\a`, `a`},
+	{`\\host\share\a\b`, `b`},
+}
+
+func TestBase(t *testing.T) {
+	tests := basetests
+	if runtime.GOOS == "windows" {
+		// make unix tests work on windows
+		for i := range tests {
+			tests[i].result = filepath.Clean(tests[i].result)
+		}
+		// add windows specific tests
+		tests = append(tests, winbasetests...)
+	}
+	for _, test := range tests {
+		if s := filepath.Base(test.path); s != test.result {
+			t.Errorf("Base(%q) = %q, want %q", test.path, s, test.result)
+		}
+	}
+}
+
+func TestBaseMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+
+	tests := basetests
+	if runtime.GOOS == "windows" {
+		tests = append(tests, winbasetests...)
+	}
+	for _, test := range tests {
+		allocs := testing.AllocsPerRun(100, func() { filepath.Base(test.path) })
+		if allocs > 0 {
+			t.Errorf("Base(%q): %v allocs, want zero", test.path, allocs)
+		}
+	}
+}
+
+var dirtests = []PathTest{
 	{"", "."},
 	{".", "."},
 	{"/.", "/"},
@@ -764,6 +1392,31 @@ func TestDir(t *testing.T) {
 	}
 }
 
+func TestDirMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+
+	tests := dirtests
+	if runtime.GOOS == "windows" {
+		tests = append(tests, windirtests...)
+	}
+	for _, test := range tests {
+		if filepath.Clean(test.path) != test.path {
+			// Dir may need to allocate to clean the result.
+			continue
+		}
+		allocs := testing.AllocsPerRun(100, func() { filepath.Dir(test.path) })
+		if allocs > 0 {
+			t.Errorf("Dir(%q): %v allocs, want zero", test.path, allocs)
+		}
+	}
+}
+
 type IsAbsTest struct {
 	path  string
 	isAbs bool
@@ -993,6 +1646,209 @@ func TestEvalSymlinksIsNotExist(t *testing.T) {
 	}
 }
 
+func TestSymlinkCache(t *testing.T) {
+	testenv.MustHaveSymlink(t)
+
+	tmpDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal("eval symlink for tmp dir:", err)
+	}
+	for _, d := range EvalSymlinksTestDirs {
+		var err error
+		path := simpleJoin(tmpDir, d.path)
+		if d.dest == "" {
+			err = os.Mkdir(path, 0755)
+		} else {
+			err = os.Symlink(d.dest, path)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var c filepath.SymlinkCache
+	// Resolve every path twice so that the second pass is
+	// answered from the cache.
+	for i := 0; i < 2; i++ {
+		for _, test := range EvalSymlinksTests {
+			path := simpleJoin(tmpDir, test.path)
+			want := simpleJoin(tmpDir, test.dest)
+			if filepath.IsAbs(test.dest) || os.IsPathSeparator(test.dest[0]) {
+				want = test.dest
+			}
+			have, err := c.EvalSymlinks(path)
+			if err != nil {
+				t.Errorf("SymlinkCache.EvalSymlinks(%q) error: %v", path, err)
+				continue
+			}
+			if filepath.Clean(have) != filepath.Clean(want) {
+				t.Errorf("SymlinkCache.EvalSymlinks(%q) = %q, want %q", path, have, want)
+			}
+		}
+	}
+
+	// The cache does not notice changes to the file system until Reset.
+	link := simpleJoin(tmpDir, "test/link2")
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(".", link); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"test/dir", "test"} {
+		want = simpleJoin(tmpDir, want)
+		have, err := c.EvalSymlinks(link)
+		if err != nil || filepath.Clean(have) != filepath.Clean(want) {
+			t.Errorf("SymlinkCache.EvalSymlinks(%q) = %q, %v; want %q", link, have, err, want)
+		}
+		c.Reset()
+	}
+
+	// Errors are reported like EvalSymlinks.
+	if _, err := c.EvalSymlinks(simpleJoin(tmpDir, "notexist")); !os.IsNotExist(err) {
+		t.Errorf("expected the file is not found, got %v", err)
+	}
+}
+
+func TestEvalSymlinksPartial(t *testing.T) {
+	testenv.MustHaveSymlink(t)
+
+	tmpDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal("eval symlink for tmp dir:", err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "a", "b"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "a", "file"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("a", "b"), filepath.Join(tmpDir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("a", "missing", "x"), filepath.Join(tmpDir, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path                 string
+		resolved, unresolved string
+	}{
+		{"a/b", "a/b", ""},
+		{"link", "a/b", ""},
+		{"missing", "", "missing"},
+		{"a/missing", "a", "missing"},
+		{"a/b/c/d", "a/b", "c/d"},
+		{"link/c/d", "a/b", "c/d"},
+		{"dangling", "a", "missing/x"},
+		{"dangling/y", "a", "missing/x/y"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(tmpDir, filepath.FromSlash(test.path))
+		resolved, unresolved, err := filepath.EvalSymlinksPartial(path)
+		if err != nil {
+			t.Errorf("EvalSymlinksPartial(%q) error: %v", path, err)
+			continue
+		}
+		wantResolved := filepath.Join(tmpDir, filepath.FromSlash(test.resolved))
+		wantUnresolved := filepath.FromSlash(test.unresolved)
+		if resolved != wantResolved || unresolved != wantUnresolved {
+			t.Errorf("EvalSymlinksPartial(%q) = %q, %q, want %q, %q", path, resolved, unresolved, wantResolved, wantUnresolved)
+		}
+	}
+
+	// A file in the middle of the path is still an error.
+	path := filepath.Join(tmpDir, "a", "file", "x")
+	if _, _, err := filepath.EvalSymlinksPartial(path); err == nil {
+		t.Errorf("EvalSymlinksPartial(%q) succeeded, want error", path)
+	}
+}
+
+func TestStoredCase(t *testing.T) {
+	tmpDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal("eval symlink for tmp dir:", err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "Foo", "bAr"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "Foo", "bAr", "Baz.txt"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ path, want string }{
+		{"Foo/bAr/Baz.txt", "Foo/bAr/Baz.txt"},
+		{"foo/BAR/baz.TXT", "Foo/bAr/Baz.txt"},
+		{"FOO/bar", "Foo/bAr"},
+		{"foo/missing", "Foo/missing"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(tmpDir, filepath.FromSlash(test.path))
+		want := filepath.Join(tmpDir, filepath.FromSlash(test.want))
+		if got := filepath.StoredCase(path); got != want {
+			t.Errorf("storedCase(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	// An exact match is preferred, if the file system allows both.
+	if err := os.WriteFile(filepath.Join(tmpDir, "FOO"), nil, 0666); err == nil {
+		path := filepath.Join(tmpDir, "FOO")
+		if got := filepath.StoredCase(path); got != path {
+			t.Errorf("storedCase(%q) = %q, want %q", path, got, path)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tmpDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal("eval symlink for tmp dir:", err)
+	}
+	want := filepath.Join(tmpDir, "Dir", "File")
+	if err := os.Mkdir(filepath.Dir(want), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(want, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{want, filepath.Join(tmpDir, "Dir", ".", "..", "Dir", "File")}
+	if testenv.HasSymlink() {
+		if err := os.Symlink("Dir", filepath.Join(tmpDir, "link")); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, filepath.Join(tmpDir, "link", "File"))
+	}
+	switch runtime.GOOS {
+	case "darwin", "ios", "windows":
+		// Assume the default, case-insensitive file system.
+		paths = append(paths, filepath.Join(tmpDir, "DIR", "file"))
+	}
+	for _, path := range paths {
+		got, err := filepath.Canonical(path)
+		if err != nil || got != want {
+			t.Errorf("Canonical(%q) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+
+	defer chtmpdir(t)()
+	if err := os.WriteFile("rel", nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err = filepath.EvalSymlinks(wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := filepath.Canonical("rel"); err != nil || got != filepath.Join(wd, "rel") {
+		t.Errorf("Canonical(%q) = %q, %v; want %q", "rel", got, err, filepath.Join(wd, "rel"))
+	}
+	if _, err := filepath.Canonical("notexist"); !os.IsNotExist(err) {
+		t.Errorf("Canonical(%q) error = %v, want not exist", "notexist", err)
+	}
+}
+
 func TestIssue13582(t *testing.T) {
 	testenv.MustHaveSymlink(t)
 
@@ -1178,6 +2034,82 @@ func TestAbsEmptyString(t *testing.T) {
 	}
 }
 
+type AbsFromTest struct {
+	wd, path, want string
+}
+
+var absfromtests = []AbsFromTest{
+	{"/a/b", "", "/a/b"},
+	{"/a/b", ".", "/a/b"},
+	{"/a/b", "c", "/a/b/c"},
+	{"/a/b", "../c", "/a/c"},
+	{"/a/b/", "./c/", "/a/b/c"},
+	{"/a/b", "/c/../d", "/d"},
+	{"/", "../..", "/"},
+	{"a", "b", "err"},
+	{"", "b", "err"},
+}
+
+var winabsfromtests = []AbsFromTest{
+	{`C:\a\b`, `c`, `C:\a\b\c`},
+	{`C:\a\b`, `\c`, `C:\c`},
+	{`C:\a\b`, `/c/../d`, `C:\d`},
+	{`C:\a\b`, `C:c`, `C:\a\b\c`},
+	{`C:\a\b`, `c:`, `C:\a\b`},
+	{`C:\a\b`, `D:\c`, `D:\c`},
+	{`C:\a\b`, `D:c`, `err`},
+	{`\\host\share\a`, `b`, `\\host\share\a\b`},
+	{`\\host\share\a`, `\b`, `\\host\share\b`},
+	{`\a\b`, `c`, `err`},
+	{`C:a`, `c`, `err`},
+}
+
+func TestAbsFrom(t *testing.T) {
+	var tests []AbsFromTest
+	if runtime.GOOS == "windows" {
+		tests = winabsfromtests
+	} else {
+		tests = absfromtests
+	}
+	for _, test := range tests {
+		got, err := filepath.AbsFrom(test.wd, test.path)
+		if test.want == "err" {
+			if err == nil {
+				t.Errorf("AbsFrom(%q, %q)=%q, want error", test.wd, test.path, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("AbsFrom(%q, %q): want %q, got error: %s", test.wd, test.path, test.want, err)
+		}
+		if got != test.want {
+			t.Errorf("AbsFrom(%q, %q)=%q, want %q", test.wd, test.path, got, test.want)
+		}
+	}
+}
+
+func TestAbsFromMatchesAbs(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal("getwd failed: ", err)
+	}
+	paths := []string{"", ".", "a", "a/../b", "..", "../..", string(filepath.Separator) + "c"}
+	if runtime.GOOS == "windows" {
+		vol := filepath.VolumeName(wd)
+		paths = append(paths, vol+"a", vol+`\a`, vol)
+	}
+	for _, path := range paths {
+		want, err := filepath.Abs(path)
+		if err != nil {
+			t.Fatalf("Abs(%q) error: %v", path, err)
+		}
+		got, err := filepath.AbsFrom(wd, path)
+		if err != nil || got != want {
+			t.Errorf("AbsFrom(%q, %q) = %q, %v; want %q", wd, path, got, err, want)
+		}
+	}
+}
+
 type RelTests struct {
 	root, path, want string
 }
@@ -1234,6 +2166,17 @@ var winreltests = []RelTests{
 	{`C:\Projects`, `c:\projects`, `.`},
 	{`C:\Projects\a\..`, `c:\projects`, `.`},
 	{`\\host\share`, `\\host\share\file.txt`, `file.txt`},
+	{`\\host\share`, `\\host\share`, `.`},
+	{`\\host\share`, `\\host\share\`, `.`},
+	{`\\host\share\`, `\\host\share`, `.`},
+	{`\\host\share`, `\\HOST\Share\`, `.`},
+	{`\\host\share`, `\\host\share\a\..`, `.`},
+	{`\\host\share\`, `\\host\share\a`, `a`},
+	{`\\host\share\a`, `\\host\share`, `..`},
+	{`\\host\share\a\b`, `\\host\share\`, `..\..`},
+	{`\\host\share\a`, `\\host\share\b`, `..\b`},
+	{`\\host\share`, `\\host\other\`, `err`},
+	{`\\host\share`, `C:\`, `err`},
 }
 
 func TestRel(t *testing.T) {
@@ -1261,6 +2204,305 @@ func TestRel(t *testing.T) {
 	}
 }
 
+var relfoldtests = []RelTests{
+	{"a/b", "A/B", "."},
+	{"/Users/Gopher", "/users/gopher/Src/Go", "Src/Go"},
+	{"/users/gopher/src", "/Users/Gopher/Pkg", "../Pkg"},
+	{"ab/CD", "AB/cde", "../cde"},
+	{"/a/B", "/c/d", "../../c/d"},
+	{"..", "a", "err"},
+	{"a", "/A", "err"},
+}
+
+func TestRelFold(t *testing.T) {
+	tests := append([]RelTests{}, reltests...)
+	tests = append(tests, relfoldtests...)
+	if runtime.GOOS == "windows" {
+		for i := range tests {
+			tests[i].want = filepath.FromSlash(tests[i].want)
+		}
+		tests = append(tests, winreltests...)
+	}
+	for _, test := range tests {
+		got, err := filepath.RelFold(test.root, test.path)
+		if test.want == "err" {
+			if err == nil {
+				t.Errorf("RelFold(%q, %q)=%q, want error", test.root, test.path, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("RelFold(%q, %q): want %q, got error: %s", test.root, test.path, test.want, err)
+		}
+		if got != test.want {
+			t.Errorf("RelFold(%q, %q)=%q, want %q", test.root, test.path, got, test.want)
+		}
+	}
+}
+
+func TestRelVolumeError(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		for _, test := range reltests {
+			got, err := filepath.RelOrTarget(test.root, test.path)
+			if test.want == "err" {
+				if _, ok := err.(*filepath.RelVolumeError); err == nil || ok {
+					t.Errorf("RelOrTarget(%q, %q) = %q, %v; want non-volume error", test.root, test.path, got, err)
+				}
+				continue
+			}
+			if err != nil || got != test.want {
+				t.Errorf("RelOrTarget(%q, %q) = %q, %v; want %q", test.root, test.path, got, err, test.want)
+			}
+		}
+		return
+	}
+	tests := []struct {
+		base, targ       string
+		baseVol, targVol string
+		relOrTarget      string
+	}{
+		{`C:\a`, `D:\b`, `C:`, `D:`, `D:\b`},
+		{`C:\a`, `d:b\..\c`, `C:`, `d:`, `d:c`},
+		{`\\host\share\a`, `\\host\other\a`, `\\host\share`, `\\host\other`, `\\host\other\a`},
+		{`\a`, `C:\a`, ``, `C:`, `C:\a`},
+	}
+	for _, test := range tests {
+		_, err := filepath.Rel(test.base, test.targ)
+		verr, ok := err.(*filepath.RelVolumeError)
+		if !ok {
+			t.Errorf("Rel(%q, %q) error = %v, want *RelVolumeError", test.base, test.targ, err)
+			continue
+		}
+		want := filepath.RelVolumeError{Base: test.base, Targ: test.targ, BaseVolume: test.baseVol, TargVolume: test.targVol}
+		if *verr != want {
+			t.Errorf("Rel(%q, %q) error = %+v, want %+v", test.base, test.targ, *verr, want)
+		}
+		if got, err := filepath.RelOrTarget(test.base, test.targ); err != nil || got != test.relOrTarget {
+			t.Errorf("RelOrTarget(%q, %q) = %q, %v; want %q", test.base, test.targ, got, err, test.relOrTarget)
+		}
+	}
+	// Other errors are returned unchanged.
+	if _, err := filepath.RelOrTarget(`C:a`, `C:\a`); err == nil {
+		t.Errorf("RelOrTarget(%q, %q) succeeded, want error", `C:a`, `C:\a`)
+	}
+}
+
+func TestRelMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+
+	for _, test := range []RelTests{
+		{"a/b", "a/b/c/d", "c/d"},
+		{"/a/b", "/a/b/c", "c"},
+		{"/", "/a/b", "a/b"},
+		{"../a", "../a/b", "b"},
+	} {
+		root, path := filepath.FromSlash(test.root), filepath.FromSlash(test.path)
+		allocs := testing.AllocsPerRun(100, func() { filepath.Rel(root, path) })
+		if allocs > 0 {
+			t.Errorf("Rel(%q, %q): %v allocs, want zero", root, path, allocs)
+		}
+	}
+}
+
+type HasPrefixFoldTest struct {
+	path, prefix string
+	want         bool
+}
+
+var hasprefixfoldtests = []HasPrefixFoldTest{
+	{"/a/b", "/a", true},
+	{"/A/b", "/a", true},
+	{"/a/b", "/A/B", true},
+	{"/a/b", "/a/b/", true},
+	{"/a//b/c", "/a/b", true},
+	{"/a/b", "/", true},
+	{"/", "/", true},
+	{"a/b", "a", true},
+	{"a", "", true},
+	{"/a", "", true},
+	{"/ab", "/a", false},
+	{"/a", "/a/b", false},
+	{"/a", "a", false},
+	{"a", "/a", false},
+	{"a", "/", false},
+	{"/a/../b", "/b", false},
+	{"/Straße/x", "/STRASSE", false},
+	{"/\u212a/x", "/k", true}, // KELVIN SIGN
+}
+
+var winhasprefixfoldtests = []HasPrefixFoldTest{
+	{`C:\Windows\System32`, `c:\windows`, true},
+	{`C:/Windows/System32`, `c:\windows\`, true},
+	{`C:\Windows`, `C:\`, true},
+	{`C:\Windows`, `C:`, true},
+	{`C:\Windows`, `D:\Windows`, false},
+	{`C:\WindowsX`, `C:\Windows`, false},
+	{`C:Windows`, `C:\Windows`, false},
+	{`\\Host\Share\a`, `\\host\share`, true},
+	{`\\host\share2\a`, `\\host\share`, false},
+}
+
+func TestHasPrefixFold(t *testing.T) {
+	tests := hasprefixfoldtests
+	if runtime.GOOS == "windows" {
+		tests = append(tests, winhasprefixfoldtests...)
+	}
+	for _, test := range tests {
+		if got := filepath.HasPrefixFold(test.path, test.prefix); got != test.want {
+			t.Errorf("HasPrefixFold(%q, %q) = %v, want %v", test.path, test.prefix, got, test.want)
+		}
+	}
+}
+
+func TestHasPrefixFoldMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+
+	tests := hasprefixfoldtests
+	if runtime.GOOS == "windows" {
+		tests = append(tests, winhasprefixfoldtests...)
+	}
+	for _, test := range tests {
+		allocs := testing.AllocsPerRun(100, func() { filepath.HasPrefixFold(test.path, test.prefix) })
+		if allocs > 0 {
+			t.Errorf("HasPrefixFold(%q, %q): %v allocs, want zero", test.path, test.prefix, allocs)
+		}
+	}
+}
+
+type JoinRootTest struct {
+	root string
+	elem []string
+	want string
+}
+
+var joinroottests = []JoinRootTest{
+	{"/root", nil, "/root"},
+	{"/root/", []string{""}, "/root"},
+	{"/root", []string{"a", "b"}, "/root/a/b"},
+	{"/root", []string{"a/../b"}, "/root/b"},
+	{"/root", []string{"a", ".."}, "/root"},
+	{"/root", []string{"a", "../.."}, "err"},
+	{"/root", []string{".."}, "err"},
+	{"/root", []string{"../root2"}, "err"},
+	{"/root", []string{"a/../../root/b"}, "/root/b"},
+	{"root", []string{"a", "b"}, "root/a/b"},
+	{"root", []string{"../x"}, "err"},
+	{"", []string{"a"}, "a"},
+	{"", []string{"../a"}, "err"},
+	{".", []string{"a/.."}, "."},
+}
+
+var nonwinjoinroottests = []JoinRootTest{
+	{"/root", []string{"/a"}, "err"},
+	{"/root", []string{"a", "/b"}, "err"},
+}
+
+var winjoinroottests = []JoinRootTest{
+	{`C:\root`, []string{`a\b`}, `C:\root\a\b`},
+	{`C:\root`, []string{`a/../b`}, `C:\root\b`},
+	{`C:\root`, []string{`\a`}, `C:\root\a`},
+	{`C:\root`, []string{`..\a`}, "err"},
+	{`C:\root`, []string{`C:\root\a`}, "err"},
+	{`C:\root`, []string{`C:a`}, "err"},
+	{`C:\root`, []string{`D:a`}, "err"},
+	{`C:\root`, []string{`\\host\share\a`}, "err"},
+	{`C:\root`, []string{`//host/share/a`}, "err"},
+	{`C:\root`, []string{`NUL`}, "err"},
+	{`\\host\share`, []string{`a`, `..`}, `\\host\share\`},
+	{`\\host\share`, []string{`..`, `a`}, `\\host\share\a`},
+	{`\\host\share\a`, []string{`..`}, `err`},
+}
+
+func TestJoinRoot(t *testing.T) {
+	tests := append([]JoinRootTest{}, joinroottests...)
+	if runtime.GOOS == "windows" {
+		for i := range tests {
+			tests[i].want = filepath.FromSlash(tests[i].want)
+		}
+		tests = append(tests, winjoinroottests...)
+	} else {
+		tests = append(tests, nonwinjoinroottests...)
+	}
+	for _, test := range tests {
+		got, err := filepath.JoinRoot(test.root, test.elem...)
+		if test.want == "err" {
+			if err == nil {
+				t.Errorf("JoinRoot(%q, %q) = %q, want error", test.root, test.elem, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("JoinRoot(%q, %q) = %q, %v; want %q", test.root, test.elem, got, err, test.want)
+		}
+	}
+}
+
+type IsWithinTest struct {
+	root, path string
+	rel        string
+	ok         bool
+}
+
+var iswithintests = []IsWithinTest{
+	{"/a", "/a", ".", true},
+	{"/a", "/a/b/c", "b/c", true},
+	{"/a/", "/a/b", "b", true},
+	{"/a/b/..", "/a/c", "c", true},
+	{"/", "/a", "a", true},
+	{"a", "a/b", "b", true},
+	{".", "a", "a", true},
+	{"..", "../a", "a", true},
+	{"/a", "/ab", "", false},
+	{"/a/b", "/a", "", false},
+	{"/a", "/a/../b", "", false},
+	{"/a", "/b/../../a/c", "c", true},
+	{"a", "/a", "", false},
+	{"/a", "a", "", false},
+	{".", "..", "", false},
+	{".", "../a", "", false},
+	{"..", "a", "", false},
+	{"a/..", "..", "", false},
+}
+
+var winiswithintests = []IsWithinTest{
+	{`C:\Projects`, `c:\projects\src`, `src`, true},
+	{`C:\Projects`, `C:/Projects/src/a`, `src\a`, true},
+	{`C:\Projects`, `D:\Projects\src`, ``, false},
+	{`C:\Projects`, `C:\ProjectsX`, ``, false},
+	{`C:\Projects`, `\Projects`, ``, false},
+	{`C:Projects`, `C:\Projects`, ``, false},
+	{`\\host\share`, `\\host\share\file.txt`, `file.txt`, true},
+	{`\\host\share`, `\\host\other\file.txt`, ``, false},
+}
+
+func TestIsWithin(t *testing.T) {
+	tests := append([]IsWithinTest{}, iswithintests...)
+	if runtime.GOOS == "windows" {
+		for i := range tests {
+			tests[i].rel = filepath.FromSlash(tests[i].rel)
+		}
+		tests = append(tests, winiswithintests...)
+	}
+	for _, test := range tests {
+		rel, ok := filepath.IsWithin(test.root, test.path)
+		if rel != test.rel || ok != test.ok {
+			t.Errorf("IsWithin(%q, %q) = %q, %v; want %q, %v", test.root, test.path, rel, ok, test.rel, test.ok)
+		}
+	}
+}
+
 type VolumeNameTest struct {
 	path string
 	vol  string
@@ -1289,6 +2531,15 @@ var volumenametests = []VolumeNameTest{
 	{`//host/share//foo///bar////baz`, `//host/share`},
 	{`\\host\share\foo\..\bar`, `\\host\share`},
 	{`//host/share/foo/../bar`, `//host/share`},
+	{`\\.\C:\foo`, `\\.\C:`},
+	{`//./C:/foo`, `//./C:`},
+	{`\\?\C:\foo`, `\\?\C:`},
+	{`\\?\C:`, `\\?\C:`},
+	{`\\.\pipe\name`, `\\.\pipe`},
+	{`\\?\Volume{b75e2c83-0000-0000-0000-602f00000000}\foo`, `\\?\Volume{b75e2c83-0000-0000-0000-602f00000000}`},
+	{`\\?\UNC\host\share\foo`, `\\?\UNC\host\share`},
+	{`\\?\unc\host\share`, `\\?\unc\host\share`},
+	{`\\.\UNC\host`, `\\.\UNC\host`},
 }
 
 func TestVolumeName(t *testing.T) {
@@ -1302,6 +2553,85 @@ func TestVolumeName(t *testing.T) {
 	}
 }
 
+func TestSplitVolume(t *testing.T) {
+	tests := []VolumeNameTest{
+		{"", ""},
+		{"a/b", ""},
+		{"/a/b", ""},
+		{"//host/share/a", ""},
+	}
+	if runtime.GOOS == "windows" {
+		tests = volumenametests
+	}
+	for _, v := range tests {
+		vol, rest := filepath.SplitVolume(v.path)
+		if vol != v.vol || vol+rest != v.path {
+			t.Errorf("SplitVolume(%q) = %q, %q; want %q, %q", v.path, vol, rest, v.vol, strings.TrimPrefix(v.path, v.vol))
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string // on Unix and Plan 9
+		windows string
+	}{
+		{"", "err", "err"},
+		{".", ".", "."},
+		{"a", "a", "a"},
+		{"a/b/c", "a/b/c", `a\b\c`},
+		{"a.txt", "a.txt", "a.txt"},
+		{".a", ".a", ".a"},
+		{"/a", "err", "err"},
+		{"a/", "err", "err"},
+		{"a//b", "err", "err"},
+		{"..", "err", "err"},
+		{"a/../b", "err", "err"},
+		{"./a", "err", "err"},
+		{"a\x00b", "err", "err"},
+		{`a\b`, `a\b`, "err"},
+		{"c:", "c:", "err"},
+		{"c:/a", "c:/a", "err"},
+		{"a*b", "a*b", "err"},
+		{"a?", "a?", "err"},
+		{"a\x01", "a\x01", "err"},
+		{"NUL", "NUL", "err"},
+		{"nul", "nul", "err"},
+		{"a/COM1.txt", "a/COM1.txt", "err"},
+		{"com1 .tar.gz", "com1 .tar.gz", "err"},
+		{"CONSOLE", "CONSOLE", "CONSOLE"},
+		{"a./b", "a./b", "err"},
+		{"a/b ", "a/b ", "err"},
+		{"a.b/c", "a.b/c", `a.b\c`},
+		{"#c", "#c", "#c"},
+	}
+	for _, test := range tests {
+		want := test.want
+		switch runtime.GOOS {
+		case "windows":
+			want = test.windows
+		case "plan9":
+			if strings.HasPrefix(test.path, "#") {
+				want = "err"
+			}
+		}
+		got, err := filepath.Localize(test.path)
+		if want == "err" {
+			if err == nil {
+				t.Errorf("Localize(%q) = %q, want error", test.path, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("Localize(%q) = %q, %v; want %q", test.path, got, err, want)
+		}
+		if filepath.IsAbs(got) || filepath.VolumeName(got) != "" {
+			t.Errorf("Localize(%q) = %q, not local", test.path, got)
+		}
+	}
+}
+
 func TestDriveLetterInEvalSymlinks(t *testing.T) {
 	if runtime.GOOS != "windows" {
 		return
@@ -6,19 +6,36 @@
 
 package filepath
 
-import "strings"
+import (
+	"io/fs"
+	"strings"
+	"syscall"
+)
 
 // IsAbs reports whether the path is absolute.
 func IsAbs(path string) bool {
 	return strings.HasPrefix(path, "/")
 }
 
+func localize(path string) (string, error) {
+	if strings.IndexByte(path, 0) >= 0 {
+		return "", errInvalidPath
+	}
+	return path, nil
+}
+
 // volumeNameLen returns length of the leading volume name on Windows.
 // It returns 0 elsewhere.
 func volumeNameLen(path string) int {
 	return 0
 }
 
+// isVerbatim reports whether path must not be cleaned. It is always
+// false except on Windows.
+func isVerbatim(path string) bool {
+	return false
+}
+
 // HasPrefix exists for historical compatibility and should not be used.
 //
 // Deprecated: HasPrefix does not respect path boundaries and
@@ -34,14 +51,25 @@ func splitList(path string) []string {
 	return strings.Split(path, string(ListSeparator))
 }
 
+func joinList(elems []string) string {
+	return strings.Join(elems, string(ListSeparator))
+}
+
 func abs(path string) (string, error) {
 	return unixAbs(path)
 }
 
+func absFrom(wd, path string) (string, error) {
+	return unixAbsFrom(wd, path)
+}
+
 func join(elem []string) string {
 	// If there's a bug here, fix the logic in ./path_plan9.go too.
 	for i, e := range elem {
 		if e != "" {
+			if p, ok := joinClean(elem[i:]); ok {
+				return p
+			}
 			return Clean(strings.Join(elem[i:], string(Separator)))
 		}
 	}
@@ -51,3 +79,17 @@ func join(elem []string) string {
 func sameWord(a, b string) bool {
 	return a == b
 }
+
+// dirKey returns the device and inode number of the directory d,
+// found at path.
+func dirKey(path string, d fs.DirEntry) (fileKey, bool) {
+	info, err := d.Info()
+	if err != nil {
+		return fileKey{}, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileKey{}, false
+	}
+	return fileKey{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
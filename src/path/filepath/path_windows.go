@@ -5,6 +5,8 @@
 package filepath
 
 import (
+	"errors"
+	"io/fs"
 	"strings"
 	"syscall"
 )
@@ -36,6 +38,39 @@ func isReservedName(path string) bool {
 	return false
 }
 
+func localize(path string) (string, error) {
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c < ' ', c == '\\', c == ':', c == '<', c == '>', c == '"', c == '|', c == '?', c == '*':
+			return "", errInvalidPath
+		}
+	}
+	for elem := path; elem != ""; {
+		var rest string
+		if i := strings.IndexByte(elem, '/'); i >= 0 {
+			elem, rest = elem[:i], elem[i+1:]
+		}
+		if elem != "." && !validLocalElem(elem) {
+			return "", errInvalidPath
+		}
+		elem = rest
+	}
+	return strings.ReplaceAll(path, "/", `\`), nil
+}
+
+// validLocalElem reports whether elem can be used unchanged as the name
+// of a file: it must not end in a dot or space, which Windows strips,
+// or be a reserved device name, even with an extension.
+func validLocalElem(elem string) bool {
+	if c := elem[len(elem)-1]; c == '.' || c == ' ' {
+		return false
+	}
+	if i := strings.IndexByte(elem, '.'); i >= 0 {
+		elem = elem[:i]
+	}
+	return !isReservedName(strings.TrimRight(elem, " "))
+}
+
 // IsAbs reports whether the path is absolute.
 func IsAbs(path string) (b bool) {
 	if isReservedName(path) {
@@ -67,6 +102,20 @@ func volumeNameLen(path string) int {
 	if path[1] == ':' && ('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
 		return 2
 	}
+	// is it a device path, as in `\\.\C:` or `\\?\C:`?
+	// The volume name is the prefix and the element that follows it,
+	// or, for `\\?\UNC\host\share`, the three elements that follow it.
+	if len(path) >= 4 && isSlash(path[0]) && isSlash(path[1]) &&
+		(path[2] == '.' || path[2] == '?') && isSlash(path[3]) {
+		n := nextSlash(path, 4)
+		if n-4 == 3 && strings.EqualFold(path[4:n], "UNC") {
+			// Include the host and share names.
+			for i := 0; i < 2 && n < len(path); i++ {
+				n = nextSlash(path, n+1)
+			}
+		}
+		return n
+	}
 	// is it UNC? https://msdn.microsoft.com/en-us/library/windows/desktop/aa365247(v=vs.85).aspx
 	if l := len(path); l >= 5 && isSlash(path[0]) && isSlash(path[1]) &&
 		!isSlash(path[2]) && path[2] != '.' {
@@ -94,6 +143,23 @@ func volumeNameLen(path string) int {
 	return 0
 }
 
+// nextSlash returns the index of the first slash in path at or after i,
+// or len(path) if there is none.
+func nextSlash(path string, i int) int {
+	for i < len(path) && !isSlash(path[i]) {
+		i++
+	}
+	return i
+}
+
+// isVerbatim reports whether path begins with the `\\?\` prefix, which
+// tells Windows to pass the rest of the path to the file system as is:
+// slashes are not separators and "." and ".." elements are not special.
+// Only backslashes form the prefix; `//?/` is an ordinary device path.
+func isVerbatim(path string) bool {
+	return len(path) >= 4 && path[:4] == `\\?\`
+}
+
 // HasPrefix exists for historical compatibility and should not be used.
 //
 // Deprecated: HasPrefix does not respect path boundaries and
@@ -113,29 +179,52 @@ func splitList(path string) []string {
 		return []string{}
 	}
 
-	// Split path, respecting but preserving quotes.
+	// Split path, respecting quotes and removing them.
+	// Within quotes, two double quotes stand for one,
+	// as joinList writes them.
 	list := []string{}
-	start := 0
+	var elem []byte
 	quo := false
 	for i := 0; i < len(path); i++ {
 		switch c := path[i]; {
+		case c == '"' && quo && i+1 < len(path) && path[i+1] == '"':
+			elem = append(elem, '"')
+			i++
 		case c == '"':
 			quo = !quo
 		case c == ListSeparator && !quo:
-			list = append(list, path[start:i])
-			start = i + 1
+			list = append(list, string(elem))
+			elem = elem[:0]
+		default:
+			elem = append(elem, c)
 		}
 	}
-	list = append(list, path[start:])
-
-	// Remove quotes.
-	for i, s := range list {
-		list[i] = strings.ReplaceAll(s, `"`, ``)
-	}
+	list = append(list, string(elem))
 
 	return list
 }
 
+func joinList(elems []string) string {
+	if len(elems) == 1 && elems[0] == "" {
+		// Without quotes, the list would be empty.
+		return `""`
+	}
+	var b strings.Builder
+	for i, e := range elems {
+		if i > 0 {
+			b.WriteByte(ListSeparator)
+		}
+		if strings.IndexByte(e, ListSeparator) >= 0 || strings.IndexByte(e, '"') >= 0 {
+			b.WriteByte('"')
+			b.WriteString(strings.ReplaceAll(e, `"`, `""`))
+			b.WriteByte('"')
+		} else {
+			b.WriteString(e)
+		}
+	}
+	return b.String()
+}
+
 func abs(path string) (string, error) {
 	if path == "" {
 		// syscall.FullPath returns an error on empty path, because it's not a valid path.
@@ -150,6 +239,26 @@ func abs(path string) (string, error) {
 	return Clean(fullPath), nil
 }
 
+func absFrom(wd, path string) (string, error) {
+	if IsAbs(path) {
+		return Clean(path), nil
+	}
+	vol := VolumeName(path)
+	wdVol := VolumeName(wd)
+	if vol == "" {
+		if path != "" && isSlash(path[0]) {
+			// Rooted on the volume of wd, as in `\a`.
+			return Clean(wdVol + path), nil
+		}
+		return Join(wd, path), nil
+	}
+	// Relative to the working directory of a drive, as in `C:a`.
+	if !strings.EqualFold(vol, wdVol) {
+		return "", errors.New("AbsFrom: can't make " + path + " absolute from " + wd)
+	}
+	return Join(wd, path[len(vol):]), nil
+}
+
 func join(elem []string) string {
 	for i, e := range elem {
 		if e != "" {
@@ -161,6 +270,9 @@ func join(elem []string) string {
 
 // joinNonEmpty is like join, but it assumes that the first element is non-empty.
 func joinNonEmpty(elem []string) string {
+	if isVerbatim(elem[0]) {
+		return joinVerbatim(elem)
+	}
 	if len(elem[0]) == 2 && elem[0][1] == ':' {
 		// First element is drive letter without terminating slash.
 		// Keep path relative to current directory on that drive.
@@ -173,6 +285,11 @@ func joinNonEmpty(elem []string) string {
 		}
 		return Clean(elem[0] + strings.Join(elem[i:], string(Separator)))
 	}
+	if p, ok := joinClean(elem); ok {
+		// Without volume names or doubled separators,
+		// the result cannot be a UNC path.
+		return p
+	}
 	// The following logic prevents Join from inadvertently creating a
 	// UNC path on Windows. Unless the first element is a UNC path, Join
 	// shouldn't create a UNC path. See golang.org/issue/9167.
@@ -194,6 +311,24 @@ func joinNonEmpty(elem []string) string {
 	return head + string(Separator) + tail
 }
 
+// joinVerbatim joins elem, whose first element is a `\\?\` path.
+// Clean leaves such paths alone, so the elements are joined as they
+// are, skipping empty elements and adding a Separator only where one
+// is missing.
+func joinVerbatim(elem []string) string {
+	var b strings.Builder
+	for _, e := range elem {
+		if e == "" {
+			continue
+		}
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), string(Separator)) {
+			b.WriteByte(Separator)
+		}
+		b.WriteString(e)
+	}
+	return b.String()
+}
+
 // isUNC reports whether path is a UNC path.
 func isUNC(path string) bool {
 	return volumeNameLen(path) > 2
@@ -202,3 +337,23 @@ func isUNC(path string) bool {
 func sameWord(a, b string) bool {
 	return strings.EqualFold(a, b)
 }
+
+// dirKey returns the volume serial number and file index of the
+// directory d, found at path.
+func dirKey(path string, d fs.DirEntry) (fileKey, bool) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return fileKey{}, false
+	}
+	// FILE_FLAG_BACKUP_SEMANTICS is required to open a directory.
+	h, err := syscall.CreateFile(p, 0, 0, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return fileKey{}, false
+	}
+	defer syscall.CloseHandle(h)
+	var i syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(h, &i); err != nil {
+		return fileKey{}, false
+	}
+	return fileKey{dev: uint64(i.VolumeSerialNumber), ino: uint64(i.FileIndexHigh)<<32 | uint64(i.FileIndexLow)}, true
+}
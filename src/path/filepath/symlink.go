@@ -9,10 +9,119 @@ import (
 	"io/fs"
 	"os"
 	"runtime"
+	"sync"
 	"syscall"
 )
 
-func walkSymlinks(path string) (string, error) {
+// A SymlinkCache evaluates symbolic links like EvalSymlinks, but
+// remembers the type of each path component it examines and the target
+// of each symbolic link it reads. Resolving many paths that share
+// leading directories through one SymlinkCache avoids repeating the
+// underlying Lstat and Readlink system calls.
+//
+// The cache assumes that the file system does not change while it is
+// in use: the results of failed lookups are remembered too, and relative
+// paths are cached as given, so the working directory must not change
+// either. Call Reset to discard the cached results.
+//
+// The zero value is an empty cache ready to use. A SymlinkCache is safe
+// for concurrent use by multiple goroutines.
+type SymlinkCache struct {
+	mu      sync.Mutex
+	entries map[string]*symlinkEntry
+}
+
+// A symlinkEntry records what SymlinkCache knows about one path.
+type symlinkEntry struct {
+	mode    fs.FileMode
+	statErr error
+
+	linkRead bool // link and linkErr are valid
+	link     string
+	linkErr  error
+}
+
+// EvalSymlinks returns the path name after the evaluation of any
+// symbolic links, as EvalSymlinks does, using and updating the cache.
+func (c *SymlinkCache) EvalSymlinks(path string) (string, error) {
+	return evalSymlinks(path, c)
+}
+
+// Reset discards all cached results.
+func (c *SymlinkCache) Reset() {
+	c.mu.Lock()
+	c.entries = nil
+	c.mu.Unlock()
+}
+
+func (c *SymlinkCache) entry(path string) *symlinkEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[path]
+}
+
+// lstat returns the mode of path. If c is nil, it calls os.Lstat.
+func (c *SymlinkCache) lstat(path string) (fs.FileMode, error) {
+	if c == nil {
+		fi, err := os.Lstat(path)
+		if err != nil {
+			return 0, err
+		}
+		return fi.Mode(), nil
+	}
+	if e := c.entry(path); e != nil {
+		return e.mode, e.statErr
+	}
+	e := new(symlinkEntry)
+	if fi, err := os.Lstat(path); err != nil {
+		e.statErr = err
+	} else {
+		e.mode = fi.Mode()
+	}
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*symlinkEntry)
+	}
+	if e1 := c.entries[path]; e1 != nil {
+		// Another goroutine got here first.
+		e = e1
+	} else {
+		c.entries[path] = e
+	}
+	c.mu.Unlock()
+	return e.mode, e.statErr
+}
+
+// readlink returns the target of the symbolic link path.
+// If c is nil, it calls os.Readlink.
+func (c *SymlinkCache) readlink(path string) (string, error) {
+	if c == nil {
+		return os.Readlink(path)
+	}
+	e := c.entry(path)
+	if e == nil {
+		// Reset since the call to lstat.
+		return os.Readlink(path)
+	}
+	c.mu.Lock()
+	read, link, err := e.linkRead, e.link, e.linkErr
+	c.mu.Unlock()
+	if read {
+		return link, err
+	}
+	link, err = os.Readlink(path)
+	c.mu.Lock()
+	e.linkRead, e.link, e.linkErr = true, link, err
+	c.mu.Unlock()
+	return link, err
+}
+
+// walkSymlinks evaluates the symbolic links in path,
+// looking up path components through c, which may be nil.
+// If partial is true, walkSymlinks stops at the first path
+// component that does not exist, returning the resolved path
+// before it and the rest of the path, starting with it.
+func walkSymlinks(path string, c *SymlinkCache, partial bool) (resolved, unresolved string, err error) {
 	volLen := volumeNameLen(path)
 	pathSeparator := string(os.PathSeparator)
 
@@ -73,6 +182,7 @@ func walkSymlinks(path string) (string, error) {
 
 		// Ordinary path component. Add it to result.
 
+		parent := dest
 		if len(dest) > volumeNameLen(dest) && !os.IsPathSeparator(dest[len(dest)-1]) {
 			dest += pathSeparator
 		}
@@ -81,14 +191,17 @@ func walkSymlinks(path string) (string, error) {
 
 		// Resolve symlink.
 
-		fi, err := os.Lstat(dest)
+		mode, err := c.lstat(dest)
 		if err != nil {
-			return "", err
+			if partial && errors.Is(err, fs.ErrNotExist) {
+				return Clean(parent), path[start:], nil
+			}
+			return "", "", err
 		}
 
-		if fi.Mode()&fs.ModeSymlink == 0 {
-			if !fi.Mode().IsDir() && end < len(path) {
-				return "", syscall.ENOTDIR
+		if mode&fs.ModeSymlink == 0 {
+			if !mode.IsDir() && end < len(path) {
+				return "", "", syscall.ENOTDIR
 			}
 			continue
 		}
@@ -97,12 +210,12 @@ func walkSymlinks(path string) (string, error) {
 
 		linksWalked++
 		if linksWalked > 255 {
-			return "", errors.New("EvalSymlinks: too many links")
+			return "", "", errors.New("EvalSymlinks: too many links")
 		}
 
-		link, err := os.Readlink(dest)
+		link, err := c.readlink(dest)
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
 
 		if isWindowsDot && !IsAbs(link) {
@@ -143,5 +256,5 @@ func walkSymlinks(path string) (string, error) {
 			end = 0
 		}
 	}
-	return Clean(dest), nil
+	return Clean(dest), "", nil
 }
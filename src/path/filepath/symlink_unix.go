@@ -2,6 +2,11 @@
 
 package filepath
 
-func evalSymlinks(path string) (string, error) {
-	return walkSymlinks(path)
+func evalSymlinks(path string, c *SymlinkCache) (string, error) {
+	resolved, _, err := walkSymlinks(path, c, false)
+	return resolved, err
+}
+
+func evalSymlinksPartial(path string) (resolved, unresolved string, err error) {
+	return walkSymlinks(path, nil, true)
 }
@@ -106,8 +106,8 @@ func toNorm(path string, normBase func(string) (string, error)) (string, error)
 	return volume + normPath, nil
 }
 
-func evalSymlinks(path string) (string, error) {
-	newpath, err := walkSymlinks(path)
+func evalSymlinks(path string, c *SymlinkCache) (string, error) {
+	newpath, _, err := walkSymlinks(path, c, false)
 	if err != nil {
 		return "", err
 	}
@@ -117,3 +117,15 @@ func evalSymlinks(path string) (string, error) {
 	}
 	return newpath, nil
 }
+
+func evalSymlinksPartial(path string) (resolved, unresolved string, err error) {
+	resolved, unresolved, err = walkSymlinks(path, nil, true)
+	if err != nil {
+		return "", "", err
+	}
+	resolved, err = toNorm(resolved, normBase)
+	if err != nil {
+		return "", "", err
+	}
+	return resolved, unresolved, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package filepath
+
+import (
+	"io/fs"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// WalkDirParallel is like WalkDir but walks the file tree rooted at root
+// using up to workers goroutines, which can be substantially faster for
+// large trees. If workers is less than 1, runtime.GOMAXPROCS(0) is used.
+//
+// Because directories are walked concurrently, fn may be called
+// simultaneously from multiple goroutines and must be safe for concurrent
+// use. The entries of each directory are visited in lexical order by a
+// single goroutine, and a directory is always visited before any of the
+// entries within it, but there is no ordering between entries of
+// different directories.
+//
+// SkipDir and SkipAll have the same meaning as for WalkDir. If fn returns
+// any other non-nil error, WalkDirParallel stops starting new work,
+// waits for outstanding calls to fn to finish and returns an error.
+// If more than one call to fn returned an error before the walk stopped,
+// the error returned is the one whose path sorts first, so the result
+// does not depend on goroutine scheduling when the walk fails at a
+// single path.
+//
+// WalkDirParallel does not follow symbolic links.
+func WalkDirParallel(root string, workers int, fn fs.WalkDirFunc) error {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	info, err := os.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		d := &statDirEntry{info}
+		err = fn(root, d, nil)
+		if err == nil && d.IsDir() {
+			// The calling goroutine is the first worker.
+			w := &parallelWalker{fn: fn, sem: make(chan struct{}, workers-1)}
+			w.walk(root, d)
+			w.wg.Wait()
+			err = w.err
+		}
+	}
+	if err == SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+// A parallelWalker holds the state shared by the goroutines
+// of a WalkDirParallel call.
+type parallelWalker struct {
+	fn  fs.WalkDirFunc
+	sem chan struct{} // limits the number of extra goroutines
+	wg  sync.WaitGroup
+
+	stopped int32 // atomic; set once the walk must stop
+
+	mu      sync.Mutex
+	err     error  // first error by path, or nil
+	errPath string // path at which err occurred
+}
+
+// walk reads the directory path, described by d, and visits its entries.
+// The walk function has already been called for path itself.
+func (w *parallelWalker) walk(path string, d fs.DirEntry) {
+	if w.isStopped() {
+		return
+	}
+	dirs, err := readDir(path)
+	if err != nil {
+		// Second call, to report ReadDir error.
+		if err = w.fn(path, d, err); err != nil {
+			if err != SkipDir {
+				w.stop(path, err)
+			}
+			return
+		}
+	}
+	for _, d1 := range dirs {
+		if w.isStopped() {
+			return
+		}
+		path1 := Join(path, d1.Name())
+		if err := w.fn(path1, d1, nil); err != nil {
+			if err == SkipDir {
+				if d1.IsDir() {
+					// Successfully skipped directory.
+					continue
+				}
+				// Skip the remaining entries in path.
+				return
+			}
+			w.stop(path1, err)
+			return
+		}
+		if d1.IsDir() {
+			w.spawn(path1, d1)
+		}
+	}
+}
+
+// spawn walks the directory path in a new goroutine if the worker
+// limit allows it, and otherwise in the calling goroutine.
+func (w *parallelWalker) spawn(path string, d fs.DirEntry) {
+	select {
+	case w.sem <- struct{}{}:
+		w.wg.Add(1)
+		go func() {
+			defer func() {
+				<-w.sem
+				w.wg.Done()
+			}()
+			w.walk(path, d)
+		}()
+	default:
+		w.walk(path, d)
+	}
+}
+
+func (w *parallelWalker) isStopped() bool {
+	return atomic.LoadInt32(&w.stopped) != 0
+}
+
+// stop stops the walk because fn returned err for path.
+// SkipAll stops the walk without recording an error.
+func (w *parallelWalker) stop(path string, err error) {
+	atomic.StoreInt32(&w.stopped, 1)
+	if err == SkipAll {
+		return
+	}
+	w.mu.Lock()
+	if w.err == nil || path < w.errPath {
+		w.err, w.errPath = err, path
+	}
+	w.mu.Unlock()
+}
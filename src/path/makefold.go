@@ -0,0 +1,129 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build ignore
+// +build ignore
+
+//
+// usage:
+//
+// go run makefold.go -output fold.go
+//
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"unicode"
+)
+
+var filename = flag.String("output", "fold.go", "output file name")
+
+// upperLower is the foldRange delta marking a range of alternating
+// upper and lower case pairs. It must match the value in match.go.
+const upperLower = unicode.MaxRune + 1
+
+type foldRange struct {
+	lo, hi uint32
+	delta  int32
+}
+
+// next returns the next rune in r's simple case folding orbit.
+func next(r rune) rune {
+	return unicode.SimpleFold(r)
+}
+
+// isPair reports whether r and r+1 form a two-element orbit.
+func isPair(r rune) bool {
+	return next(r) == r+1 && next(r+1) == r
+}
+
+func scan() []foldRange {
+	var ranges []foldRange
+	for r := rune(0); r <= unicode.MaxRune; {
+		n := next(r)
+		if n == r {
+			r++
+			continue
+		}
+		if isPair(r) {
+			lo := r
+			for r+2 <= unicode.MaxRune && isPair(r+2) {
+				r += 2
+			}
+			ranges = append(ranges, foldRange{uint32(lo), uint32(r + 1), upperLower})
+			r += 2
+			continue
+		}
+		lo, delta := r, n-r
+		for r+1 <= unicode.MaxRune && next(r+1) == r+1+delta && next(r+1) != r+1 {
+			r++
+		}
+		ranges = append(ranges, foldRange{uint32(lo), uint32(r), delta})
+		r++
+	}
+	return ranges
+}
+
+func lookup(ranges []foldRange, r rune) rune {
+	for _, fr := range ranges {
+		if uint32(r) < fr.lo || fr.hi < uint32(r) {
+			continue
+		}
+		if fr.delta == upperLower {
+			if (uint32(r)-fr.lo)&1 == 0 {
+				return r + 1
+			}
+			return r - 1
+		}
+		return r + rune(fr.delta)
+	}
+	return r
+}
+
+func main() {
+	flag.Parse()
+
+	ranges := scan()
+	for r := rune(0); r <= unicode.MaxRune; r++ {
+		if got, want := lookup(ranges, r), next(r); got != want {
+			log.Fatalf("%U: fold=%U, want %U\n", r, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, `// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.`+"\n\n")
+	fmt.Fprintf(&buf, "// Code generated by go run makefold.go -output fold.go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package path\n\n")
+
+	fmt.Fprintf(&buf, "// Unicode version %s: %d*12 = %d bytes\n\n",
+		unicode.Version, len(ranges), len(ranges)*12)
+
+	fmt.Fprintf(&buf, "var foldRanges = []foldRange{\n")
+	for _, fr := range ranges {
+		if fr.delta == upperLower {
+			fmt.Fprintf(&buf, "\t{%#04x, %#04x, upperLower},\n", fr.lo, fr.hi)
+		} else {
+			fmt.Fprintf(&buf, "\t{%#04x, %#04x, %d},\n", fr.lo, fr.hi, fr.delta)
+		}
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	data, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = os.WriteFile(*filename, data, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -2,6 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+//go:generate go run makefold.go -output fold.go
+
 package path
 
 import (
@@ -36,6 +38,19 @@ var ErrBadPattern = errors.New("syntax error in pattern")
 // is malformed.
 //
 func Match(pattern, name string) (matched bool, err error) {
+	return matchPattern(pattern, name, false)
+}
+
+// MatchFold is like Match but matches name without regard to case,
+// under simple Unicode case folding. Literal characters in pattern match
+// any character in name that is equal under folding, and a character
+// class matches if any case variant of the name character is in one of
+// its ranges.
+func MatchFold(pattern, name string) (matched bool, err error) {
+	return matchPattern(pattern, name, true)
+}
+
+func matchPattern(pattern, name string, fold bool) (matched bool, err error) {
 Pattern:
 	for len(pattern) > 0 {
 		var star bool
@@ -46,7 +61,7 @@ Pattern:
 			return bytealg.IndexByteString(name, '/') < 0, nil
 		}
 		// Look for match at current position.
-		t, ok, err := matchChunk(chunk, name)
+		t, ok, err := matchChunk(chunk, name, fold)
 		// if we're the last chunk, make sure we've exhausted the name
 		// otherwise we'll give a false result even if we could still match
 		// using the star
@@ -61,7 +76,7 @@ Pattern:
 			// Look for match skipping i+1 bytes.
 			// Cannot skip /.
 			for i := 0; i < len(name) && name[i] != '/'; i++ {
-				t, ok, err := matchChunk(chunk, name[i+1:])
+				t, ok, err := matchChunk(chunk, name[i+1:], fold)
 				if ok {
 					// if we're the last chunk, make sure we exhausted the name
 					if len(pattern) == 0 && len(t) > 0 {
@@ -79,7 +94,7 @@ Pattern:
 		// check that the remainder of the pattern is syntactically valid.
 		for len(pattern) > 0 {
 			_, chunk, pattern = scanChunk(pattern)
-			if _, _, err := matchChunk(chunk, ""); err != nil {
+			if _, _, err := matchChunk(chunk, "", fold); err != nil {
 				return false, err
 			}
 		}
@@ -121,7 +136,8 @@ Scan:
 // matchChunk checks whether chunk matches the beginning of s.
 // If so, it returns the remainder of s (after the match).
 // Chunk is all single-character operators: literals, char classes, and ?.
-func matchChunk(chunk, s string) (rest string, ok bool, err error) {
+// If fold is set, literals and char classes match under simple case folding.
+func matchChunk(chunk, s string, fold bool) (rest string, ok bool, err error) {
 	// failed records whether the match has failed.
 	// After the match fails, the loop continues on processing chunk,
 	// checking that the pattern is well-formed but no longer reading s.
@@ -164,7 +180,7 @@ func matchChunk(chunk, s string) (rest string, ok bool, err error) {
 						return "", false, err
 					}
 				}
-				if lo <= r && r <= hi {
+				if inRange(lo, hi, r, fold) {
 					match = true
 				}
 				nrange++
@@ -191,8 +207,20 @@ func matchChunk(chunk, s string) (rest string, ok bool, err error) {
 			fallthrough
 
 		default:
+			if fold && (chunk[0] >= utf8.RuneSelf || s != "" && s[0] >= utf8.RuneSelf) {
+				c, cn := utf8.DecodeRuneInString(chunk)
+				if !failed {
+					r, n := utf8.DecodeRuneInString(s)
+					if !equalRuneFold(c, cn, r, n) {
+						failed = true
+					}
+					s = s[n:]
+				}
+				chunk = chunk[cn:]
+				break
+			}
 			if !failed {
-				if chunk[0] != s[0] {
+				if chunk[0] != s[0] && (!fold || lower(chunk[0]) != lower(s[0])) {
 					failed = true
 				}
 				s = s[1:]
@@ -229,3 +257,86 @@ func getEsc(chunk string) (r rune, nchunk string, err error) {
 	}
 	return
 }
+
+// upperLower is the foldRange delta marking a range of alternating
+// upper and lower case pairs, starting with the upper case rune.
+const upperLower = utf8.MaxRune + 1
+
+// A foldRange maps each rune in [lo, hi] to the next rune in its simple
+// case folding orbit by adding delta.
+type foldRange struct {
+	lo, hi uint32
+	delta  int32
+}
+
+// simpleFold is unicode.SimpleFold, which package path cannot import.
+// It returns the next rune in r's simple case folding orbit, or r
+// itself if r has no other case variants.
+func simpleFold(r rune) rune {
+	if r < 0 || r > utf8.MaxRune {
+		return r
+	}
+	// Find the first range with hi >= r.
+	i, j := 0, len(foldRanges)
+	for i < j {
+		h := int(uint(i+j) >> 1)
+		if foldRanges[h].hi < uint32(r) {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	if i >= len(foldRanges) || uint32(r) < foldRanges[i].lo {
+		return r
+	}
+	fr := foldRanges[i]
+	if fr.delta == upperLower {
+		if (uint32(r)-fr.lo)&1 == 0 {
+			return r + 1
+		}
+		return r - 1
+	}
+	return r + rune(fr.delta)
+}
+
+// lower returns the ASCII lower case form of c.
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// equalRuneFold reports whether the runes c and r, decoded from
+// cn and n bytes respectively, are equal under simple case folding.
+// Invalid encodings only match themselves.
+func equalRuneFold(c rune, cn int, r rune, n int) bool {
+	if c == utf8.RuneError && cn <= 1 || r == utf8.RuneError && n <= 1 {
+		return c == r && cn == n
+	}
+	if c == r {
+		return true
+	}
+	for f := simpleFold(c); f != c; f = simpleFold(f) {
+		if f == r {
+			return true
+		}
+	}
+	return false
+}
+
+// inRange reports whether lo <= r <= hi or, if fold is set, whether
+// any rune in r's simple case folding orbit is within that range.
+func inRange(lo, hi, r rune, fold bool) bool {
+	if lo <= r && r <= hi {
+		return true
+	}
+	if fold {
+		for f := simpleFold(r); f != r; f = simpleFold(f) {
+			if lo <= f && f <= hi {
+				return true
+			}
+		}
+	}
+	return false
+}
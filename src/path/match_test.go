@@ -7,6 +7,7 @@ package path_test
 import (
 	. "path"
 	"testing"
+	"unicode"
 )
 
 type MatchTest struct {
@@ -82,3 +83,66 @@ func TestMatch(t *testing.T) {
 		}
 	}
 }
+
+var matchFoldTests = []MatchTest{
+	{"abc", "ABC", true, nil},
+	{"ABC", "abc", true, nil},
+	{"abc", "abd", false, nil},
+	{"*.JPG", "photo.jpg", true, nil},
+	{"*.jpg", "a/photo.JPG", false, nil},
+	{"a*/B", "Axx/b", true, nil},
+	{"[a-c]x", "BX", true, nil},
+	{"[^a-c]x", "BX", false, nil},
+	{"[A-C]", "d", false, nil},
+	{"k", "\u212a", true, nil}, // KELVIN SIGN
+	{"K", "\u212a", true, nil}, // KELVIN SIGN
+	{"\u212a", "k", true, nil}, // KELVIN SIGN
+	{"s", "\u017f", true, nil}, // LATIN SMALL LETTER LONG S
+	{"[r-t]", "\u017f", true, nil},
+	{"σ*", "ΣΑ", true, nil},
+	{"σ", "ς", true, nil},
+	{"Σ", "ς", true, nil},
+	{"ǆ", "ǅ", true, nil},
+	{"ǆ", "Ǆ", true, nil},
+	{"a?b", "A☺B", true, nil},
+	{"\\A", "a", true, nil},
+	{"\xff", "\xff", true, nil},
+	{"\ufffd", "\xff", false, nil},
+	{"\xff", "\ufffd", false, nil},
+	{"é", "É", true, nil},
+	{"a[", "A", false, ErrBadPattern},
+	{"[]a]", "]", false, ErrBadPattern},
+	{"\\", "a", false, ErrBadPattern},
+}
+
+func TestMatchFold(t *testing.T) {
+	for _, tt := range matchFoldTests {
+		ok, err := MatchFold(tt.pattern, tt.s)
+		if ok != tt.match || err != tt.err {
+			t.Errorf("MatchFold(%#q, %#q) = %v, %v want %v, %v", tt.pattern, tt.s, ok, err, tt.match, tt.err)
+		}
+	}
+	// Without case differences, MatchFold must agree with Match.
+	for _, tt := range matchTests {
+		if tt.pattern == "*[a-ζ]" {
+			continue
+		}
+		ok, err := MatchFold(tt.pattern, tt.s)
+		if ok != tt.match || err != tt.err {
+			t.Errorf("MatchFold(%#q, %#q) = %v, %v want %v, %v", tt.pattern, tt.s, ok, err, tt.match, tt.err)
+		}
+	}
+}
+
+func TestSimpleFold(t *testing.T) {
+	for r := rune(0); r <= unicode.MaxRune; r++ {
+		if got, want := SimpleFold(r), unicode.SimpleFold(r); got != want {
+			t.Fatalf("simpleFold(%U) = %U, want %U", r, got, want)
+		}
+	}
+	for _, r := range []rune{-1, unicode.MaxRune + 1} {
+		if got := SimpleFold(r); got != r {
+			t.Errorf("simpleFold(%U) = %U, want %U", r, got, r)
+		}
+	}
+}
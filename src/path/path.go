@@ -135,6 +135,103 @@ func Clean(path string) string {
 	return out.string()
 }
 
+// EqualClean reports whether a and b name the same path after Cleaning,
+// that is, whether Clean(a) == Clean(b). Unless either path contains a
+// .. element, EqualClean compares the paths element by element without
+// constructing their Cleaned forms.
+func EqualClean(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if IsAbs(a) != IsAbs(b) {
+		return false
+	}
+	if hasDotDot(a) || hasDotDot(b) {
+		return Clean(a) == Clean(b)
+	}
+	// Without .. elements, Clean only removes empty and . elements.
+	var ea, eb string
+	i, j := 0, 0
+	for {
+		ea, i = nextElem(a, i)
+		eb, j = nextElem(b, j)
+		if ea != eb {
+			return false
+		}
+		if ea == "" {
+			return true
+		}
+	}
+}
+
+// nextElem returns the first element of path[i:] that is neither empty
+// nor ., along with the index following it. At the end of path it
+// returns an empty element.
+func nextElem(path string, i int) (elem string, next int) {
+	for i < len(path) {
+		if path[i] == '/' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(path) && path[j] != '/' {
+			j++
+		}
+		if j-i != 1 || path[i] != '.' {
+			return path[i:j], j
+		}
+		i = j
+	}
+	return "", i
+}
+
+// hasDotDot reports whether path contains a .. element.
+func hasDotDot(path string) bool {
+	for i := 0; i+1 < len(path); i++ {
+		if path[i] == '.' && path[i+1] == '.' &&
+			(i == 0 || path[i-1] == '/') &&
+			(i+2 == len(path) || path[i+2] == '/') {
+			return true
+		}
+	}
+	return false
+}
+
+// Depth returns the number of elements in Clean(path), not counting
+// the root or a lone ".". Any .. elements that remain at the start of
+// a relative path after cleaning are counted. Depth does not allocate.
+func Depth(path string) int {
+	rooted := IsAbs(path)
+	// n is the number of elements so far, of which the first
+	// dotdot are .. elements that cannot be backtracked over.
+	n, dotdot := 0, 0
+	for i := 0; i < len(path); {
+		if path[i] == '/' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(path) && path[j] != '/' {
+			j++
+		}
+		switch elem := path[i:j]; {
+		case elem == ".":
+		case elem == "..":
+			switch {
+			case n > dotdot:
+				n--
+			case !rooted:
+				n++
+				dotdot++
+			}
+		default:
+			n++
+		}
+		i = j
+	}
+	return n
+}
+
 // lastSlash(s) is strings.LastIndex(s, "/") but we can't import strings.
 func lastSlash(s string) int {
 	i := len(s) - 1
@@ -154,6 +251,59 @@ func Split(path string) (dir, file string) {
 	return path[:i+1], path[i+1:]
 }
 
+// SplitN splits path after its first n elements, separating it into
+// the leading elements and the remainder. Elements are separated by one
+// or more slashes; any leading slashes are kept in head and the slashes
+// between head and rest are dropped.
+// If path has fewer than n elements, head holds all of them and rest is
+// empty. If n <= 0, SplitN returns an empty head and rest set to path.
+// The path is not Cleaned, so . and .. count as elements, and head and
+// rest are always substrings of path.
+func SplitN(path string, n int) (head, rest string) {
+	if n <= 0 {
+		return "", path
+	}
+	i := 0
+	for i < len(path) && path[i] == '/' {
+		i++
+	}
+	end := i
+	for ; n > 0 && i < len(path); n-- {
+		for i < len(path) && path[i] != '/' {
+			i++
+		}
+		end = i
+		for i < len(path) && path[i] == '/' {
+			i++
+		}
+	}
+	return path[:end], path[i:]
+}
+
+// TrimElementPrefix returns path without the leading prefix and any
+// slashes that follow it, provided prefix ends at an element boundary
+// of path: "/foo/bar/baz" has the prefix "/foo/bar" but "/foo/barbaz"
+// does not. If path does not have the prefix, TrimElementPrefix returns
+// path unchanged and false. An empty prefix matches every path.
+// The paths are compared lexically; callers should Clean them first
+// if they may contain redundant slashes or . and .. elements.
+func TrimElementPrefix(path, prefix string) (string, bool) {
+	if prefix == "" {
+		return path, true
+	}
+	if len(path) < len(prefix) || path[:len(prefix)] != prefix {
+		return path, false
+	}
+	rest := path[len(prefix):]
+	if rest != "" && rest[0] != '/' && prefix[len(prefix)-1] != '/' {
+		return path, false
+	}
+	for rest != "" && rest[0] == '/' {
+		rest = rest[1:]
+	}
+	return rest, true
+}
+
 // Join joins any number of path elements into a single path,
 // separating them with slashes. Empty elements are ignored.
 // The result is Cleaned. However, if the argument list is
@@ -192,6 +342,66 @@ func Ext(path string) string {
 	return ""
 }
 
+// HasExt reports whether the final element of path ends in any of
+// the extensions exts. Each extension must include its leading dot, as
+// returned by Ext; unlike Ext, an extension may itself contain dots,
+// so that "a/b.tar.gz" has the extension ".tar.gz" as well as ".gz".
+// Extensions that are empty, lack the leading dot, or contain a slash
+// never match. HasExt does not allocate.
+func HasExt(path string, exts ...string) bool {
+	for _, ext := range exts {
+		if e, ok := extSuffix(path, ext); ok && e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// HasExtFold is like HasExt but compares extensions without regard
+// to ASCII case, so that "photo.JPG" has the extension ".jpg".
+func HasExtFold(path string, exts ...string) bool {
+	for _, ext := range exts {
+		if e, ok := extSuffix(path, ext); ok && equalFoldASCII(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// extSuffix returns the suffix of path with the same length as ext,
+// if ext is a valid extension and the suffix lies within the final
+// element of path.
+func extSuffix(path, ext string) (string, bool) {
+	if ext == "" || ext[0] != '.' || len(ext) > len(path) {
+		return "", false
+	}
+	for i := 0; i < len(ext); i++ {
+		if ext[i] == '/' {
+			return "", false
+		}
+	}
+	e := path[len(path)-len(ext):]
+	for i := 0; i < len(e); i++ {
+		if e[i] == '/' {
+			return "", false
+		}
+	}
+	return e, true
+}
+
+// equalFoldASCII reports whether s and t are equal under ASCII case folding.
+func equalFoldASCII(s, t string) bool {
+	if len(s) != len(t) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if lower(s[i]) != lower(t[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // Base returns the last element of path.
 // Trailing slashes are removed before extracting the last element.
 // If the path is empty, Base returns ".".
@@ -220,6 +430,41 @@ func IsAbs(path string) bool {
 	return len(path) > 0 && path[0] == '/'
 }
 
+// IsLocal reports whether path, using lexical analysis only, has all
+// of these properties:
+//
+//   - is within the subtree rooted at the directory in which path is evaluated
+//   - is not an absolute path
+//   - is not empty
+//
+// IsLocal is a purely lexical operation: "a/../b" is local but "a/../../b"
+// is not. It does not account for symbolic links or other file system
+// indirection.
+func IsLocal(path string) bool {
+	if path == "" || IsAbs(path) {
+		return false
+	}
+	depth := 0
+	for i := 0; i < len(path); {
+		j := i
+		for j < len(path) && path[j] != '/' {
+			j++
+		}
+		switch path[i:j] {
+		case "", ".":
+		case "..":
+			if depth == 0 {
+				return false
+			}
+			depth--
+		default:
+			depth++
+		}
+		i = j + 1
+	}
+	return true
+}
+
 // Dir returns all but the last element of path, typically the path's directory.
 // After dropping the final element using Split, the path is Cleaned and trailing
 // slashes are removed.
@@ -229,5 +474,39 @@ func IsAbs(path string) bool {
 // slash.
 func Dir(path string) string {
 	dir, _ := Split(path)
+	// Fast path: if the directory is already clean apart from
+	// its trailing slash, return it without calling Clean.
+	if len(dir) > 1 {
+		if d := dir[:len(dir)-1]; isClean(d) {
+			return d
+		}
+	}
 	return Clean(dir)
 }
+
+// isClean reports whether Clean(path) == path, conservatively
+// returning false for any path containing a .. element.
+func isClean(path string) bool {
+	if path == "/" {
+		return true
+	}
+	if path == "" || path[len(path)-1] == '/' {
+		return false
+	}
+	i := 0
+	if path[0] == '/' {
+		i = 1
+	}
+	for i < len(path) {
+		j := i
+		for j < len(path) && path[j] != '/' {
+			j++
+		}
+		switch elem := path[i:j]; elem {
+		case "", ".", "..":
+			return false
+		}
+		i = j + 1
+	}
+	return true
+}
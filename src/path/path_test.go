@@ -7,6 +7,7 @@ package path_test
 import (
 	. "path"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -92,6 +93,117 @@ func TestCleanMallocs(t *testing.T) {
 	}
 }
 
+type EqualCleanTest struct {
+	a, b  string
+	equal bool
+}
+
+var equalCleanTests = []EqualCleanTest{
+	{"", "", true},
+	{"", ".", true},
+	{"", "./", true},
+	{"", "/", false},
+	{"a", "a", true},
+	{"a", "b", false},
+	{"a", "a/", true},
+	{"a//b", "a/b", true},
+	{"a/./b/.", "a/b", true},
+	{"./a", "a", true},
+	{"a/b", "a/bc", false},
+	{"a/bc", "a/b/c", false},
+	{"a/b", "a", false},
+	{"/a", "a", false},
+	{"//a", "/a", true},
+	{"/", "//", true},
+	{"/", "/.", true},
+	{"..", "../", true},
+	{"..", ".", false},
+	{"a/..", ".", true},
+	{"a/../b", "b", true},
+	{"/../a", "/a", true},
+	{"../a", "a", false},
+	{"a/b/../../..", "..", true},
+	{"a/.../b", "a/.../b/", true},
+	{"a/..b", "a/..b", true},
+	{"a/..b", "..b", false},
+}
+
+func TestEqualClean(t *testing.T) {
+	for _, test := range equalCleanTests {
+		for _, ab := range [][2]string{{test.a, test.b}, {test.b, test.a}} {
+			if eq := EqualClean(ab[0], ab[1]); eq != test.equal {
+				t.Errorf("EqualClean(%q, %q) = %v, want %v", ab[0], ab[1], eq, test.equal)
+			}
+			if want := Clean(ab[0]) == Clean(ab[1]); want != test.equal {
+				t.Errorf("bad test: Clean(%q) == Clean(%q) is %v", ab[0], ab[1], want)
+			}
+		}
+	}
+	for _, test := range cleantests {
+		if !EqualClean(test.path, test.result) {
+			t.Errorf("EqualClean(%q, %q) = false, want true", test.path, test.result)
+		}
+	}
+}
+
+func TestEqualCleanMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+
+	for _, test := range equalCleanTests {
+		if strings.Contains(test.a+"/"+test.b, "..") {
+			// Paths with .. elements may be Cleaned.
+			continue
+		}
+		allocs := testing.AllocsPerRun(100, func() { EqualClean(test.a, test.b) })
+		if allocs > 0 {
+			t.Errorf("EqualClean(%q, %q): %v allocs, want zero", test.a, test.b, allocs)
+		}
+	}
+}
+
+var depthTests = []struct {
+	path  string
+	depth int
+}{
+	{"", 0},
+	{".", 0},
+	{"/", 0},
+	{"//", 0},
+	{"a", 1},
+	{"/a", 1},
+	{"a/b/c", 3},
+	{"a//b/./c/", 3},
+	{"a/b/..", 1},
+	{"a/../..", 1},
+	{"../../a", 3},
+	{"/../a", 1},
+	{"a/.../b", 3},
+}
+
+func TestDepth(t *testing.T) {
+	for _, test := range depthTests {
+		if d := Depth(test.path); d != test.depth {
+			t.Errorf("Depth(%q) = %d, want %d", test.path, d, test.depth)
+		}
+	}
+	// Depth must agree with the number of elements in the Cleaned path.
+	for _, test := range cleantests {
+		want := 0
+		if test.result != "." && test.result != "/" {
+			want = strings.Count(strings.TrimPrefix(test.result, "/"), "/") + 1
+		}
+		if d := Depth(test.path); d != want {
+			t.Errorf("Depth(%q) = %d, want %d", test.path, d, want)
+		}
+	}
+}
+
 type SplitTest struct {
 	path, dir, file string
 }
@@ -112,6 +224,70 @@ func TestSplit(t *testing.T) {
 	}
 }
 
+type SplitNTest struct {
+	path       string
+	n          int
+	head, rest string
+}
+
+var splitNTests = []SplitNTest{
+	{"", 1, "", ""},
+	{"a", 0, "", "a"},
+	{"a", -1, "", "a"},
+	{"a", 1, "a", ""},
+	{"a", 2, "a", ""},
+	{"a/b/c", 1, "a", "b/c"},
+	{"a/b/c", 2, "a/b", "c"},
+	{"a/b/c", 3, "a/b/c", ""},
+	{"a//b//c", 1, "a", "b//c"},
+	{"a/b/", 2, "a/b", ""},
+	{"a/b/", 3, "a/b", ""},
+	{"/", 1, "/", ""},
+	{"/a/b", 0, "", "/a/b"},
+	{"/a/b", 1, "/a", "b"},
+	{"//a/b", 1, "//a", "b"},
+	{"./a/../b", 2, "./a", "../b"},
+	{"example.com/m/v2/pkg", 3, "example.com/m/v2", "pkg"},
+}
+
+func TestSplitN(t *testing.T) {
+	for _, test := range splitNTests {
+		if h, r := SplitN(test.path, test.n); h != test.head || r != test.rest {
+			t.Errorf("SplitN(%q, %d) = %q, %q, want %q, %q", test.path, test.n, h, r, test.head, test.rest)
+		}
+	}
+}
+
+var trimElementPrefixTests = []struct {
+	path, prefix, rest string
+	ok                 bool
+}{
+	{"/foo/bar/baz", "/foo/bar", "baz", true},
+	{"/foo/barbaz", "/foo/bar", "/foo/barbaz", false},
+	{"/foo/bar", "/foo/bar", "", true},
+	{"/foo/bar/", "/foo/bar", "", true},
+	{"/foo/bar//baz", "/foo/bar", "baz", true},
+	{"/foo/bar/baz", "/foo/bar/", "baz", true},
+	{"/foo/bar", "/foo/bar/", "/foo/bar", false},
+	{"/foo", "/", "foo", true},
+	{"foo", "/", "foo", false},
+	{"foo/bar", "", "foo/bar", true},
+	{"/foo", "", "/foo", true},
+	{"", "", "", true},
+	{"", "a", "", false},
+	{"a/b", "b", "a/b", false},
+	{"ab/c", "a", "ab/c", false},
+}
+
+func TestTrimElementPrefix(t *testing.T) {
+	for _, test := range trimElementPrefixTests {
+		rest, ok := TrimElementPrefix(test.path, test.prefix)
+		if rest != test.rest || ok != test.ok {
+			t.Errorf("TrimElementPrefix(%q, %q) = %q, %v, want %q, %v", test.path, test.prefix, rest, ok, test.rest, test.ok)
+		}
+	}
+}
+
 type JoinTest struct {
 	elem []string
 	path string
@@ -164,6 +340,59 @@ func TestExt(t *testing.T) {
 	}
 }
 
+var hasExtTests = []struct {
+	path      string
+	exts      []string
+	has, fold bool
+}{
+	{"path.go", []string{".go"}, true, true},
+	{"path.go", []string{".c", ".go"}, true, true},
+	{"path.go", []string{".c", ".h"}, false, false},
+	{"path.go", nil, false, false},
+	{"path.GO", []string{".go"}, false, true},
+	{"a/b/PHOTO.Jpg", []string{".png", ".jpg"}, false, true},
+	{"a.tar.gz", []string{".tar.gz"}, true, true},
+	{"a.tar.gz", []string{".gz"}, true, true},
+	{"a.dir/b", []string{".dir"}, false, false},
+	{"a.dir/b", []string{".dir/b"}, false, false},
+	{"a.go/", []string{".go"}, false, false},
+	{".go", []string{".go"}, true, true},
+	{"go", []string{".go"}, false, false},
+	{"path.go", []string{"go"}, false, false},
+	{"path.go", []string{""}, false, false},
+	{"", []string{".go"}, false, false},
+}
+
+func TestHasExt(t *testing.T) {
+	for _, test := range hasExtTests {
+		if has := HasExt(test.path, test.exts...); has != test.has {
+			t.Errorf("HasExt(%q, %q) = %v, want %v", test.path, test.exts, has, test.has)
+		}
+		if fold := HasExtFold(test.path, test.exts...); fold != test.fold {
+			t.Errorf("HasExtFold(%q, %q) = %v, want %v", test.path, test.exts, fold, test.fold)
+		}
+	}
+}
+
+func TestHasExtMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+
+	exts := []string{".png", ".jpeg", ".jpg"}
+	allocs := testing.AllocsPerRun(100, func() {
+		HasExt("a/b/photo.JPG", exts...)
+		HasExtFold("a/b/photo.JPG", exts...)
+	})
+	if allocs > 0 {
+		t.Errorf("HasExt: %v allocs, want zero", allocs)
+	}
+}
+
 var basetests = []PathTest{
 	// Already clean
 	{"", "."},
@@ -187,6 +416,23 @@ func TestBase(t *testing.T) {
 	}
 }
 
+func TestBaseMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+
+	for _, test := range basetests {
+		allocs := testing.AllocsPerRun(100, func() { Base(test.path) })
+		if allocs > 0 {
+			t.Errorf("Base(%q): %v allocs, want zero", test.path, allocs)
+		}
+	}
+}
+
 var dirtests = []PathTest{
 	{"", "."},
 	{".", "."},
@@ -201,6 +447,14 @@ var dirtests = []PathTest{
 	{"a/b/.x", "a/b"},
 	{"a/b/c.", "a/b"},
 	{"a/b/c.x", "a/b"},
+	{"//foo", "/"},
+	{"a/./b", "a"},
+	{"a/../b", "."},
+	{"../b", ".."},
+	{"a/b/../c", "a"},
+	{"a//b//c", "a/b"},
+	{"/a/b/", "/a/b"},
+	{"./a", "."},
 }
 
 func TestDir(t *testing.T) {
@@ -211,6 +465,27 @@ func TestDir(t *testing.T) {
 	}
 }
 
+func TestDirMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		t.Log("skipping AllocsPerRun checks; GOMAXPROCS>1")
+		return
+	}
+
+	for _, test := range dirtests {
+		if !strings.HasPrefix(test.path, test.result) {
+			// The result is not a substring of the input.
+			continue
+		}
+		allocs := testing.AllocsPerRun(100, func() { Dir(test.path) })
+		if allocs > 0 {
+			t.Errorf("Dir(%q): %v allocs, want zero", test.path, allocs)
+		}
+	}
+}
+
 type IsAbsTest struct {
 	path  string
 	isAbs bool
@@ -234,3 +509,37 @@ func TestIsAbs(t *testing.T) {
 		}
 	}
 }
+
+var isLocalTests = []struct {
+	path    string
+	isLocal bool
+}{
+	{"", false},
+	{".", true},
+	{"..", false},
+	{"../a", false},
+	{"/", false},
+	{"/a", false},
+	{"a", true},
+	{"a/b", true},
+	{"a/", true},
+	{"a//b", true},
+	{"./a", true},
+	{"a/..", true},
+	{"a/../b", true},
+	{"a/../..", false},
+	{"a/../../b", false},
+	{"a/b/../../c", true},
+	{"a/.../b", true},
+	{"..a", true},
+	{"a/..b", true},
+	{`a\..`, true},
+}
+
+func TestIsLocal(t *testing.T) {
+	for _, test := range isLocalTests {
+		if got := IsLocal(test.path); got != test.isLocal {
+			t.Errorf("IsLocal(%q) = %v, want %v", test.path, got, test.isLocal)
+		}
+	}
+}
@@ -5,6 +5,8 @@
 package strings
 
 import (
+	"internal/bytealg"
+	"io"
 	"unicode/utf8"
 	"unsafe"
 )
@@ -62,9 +64,11 @@ func (b *Builder) Reset() {
 }
 
 // grow copies the buffer to a new, larger buffer so that there are at least n
-// bytes of capacity beyond len(b.buf).
+// bytes of capacity beyond len(b.buf). The new bytes beyond len(b.buf) are
+// not zeroed, since they are always written before they become part of the
+// string.
 func (b *Builder) grow(n int) {
-	buf := make([]byte, len(b.buf), 2*cap(b.buf)+n)
+	buf := bytealg.MakeNoZero(2*cap(b.buf) + n)[:len(b.buf)]
 	copy(buf, b.buf)
 	b.buf = buf
 }
@@ -123,3 +127,47 @@ func (b *Builder) WriteString(s string) (int, error) {
 	b.buf = append(b.buf, s...)
 	return len(s), nil
 }
+
+// minRead is the minimum slice size passed to a Read call by ReadFrom.
+const minRead = 512
+
+// ReadFrom reads data from r until EOF and appends it to b's buffer,
+// growing the buffer as needed. The return value n is the number of bytes
+// read. Any error except io.EOF encountered during the read is also
+// returned.
+//
+// ReadFrom reads into b's buffer directly, so io.Copy to a Builder does
+// not copy each chunk through an intermediate buffer.
+func (b *Builder) ReadFrom(r io.Reader) (n int64, err error) {
+	b.copyCheck()
+	// The bytes beyond len(b.buf) may be uninitialized (see grow), so
+	// clear them before r sees them: on entry, and after each growth.
+	// Those a read leaves beyond len(b.buf) have been seen by r already.
+	dirty := true
+	for {
+		if cap(b.buf)-len(b.buf) < minRead {
+			b.grow(minRead)
+			dirty = true
+		}
+		l := len(b.buf)
+		if dirty {
+			free := b.buf[l:cap(b.buf)]
+			for i := range free {
+				free[i] = 0
+			}
+			dirty = false
+		}
+		m, e := r.Read(b.buf[l:cap(b.buf)])
+		if m < 0 {
+			panic("strings.Builder.ReadFrom: reader returned negative count from Read")
+		}
+		b.buf = b.buf[:l+m]
+		n += int64(m)
+		if e == io.EOF {
+			return n, nil // e is EOF, so return nil explicitly
+		}
+		if e != nil {
+			return n, e
+		}
+	}
+}
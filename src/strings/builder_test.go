@@ -6,6 +6,8 @@ package strings_test
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	. "strings"
 	"testing"
 	"unicode/utf8"
@@ -178,6 +180,76 @@ func TestBuilderWriteByte(t *testing.T) {
 	check(t, &b, "a\x00")
 }
 
+func TestBuilderReadFrom(t *testing.T) {
+	for _, want := range []string{"", "hello", Repeat("0123456789", 1000)} {
+		var b Builder
+		b.WriteString("prefix:")
+		n, err := b.ReadFrom(NewReader(want))
+		if err != nil || n != int64(len(want)) {
+			t.Errorf("ReadFrom: got %d,%v; want %d,nil", n, err, len(want))
+		}
+		check(t, &b, "prefix:"+want)
+	}
+
+	// The contents read before an error are kept.
+	errRead := errors.New("read error")
+	var b Builder
+	r := io.MultiReader(NewReader("abc"), &errReader{errRead})
+	if n, err := b.ReadFrom(r); err != errRead || n != 3 {
+		t.Errorf("ReadFrom: got %d,%v; want 3,%v", n, err, errRead)
+	}
+	check(t, &b, "abc")
+
+	// io.Copy uses ReadFrom rather than copying through a buffer
+	// of its own.
+	b.Reset()
+	r = io.LimitReader(bytes.NewReader(bytes.Repeat([]byte("x"), 5000)), 4000)
+	if n, err := io.Copy(&b, r); err != nil || n != 4000 {
+		t.Errorf("io.Copy: got %d,%v; want 4000,nil", n, err)
+	}
+	check(t, &b, Repeat("x", 4000))
+}
+
+func TestBuilderReadFromZeroed(t *testing.T) {
+	// Grow leaves the bytes beyond the end of the string uninitialized;
+	// the reader must not see them.
+	for _, grow := range []int{0, 100, 10000} {
+		var b Builder
+		b.WriteString("abc")
+		b.Grow(grow)
+		r := &zeroCheckReader{t: t, s: Repeat("y", 3000)}
+		b.ReadFrom(r)
+		check(t, &b, "abc"+r.s)
+	}
+}
+
+// zeroCheckReader reads from s, at most half of each buffer at a time, and
+// reports an error if a buffer it is given to read into is not all zeros.
+type zeroCheckReader struct {
+	t *testing.T
+	s string
+	i int
+}
+
+func (r *zeroCheckReader) Read(p []byte) (int, error) {
+	for i, c := range p {
+		if c != 0 {
+			r.t.Errorf("Read buffer holds %#x at %d, want 0", c, i)
+			break
+		}
+	}
+	if r.i == len(r.s) {
+		return 0, io.EOF
+	}
+	n := copy(p[:len(p)/2+1], r.s[r.i:])
+	r.i += n
+	return n, nil
+}
+
+type errReader struct{ err error }
+
+func (r *errReader) Read([]byte) (int, error) { return 0, r.err }
+
 func TestBuilderAllocs(t *testing.T) {
 	// Issue 23382; verify that copyCheck doesn't force the
 	// Builder to escape and be heap allocated.
@@ -289,6 +361,16 @@ func TestBuilderCopyPanic(t *testing.T) {
 				b.Grow(2)
 			},
 		},
+		{
+			name:      "ReadFrom",
+			wantPanic: true,
+			fn: func() {
+				var a Builder
+				a.WriteByte('x')
+				b := a
+				b.ReadFrom(NewReader("y"))
+			},
+		},
 	}
 	for _, tt := range tests {
 		didPanic := make(chan bool)
@@ -360,3 +442,13 @@ func BenchmarkBuildString_ByteBuffer(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkBuilderReadFrom(b *testing.B) {
+	data := bytes.Repeat(someBytes, 2000)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		var sb Builder
+		sb.ReadFrom(bytes.NewReader(data))
+		sinkS = sb.String()
+	}
+}
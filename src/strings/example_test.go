@@ -141,6 +141,30 @@ func ExampleIndex() {
 	// -1
 }
 
+func ExampleSearcher() {
+	sr := strings.NewSearcher("ken")
+	for _, s := range []string{"chicken", "kitten", "kennel"} {
+		fmt.Println(s, sr.Index(s))
+	}
+	// Output:
+	// chicken 4
+	// kitten -1
+	// kennel 0
+}
+
+func ExampleMultiSearcher() {
+	m := strings.NewMultiSearcher("cat", "dog", "bird")
+	s := "the dog chased the cat up a tree"
+	fmt.Println(m.IndexAnyOf(s))
+	for _, match := range m.FindAll(s, -1) {
+		fmt.Println(match.Index, s[match.Index:match.Index+3])
+	}
+	// Output:
+	// 4 1
+	// 4 dog
+	// 19 cat
+}
+
 func ExampleIndexFunc() {
 	f := func(c rune) bool {
 		return unicode.Is(unicode.Han, c)
@@ -9,9 +9,10 @@ func (r *Replacer) Replacer() any {
 	return r.r
 }
 
+// PrintTrie prints the trie of a genericReplacer for the old strings
+// of r, whichever implementation r uses.
 func (r *Replacer) PrintTrie() string {
-	r.once.Do(r.buildOnce)
-	gen := r.r.(*genericReplacer)
+	gen := makeGenericReplacer(r.oldnew)
 	return gen.printNode(&gen.root, 0)
 }
 
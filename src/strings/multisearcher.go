@@ -0,0 +1,252 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package strings
+
+import (
+	"internal/bytealg"
+	"unicode/utf8"
+)
+
+// A MultiSearcher finds instances of any of a fixed set of strings,
+// its patterns, in other strings. It reads each byte of the string
+// searched at most once, however many patterns there are, rather than
+// once per pattern as a call to Index for each would.
+//
+// A MultiSearcher is safe for concurrent use by multiple goroutines.
+type MultiSearcher struct {
+	patterns []string
+	maxLen   int // length of the longest pattern
+
+	// The searcher is an Aho-Corasick automaton whose states are the
+	// prefixes of the patterns, state 0 being the empty prefix. Having
+	// read a string, it is in the state of the longest prefix that is
+	// a suffix of the string.
+	//
+	// Its transitions are on byte classes rather than bytes, to keep
+	// the table small: each byte in a pattern has a class of its own,
+	// numbered from 0, and all other bytes share class nclasses.
+	// trans[state*(nclasses+1)+class] is the next state.
+	mapping  [256]byte
+	nclasses int
+	trans    []int32
+
+	// out[state] is the index of the longest pattern that is a suffix
+	// of the state's prefix, the one listed first if several are equal,
+	// or -1 if there is none.
+	out []int32
+
+	// In state 0, the searcher skips to the next byte that begins a
+	// pattern. If all the patterns begin with the same byte, first is
+	// that byte; otherwise, if they all begin with ASCII bytes, firstSet
+	// is the set of them.
+	skip     int // skipNone, skipByte or skipSet
+	first    byte
+	firstSet bytealg.ASCIISet
+}
+
+const (
+	skipNone = iota
+	skipByte
+	skipSet
+)
+
+// A MultiMatch is an instance of one of the patterns of a
+// MultiSearcher in a string.
+type MultiMatch struct {
+	Index   int // index in the string of the instance
+	Pattern int // index of the pattern in the arguments to NewMultiSearcher
+}
+
+// NewMultiSearcher returns a MultiSearcher for the given patterns.
+// The time it takes and the space used by the MultiSearcher are
+// proportional to the total length of the patterns times the number
+// of distinct bytes in them.
+func NewMultiSearcher(patterns ...string) *MultiSearcher {
+	m := &MultiSearcher{patterns: append([]string(nil), patterns...)}
+
+	// Find each byte used, then assign them each a class.
+	for _, p := range patterns {
+		for i := 0; i < len(p); i++ {
+			m.mapping[p[i]] = 1
+		}
+		if len(p) > m.maxLen {
+			m.maxLen = len(p)
+		}
+	}
+	for _, b := range m.mapping {
+		m.nclasses += int(b)
+	}
+	var class byte
+	for i, b := range m.mapping {
+		if b == 0 {
+			m.mapping[i] = byte(m.nclasses)
+		} else {
+			m.mapping[i] = class
+			class++
+		}
+	}
+	stride := m.nclasses + 1
+
+	// Build the trie of the patterns, with -1 for a missing transition.
+	m.trans = make([]int32, stride)
+	m.out = []int32{-1}
+	for i := range m.trans {
+		m.trans[i] = -1
+	}
+	for pi, p := range patterns {
+		state := int32(0)
+		for i := 0; i < len(p); i++ {
+			t := int(state)*stride + int(m.mapping[p[i]])
+			if m.trans[t] < 0 {
+				m.trans[t] = int32(len(m.out))
+				m.out = append(m.out, -1)
+				for j := 0; j < stride; j++ {
+					m.trans = append(m.trans, -1)
+				}
+			}
+			state = m.trans[t]
+		}
+		if m.out[state] < 0 {
+			m.out[state] = int32(pi)
+		}
+	}
+
+	// Complete the transitions, in breadth-first order so that each
+	// state's failure state, that of its longest proper suffix, is
+	// complete before the state itself. A missing transition from a
+	// state is the one from its failure state.
+	fail := make([]int32, len(m.out))
+	queue := make([]int32, 0, len(m.out))
+	for c := 0; c < stride; c++ {
+		if t := m.trans[c]; t > 0 {
+			queue = append(queue, t)
+		} else {
+			m.trans[c] = 0
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		if m.out[state] < 0 {
+			m.out[state] = m.out[fail[state]]
+		}
+		row := m.trans[int(state)*stride : int(state+1)*stride]
+		frow := m.trans[int(fail[state])*stride : int(fail[state]+1)*stride]
+		for c, t := range row {
+			if t < 0 {
+				row[c] = frow[c]
+			} else {
+				fail[t] = frow[c]
+				queue = append(queue, t)
+			}
+		}
+	}
+
+	// Use the first bytes of the patterns to skip ahead in state 0,
+	// unless one of them is empty and so matches everywhere.
+	var firsts string
+	m.skip = skipSet
+	for _, p := range patterns {
+		if p == "" {
+			m.skip = skipNone
+			break
+		}
+		if IndexByte(firsts, p[0]) < 0 {
+			firsts += p[:1]
+		}
+	}
+	switch {
+	case m.skip == skipNone:
+	case len(firsts) == 1:
+		m.skip = skipByte
+		m.first = firsts[0]
+	default:
+		var isASCII bool
+		if m.firstSet, isASCII = bytealg.MakeASCIISet(firsts); !isASCII {
+			m.skip = skipNone
+		}
+	}
+	return m
+}
+
+// IndexAnyOf returns the index of the first instance in s of any of
+// the patterns and the index of that pattern in the arguments to
+// NewMultiSearcher. If instances of several patterns begin at that
+// index, it returns the pattern listed first. If there is no instance
+// of any pattern in s, it returns -1, -1.
+func (m *MultiSearcher) IndexAnyOf(s string) (index, pattern int) {
+	index, pattern = -1, -1
+	if len(m.patterns) == 0 {
+		return
+	}
+	stride := m.nclasses + 1
+	limit := len(s)
+	if o := m.out[0]; o >= 0 {
+		// An empty pattern matches at 0.
+		index, pattern = 0, int(o)
+		limit = min(len(s), m.maxLen)
+	}
+	state := int32(0)
+	for i := 0; i < limit; i++ {
+		if state == 0 && m.skip != skipNone {
+			var j int
+			if m.skip == skipByte {
+				j = IndexByte(s[i:limit], m.first)
+			} else {
+				j = bytealg.IndexAnyString(s[i:limit], &m.firstSet)
+			}
+			if j < 0 {
+				break
+			}
+			i += j
+		}
+		state = m.trans[int(state)*stride+int(m.mapping[s[i]])]
+		if o := m.out[state]; o >= 0 {
+			start := i + 1 - len(m.patterns[o])
+			if index < 0 || start < index || start == index && int(o) < pattern {
+				// Instances beginning no later than start
+				// end no later than start+maxLen.
+				index, pattern = start, int(o)
+				limit = min(len(s), start+m.maxLen)
+			}
+		}
+	}
+	return index, pattern
+}
+
+// FindAll returns the successive non-overlapping instances of the
+// patterns in s, each found as by IndexAnyOf. After an instance of an
+// empty pattern, the search resumes after the next UTF-8 sequence.
+// The count determines the number of instances to return:
+//
+//	n > 0: at most n instances
+//	n == 0: the result is nil (zero instances)
+//	n < 0: all instances
+func (m *MultiSearcher) FindAll(s string, n int) []MultiMatch {
+	var matches []MultiMatch
+	for p := 0; p <= len(s) && n != 0; n-- {
+		i, pat := m.IndexAnyOf(s[p:])
+		if i < 0 {
+			break
+		}
+		matches = append(matches, MultiMatch{Index: p + i, Pattern: pat})
+		p += i + len(m.patterns[pat])
+		if len(m.patterns[pat]) == 0 {
+			if p == len(s) {
+				break
+			}
+			_, size := utf8.DecodeRuneInString(s[p:])
+			p += size
+		}
+	}
+	return matches
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,151 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package strings_test
+
+import (
+	"reflect"
+	. "strings"
+	"testing"
+)
+
+// indexAnyOfNaive is a simple implementation of IndexAnyOf
+// using Index for each pattern.
+func indexAnyOfNaive(s string, patterns []string) (index, pattern int) {
+	index, pattern = -1, -1
+	for i, p := range patterns {
+		if j := Index(s, p); j >= 0 && (index < 0 || j < index) {
+			index, pattern = j, i
+		}
+	}
+	return index, pattern
+}
+
+var multiSearcherTests = []struct {
+	patterns []string
+	s        string
+	index    int
+	pattern  int
+}{
+	{nil, "", -1, -1},
+	{nil, "abc", -1, -1},
+	{[]string{""}, "", 0, 0},
+	{[]string{""}, "abc", 0, 0},
+	{[]string{"a"}, "", -1, -1},
+	{[]string{"x", ""}, "abc", 0, 1},
+	{[]string{"x", ""}, "xbc", 0, 0},
+	{[]string{"abc", "ab"}, "xxabc", 2, 0},
+	{[]string{"ab", "abc"}, "xxabc", 2, 0},
+	{[]string{"abc", "ab"}, "xxabd", 2, 1},
+	{[]string{"bcd", "abcx"}, "abcd", 1, 0},
+	{[]string{"bc", "abcd"}, "abcd", 0, 1},
+	{[]string{"he", "she", "his", "hers"}, "ushers", 1, 1},
+	{[]string{"he", "she", "his", "hers"}, "ahishers", 1, 2},
+	{[]string{"b", "b"}, "abc", 1, 0},
+	{[]string{"foo", "bar"}, "foofoo", 0, 0},
+	{[]string{"foo", "bar"}, "barfoo", 0, 1},
+	{[]string{"foo", "bar"}, "fobar", 2, 1},
+	{[]string{"foo", "bar"}, "fooba", 0, 0},
+	{[]string{"foo", "bar"}, "baz", -1, -1},
+	{[]string{"aab", "ab"}, "aaab", 1, 0},
+	{[]string{"世界", "界"}, "Hello, 世界", 7, 0},
+	{[]string{"界", "x"}, "Hello, 世界", 10, 0},
+	{[]string{"\xff", "\x80"}, "abc\x80\xff", 3, 1},
+}
+
+func TestMultiSearcher(t *testing.T) {
+	for _, tt := range multiSearcherTests {
+		m := NewMultiSearcher(tt.patterns...)
+		if i, p := m.IndexAnyOf(tt.s); i != tt.index || p != tt.pattern {
+			t.Errorf("NewMultiSearcher(%q).IndexAnyOf(%q) = %d, %d, want %d, %d",
+				tt.patterns, tt.s, i, p, tt.index, tt.pattern)
+		}
+	}
+}
+
+func TestMultiSearcherExhaustive(t *testing.T) {
+	// Sets of up to three patterns over a small alphabet, searched
+	// for in every short string over it, exercise the failure
+	// transitions and the choice among overlapping instances.
+	patterns := allStrings("abc", 3)
+	haystacks := allStrings("abc", 6)
+	if testing.Short() {
+		haystacks = allStrings("abc", 5)
+	}
+	sets := [][]string{}
+	for _, p := range patterns {
+		sets = append(sets, []string{p})
+	}
+	for i, p := range patterns {
+		for _, q := range patterns[i:] {
+			if len(p) >= 2 && len(q) >= 2 && p[0] == q[0] {
+				sets = append(sets, []string{p, q}, []string{q, p, "cc"})
+			}
+			if len(p) == 3 && len(q) == 1 {
+				sets = append(sets, []string{p, q})
+			}
+		}
+	}
+	for _, set := range sets {
+		m := NewMultiSearcher(set...)
+		for _, s := range haystacks {
+			i, p := m.IndexAnyOf(s)
+			wi, wp := indexAnyOfNaive(s, set)
+			if i != wi || p != wp {
+				t.Fatalf("NewMultiSearcher(%q).IndexAnyOf(%q) = %d, %d, want %d, %d",
+					set, s, i, p, wi, wp)
+			}
+		}
+	}
+}
+
+var multiSearcherFindAllTests = []struct {
+	patterns []string
+	s        string
+	n        int
+	out      []MultiMatch
+}{
+	{[]string{"a"}, "aaa", 0, nil},
+	{[]string{"a"}, "bbb", -1, nil},
+	{[]string{"a"}, "aaa", -1, []MultiMatch{{0, 0}, {1, 0}, {2, 0}}},
+	{[]string{"a"}, "aaa", 2, []MultiMatch{{0, 0}, {1, 0}}},
+	{[]string{"aa"}, "aaaaa", -1, []MultiMatch{{0, 0}, {2, 0}}},
+	{[]string{"ab", "ba"}, "abababa", -1, []MultiMatch{{0, 0}, {2, 0}, {4, 0}}},
+	{[]string{"ba", "aba"}, "ababa", -1, []MultiMatch{{0, 1}, {3, 0}}},
+	{[]string{"cat", "dog"}, "dog and cat", -1, []MultiMatch{{0, 1}, {8, 0}}},
+	{[]string{""}, "", -1, []MultiMatch{{0, 0}}},
+	{[]string{""}, "ab", -1, []MultiMatch{{0, 0}, {1, 0}, {2, 0}}},
+	{[]string{""}, "世界", -1, []MultiMatch{{0, 0}, {3, 0}, {6, 0}}},
+	{[]string{"b", ""}, "abc", -1, []MultiMatch{{0, 1}, {1, 0}, {2, 1}, {3, 1}}},
+}
+
+func TestMultiSearcherFindAll(t *testing.T) {
+	for _, tt := range multiSearcherFindAllTests {
+		m := NewMultiSearcher(tt.patterns...)
+		if got := m.FindAll(tt.s, tt.n); !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("NewMultiSearcher(%q).FindAll(%q, %d) = %v, want %v",
+				tt.patterns, tt.s, tt.n, got, tt.out)
+		}
+	}
+}
+
+func BenchmarkMultiSearcher(b *testing.B) {
+	patterns := []string{
+		"January", "February", "March", "April", "May", "June", "July",
+		"August", "September", "October", "November", "December",
+	}
+	s := Repeat("The quick brown fox jumps over the lazy dog. ", 200) + "December"
+	b.SetBytes(int64(len(s)))
+	b.Run("Index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			indexAnyOfNaive(s, patterns)
+		}
+	})
+	b.Run("MultiSearcher", func(b *testing.B) {
+		m := NewMultiSearcher(patterns...)
+		for i := 0; i < b.N; i++ {
+			m.IndexAnyOf(s)
+		}
+	})
+}
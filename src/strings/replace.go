@@ -21,6 +21,7 @@ type Replacer struct {
 type replacer interface {
 	Replace(s string) string
 	WriteString(w io.Writer, s string) (n int, err error)
+	AppendReplace(dst []byte, s string) []byte
 }
 
 // NewReplacer returns a new Replacer from a list of old, new string
@@ -50,6 +51,9 @@ func (b *Replacer) build() replacer {
 	allNewBytes := true
 	for i := 0; i < len(oldnew); i += 2 {
 		if len(oldnew[i]) != 1 {
+			if len(oldnew)/2 >= multiStringCutOff && !hasEmptyOld(oldnew) {
+				return makeMultiStringReplacer(oldnew)
+			}
 			return makeGenericReplacer(oldnew)
 		}
 		if len(oldnew[i+1]) != 1 {
@@ -103,6 +107,13 @@ func (r *Replacer) WriteString(w io.Writer, s string) (n int, err error) {
 	return r.r.WriteString(w, s)
 }
 
+// AppendReplace appends s to dst with all replacements performed
+// and returns the extended buffer.
+func (r *Replacer) AppendReplace(dst []byte, s string) []byte {
+	r.once.Do(r.buildOnce)
+	return r.r.AppendReplace(dst, s)
+}
+
 // trieNode is a node in a lookup trie for prioritized key/value pairs. Keys
 // and values may be empty. For example, the trie containing keys "ax", "ay",
 // "bcbc", "x" and "xy" could have eight nodes:
@@ -333,6 +344,12 @@ func (r *genericReplacer) Replace(s string) string {
 	return string(buf)
 }
 
+func (r *genericReplacer) AppendReplace(dst []byte, s string) []byte {
+	buf := appendSliceWriter(dst)
+	r.WriteString(&buf, s)
+	return buf
+}
+
 func (r *genericReplacer) WriteString(w io.Writer, s string) (n int, err error) {
 	sw := getStringWriter(w)
 	var last, wn int
@@ -407,6 +424,20 @@ func (r *singleStringReplacer) Replace(s string) string {
 	return buf.String()
 }
 
+func (r *singleStringReplacer) AppendReplace(dst []byte, s string) []byte {
+	i := 0
+	for {
+		match := r.finder.next(s[i:])
+		if match == -1 {
+			break
+		}
+		dst = append(dst, s[i:i+match]...)
+		dst = append(dst, r.value...)
+		i += match + len(r.finder.pattern)
+	}
+	return append(dst, s[i:]...)
+}
+
 func (r *singleStringReplacer) WriteString(w io.Writer, s string) (n int, err error) {
 	sw := getStringWriter(w)
 	var i, wn int
@@ -454,6 +485,15 @@ func (r *byteReplacer) Replace(s string) string {
 	return string(buf)
 }
 
+func (r *byteReplacer) AppendReplace(dst []byte, s string) []byte {
+	n := len(dst)
+	dst = append(dst, s...)
+	for i, b := range dst[n:] {
+		dst[n+i] = r[b]
+	}
+	return dst
+}
+
 func (r *byteReplacer) WriteString(w io.Writer, s string) (n int, err error) {
 	// TODO(bradfitz): use io.WriteString with slices of s, avoiding allocation.
 	bufsize := 32 << 10
@@ -538,6 +578,20 @@ func (r *byteStringReplacer) Replace(s string) string {
 	return string(buf)
 }
 
+func (r *byteStringReplacer) AppendReplace(dst []byte, s string) []byte {
+	last := 0
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if r.replacements[b] == nil {
+			continue
+		}
+		dst = append(dst, s[last:i]...)
+		dst = append(dst, r.replacements[b]...)
+		last = i + 1
+	}
+	return append(dst, s[last:]...)
+}
+
 func (r *byteStringReplacer) WriteString(w io.Writer, s string) (n int, err error) {
 	sw := getStringWriter(w)
 	last := 0
@@ -567,3 +621,100 @@ func (r *byteStringReplacer) WriteString(w io.Writer, s string) (n int, err erro
 	}
 	return
 }
+
+// multiStringCutOff is the number of old strings at or above which
+// NewReplacer uses a multiStringReplacer rather than a genericReplacer,
+// if none of the old strings is empty. Below it, the genericReplacer's
+// trie is faster to build and about as fast to search.
+const multiStringCutOff = 8
+
+// hasEmptyOld reports whether any of the old strings in oldnew is empty.
+func hasEmptyOld(oldnew []string) bool {
+	for i := 0; i < len(oldnew); i += 2 {
+		if oldnew[i] == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// multiStringReplacer is the implementation that's used when there are
+// many old strings, none of them empty. It finds them with a MultiSearcher,
+// whose Aho-Corasick automaton reads each byte of the target string once
+// however many old strings there are, where the genericReplacer walks its
+// trie from each candidate byte.
+type multiStringReplacer struct {
+	searcher *MultiSearcher
+	// new[i] is the new string that replaces the i'th old string.
+	new []string
+}
+
+func makeMultiStringReplacer(oldnew []string) *multiStringReplacer {
+	old := make([]string, len(oldnew)/2)
+	r := &multiStringReplacer{new: make([]string, len(oldnew)/2)}
+	for i := 0; i < len(oldnew); i += 2 {
+		old[i/2] = oldnew[i]
+		r.new[i/2] = oldnew[i+1]
+	}
+	// IndexAnyOf prefers the old string listed first among those
+	// found at the same index, as the genericReplacer does.
+	r.searcher = NewMultiSearcher(old...)
+	return r
+}
+
+func (r *multiStringReplacer) Replace(s string) string {
+	match, p := r.searcher.IndexAnyOf(s)
+	if match < 0 {
+		return s
+	}
+	var buf Builder
+	buf.Grow(len(s))
+	i := 0
+	for match >= 0 {
+		buf.WriteString(s[i : i+match])
+		buf.WriteString(r.new[p])
+		i += match + len(r.searcher.patterns[p])
+		match, p = r.searcher.IndexAnyOf(s[i:])
+	}
+	buf.WriteString(s[i:])
+	return buf.String()
+}
+
+func (r *multiStringReplacer) AppendReplace(dst []byte, s string) []byte {
+	i := 0
+	for {
+		match, p := r.searcher.IndexAnyOf(s[i:])
+		if match < 0 {
+			break
+		}
+		dst = append(dst, s[i:i+match]...)
+		dst = append(dst, r.new[p]...)
+		i += match + len(r.searcher.patterns[p])
+	}
+	return append(dst, s[i:]...)
+}
+
+func (r *multiStringReplacer) WriteString(w io.Writer, s string) (n int, err error) {
+	sw := getStringWriter(w)
+	var i, wn int
+	for {
+		match, p := r.searcher.IndexAnyOf(s[i:])
+		if match < 0 {
+			break
+		}
+		wn, err = sw.WriteString(s[i : i+match])
+		n += wn
+		if err != nil {
+			return
+		}
+		wn, err = sw.WriteString(r.new[p])
+		n += wn
+		if err != nil {
+			return
+		}
+		i += match + len(r.searcher.patterns[p])
+	}
+	wn, err = sw.WriteString(s[i:])
+	n += wn
+	return
+}
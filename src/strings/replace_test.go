@@ -215,6 +215,33 @@ func TestReplacer(t *testing.T) {
 		testCase{genAll, "", ""},
 	)
 
+	// months has many old strings, some sharing prefixes and some
+	// containing others.
+	months := NewReplacer(
+		"January", "1",
+		"February", "2",
+		"March", "3",
+		"April", "4",
+		"May", "5",
+		"June", "6",
+		"July", "7",
+		"August", "8",
+		"September", "9",
+		"October", "10",
+		"November", "11",
+		"December", "12",
+		"Ma", "M?",
+		"Ju", "J?",
+		"uar", "[uar]",
+	)
+	testCases = append(testCases,
+		testCase{months, "1 January 2000", "1 1 2000"},
+		testCase{months, "JuneJulyJuJanuar", "67J?Jan[uar]"},
+		testCase{months, "MarMayMarchMad", "M?r53M?d"},
+		testCase{months, "Sept, Oct, Nov", "Sept, Oct, Nov"},
+		testCase{months, "", ""},
+	)
+
 	// Test cases with empty old strings.
 
 	blankToX1 := NewReplacer("", "X")
@@ -290,6 +317,9 @@ func TestReplacer(t *testing.T) {
 		if s := tc.r.Replace(tc.in); s != tc.out {
 			t.Errorf("%d. Replace(%q) = %q, want %q", i, tc.in, s, tc.out)
 		}
+		if b := tc.r.AppendReplace([]byte("prefix"), tc.in); string(b) != "prefix"+tc.out {
+			t.Errorf("%d. AppendReplace(%q, %q) = %q, want %q", i, "prefix", tc.in, b, "prefix"+tc.out)
+		}
 		var buf bytes.Buffer
 		n, err := tc.r.WriteString(&buf, tc.in)
 		if err != nil {
@@ -308,6 +338,51 @@ func TestReplacer(t *testing.T) {
 	}
 }
 
+// replaceNaive is a simple implementation of Replacer.Replace
+// for non-empty old strings.
+func replaceNaive(s string, oldnew []string) string {
+	var old []string
+	for i := 0; i < len(oldnew); i += 2 {
+		old = append(old, oldnew[i])
+	}
+	var b Builder
+	for {
+		i, p := indexAnyOfNaive(s, old)
+		if i < 0 {
+			break
+		}
+		b.WriteString(s[:i])
+		b.WriteString(oldnew[2*p+1])
+		s = s[i+len(old[p]):]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// TestReplacerManyStrings compares Replacers with as many old strings
+// as the multiStringReplacer needs against a naive implementation.
+func TestReplacerManyStrings(t *testing.T) {
+	olds := allStrings("abc", 3)[1:]
+	haystacks := allStrings("abc", 6)
+	if testing.Short() {
+		haystacks = allStrings("abc", 5)
+	}
+	for _, n := range []int{2, 8, 12, len(olds)} {
+		for start := 0; start+n <= len(olds); start += 5 {
+			var oldnew []string
+			for i, old := range olds[start : start+n] {
+				oldnew = append(oldnew, old, fmt.Sprintf("<%d>", i))
+			}
+			r := NewReplacer(oldnew...)
+			for _, s := range haystacks {
+				if got, want := r.Replace(s), replaceNaive(s, oldnew); got != want {
+					t.Fatalf("NewReplacer(%q).Replace(%q) = %q, want %q", oldnew, s, got, want)
+				}
+			}
+		}
+	}
+}
+
 var algorithmTestCases = []struct {
 	r    *Replacer
 	want string
@@ -318,6 +393,8 @@ var algorithmTestCases = []struct {
 	{NewReplacer("1", "12"), "*strings.byteStringReplacer"},
 	{NewReplacer("", "X"), "*strings.genericReplacer"},
 	{NewReplacer("a", "1", "b", "12", "cde", "123"), "*strings.genericReplacer"},
+	{NewReplacer("a", "1", "b", "2", "c", "3", "d", "4", "e", "5", "f", "6", "g", "7", "hi", "8"), "*strings.multiStringReplacer"},
+	{NewReplacer("a", "1", "b", "2", "c", "3", "d", "4", "e", "5", "f", "6", "g", "7", "", "8"), "*strings.genericReplacer"},
 }
 
 // TestPickAlgorithm tests that NewReplacer picks the correct algorithm.
@@ -451,6 +528,30 @@ func benchmarkSingleString(b *testing.B, pattern, text string) {
 	}
 }
 
+func BenchmarkReplacerManyStrings(b *testing.B) {
+	words := Fields("the quick brown fox jumps over lazy dog and then " +
+		"some other words like alpha bravo charlie delta echo foxtrot golf " +
+		"hotel india juliet kilo lima mike november oscar papa quebec romeo " +
+		"sierra tango uniform victor whiskey xray yankee zulu")
+	text := Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit, the lazy dog. ", 100)
+	for _, n := range []int{4, 8, 16, 64} {
+		var oldnew []string
+		for i := 0; i < n && i < len(words); i++ {
+			oldnew = append(oldnew, words[i], Title(words[i]))
+		}
+		for len(oldnew) < 2*n {
+			oldnew = append(oldnew, fmt.Sprintf("word%d", len(oldnew)), "")
+		}
+		r := NewReplacer(oldnew...)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				r.Replace(text)
+			}
+		})
+	}
+}
+
 func BenchmarkSingleMaxSkipping(b *testing.B) {
 	benchmarkSingleString(b, Repeat("b", 25), Repeat("a", 10000))
 }
@@ -0,0 +1,43 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package strings
+
+import "internal/bytealg"
+
+// A Searcher finds instances of a fixed string, its needle, in other
+// strings. Index does some work that depends only on the string it
+// searches for on each call; a Searcher does that work once, in
+// NewSearcher, which makes it the better choice for searching many
+// strings for the same long needle.
+//
+// A Searcher is safe for concurrent use by multiple goroutines.
+type Searcher struct {
+	needle string
+	tw     bytealg.TwoWay
+}
+
+// NewSearcher returns a Searcher for needle.
+func NewSearcher(needle string) *Searcher {
+	return &Searcher{needle: needle, tw: bytealg.MakeTwoWay(needle)}
+}
+
+// Index returns the index of the first instance of the needle in s,
+// or -1 if it is not present in s. It is equivalent to Index(s, needle).
+func (sr *Searcher) Index(s string) int {
+	n := len(sr.needle)
+	if n <= 1 || n >= len(s) || n <= bytealg.MaxLen || n <= bytealg.MaxWideLen {
+		// Index has nothing to precompute for these. MaxLen and
+		// MaxWideLen are 0 on some ports, so short needles must be
+		// checked for separately.
+		return Index(s, sr.needle)
+	}
+	return indexLong(s, sr.needle, &sr.tw)
+}
+
+// Contains reports whether the needle is within s.
+// It is equivalent to Contains(s, needle).
+func (sr *Searcher) Contains(s string) bool {
+	return sr.Index(s) >= 0
+}
@@ -0,0 +1,90 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package strings_test
+
+import (
+	. "strings"
+	"testing"
+)
+
+func TestSearcher(t *testing.T) {
+	for _, tt := range indexTests {
+		sr := NewSearcher(tt.sep)
+		if got := sr.Index(tt.s); got != tt.out {
+			t.Errorf("NewSearcher(%q).Index(%q) = %d, want %d", tt.sep, tt.s, got, tt.out)
+		}
+		if got := sr.Contains(tt.s); got != (tt.out >= 0) {
+			t.Errorf("NewSearcher(%q).Contains(%q) = %v, want %v", tt.sep, tt.s, got, tt.out >= 0)
+		}
+	}
+
+	// Empty and single-byte needles, which indexLong cannot handle.
+	for _, tt := range []struct {
+		s, sep string
+		out    int
+	}{
+		{"", "", 0},
+		{"abc", "", 0},
+		{"", "a", -1},
+		{"a", "a", 0},
+		{"abc", "c", 2},
+		{"abc", "x", -1},
+		{Repeat("a", 100) + "b", "b", 100},
+		{Repeat("a", 100), "b", -1},
+	} {
+		if got := NewSearcher(tt.sep).Index(tt.s); got != tt.out {
+			t.Errorf("NewSearcher(%q).Index(%q) = %d, want %d", tt.sep, tt.s, got, tt.out)
+		}
+	}
+
+	// Needles too long for bytealg.IndexString, in haystacks that make
+	// Index give up on IndexByte and use the Two-Way search.
+	for _, n := range []int{65, 100, 1000} {
+		for _, needle := range []string{
+			Repeat("a", n-1) + "b",
+			"b" + Repeat("a", n-1),
+			Repeat("ab", n/2) + "c",
+			Repeat("a", n/2) + "b" + Repeat("a", n/2),
+		} {
+			sr := NewSearcher(needle)
+			for _, s := range []string{
+				Repeat("a", 4*n),
+				Repeat("a", 4*n) + needle,
+				Repeat("ab", 2*n) + needle + Repeat("ab", 2*n),
+				Repeat("a", n/2) + "b" + Repeat("a", 4*n) + needle,
+			} {
+				if got, want := sr.Index(s), Index(s, needle); got != want {
+					t.Errorf("NewSearcher(%q).Index(%q) = %d, want %d", needle, s, got, want)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkSearcher(b *testing.B) {
+	// Each line has many near matches of the needle, so that Index
+	// uses the Two-Way search, whose factorization of the needle
+	// the Searcher computes only once.
+	needle := Repeat("a", 500) + "b"
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = Repeat("a", 600+i) + "c"
+	}
+	b.Run("Index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, line := range lines {
+				Index(line, needle)
+			}
+		}
+	})
+	b.Run("Searcher", func(b *testing.B) {
+		sr := NewSearcher(needle)
+		for i := 0; i < b.N; i++ {
+			for _, line := range lines {
+				sr.Index(line)
+			}
+		}
+	})
+}
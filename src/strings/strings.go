@@ -46,15 +46,7 @@ func Count(s, substr string) int {
 	if len(substr) == 1 {
 		return bytealg.CountString(s, substr[0])
 	}
-	n := 0
-	for {
-		i := Index(s, substr)
-		if i == -1 {
-			return n
-		}
-		n++
-		s = s[i+len(substr):]
-	}
+	return bytealg.CountSepString(s, substr)
 }
 
 // Contains reports whether substr is within s.
@@ -87,21 +79,23 @@ func LastIndex(s, substr string) int {
 		return -1
 	case n > len(s):
 		return -1
+	case n >= bytealg.MinSkipLen:
+		return bytealg.LastIndexSkip(s, substr)
 	}
 	// Rabin-Karp search from the end of the string
-	hashss, pow := bytealg.HashStrRev(substr)
+	hashss, pow := bytealg.HashStrRev64(substr)
 	last := len(s) - n
-	var h uint32
+	var h uint64
 	for i := len(s) - 1; i >= last; i-- {
-		h = h*bytealg.PrimeRK + uint32(s[i])
+		h = h*bytealg.PrimeRK64 + uint64(s[i])
 	}
 	if h == hashss && s[last:] == substr {
 		return last
 	}
 	for i := last - 1; i >= 0; i-- {
-		h *= bytealg.PrimeRK
-		h += uint32(s[i])
-		h -= pow * uint32(s[i+n])
+		h *= bytealg.PrimeRK64
+		h += uint64(s[i])
+		h -= pow * uint64(s[i+n])
 		if h == hashss && s[i:i+n] == substr {
 			return i
 		}
@@ -152,13 +146,8 @@ func IndexAny(s, chars string) int {
 		return IndexRune(s, r)
 	}
 	if len(s) > 8 {
-		if as, isASCII := makeASCIISet(chars); isASCII {
-			for i := 0; i < len(s); i++ {
-				if as.contains(s[i]) {
-					return i
-				}
-			}
-			return -1
+		if as, isASCII := bytealg.MakeASCIISet(chars); isASCII {
+			return bytealg.IndexAnyString(s, &as)
 		}
 	}
 	for i, c := range s {
@@ -188,13 +177,8 @@ func LastIndexAny(s, chars string) int {
 		return -1
 	}
 	if len(s) > 8 {
-		if as, isASCII := makeASCIISet(chars); isASCII {
-			for i := len(s) - 1; i >= 0; i-- {
-				if as.contains(s[i]) {
-					return i
-				}
-			}
-			return -1
+		if as, isASCII := bytealg.MakeASCIISet(chars); isASCII {
+			return bytealg.LastIndexAnyString(s, &as)
 		}
 	}
 	if len(chars) == 1 {
@@ -316,6 +300,53 @@ func SplitAfter(s, sep string) []string {
 	return genSplit(s, sep, len(sep), -1)
 }
 
+// SplitSeq returns an iterator over the substrings of s separated by sep.
+// It yields the same strings that Split returns, in order, without
+// building the slice.
+//
+// Iteration stops early if yield returns false.
+func SplitSeq(s, sep string) func(yield func(string) bool) {
+	return splitSeq(s, sep, 0)
+}
+
+// SplitAfterSeq returns an iterator over the substrings of s split after
+// each instance of sep. It yields the same strings that SplitAfter
+// returns, in order, without building the slice.
+//
+// Iteration stops early if yield returns false.
+func SplitAfterSeq(s, sep string) func(yield func(string) bool) {
+	return splitSeq(s, sep, len(sep))
+}
+
+// splitSeq is the iterator form of genSplit with n < 0: it splits after
+// each instance of sep, including sepSave bytes of sep in the substrings.
+func splitSeq(s, sep string, sepSave int) func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		if sep == "" {
+			// Split after each UTF-8 sequence, as explode does.
+			for len(s) > 0 {
+				_, size := utf8.DecodeRuneInString(s)
+				if !yield(s[:size]) {
+					return
+				}
+				s = s[size:]
+			}
+			return
+		}
+		for {
+			i := Index(s, sep)
+			if i < 0 {
+				break
+			}
+			if !yield(s[:i+sepSave]) {
+				return
+			}
+			s = s[i+len(sep):]
+		}
+		yield(s)
+	}
+}
+
 var asciiSpace = [256]uint8{'\t': 1, '\n': 1, '\v': 1, '\f': 1, '\r': 1, ' ': 1}
 
 // Fields splits the string s around each instance of one or more consecutive white space
@@ -420,6 +451,69 @@ func FieldsFunc(s string, f func(rune) bool) []string {
 	return a
 }
 
+// FieldsSeq returns an iterator over the substrings of s split around runs
+// of white space characters, as defined by unicode.IsSpace. It yields the
+// same strings that Fields returns, in order, without building the slice.
+//
+// Iteration stops early if yield returns false.
+func FieldsSeq(s string) func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		start := -1 // valid field start if >= 0
+		for i := 0; i < len(s); {
+			size := 1
+			isSpace := asciiSpace[s[i]] != 0
+			if s[i] >= utf8.RuneSelf {
+				var r rune
+				r, size = utf8.DecodeRuneInString(s[i:])
+				isSpace = unicode.IsSpace(r)
+			}
+			if isSpace {
+				if start >= 0 {
+					if !yield(s[start:i]) {
+						return
+					}
+					start = -1
+				}
+			} else if start < 0 {
+				start = i
+			}
+			i += size
+		}
+		// Last field might end at EOF.
+		if start >= 0 {
+			yield(s[start:])
+		}
+	}
+}
+
+// FieldsFuncSeq returns an iterator over the substrings of s split at each
+// run of Unicode code points c satisfying f(c). It yields the same strings
+// that FieldsFunc returns, in order, without building the slice.
+//
+// FieldsFuncSeq calls f(c) once for each code point in s, in order, as
+// the iteration reaches it. Iteration stops early if yield returns false.
+func FieldsFuncSeq(s string, f func(rune) bool) func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		start := -1 // valid field start if >= 0
+		for i, r := range s {
+			if f(r) {
+				if start >= 0 {
+					if !yield(s[start:i]) {
+						return
+					}
+					start = -1
+				}
+			} else if start < 0 {
+				start = i
+			}
+		}
+		// Last field might end at EOF.
+		if start >= 0 {
+			yield(s[start:])
+		}
+	}
+}
+
 // Join concatenates the elements of its first argument to create a single string. The separator
 // string sep is placed between elements in the resulting string.
 func Join(elems []string, sep string) string {
@@ -552,62 +646,37 @@ func Repeat(s string, count int) string {
 
 // ToUpper returns s with all Unicode letters mapped to their upper case.
 func ToUpper(s string) string {
-	isASCII, hasLower := true, false
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= utf8.RuneSelf {
-			isASCII = false
-			break
-		}
-		hasLower = hasLower || ('a' <= c && c <= 'z')
-	}
-
-	if isASCII { // optimize for ASCII-only strings.
-		if !hasLower {
-			return s
-		}
-		var b Builder
-		b.Grow(len(s))
-		for i := 0; i < len(s); i++ {
-			c := s[i]
-			if 'a' <= c && c <= 'z' {
-				c -= 'a' - 'A'
-			}
-			b.WriteByte(c)
-		}
-		return b.String()
-	}
-	return Map(unicode.ToUpper, s)
+	return changeCase(s, 'a', unicode.ToUpper)
 }
 
 // ToLower returns s with all Unicode letters mapped to their lower case.
 func ToLower(s string) string {
-	isASCII, hasUpper := true, false
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= utf8.RuneSelf {
-			isASCII = false
-			break
-		}
-		hasUpper = hasUpper || ('A' <= c && c <= 'Z')
-	}
+	return changeCase(s, 'A', unicode.ToLower)
+}
 
-	if isASCII { // optimize for ASCII-only strings.
-		if !hasUpper {
-			return s
-		}
-		var b Builder
-		b.Grow(len(s))
-		for i := 0; i < len(s); i++ {
-			c := s[i]
-			if 'A' <= c && c <= 'Z' {
-				c += 'a' - 'A'
-			}
-			b.WriteByte(c)
-		}
-		return b.String()
+// changeCase returns s with all Unicode letters mapped by mapping,
+// which changes the case of the 26 ASCII letters starting at lo and
+// of no other ASCII bytes.
+func changeCase(s string, lo byte, mapping func(rune) rune) string {
+	i := bytealg.IndexCaseASCIIString(s, lo)
+	if i < 0 {
+		return s
 	}
-	return Map(unicode.ToLower, s)
+	if s[i] >= utf8.RuneSelf {
+		return Map(mapping, s)
+	}
+	// Change the ASCII bytes of s a block at a time, up to the
+	// first that is not ASCII, and the rest a rune at a time.
+	var b Builder
+	b.Grow(len(s))
+	b.buf = b.buf[:len(s)]
+	copy(b.buf, s[:i])
+	n := i + bytealg.ChangeCaseASCIIString(b.buf[i:], s[i:], lo)
+	b.buf = b.buf[:n]
+	for _, r := range s[n:] {
+		b.WriteRune(mapping(r))
+	}
+	return b.String()
 }
 
 // ToTitle returns a copy of the string s with all Unicode letters mapped to
@@ -637,11 +706,14 @@ func ToTitleSpecial(c unicode.SpecialCase, s string) string {
 func ToValidUTF8(s, replacement string) string {
 	var b Builder
 
-	for i, c := range s {
+	// Skip a prefix known to be valid, checked a block at a time.
+	valid := bytealg.ValidUTF8PrefixString(s)
+	for i, c := range s[valid:] {
 		if c != utf8.RuneError {
 			continue
 		}
 
+		i += valid
 		_, wid := utf8.DecodeRuneInString(s[i:])
 		if wid == 1 {
 			b.Grow(len(s) + len(replacement))
@@ -938,31 +1010,35 @@ func trimRightUnicode(s, cutset string) string {
 // TrimSpace returns a slice of the string s, with all leading
 // and trailing white space removed, as defined by Unicode.
 func TrimSpace(s string) string {
-	// Fast path for ASCII: look for the first ASCII non-space byte
+	// Fast path for ASCII: look for the first ASCII non-space byte,
+	// a block of bytes at a time past the first few.
 	start := 0
-	for ; start < len(s); start++ {
-		c := s[start]
-		if c >= utf8.RuneSelf {
-			// If we run into a non-ASCII byte, fall back to the
-			// slower unicode-aware method on the remaining bytes
-			return TrimFunc(s[start:], unicode.IsSpace)
-		}
-		if asciiSpace[c] == 0 {
+	for ; start < len(s) && asciiSpace[s[start]] != 0; start++ {
+		if start == 16 {
+			if start = bytealg.IndexNonSpaceASCIIString(s); start < 0 {
+				return ""
+			}
 			break
 		}
 	}
+	if start < len(s) && s[start] >= utf8.RuneSelf {
+		// If we run into a non-ASCII byte, fall back to the
+		// slower unicode-aware method on the remaining bytes
+		return TrimFunc(s[start:], unicode.IsSpace)
+	}
 
-	// Now look for the first ASCII non-space byte from the end
+	// Now look for the last ASCII non-space byte likewise. There is
+	// one, at start, unless s is all spaces.
 	stop := len(s)
-	for ; stop > start; stop-- {
-		c := s[stop-1]
-		if c >= utf8.RuneSelf {
-			return TrimFunc(s[start:stop], unicode.IsSpace)
-		}
-		if asciiSpace[c] == 0 {
+	for ; stop > start && asciiSpace[s[stop-1]] != 0; stop-- {
+		if stop == len(s)-16 {
+			stop = start + bytealg.LastIndexNonSpaceASCIIString(s[start:]) + 1
 			break
 		}
 	}
+	if stop > start && s[stop-1] >= utf8.RuneSelf {
+		return TrimFunc(s[start:stop], unicode.IsSpace)
+	}
 
 	// At this point s[start:stop] starts and ends with an ASCII
 	// non-space bytes, so we're done. Non-ASCII cases have already
@@ -1093,6 +1169,200 @@ func EqualFold(s, t string) bool {
 	return s == t
 }
 
+// IndexFold returns the index of the first instance of substr in s
+// under Unicode simple case-folding, as defined by EqualFold, or -1 if
+// substr is not present in s. That is, it returns the smallest i such
+// that EqualFold(s[i:j], substr) for some j.
+func IndexFold(s, substr string) int {
+	n := len(substr)
+	switch {
+	case n == 0:
+		return 0
+	case !canFoldASCII(s, substr):
+		return indexFoldUnicode(s, substr)
+	case n == 1:
+		c := substr[0]
+		if !isASCIILetter(c) {
+			return IndexByte(s, c)
+		}
+		i := IndexByte(s, c|0x20)
+		if i < 0 {
+			i = len(s)
+		}
+		if j := IndexByte(s[:i], c&^0x20); j >= 0 {
+			return j
+		}
+		if i == len(s) {
+			return -1
+		}
+		return i
+	case n > len(s):
+		return -1
+	}
+	c0 := substr[0]
+	c1 := substr[1]
+	i := 0
+	t := len(s) - n + 1
+	fails := 0
+	for i < t {
+		o := bytealg.IndexFoldPairString(s[i:t+1], c0, c1)
+		if o < 0 {
+			return -1
+		}
+		i += o
+		if equalFoldASCII(s[i:i+n], substr) {
+			return i
+		}
+		i++
+		fails++
+		if fails >= 4+i>>4 && i < t {
+			// Too many false positives: switch to a search
+			// whose running time does not depend on them.
+			j := indexFoldRabinKarp(s[i:], substr)
+			if j < 0 {
+				return -1
+			}
+			return i + j
+		}
+	}
+	return -1
+}
+
+// canFoldASCII reports whether every instance of substr in s under
+// simple case-folding is ASCII, and so the same length as substr.
+// That holds if substr is ASCII, unless it contains 'k' or 's' and s
+// contains the non-ASCII runes they fold to, U+212A (Kelvin sign) and
+// U+017F (long s).
+func canFoldASCII(s, substr string) bool {
+	ks := false
+	for i := 0; i < len(substr); i++ {
+		switch c := substr[i]; {
+		case c >= utf8.RuneSelf:
+			return false
+		case c|0x20 == 'k' || c|0x20 == 's':
+			ks = true
+		}
+	}
+	return !ks || Index(s, "\u212A") < 0 && Index(s, "\u017F") < 0
+}
+
+func isASCIILetter(c byte) bool {
+	c |= 0x20
+	return 'a' <= c && c <= 'z'
+}
+
+// equalFoldASCII is like EqualFold for an ASCII t and an s of the same length.
+func equalFoldASCII(s, t string) bool {
+	for i := 0; i < len(t); i++ {
+		if s[i] != t[i] && (s[i]|0x20 != t[i]|0x20 || !isASCIILetter(t[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// toLowerASCII returns c in lower case if it is an ASCII letter.
+func toLowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		c += 'a' - 'A'
+	}
+	return c
+}
+
+// indexFoldRabinKarp is like IndexFold for s and substr satisfying
+// canFoldASCII. It hashes the bytes of s and substr in lower case.
+func indexFoldRabinKarp(s, substr string) int {
+	n := len(substr)
+	if n > len(s) {
+		return -1
+	}
+	var hashss, h uint64
+	for i := 0; i < n; i++ {
+		hashss = hashss*bytealg.PrimeRK64 + uint64(toLowerASCII(substr[i]))
+		h = h*bytealg.PrimeRK64 + uint64(toLowerASCII(s[i]))
+	}
+	var pow, sq uint64 = 1, bytealg.PrimeRK64
+	for i := n; i > 0; i >>= 1 {
+		if i&1 != 0 {
+			pow *= sq
+		}
+		sq *= sq
+	}
+	if h == hashss && equalFoldASCII(s[:n], substr) {
+		return 0
+	}
+	for i := n; i < len(s); {
+		h *= bytealg.PrimeRK64
+		h += uint64(toLowerASCII(s[i]))
+		h -= pow * uint64(toLowerASCII(s[i-n]))
+		i++
+		if h == hashss && equalFoldASCII(s[i-n:i], substr) {
+			return i - n
+		}
+	}
+	return -1
+}
+
+// indexFoldUnicode is IndexFold for any substr. It tries each
+// rune boundary of s in turn.
+func indexFoldUnicode(s, substr string) int {
+	for i := 0; i < len(s); {
+		if hasPrefixFold(s[i:], substr) {
+			return i
+		}
+		if s[i] < utf8.RuneSelf {
+			i++
+		} else {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+		}
+	}
+	return -1
+}
+
+// hasPrefixFold reports whether some prefix of s equals prefix
+// under simple case-folding, as defined by EqualFold.
+func hasPrefixFold(s, prefix string) bool {
+	for prefix != "" {
+		if s == "" {
+			return false
+		}
+		var sr, tr rune
+		if s[0] < utf8.RuneSelf {
+			sr, s = rune(s[0]), s[1:]
+		} else {
+			r, size := utf8.DecodeRuneInString(s)
+			sr, s = r, s[size:]
+		}
+		if prefix[0] < utf8.RuneSelf {
+			tr, prefix = rune(prefix[0]), prefix[1:]
+		} else {
+			r, size := utf8.DecodeRuneInString(prefix)
+			tr, prefix = r, prefix[size:]
+		}
+		if tr == sr {
+			continue
+		}
+		if tr < sr {
+			tr, sr = sr, tr
+		}
+		if tr < utf8.RuneSelf {
+			if 'A' <= sr && sr <= 'Z' && tr == sr+'a'-'A' {
+				continue
+			}
+			return false
+		}
+		r := unicode.SimpleFold(sr)
+		for r != sr && r < tr {
+			r = unicode.SimpleFold(r)
+		}
+		if r != tr {
+			return false
+		}
+	}
+	return true
+}
+
 // Index returns the index of the first instance of substr in s, or -1 if substr is not present in s.
 func Index(s, substr string) int {
 	n := len(substr)
@@ -1108,6 +1378,8 @@ func Index(s, substr string) int {
 		return -1
 	case n > len(s):
 		return -1
+	case n <= bytealg.MaxWideLen && len(s) >= bytealg.MinWideSearch:
+		return bytealg.IndexWideString(s, substr)
 	case n <= bytealg.MaxLen:
 		// Use brute force when s and substr both are small
 		if len(s) <= bytealg.MaxBruteForce {
@@ -1144,6 +1416,14 @@ func Index(s, substr string) int {
 		}
 		return -1
 	}
+	return indexLong(s, substr, nil)
+}
+
+// indexLong is Index for 2 <= len(substr) < len(s), used when substr
+// is too long for bytealg.IndexString. If tw is not nil, it is the
+// Two-Way factorization of substr.
+func indexLong(s, substr string, tw *bytealg.TwoWay) int {
+	n := len(substr)
 	c0 := substr[0]
 	c1 := substr[1]
 	i := 0
@@ -1164,7 +1444,12 @@ func Index(s, substr string) int {
 		fails++
 		if fails >= 4+i>>4 && i < t {
 			// See comment in ../bytes/bytes.go.
-			j := bytealg.IndexRabinKarp(s[i:], substr)
+			var j int
+			if tw != nil {
+				j = tw.IndexString(s[i:], substr)
+			} else {
+				j = bytealg.IndexTwoWay(s[i:], substr)
+			}
 			if j < 0 {
 				return -1
 			}
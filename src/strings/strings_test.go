@@ -7,6 +7,7 @@ package strings_test
 import (
 	"bytes"
 	"fmt"
+	"internal/bytealg"
 	"io"
 	"math/rand"
 	"reflect"
@@ -126,7 +127,7 @@ var indexTests = []IndexTest{
 	{"xx012345678901234567890123456789012345678901234567890123456789012"[:41], "0123456789012345678901234567890123456789", -1},
 	{"xx012345678901234567890123456789012345678901234567890123456789012", "0123456789012345678901234567890123456xxx", -1},
 	{"xx0123456789012345678901234567890123456789012345678901234567890120123456789012345678901234567890123456xxx", "0123456789012345678901234567890123456xxx", 65},
-	// test fallback to Rabin-Karp.
+	// test fallback to Two-Way search.
 	{"oxoxoxoxoxoxoxoxoxoxoxoy", "oy", 22},
 	{"oxoxoxoxoxoxoxoxoxoxoxox", "oy", -1},
 }
@@ -404,6 +405,7 @@ var splittests = []SplitTest{
 	{faces, "~", -1, []string{faces}},
 	{"1 2 3 4", " ", 3, []string{"1", "2", "3 4"}},
 	{"1 2", " ", 3, []string{"1", "2"}},
+	{"", ",", -1, []string{""}},
 }
 
 func TestSplit(t *testing.T) {
@@ -425,6 +427,9 @@ func TestSplit(t *testing.T) {
 			if !reflect.DeepEqual(a, b) {
 				t.Errorf("Split disagrees with SplitN(%q, %q, %d) = %v; want %v", tt.s, tt.sep, tt.n, b, a)
 			}
+			if b := collect(SplitSeq(tt.s, tt.sep)); !eq(b, a) {
+				t.Errorf("SplitSeq(%q, %q) yielded %q; want %q", tt.s, tt.sep, b, a)
+			}
 		}
 	}
 }
@@ -461,6 +466,9 @@ func TestSplitAfter(t *testing.T) {
 			if !reflect.DeepEqual(a, b) {
 				t.Errorf("SplitAfter disagrees with SplitAfterN(%q, %q, %d) = %v; want %v", tt.s, tt.sep, tt.n, b, a)
 			}
+			if b := collect(SplitAfterSeq(tt.s, tt.sep)); !eq(b, a) {
+				t.Errorf("SplitAfterSeq(%q, %q) yielded %q; want %q", tt.s, tt.sep, b, a)
+			}
 		}
 	}
 }
@@ -522,6 +530,74 @@ func TestFieldsFunc(t *testing.T) {
 	}
 }
 
+// collect returns the strings seq yields, or an empty slice if none.
+func collect(seq func(yield func(string) bool)) []string {
+	a := []string{}
+	seq(func(s string) bool {
+		a = append(a, s)
+		return true
+	})
+	return a
+}
+
+func TestFieldsSeq(t *testing.T) {
+	for _, tt := range fieldstests {
+		if a := collect(FieldsSeq(tt.s)); !eq(a, tt.a) {
+			t.Errorf("FieldsSeq(%q) yielded %q; want %q", tt.s, a, tt.a)
+		}
+		if a := collect(FieldsFuncSeq(tt.s, unicode.IsSpace)); !eq(a, tt.a) {
+			t.Errorf("FieldsFuncSeq(%q, unicode.IsSpace) yielded %q; want %q", tt.s, a, tt.a)
+		}
+	}
+	pred := func(c rune) bool { return c == 'X' }
+	for _, tt := range FieldsFuncTests {
+		if a := collect(FieldsFuncSeq(tt.s, pred)); !eq(a, tt.a) {
+			t.Errorf("FieldsFuncSeq(%q) yielded %q, want %q", tt.s, a, tt.a)
+		}
+	}
+	// Invalid UTF-8 is not space.
+	for _, s := range []string{"a\xffb c", "\xff \xc0\x80", " \xe2\x80 x"} {
+		if a, want := collect(FieldsSeq(s)), Fields(s); !eq(a, want) {
+			t.Errorf("FieldsSeq(%q) yielded %q; want %q", s, a, want)
+		}
+	}
+}
+
+func TestSeqStop(t *testing.T) {
+	for _, seq := range []func(func(string) bool){
+		FieldsSeq(" a b c "),
+		FieldsFuncSeq(" a b c ", unicode.IsSpace),
+		SplitSeq("a,b,c", ","),
+		SplitSeq("abc", ""),
+	} {
+		var a []string
+		seq(func(s string) bool {
+			a = append(a, s)
+			return s != "b"
+		})
+		if want := []string{"a", "b"}; !eq(a, want) {
+			t.Errorf("yielded %q before stopping; want %q", a, want)
+		}
+	}
+}
+
+func TestFieldsSeqAllocs(t *testing.T) {
+	// The iteration allocates nothing per field.
+	allocs := func(s string) float64 {
+		n := 0
+		return testing.AllocsPerRun(100, func() {
+			FieldsSeq(s)(func(string) bool {
+				n++
+				return true
+			})
+		})
+	}
+	few := "  one two\tthree\u2000four  five  "
+	if a, b := allocs(few), allocs(Repeat(few, 100)); a != b {
+		t.Errorf("FieldsSeq allocs = %v for 5 fields, %v for 500; want equal", a, b)
+	}
+}
+
 // Test case for any function which accepts and returns a single string.
 type StringTest struct {
 	in, out string
@@ -548,6 +624,9 @@ var upperTests = []StringTest{
 	{"long\u0250string\u0250with\u0250nonascii\u2C6Fchars", "LONG\u2C6FSTRING\u2C6FWITH\u2C6FNONASCII\u2C6FCHARS"},
 	{"\u0250\u0250\u0250\u0250\u0250", "\u2C6F\u2C6F\u2C6F\u2C6F\u2C6F"}, // grows one byte per char
 	{"a\u0080\U0010FFFF", "A\u0080\U0010FFFF"},                           // test utf8.RuneSelf and utf8.MaxRune
+	{"the quick brown fox jumps over the lazy dog", "THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG"},
+	{"the quick brown fox jumps over the lazy dog\u0250", "THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG\u2C6F"},
+	{"the quick brown fox jumps over the lazy dog\xffab", "THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG\uFFFDAB"},
 }
 
 var lowerTests = []StringTest{
@@ -559,6 +638,9 @@ var lowerTests = []StringTest{
 	{"LONG\u2C6FSTRING\u2C6FWITH\u2C6FNONASCII\u2C6FCHARS", "long\u0250string\u0250with\u0250nonascii\u0250chars"},
 	{"\u2C6D\u2C6D\u2C6D\u2C6D\u2C6D", "\u0251\u0251\u0251\u0251\u0251"}, // shrinks one byte per char
 	{"A\u0080\U0010FFFF", "a\u0080\U0010FFFF"},                           // test utf8.RuneSelf and utf8.MaxRune
+	{"THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG", "the quick brown fox jumps over the lazy dog"},
+	{"THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG\u2C6F", "the quick brown fox jumps over the lazy dog\u0250"},
+	{"THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG\xffAB", "the quick brown fox jumps over the lazy dog\uFFFDab"},
 }
 
 const space = "\t\v\r\f\n\u0085\u00a0\u2000\u3000"
@@ -711,6 +793,41 @@ func TestToUpper(t *testing.T) { runStringTests(t, ToUpper, "ToUpper", upperTest
 
 func TestToLower(t *testing.T) { runStringTests(t, ToLower, "ToLower", lowerTests) }
 
+func TestToUpperLowerASCIIBlocks(t *testing.T) {
+	// Put each of the bytes that ToUpper and ToLower treat differently
+	// at each position of strings around the block sizes of bytealg's
+	// IndexCaseASCII and ChangeCaseASCII.
+	special := []string{"@", "A", "Z", "[", "`", "a", "z", "{", "\x7f", "\xff", "\u0250", "\u2C6F"}
+	for n := 0; n <= 100; n++ {
+		for _, base := range []string{Repeat("q", n), Repeat("Q", n), Repeat("0", n)} {
+			for i := 0; i <= n; i++ {
+				for _, c := range special {
+					s := base[:i] + c + base[i:]
+					if got, want := ToUpper(s), Map(unicode.ToUpper, s); got != want {
+						t.Fatalf("ToUpper(%q) = %q, want %q", s, got, want)
+					}
+					if got, want := ToLower(s), Map(unicode.ToLower, s); got != want {
+						t.Fatalf("ToLower(%q) = %q, want %q", s, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestToUpperLowerNoChangeAllocs(t *testing.T) {
+	upper := Repeat("THE QUICK BROWN FOX, 1234567890. ", 10)
+	lower := Repeat("the quick brown fox, 1234567890. ", 10)
+	allocs := testing.AllocsPerRun(10, func() {
+		if ToUpper(upper) != upper || ToLower(lower) != lower {
+			t.Fatal("changed")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("ToUpper and ToLower of unchanged strings: %v allocs, want 0", allocs)
+	}
+}
+
 var toValidUTF8Tests = []struct {
 	in   string
 	repl string
@@ -730,6 +847,8 @@ var toValidUTF8Tests = []struct {
 	{"\xF0\x80\x80\xaf", "☺", "☺"},
 	{"\xF8\x80\x80\x80\xAF", "\uFFFD", "\uFFFD"},
 	{"\xFC\x80\x80\x80\x80\xAF", "\uFFFD", "\uFFFD"},
+	{"\u00e9\u00e9\u00e9\u00e9\u00e9\u00e9\u00e9\u00e9\u00e9\u00e9\xffx", "?", "\u00e9\u00e9\u00e9\u00e9\u00e9\u00e9\u00e9\u00e9\u00e9\u00e9?x"},
+	{"0123456789abcdef0123456789abcde\u00e9\xe9", "?", "0123456789abcdef0123456789abcde\u00e9?"},
 }
 
 func TestToValidUTF8(t *testing.T) {
@@ -799,6 +918,23 @@ func TestSpecialCase(t *testing.T) {
 
 func TestTrimSpace(t *testing.T) { runStringTests(t, TrimSpace, "TrimSpace", trimSpaceTests) }
 
+func TestTrimSpaceASCIIBlocks(t *testing.T) {
+	// Runs of white space around the block size of bytealg's
+	// IndexNonSpaceASCII and LastIndexNonSpaceASCII, next to
+	// non-space bytes, ASCII and not.
+	for n := 0; n <= 40; n++ {
+		for _, ws := range []string{Repeat(" ", n), Repeat("\t\n\v\f\r ", n)[:n]} {
+			for _, inner := range []string{"", "x", "x y", "\x1f", "!", "\xff", "\u2000", "\u3000x\u0085", "é"} {
+				for _, s := range []string{ws + inner, inner + ws, ws + inner + ws, ws + inner + ws + inner + ws} {
+					if got, want := TrimSpace(s), TrimFunc(s, unicode.IsSpace); got != want {
+						t.Fatalf("TrimSpace(%q) = %q, want %q", s, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
 var trimTests = []struct {
 	f            string
 	in, arg, out string
@@ -1584,6 +1720,69 @@ func TestCount(t *testing.T) {
 	}
 }
 
+// countNaive counts the instances of substr in s with indexNaive.
+func countNaive(s, substr string) int {
+	n := 0
+	for {
+		i := indexNaive(s, substr)
+		if i < 0 {
+			return n
+		}
+		n++
+		s = s[i+len(substr):]
+	}
+}
+
+func TestCountExhaustive(t *testing.T) {
+	haystacks := allStrings("ab", 10)
+	if testing.Short() {
+		haystacks = allStrings("ab", 8)
+	}
+	for _, sep := range allStrings("ab", 5) {
+		if len(sep) < 2 {
+			continue
+		}
+		for _, s := range haystacks {
+			if got, want := Count(s, sep), countNaive(s, sep); got != want {
+				t.Fatalf("Count(%q, %q) = %d, want %d", s, sep, got, want)
+			}
+		}
+	}
+}
+
+func TestCountLong(t *testing.T) {
+	// Long inputs take each of the searches Count switches between:
+	// the wide search of long strings, the search after too many
+	// false first-byte matches, and Two-Way search for long seps.
+	tm, ctm := thueMorse(12)
+	tests := []struct{ s, sep string }{
+		{Repeat("a", 10000), "ab"},
+		{Repeat("a", 10000) + "b", "ab"},
+		{Repeat("ab", 10000), "ab"},
+		{Repeat("aab", 10000), "aab"},
+		{Repeat("aab", 10000), "aba"},
+		{Repeat("some line of text\r\n", 1000), "\r\n"},
+		{Repeat("ABC", 1<<12) + "123" + Repeat("ABC", 1<<12), "ABC123ABC"},
+		{Repeat("ABC", 1<<12), Repeat("ABC", 1<<5)},
+		{Repeat("a", 10000) + Repeat("a", 100) + "b", Repeat("a", 100) + "b"},
+		{Repeat(Repeat("a", 99)+"b", 200), Repeat("a", 100)},
+		{tm + ctm + tm, tm[:200]},
+		{tm + ctm + tm, ctm[:2000]},
+	}
+	for _, tt := range tests {
+		for _, s := range []string{tt.s, tt.s[1:], tt.s[:len(tt.s)/3]} {
+			if got, want := Count(s, tt.sep), countNaive(s, tt.sep); got != want {
+				t.Errorf("Count(%.20q (len %d), %.20q (len %d)) = %d, want %d",
+					s, len(s), tt.sep, len(tt.sep), got, want)
+			}
+			if got, want := len(Split(s, tt.sep)), countNaive(s, tt.sep)+1; got != want {
+				t.Errorf("len(Split(%.20q (len %d), %.20q (len %d))) = %d, want %d",
+					s, len(s), tt.sep, len(tt.sep), got, want)
+			}
+		}
+	}
+}
+
 var cutTests = []struct {
 	s, sep        string
 	before, after string
@@ -1654,6 +1853,9 @@ func BenchmarkIndexHard4(b *testing.B) {
 func BenchmarkLastIndexHard1(b *testing.B) { benchmarkLastIndexHard(b, "<>") }
 func BenchmarkLastIndexHard2(b *testing.B) { benchmarkLastIndexHard(b, "</pre>") }
 func BenchmarkLastIndexHard3(b *testing.B) { benchmarkLastIndexHard(b, "<b>hello world</b>") }
+func BenchmarkLastIndexHard4(b *testing.B) {
+	benchmarkLastIndexHard(b, "<pre><b>hello</b><strong>world</strong></pre>")
+}
 
 func BenchmarkCountHard1(b *testing.B) { benchmarkCountHard(b, "<>") }
 func BenchmarkCountHard2(b *testing.B) { benchmarkCountHard(b, "</pre>") }
@@ -1772,6 +1974,23 @@ func BenchmarkFieldsFunc(b *testing.B) {
 	}
 }
 
+func BenchmarkFieldsSeq(b *testing.B) {
+	for _, sd := range stringdata {
+		b.Run(sd.name, func(b *testing.B) {
+			for j := 1 << 4; j <= 1<<20; j <<= 4 {
+				b.Run(fmt.Sprintf("%d", j), func(b *testing.B) {
+					b.ReportAllocs()
+					b.SetBytes(int64(j))
+					data := sd.data[:j]
+					for i := 0; i < b.N; i++ {
+						FieldsSeq(data)(func(string) bool { return true })
+					}
+				})
+			}
+		})
+	}
+}
+
 func BenchmarkSplitEmptySeparator(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		Split(benchInputHard, "")
@@ -1790,6 +2009,18 @@ func BenchmarkSplitMultiByteSeparator(b *testing.B) {
 	}
 }
 
+func BenchmarkSplitSeqSingleByteSeparator(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		SplitSeq(benchInputHard, "/")(func(string) bool { return true })
+	}
+}
+
+func BenchmarkSplitSeqMultiByteSeparator(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		SplitSeq(benchInputHard, "hello")(func(string) bool { return true })
+	}
+}
+
 func BenchmarkSplitNSingleByteSeparator(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		SplitN(benchInputHard, "/", 10)
@@ -1815,6 +2046,25 @@ func BenchmarkRepeat(b *testing.B) {
 	}
 }
 
+func BenchmarkRepeatLarge(b *testing.B) {
+	s := Repeat("@", 8*1024)
+	for j := 8; j <= 24; j += 4 {
+		for _, k := range []int{1, 16, 4097} {
+			s := s[:k]
+			n := (1 << j) / k
+			if n == 0 {
+				continue
+			}
+			b.Run(fmt.Sprintf("%d/%d", 1<<j, k), func(b *testing.B) {
+				b.SetBytes(int64(n * len(s)))
+				for i := 0; i < b.N; i++ {
+					stringSink = Repeat(s, n)
+				}
+			})
+		}
+	}
+}
+
 func BenchmarkIndexAnyASCII(b *testing.B) {
 	x := Repeat("#", 2048) // Never matches set
 	cs := "0123456789abcdefghijklmnopqrstuvwxyz0123456789abcdefghijklmnopqrstuvwxyz"
@@ -1923,6 +2173,7 @@ func BenchmarkTrimSpace(b *testing.B) {
 		{"ASCII", "  foo bar  "},
 		{"SomeNonASCII", "    \u2000\t\r\n x\t\t\r\r\ny\n \u3000    "},
 		{"JustNonASCII", "\u2000\u2000\u2000☺☺☺☺\u3000\u3000\u3000"},
+		{"LongASCII", Repeat(" ", 100) + "foo bar" + Repeat("\t\r\n", 100)},
 	}
 	for _, test := range tests {
 		b.Run(test.name, func(b *testing.B) {
@@ -1941,3 +2192,508 @@ func BenchmarkReplaceAll(b *testing.B) {
 		stringSink = ReplaceAll("banana", "a", "<>")
 	}
 }
+
+// indexNaive is the obvious quadratic implementation of Index,
+// against which the faster algorithms are checked.
+func indexNaive(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if s[i:i+len(substr)] == substr {
+			return i
+		}
+	}
+	return -1
+}
+
+// allStrings returns every string of at most n runes over alphabet.
+func allStrings(alphabet string, n int) []string {
+	list := []string{""}
+	for prev := list; n > 0; n-- {
+		var next []string
+		for _, s := range prev {
+			for _, r := range alphabet {
+				next = append(next, s+string(r))
+			}
+		}
+		list = append(list, next...)
+		prev = next
+	}
+	return list
+}
+
+func TestIndexTwoWay(t *testing.T) {
+	// Every needle and haystack over a small alphabet exercises
+	// all of the factorizations and shifts of the algorithm.
+	needles := allStrings("ab", 6)
+	haystacks := allStrings("ab", 10)
+	if testing.Short() {
+		haystacks = allStrings("ab", 8)
+	}
+	for _, substr := range needles {
+		for _, s := range haystacks {
+			if got, want := bytealg.IndexTwoWay(s, substr), indexNaive(s, substr); got != want {
+				t.Fatalf("IndexTwoWay(%q, %q) = %d, want %d", s, substr, got, want)
+			}
+		}
+	}
+	for _, substr := range allStrings("abc", 4) {
+		for _, s := range allStrings("abc", 6) {
+			if got, want := bytealg.IndexTwoWay(s, substr), indexNaive(s, substr); got != want {
+				t.Fatalf("IndexTwoWay(%q, %q) = %d, want %d", s, substr, got, want)
+			}
+		}
+	}
+}
+
+// lastIndexNaive is the obvious quadratic implementation of LastIndex.
+func lastIndexNaive(s, substr string) int {
+	for i := len(s) - len(substr); i >= 0; i-- {
+		if s[i:i+len(substr)] == substr {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestLastIndexTwoWay(t *testing.T) {
+	needles := allStrings("ab", 6)
+	haystacks := allStrings("ab", 10)
+	if testing.Short() {
+		haystacks = allStrings("ab", 8)
+	}
+	for _, substr := range needles {
+		for _, s := range haystacks {
+			if got, want := bytealg.LastIndexTwoWay(s, substr), lastIndexNaive(s, substr); got != want {
+				t.Fatalf("LastIndexTwoWay(%q, %q) = %d, want %d", s, substr, got, want)
+			}
+		}
+	}
+	for _, substr := range allStrings("abc", 4) {
+		for _, s := range allStrings("abc", 6) {
+			if got, want := bytealg.LastIndexTwoWay(s, substr), lastIndexNaive(s, substr); got != want {
+				t.Fatalf("LastIndexTwoWay(%q, %q) = %d, want %d", s, substr, got, want)
+			}
+		}
+	}
+}
+
+func TestLastIndexLong(t *testing.T) {
+	tm, ctm := thueMorse(10)
+	tests := []struct{ s, substr string }{
+		{Repeat("a", 10000), "b" + Repeat("a", 100)},
+		{"b" + Repeat("a", 10000), "b" + Repeat("a", 100)},
+		{Repeat("a", 100) + "b" + Repeat("a", 10000), Repeat("a", 100) + "b"},
+		{Repeat("ab", 5000), Repeat("ab", 40)},
+		{Repeat("ab", 5000), Repeat("ab", 40) + "a"},
+		{Repeat("ab", 5000), "b" + Repeat("ab", 40) + "b"},
+		{tm + ctm + tm + ctm, tm[:300]},
+		{tm + ctm + tm + ctm, ctm[100:500]},
+		{tm + ctm + tm + ctm, tm[:200] + "x"},
+		{Repeat("some text here ", 1000), "some text here some"},
+	}
+	for _, tt := range tests {
+		for _, s := range []string{tt.s, tt.s[1:], tt.s[:len(tt.s)-1]} {
+			if got, want := LastIndex(s, tt.substr), lastIndexNaive(s, tt.substr); got != want {
+				t.Errorf("LastIndex(%.20q (len %d), %.20q (len %d)) = %d, want %d",
+					s, len(s), tt.substr, len(tt.substr), got, want)
+			}
+		}
+	}
+}
+
+func TestLastIndexSkip(t *testing.T) {
+	// Random strings over small alphabets make for short shifts, and
+	// so exercise the switch to the Two-Way search as well.
+	r := rand.New(rand.NewSource(1))
+	for _, alphabet := range []string{"ab", "abc", "abcdefgh", "abcdefghijklmnopqrstuvwxyz"} {
+		for iter := 0; iter < 200; iter++ {
+			b := make([]byte, 1+r.Intn(1000))
+			for i := range b {
+				b[i] = alphabet[r.Intn(len(alphabet))]
+			}
+			s := string(b)
+			for _, n := range []int{2, 3, 8, 9, 16, 31, 100, 300} {
+				if n > len(s) {
+					break
+				}
+				// A needle from s is found; a random one likely not.
+				i := r.Intn(len(s) - n + 1)
+				substrs := []string{s[i : i+n], string(b[len(b)-n:]), s[:n]}
+				rb := make([]byte, n)
+				for i := range rb {
+					rb[i] = alphabet[r.Intn(len(alphabet))]
+				}
+				substrs = append(substrs, string(rb))
+				for _, substr := range substrs {
+					want := lastIndexNaive(s, substr)
+					if got := bytealg.LastIndexSkip(s, substr); got != want {
+						t.Fatalf("LastIndexSkip(%q, %q) = %d, want %d", s, substr, got, want)
+					}
+					if got := LastIndex(s, substr); got != want {
+						t.Fatalf("LastIndex(%q, %q) = %d, want %d", s, substr, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func FuzzLastIndexTwoWay(f *testing.F) {
+	f.Add("oyoxoxoxoxoxoxoxoxoxoxox", "oy")
+	f.Add("baaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "baaaaaaaa")
+	f.Add("cbababababacbababababa", "cbababababa")
+	f.Fuzz(func(t *testing.T, s, substr string) {
+		if got, want := bytealg.LastIndexTwoWay(s, substr), lastIndexNaive(s, substr); got != want {
+			t.Errorf("LastIndexTwoWay(%q, %q) = %d, want %d", s, substr, got, want)
+		}
+		if got, want := LastIndex(s, substr), lastIndexNaive(s, substr); got != want {
+			t.Errorf("LastIndex(%q, %q) = %d, want %d", s, substr, got, want)
+		}
+	})
+}
+
+func FuzzIndexTwoWay(f *testing.F) {
+	f.Add("oxoxoxoxoxoxoxoxoxoxoxoy", "oy")
+	f.Add("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaab", "aaaaaaaab")
+	f.Add("abababababcabababababc", "ababababc")
+	f.Add("xyzzyxyzzyxyzzy", "zzyxyzzy")
+	f.Fuzz(func(t *testing.T, s, substr string) {
+		if got, want := bytealg.IndexTwoWay(s, substr), indexNaive(s, substr); got != want {
+			t.Errorf("IndexTwoWay(%q, %q) = %d, want %d", s, substr, got, want)
+		}
+		if got, want := Index(s, substr), indexNaive(s, substr); got != want {
+			t.Errorf("Index(%q, %q) = %d, want %d", s, substr, got, want)
+		}
+	})
+}
+
+func TestIndexLongPeriodicNeedle(t *testing.T) {
+	// A needle longer than bytealg.MaxLen that almost matches at
+	// every position makes Index fall back to Two-Way search.
+	substr := Repeat("a", 100) + "b"
+	s := Repeat("a", 10000) + "b"
+	if got, want := Index(s, substr), len(s)-len(substr); got != want {
+		t.Errorf("Index(a^10000 b, a^100 b) = %d, want %d", got, want)
+	}
+	if got := Index(s[:len(s)-1], substr); got != -1 {
+		t.Errorf("Index(a^10000, a^100 b) = %d, want -1", got)
+	}
+}
+
+// thueMorse returns the Thue-Morse string of length 1<<n over the bytes
+// 'a' and 'b', and its complement. Under a polynomial hash modulo a
+// power of two with an odd base, the two collide once n is large enough.
+func thueMorse(n int) (s, complement string) {
+	s, complement = "a", "b"
+	for i := 0; i < n; i++ {
+		s, complement = s+complement, complement+s
+	}
+	return s, complement
+}
+
+func TestHashStr64ThueMorse(t *testing.T) {
+	tm, ctm := thueMorse(8)
+	h32, _ := bytealg.HashStr(tm)
+	c32, _ := bytealg.HashStr(ctm)
+	if h32 != c32 {
+		t.Errorf("HashStr of 256-byte Thue-Morse string and complement differ, want collision")
+	}
+	h64, _ := bytealg.HashStr64(tm)
+	c64, _ := bytealg.HashStr64(ctm)
+	if h64 == c64 {
+		t.Errorf("HashStr64 of 256-byte Thue-Morse string and complement = %#x, want different", h64)
+	}
+	h64, _ = bytealg.HashStrRev64(tm)
+	c64, _ = bytealg.HashStrRev64(ctm)
+	if h64 == c64 {
+		t.Errorf("HashStrRev64 of 256-byte Thue-Morse string and complement = %#x, want different", h64)
+	}
+}
+
+func TestIndexRabinKarp64(t *testing.T) {
+	for _, substr := range allStrings("ab", 5) {
+		if substr == "" {
+			continue
+		}
+		for _, s := range allStrings("ab", 8) {
+			if len(s) < len(substr) {
+				continue
+			}
+			if got, want := bytealg.IndexRabinKarp64(s, substr), indexNaive(s, substr); got != want {
+				t.Fatalf("IndexRabinKarp64(%q, %q) = %d, want %d", s, substr, got, want)
+			}
+		}
+	}
+
+	// Windows whose 32-bit hash equals the needle's must be rejected.
+	tm, ctm := thueMorse(8)
+	s := Repeat("x"+ctm, 8) + "x" + tm + "x" + ctm
+	if got, want := bytealg.IndexRabinKarp64(s, tm), 8*len(ctm)+9; got != want {
+		t.Errorf("IndexRabinKarp64(Thue-Morse) = %d, want %d", got, want)
+	}
+	if got, want := LastIndex(s, tm), 8*len(ctm)+9; got != want {
+		t.Errorf("LastIndex(Thue-Morse) = %d, want %d", got, want)
+	}
+}
+
+// BenchmarkIndexHashCollision searches for a needle ending in a
+// Thue-Morse string in a haystack made of copies of the needle with
+// that suffix complemented. Each copy has the same 32-bit Rabin-Karp
+// hash as the needle but differs from it only at the end.
+func BenchmarkIndexHashCollision(b *testing.B) {
+	tm, ctm := thueMorse(8)
+	prefix := Repeat("x", 1024)
+	substr := prefix + tm
+	s := Repeat(prefix+ctm, 64)
+	b.Run("IndexRabinKarp", func(b *testing.B) {
+		b.SetBytes(int64(len(s)))
+		for i := 0; i < b.N; i++ {
+			bytealg.IndexRabinKarp(s, substr)
+		}
+	})
+	b.Run("IndexRabinKarp64", func(b *testing.B) {
+		b.SetBytes(int64(len(s)))
+		for i := 0; i < b.N; i++ {
+			bytealg.IndexRabinKarp64(s, substr)
+		}
+	})
+	b.Run("LastIndex", func(b *testing.B) {
+		b.SetBytes(int64(len(s)))
+		for i := 0; i < b.N; i++ {
+			LastIndex(s, substr)
+		}
+	})
+}
+
+var indexFoldTests = []struct {
+	s, substr string
+	out       int
+}{
+	{"", "", 0},
+	{"", "a", -1},
+	{"abc", "", 0},
+	{"Hello, World", "world", 7},
+	{"Hello, World", "WORLD", 7},
+	{"Hello, World", "o, w", 4},
+	{"Hello, World", "worlds", -1},
+	{"xXxX", "X", 0},
+	{"xyzXYZ", "Z", 2},
+	{"@", "`", -1},
+	{"[", "{", -1},
+	{"abc@def", "C`D", -1},
+	{"GOPHER", "gopher", 0},
+	{"kelvin: K", "k", 0},
+	{"temp Kelvin", "kelvin", 5},
+	{"temp Kelvin", "KELVIN", 5},
+	{"temp kelvin", "Kelvin", 5},
+	{"miſter", "mister", 0},
+	{"xxſ", "S", 2},
+	{"straße", "STRASSE", -1}, // ß does not simple-fold to ss
+	{"ΑΒΓ σίγμα", "ΣΊΓΜΑ", 7},
+	{"ΑΒΓ σίγμα", "αβγ", 0},
+	{"φιλοσοφία", "ΣΟΦ", 8},
+	{"K", "k", 0},
+	{"k", "K", 0},
+	{Repeat("a", 100) + "B", Repeat("A", 20) + "b", 80},
+	{Repeat("ab", 100), "ABA", 0},
+	{Repeat("ab", 100) + "c", "BaBC", 197},
+}
+
+func TestIndexFold(t *testing.T) {
+	for _, tt := range indexFoldTests {
+		if got := IndexFold(tt.s, tt.substr); got != tt.out {
+			t.Errorf("IndexFold(%q, %q) = %d, want %d", tt.s, tt.substr, got, tt.out)
+		}
+	}
+}
+
+// indexFoldNaive is the obvious implementation of IndexFold
+// in terms of EqualFold, for valid UTF-8 s.
+func indexFoldNaive(s, substr string) int {
+	for i := range s {
+		for j := i; j <= len(s); j++ {
+			if EqualFold(s[i:j], substr) {
+				return i
+			}
+		}
+	}
+	if substr == "" {
+		return 0
+	}
+	return -1
+}
+
+func TestIndexFoldExhaustive(t *testing.T) {
+	for _, substr := range allStrings("aAB@`", 3) {
+		for _, s := range allStrings("abA`", 6) {
+			if got, want := IndexFold(s, substr), indexFoldNaive(s, substr); got != want {
+				t.Fatalf("IndexFold(%q, %q) = %d, want %d", s, substr, got, want)
+			}
+		}
+	}
+	for _, substr := range allStrings("ksK", 2) {
+		for _, s := range allStrings("KsſK", 4) {
+			if got, want := IndexFold(s, substr), indexFoldNaive(s, substr); got != want {
+				t.Fatalf("IndexFold(%q, %q) = %d, want %d", s, substr, got, want)
+			}
+		}
+	}
+}
+
+func TestIndexFoldPairString(t *testing.T) {
+	// Exercise each alignment of a match, and its absence,
+	// at each length around the vector width.
+	for n := 0; n < 70; n++ {
+		for i := -1; i < n-1; i++ {
+			b := []byte(Repeat("x", n))
+			if i >= 0 {
+				b[i], b[i+1] = 'A', 'b'
+			}
+			s := string(b)
+			if got := bytealg.IndexFoldPairString(s, 'a', 'B'); got != i {
+				t.Fatalf("IndexFoldPairString(%q, 'a', 'B') = %d, want %d", s, got, i)
+			}
+		}
+	}
+}
+
+func FuzzIndexFold(f *testing.F) {
+	f.Add("Hello, World", "o, W")
+	f.Add("temp Kelvin", "kelvin")
+	f.Add("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaab", "AAAAAAAAB")
+	f.Fuzz(func(t *testing.T, s, substr string) {
+		if !utf8.ValidString(s) || !utf8.ValidString(substr) || len(s) > 64 {
+			t.Skip()
+		}
+		if got, want := IndexFold(s, substr), indexFoldNaive(s, substr); got != want {
+			t.Errorf("IndexFold(%q, %q) = %d, want %d", s, substr, got, want)
+		}
+	})
+}
+
+func BenchmarkIndexFold(b *testing.B) {
+	s := Repeat("The Quick Brown Fox Jumps Over The Lazy Dog. ", 100)
+	for _, bm := range []struct{ name, substr string }{
+		{"ASCII", "LAZY DOG!"},
+		{"ASCIIKelvin", "JUMPS OVER THE LAZY CAT"},
+		{"Unicode", "ΣΊΓΜΑ"},
+		{"ToLower", ""},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(int64(len(s)))
+			for i := 0; i < b.N; i++ {
+				if bm.name == "ToLower" {
+					Index(ToLower(s), ToLower("LAZY DOG!"))
+				} else {
+					IndexFold(s, bm.substr)
+				}
+			}
+		})
+	}
+	b.Run("Periodic", func(b *testing.B) {
+		s := Repeat("a", 1<<12)
+		substr := Repeat("A", 20) + "B"
+		b.SetBytes(int64(len(s)))
+		for i := 0; i < b.N; i++ {
+			IndexFold(s, substr)
+		}
+	})
+}
+
+func TestIndexAnyASCIISet(t *testing.T) {
+	// The background bytes share a low or high nibble with
+	// the members of the set, or are not ASCII.
+	const chars = "/?#:"
+	const background = "O_\x8f\xaf\x0f\x3b\xbf"
+	naive := func(s string, last bool) int {
+		r := -1
+		for i := 0; i < len(s); i++ {
+			if ContainsRune(chars, rune(s[i])) {
+				if !last {
+					return i
+				}
+				r = i
+			}
+		}
+		return r
+	}
+	for n := 0; n < 70; n++ {
+		for i := -1; i < n; i++ {
+			b := make([]byte, n)
+			for j := range b {
+				b[j] = background[j%len(background)]
+			}
+			if i >= 0 {
+				b[i] = chars[i%len(chars)]
+				if i+5 < n {
+					b[i+5] = chars[(i+1)%len(chars)]
+				}
+			}
+			s := string(b)
+			if got, want := IndexAny(s, chars), naive(s, false); got != want {
+				t.Fatalf("IndexAny(%q, %q) = %d, want %d", s, chars, got, want)
+			}
+			if got, want := LastIndexAny(s, chars), naive(s, true); got != want {
+				t.Fatalf("LastIndexAny(%q, %q) = %d, want %d", s, chars, got, want)
+			}
+		}
+	}
+}
+
+func TestIndexWide(t *testing.T) {
+	if bytealg.MaxWideLen == 0 {
+		t.Skip("IndexWide not available")
+	}
+	r := rand.New(rand.NewSource(1))
+	randString := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = "ab"[r.Intn(2)]
+		}
+		return string(b)
+	}
+	for n := 2; n <= bytealg.MaxWideLen; n++ {
+		for iter := 0; iter < 100; iter++ {
+			s := randString(n + r.Intn(200))
+			var substr string
+			if iter%2 == 0 {
+				// Some substring of s, most likely found early.
+				i := r.Intn(len(s) - n + 1)
+				substr = s[i : i+n]
+			} else {
+				// Most likely not found, but matching the
+				// first and last bytes at many positions.
+				substr = s[:1] + randString(n-2) + s[len(s)-1:]
+			}
+			if got, want := bytealg.IndexWideString(s, substr), indexNaive(s, substr); got != want {
+				t.Fatalf("IndexWideString(%q, %q) = %d, want %d", s, substr, got, want)
+			}
+		}
+	}
+
+	// Index uses IndexWide for long strings.
+	s := Repeat("a", 2*bytealg.MinWideSearch)
+	for _, n := range []int{2, 31, 32, 33, bytealg.MaxWideLen} {
+		substr := Repeat("a", n-1) + "b"
+		for _, i := range []int{0, 1, 63, 64, 65, len(s) - 65, len(s) - n} {
+			s := s[:i] + substr + s[i+n:]
+			if got := Index(s, substr); got != i {
+				t.Errorf("Index(a^%d b a^%d, a^%d b) = %d, want %d", i+n-1, len(s)-i-n, n-1, got, i)
+			}
+		}
+		if got := Index(s, substr); got != -1 {
+			t.Errorf("Index(a^%d, a^%d b) = %d, want -1", len(s), n-1, got)
+		}
+	}
+}
+
+func BenchmarkIndexWide(b *testing.B) {
+	s := Repeat("2006-01-02T15:04:05Z INFO request served path=/api/v1/items status=200\n", 1<<10)
+	for _, substr := range []string{"status=500", "ERROR", "path=/api/v2/items status=404"} {
+		b.Run(fmt.Sprintf("%d", len(substr)), func(b *testing.B) {
+			b.SetBytes(int64(len(s)))
+			for i := 0; i < b.N; i++ {
+				Index(s, substr)
+			}
+		})
+	}
+}
@@ -7,6 +7,8 @@
 // See https://en.wikipedia.org/wiki/UTF-8
 package utf8
 
+import "internal/bytealg"
+
 // The conditions RuneError==unicode.ReplacementChar and
 // MaxRune==unicode.MaxRune are verified in the tests.
 // Defining them locally avoids this package depending on package unicode.
@@ -480,6 +482,9 @@ func Valid(p []byte) bool {
 	// ValidString, which was 20% faster on long ASCII strings.
 	p = p[:len(p):len(p)]
 
+	// Skip a prefix known to be valid, checked a block at a time.
+	p = p[bytealg.ValidUTF8Prefix(p):]
+
 	// Fast path. Check for and skip 8 bytes of ASCII characters per iteration.
 	for len(p) >= 8 {
 		// Combining two 32 bit loads allows the same code to be used
@@ -526,6 +531,9 @@ func Valid(p []byte) bool {
 
 // ValidString reports whether s consists entirely of valid UTF-8-encoded runes.
 func ValidString(s string) bool {
+	// Skip a prefix known to be valid, checked a block at a time.
+	s = s[bytealg.ValidUTF8PrefixString(s):]
+
 	// Fast path. Check for and skip 8 bytes of ASCII characters per iteration.
 	for len(s) >= 8 {
 		// Combining two 32 bit loads allows the same code to be used
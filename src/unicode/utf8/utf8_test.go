@@ -501,6 +501,69 @@ func TestValid(t *testing.T) {
 	}
 }
 
+// validNaive reports whether p is valid UTF-8 by decoding each rune.
+func validNaive(p []byte) bool {
+	for len(p) > 0 {
+		r, size := DecodeRune(p)
+		if r == RuneError && size == 1 {
+			return false
+		}
+		p = p[size:]
+	}
+	return true
+}
+
+func TestValidBlocks(t *testing.T) {
+	// Put sequences of bytes chosen around the boundaries of UTF-8
+	// encoding at each offset across several blocks of 16 bytes,
+	// after ASCII and after multi-byte runes, which Valid checks
+	// a block at a time on some platforms.
+	edges := []byte{0x00, 0x7f, 0x80, 0x8f, 0x90, 0x9f, 0xa0, 0xbf, 0xc0, 0xc1, 0xc2, 0xdf,
+		0xe0, 0xe1, 0xec, 0xed, 0xee, 0xef, 0xf0, 0xf1, 0xf3, 0xf4, 0xf5, 0xf7, 0xf8, 0xff}
+	var seqs [][]byte
+	for i := 0; i < 256; i++ {
+		for j := 0; j < 256; j++ {
+			seqs = append(seqs, []byte{byte(i), byte(j)})
+		}
+	}
+	for _, a := range edges {
+		for _, b := range edges {
+			for _, c := range edges {
+				seqs = append(seqs, []byte{a, b, c})
+				for _, d := range edges {
+					if a >= 0xe0 {
+						seqs = append(seqs, []byte{a, b, c, d})
+					}
+				}
+			}
+		}
+	}
+	offsets := []int{0, 13, 14, 15, 16, 31, 45}
+	if testing.Short() {
+		offsets = []int{15, 45}
+	}
+	for _, background := range []string{strings.Repeat("a", 48), strings.Repeat("\u00e9", 24), strings.Repeat("\u65e5", 16)} {
+		p := make([]byte, len(background)+4)
+		for _, off := range offsets {
+			for _, seq := range seqs {
+				copy(p, background)
+				copy(p[off:], seq)
+				q := p[:len(background)]
+				if off+len(seq) > len(q) {
+					q = p[:off+len(seq)]
+				}
+				want := validNaive(q)
+				if got := Valid(q); got != want {
+					t.Fatalf("Valid(%q) = %v, want %v", q, got, want)
+				}
+				if got := ValidString(string(q)); got != want {
+					t.Fatalf("ValidString(%q) = %v, want %v", q, got, want)
+				}
+			}
+		}
+	}
+}
+
 type ValidRuneTest struct {
 	r  rune
 	ok bool